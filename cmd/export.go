@@ -0,0 +1,101 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package cmd ...
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/client"
+)
+
+type exportCommand struct {
+	cmd *cobra.Command
+}
+
+var exportCmd = &exportCommand{}
+
+func init() {
+	exportCmd.Init()
+}
+
+// Init ...
+func (e *exportCommand) Init() {
+	cmd := &cobra.Command{
+		Use:          "export",
+		Short:        "export the resources in apisix to an archive",
+		SilenceUsage: true,
+		PreRun:       preRun,
+		RunE:         e.RunE,
+	}
+
+	cmd.Flags().String("gateway_name", "", "only export the stages of the gateway")
+	cmd.Flags().String("stage_name", "", "only export the stage, use with gateway_name")
+	cmd.Flags().Bool("redact", false, "replace the secrets in the archive, a redacted archive can only be imported in dry-run")
+	cmd.Flags().StringP("output", "o", "", "archive file to write (default is stdout)")
+
+	cmd.Flags().StringVarP(&cfgFile, "config", "c", "", "config file (default is config.yml;required)")
+	cmd.PersistentFlags().Bool("viper", true, "Use Viper for configuration")
+
+	_ = cmd.MarkFlagRequired("config")
+	viper.SetDefault("author", "blueking-paas")
+
+	rootCmd.AddCommand(cmd)
+	e.cmd = cmd
+}
+
+// RunE ...
+func (e *exportCommand) RunE(cmd *cobra.Command, args []string) error {
+	initClient()
+
+	cli, err := client.GetLeaderResourceClient(globalConfig.HttpServer.AuthPassword)
+	if err != nil {
+		logger.Infow("GetLeaderResourcesClient failed", "err", err)
+		return err
+	}
+	if cli == nil {
+		logger.Error(err, "GetLeaderResourcesClient failed")
+		return err
+	}
+
+	gatewayName, _ := cmd.Flags().GetString("gateway_name")
+	stageName, _ := cmd.Flags().GetString("stage_name")
+	redact, _ := cmd.Flags().GetBool("redact")
+	output, _ := cmd.Flags().GetString("output")
+
+	resp, err := cli.ApisixExport(&client.ApisixExportRequest{
+		GatewayName: gatewayName,
+		StageName:   stageName,
+		Redact:      redact,
+	})
+	if err != nil {
+		logger.Error(err, "apisix export request failed")
+		return err
+	}
+	if output == "" {
+		fmt.Println(string(resp))
+		return nil
+	}
+	// 归档中可能包含证书私钥等敏感信息, 只允许当前用户读写
+	return os.WriteFile(output, resp, 0o600)
+}
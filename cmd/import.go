@@ -0,0 +1,106 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package cmd ...
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/client"
+)
+
+type importCommand struct {
+	cmd *cobra.Command
+}
+
+var importCmd = &importCommand{}
+
+func init() {
+	importCmd.Init()
+}
+
+// Init ...
+func (i *importCommand) Init() {
+	cmd := &cobra.Command{
+		Use:          "import",
+		Short:        "restore the resources in an archive to apisix",
+		SilenceUsage: true,
+		PreRun:       preRun,
+		RunE:         i.RunE,
+	}
+
+	cmd.Flags().StringP("file", "f", "", "archive file created by the export command")
+	cmd.Flags().String("gateway_name", "", "only restore the stages of the gateway")
+	cmd.Flags().String("stage_name", "", "only restore the stage, use with gateway_name")
+	cmd.Flags().String("key_prefix", "", "restore to another etcd key prefix (default is the prefix of the operator)")
+	cmd.Flags().Bool("dry-run", false, "only show the changes that the restore would make")
+	_ = cmd.MarkFlagRequired("file")
+
+	cmd.Flags().StringVarP(&cfgFile, "config", "c", "", "config file (default is config.yml;required)")
+	cmd.PersistentFlags().Bool("viper", true, "Use Viper for configuration")
+
+	_ = cmd.MarkFlagRequired("config")
+	viper.SetDefault("author", "blueking-paas")
+
+	rootCmd.AddCommand(cmd)
+	i.cmd = cmd
+}
+
+// RunE ...
+func (i *importCommand) RunE(cmd *cobra.Command, args []string) error {
+	initClient()
+
+	file, _ := cmd.Flags().GetString("file")
+	content, err := os.ReadFile(file)
+	if err != nil {
+		logger.Error(err, "read archive file failed")
+		return err
+	}
+
+	cli, err := client.GetLeaderResourceClient(globalConfig.HttpServer.AuthPassword)
+	if err != nil {
+		logger.Infow("GetLeaderResourcesClient failed", "err", err)
+		return err
+	}
+	if cli == nil {
+		logger.Error(err, "GetLeaderResourcesClient failed")
+		return err
+	}
+
+	gatewayName, _ := cmd.Flags().GetString("gateway_name")
+	stageName, _ := cmd.Flags().GetString("stage_name")
+	keyPrefix, _ := cmd.Flags().GetString("key_prefix")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	resp, err := cli.ApisixImport(&client.ApisixImportRequest{
+		Archive:     content,
+		GatewayName: gatewayName,
+		StageName:   stageName,
+		KeyPrefix:   keyPrefix,
+		DryRun:      dryRun,
+	})
+	if err != nil {
+		logger.Error(err, "apisix import request failed")
+		return err
+	}
+	return printJson(resp)
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/client"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/agent"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/committer"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/eventreporter"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
@@ -141,4 +142,5 @@ func initClient() {
 func initOperator() {
 	synchronizer.Init(globalConfig)
 	agent.Init(globalConfig)
+	committer.Init(globalConfig)
 }
@@ -145,8 +145,14 @@ func (l *listApisixCommand) formatOutput(apisixListInfo client.ApisixListInfo, f
 			fmt.Printf("Stage: %s\n", stage)
 			l.printResource("Routes", listResources.Routes)
 			l.printResource("Services", listResources.Services)
+			l.printResource("Upstreams", listResources.Upstreams)
+			l.printResource("PluginConfigs", listResources.PluginConfigs)
+			l.printResource("Consumers", listResources.Consumers)
+			l.printResource("ConsumerGroups", listResources.ConsumerGroups)
 			l.printResource("PluginMetadatas", listResources.PluginMetadata)
 			l.printResource("SSLs", listResources.Ssl)
+			l.printResource("StreamRoutes", listResources.StreamRoutes)
+			l.printResource("Protos", listResources.Protos)
 		}
 	}
 	return nil
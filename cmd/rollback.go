@@ -0,0 +1,111 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package cmd ...
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/client"
+)
+
+type rollbackCommand struct {
+	cmd *cobra.Command
+}
+
+var rollbackCmd = &rollbackCommand{}
+
+func init() {
+	rollbackCmd.Init()
+}
+
+// Init ...
+func (r *rollbackCommand) Init() {
+	cmd := &cobra.Command{
+		Use:          "rollback",
+		Short:        "rollback a stage in apisix to a previous release snapshot",
+		SilenceUsage: true,
+		PreRun:       preRun,
+		RunE:         r.RunE,
+	}
+
+	cmd.Flags().String("gateway_name", "", "gateway name for rollback command")
+	cmd.Flags().String("stage_name", "", "stage name for rollback command")
+	cmd.Flags().String("publish_id", "", "publish ID of the snapshot to rollback to")
+	cmd.Flags().Bool("list", false, "list the snapshots of the stage instead of rollback")
+	_ = cmd.MarkFlagRequired("gateway_name")
+	_ = cmd.MarkFlagRequired("stage_name")
+	cmd.MarkFlagsOneRequired("publish_id", "list")
+	cmd.MarkFlagsMutuallyExclusive("publish_id", "list")
+
+	cmd.Flags().StringVarP(&cfgFile, "config", "c", "", "config file (default is config.yml;required)")
+	cmd.PersistentFlags().Bool("viper", true, "Use Viper for configuration")
+
+	_ = cmd.MarkFlagRequired("config")
+	viper.SetDefault("author", "blueking-paas")
+
+	rootCmd.AddCommand(cmd)
+	r.cmd = cmd
+}
+
+// RunE ...
+func (r *rollbackCommand) RunE(cmd *cobra.Command, args []string) error {
+	initClient()
+
+	cli, err := client.GetLeaderResourceClient(globalConfig.HttpServer.AuthPassword)
+	if err != nil {
+		logger.Infow("GetLeaderResourcesClient failed", "err", err)
+		return err
+	}
+	if cli == nil {
+		logger.Error(err, "GetLeaderResourcesClient failed")
+		return err
+	}
+
+	gatewayName, _ := cmd.Flags().GetString("gateway_name")
+	stageName, _ := cmd.Flags().GetString("stage_name")
+	publishID, _ := cmd.Flags().GetString("publish_id")
+	list, _ := cmd.Flags().GetBool("list")
+
+	req := &client.ApisixSnapshotRequest{
+		GatewayName: gatewayName,
+		StageName:   stageName,
+		PublishID:   publishID,
+	}
+	// 查询指定环境的发布快照
+	if list {
+		resp, err := cli.ApisixSnapshotList(req)
+		if err != nil {
+			logger.Error(err, "apisix snapshot list request failed")
+			return err
+		}
+		return printJson(resp)
+	}
+	// 回滚到指定发布版本的快照
+	resp, err := cli.ApisixSnapshotRollback(req)
+	if err != nil {
+		logger.Error(err, "apisix rollback request failed")
+		return err
+	}
+	fmt.Printf("rollback %s/%s to publish_id: %s\n", gatewayName, stageName, resp.PublishID)
+	return nil
+}
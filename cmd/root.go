@@ -72,7 +72,7 @@ func rootRun(cmd *cobra.Command, args []string) {
 	// start event reporter
 	eventreporter.Start(rootCtx)
 
-	// 只支持etcd模式，直接使用etcd运行器
+	// 资源来源 (etcd、目录、kubernetes) 由 dashboard.source 配置, 运行器按来源创建 registry 和选主
 	agentRunner := runner.NewEtcdAgentRunner(rootCtx, globalConfig)
 	defer agentRunner.Close()
 	agentRunner.Run(rootCtx)
@@ -3,23 +3,143 @@ debug: true
 operator:
   defaultGateway: "bk-default"
   defaultStage: "default"
-  #write apisix etcd interval
+  # max number of stages written to apisix etcd in parallel, writes of the same stage are always serial
+  agentConcurrencyLimit: 4
+  # apisix etcd resources are applied in dependency order, and each step waits for the watch to see the previous one;
+  # these are the max waiting time after putting dependencies / deleting dependents
   etcdPutInterval: "100ms"
   etcdDelInterval: "15s"
+  # compare apigw etcd with apisix etcd periodically (disabled by default), re-apply the desired config if heal is true
+  driftReconcile:
+    enable: false
+    interval: "5m"
+    jitter: "30s"
+    heal: false
+  # find the stages left in apisix etcd without a release, and delete them after the grace period if delete is true
+  orphanCollect:
+    enable: true
+    interval: "10m"
+    gracePeriod: "1h"
+    delete: false
+  # retry the failed stage commits with exponential backoff, move them to the dead letter list after maxAttempts;
+  # the pending retries and dead letters are kept in apisix etcd under keyPrefix and reloaded by the next leader
+  commitRetry:
+    maxAttempts: 5
+    baseDelay: "2s"
+    maxDelay: "5m"
+    # wait at most targetWaitTimeout for the other apisix targets, the slower writes go on in background
+    targetWaitTimeout: "5s"
+    keyPrefix: "/bk-gateway-operator/commit-retries"
+  # shadow mode for verifying a new operator against the production events without touching the live apisix:
+  # write to keyPrefix if set, otherwise only record the diffs; use GET /v1/open/shadow/report/ to compare with live
+  shadow:
+    enable: false
+    keyPrefix: ""
+  # keep the last maxCount applied configs of each stage in apisix etcd, see /v1/open/apisix/snapshots/;
+  # autoRollback rolls the stage back to the previous snapshot when the version probe fails or times out
+  snapshot:
+    enable: false
+    keyPrefix: "/bk-gateway-operator/snapshots"
+    maxCount: 10
+    autoRollback: false
+  # export the apisix state into dir periodically and keep the latest retention archives,
+  # the archives can be restored with the import command or POST /v1/open/apisix/import/
+  # redacted archives can only be restored with dry-run
+  export:
+    enable: false
+    interval: "1h"
+    dir: "/data/bkgateway/archives"
+    retention: 24
+    redact: false
 
 dashboard:
+  # etcd: read the releases from the dashboard etcd; directory: read the same layout from a tree of json files,
+  # e.g. {path}/v2/gateway/{gateway}/{stage}/route/{id}.json, for local development and air-gapped installs,
+  # publishing is just dropping the files into place; the directory source runs without leader election;
+  # kubernetes: read the labeled configmaps, one configmap holds the resources of a stage (labels
+  # gateway.bk.tencent.com/gateway and gateway.bk.tencent.com/stage) or the global resources (neither label),
+  # the data keys are {kind}.{id}.json, e.g. route.gw.prod.1.json, _bk_release.bk.release.gw.prod.json;
+  # the leader is elected by a kubernetes lease, the service account needs list/watch configmaps and
+  # get/create/update leases in the namespace;
+  # core_api: pull the latest release of each stage from the core api (eventReporter.coreAPIHost with the auth),
+  # for the network-segmented environments without access to the dashboard etcd, only the stages with a new
+  # publish id are downloaded; the core_api source runs without leader election
+  source: "etcd"
   etcd:
     endpoints: "bk-apigateway-etcd:2379"
     keyPrefix: "/bk-gateway-apigw/default"
     username: "root"
     password: "blueking"
+  # other control planes watched together with etcd (the origin "default"), etcd source only; a stage is owned by
+  # the origin that publishes it first, the same stage published by other origins is refused until the owner
+  # deletes it; a stage already published by multiple origins at startup has no owner and is refused for all of
+  # them until only one origin publishes it, see GET /v1/open/apigw/origins/; the endpoints default to the etcd
+  # above, the leader is elected on the etcd above for all origins
+  # origins:
+  #   - name: "edge"
+  #     etcd:
+  #       keyPrefix: "/bk-gateway-apigw/edge"
+  #   - name: "other-region"
+  #     etcd:
+  #       endpoints: "other-region-etcd:2379"
+  #       keyPrefix: "/bk-gateway-apigw/default"
+  #       username: "root"
+  #       password: "blueking"
+  # directory:
+  #   path: "/data/bk-gateway-apigw"
+  #   interval: 1s
+  # kubernetes:
+  #   # default to the env BK_GATEWAY_POD_NAMESPACE
+  #   namespace: "blueking"
+  #   labelSelector: "gateway.bk.tencent.com/operator-source=true"
+  #   interval: 1s
+  #   leaseName: "bk-gateway-operator-leader"
+  # coreAPI:
+  #   interval: 5s
+  #   timeout: 10s
+  #   # retry after backoff on failure, doubled each time up to maxBackoff, the cached resources are used meanwhile
+  #   backoff: 5s
+  #   maxBackoff: 2m
 
 apisix:
+  # etcd: write to the apisix etcd directly; admin_api: write by the apisix admin api with the api key,
+  # for the deployments without the apisix etcd credentials; standalone: render apisix.yaml for apisix
+  # running with `config_provider: yaml`; shadow mode and snapshot are only supported by etcd
+  backend: "etcd"
   etcd:
     endpoints: "bk-apigateway-etcd:2379"
     keyPrefix: "/bk-gateway-apisix"
     username: "root"
     password: "blueking"
+  adminAPI:
+    addr: "http://bk-apigateway-apigateway:9180"
+    apiKey: ""
+    timeout: 10s
+    # interval of listing all the resources to refresh the local cache
+    refreshInterval: 30s
+  standalone:
+    path: "/usr/local/apisix/conf/apisix.yaml"
+    # write one more <gatewayDir>/<gateway>.yaml for each gateway if not empty
+    gatewayDir: ""
+  # name of the apisix cluster above; targets are the other apisix clusters (e.g. in other availability zones)
+  # that every stage is synchronized to, a failing target is retried in background without blocking the others,
+  # see GET /v1/open/apisix/targets/ for the sync status of each target
+  name: "default"
+  targets: []
+  #  - name: "zone-b"
+  #    backend: "etcd"
+  #    etcd:
+  #      endpoints: "bk-apigateway-etcd-zone-b:2379"
+  #      keyPrefix: "/bk-gateway-apisix"
+  #      username: "root"
+  #      password: "blueking"
+  # placement rules route the stages to the apisix clusters above, the first matched rule wins and the stages
+  # matching no rule are synchronized to all clusters; resources are cleaned up when the placement of a stage moves
+  placement: []
+  #  - gateway: "dedicated-*"
+  #    stage: ""
+  #    labels: ["apisix-version=3.13.X"]
+  #    targets: ["zone-b"]
 
   virtualStage:
     extraApisixResources: "/data/config/extra-resources.yaml"
@@ -28,6 +28,7 @@ import (
 
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/apis/open/serializer"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/biz"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils"
 )
 
@@ -68,6 +69,7 @@ func (r *ResourceHandler) ApigwList(c *gin.Context) {
 			return
 		}
 		_ = json.Unmarshal(by, &resp)
+		redactConsumers(resp)
 		utils.SuccessJSONResponse(c, resp)
 		return
 	}
@@ -92,6 +94,7 @@ func (r *ResourceHandler) ApigwList(c *gin.Context) {
 		return
 	}
 	_ = json.Unmarshal(by, &resp)
+	redactConsumers(resp)
 	utils.SuccessJSONResponse(c, resp)
 }
 
@@ -136,3 +139,12 @@ func (r *ResourceHandler) ApigwStageCurrentVersion(c *gin.Context) {
 	output := serializer.ApigwListCurrentVersionInfoResponse(versionInfo)
 	utils.SuccessJSONResponse(c, output)
 }
+
+// ApigwOriginList 各控制面的环境数量、事件和被拒绝的环境, 只 watch 一个控制面时返回空列表
+func (r *ResourceHandler) ApigwOriginList(c *gin.Context) {
+	output := serializer.ApigwOriginListResponse{}
+	if multiRegistry, ok := r.apigwEtcdRegistry.(*registry.APIGWMultiRegistry); ok {
+		output = multiRegistry.OriginStatus()
+	}
+	utils.SuccessJSONResponse(c, output)
+}
@@ -67,6 +67,7 @@ func (r *ResourceHandler) ApisixList(c *gin.Context) {
 			return
 		}
 		_ = json.Unmarshal(by, &resp)
+		redactConsumers(resp)
 		utils.SuccessJSONResponse(c, resp)
 		return
 	}
@@ -82,6 +83,7 @@ func (r *ResourceHandler) ApisixList(c *gin.Context) {
 		return
 	}
 	_ = json.Unmarshal(by, &resp)
+	redactConsumers(resp)
 	utils.SuccessJSONResponse(c, resp)
 }
 
@@ -126,3 +128,25 @@ func (r *ResourceHandler) ApisixStageCurrentVersion(c *gin.Context) {
 	output := serializer.ApisixListCurrentVersionInfoResponse(versionInfo)
 	utils.SuccessJSONResponse(c, output)
 }
+
+// ApisixOrphanList 查询 apisix 中没有对应发布信息的孤儿环境
+func (r *ResourceHandler) ApisixOrphanList(c *gin.Context) {
+	orphans, err := r.orphanCollector.ListOrphans(c)
+	if err != nil {
+		utils.BaseErrorJSONResponse(
+			c,
+			utils.SystemError,
+			fmt.Sprintf("apisix orphan list err:%+v", err.Error()),
+			http.StatusOK,
+		)
+		return
+	}
+	output := serializer.ApisixOrphanListResponse(orphans)
+	utils.SuccessJSONResponse(c, output)
+}
+
+// ApisixTargetList apisix 集群的同步状态, 包括同步失败或等待重试的环境
+func (r *ResourceHandler) ApisixTargetList(c *gin.Context) {
+	output := serializer.ApisixTargetListResponse(r.synchronizer.TargetStatus())
+	utils.SuccessJSONResponse(c, output)
+}
@@ -0,0 +1,84 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package handler  ...
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/apis/open/serializer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/archive"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils"
+)
+
+// ApisixExport 导出 apisix 当前的配置
+func (r *ResourceHandler) ApisixExport(c *gin.Context) {
+	var req serializer.ApisixExportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.BadRequestErrorJSONResponse(c, utils.ValidationErrorMessage(err))
+		return
+	}
+	output, err := r.archiver.Export(archive.ExportOptions{
+		Filter: archive.Filter{Gateway: req.GatewayName, Stage: req.StageName},
+		Redact: req.Redact,
+	})
+	if err != nil {
+		utils.BaseErrorJSONResponse(c, utils.SystemError, fmt.Sprintf("export err:%+v", err.Error()), http.StatusOK)
+		return
+	}
+	utils.SuccessJSONResponse(c, output)
+}
+
+// ApisixImport 将归档恢复到 apisix
+func (r *ResourceHandler) ApisixImport(c *gin.Context) {
+	var req serializer.ApisixImportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.BadRequestErrorJSONResponse(c, utils.ValidationErrorMessage(err))
+		return
+	}
+	if len(req.Archive) == 0 {
+		utils.BadRequestErrorJSONResponse(c, "archive is required")
+		return
+	}
+	input, err := archive.Decode(bytes.NewReader(req.Archive))
+	if err != nil {
+		utils.BadRequestErrorJSONResponse(c, fmt.Sprintf("import err:%+v", err.Error()))
+		return
+	}
+	output, err := r.archiver.Import(c, input, archive.ImportOptions{
+		Filter: archive.Filter{Gateway: req.GatewayName, Stage: req.StageName},
+		Prefix: req.KeyPrefix,
+		DryRun: req.DryRun,
+	})
+	if err != nil {
+		message := fmt.Sprintf("import err:%+v", err.Error())
+		if errors.Is(err, archive.ErrRedactedArchive) || errors.Is(err, archive.ErrPrefixNotSupported) {
+			utils.BadRequestErrorJSONResponse(c, message)
+			return
+		}
+		utils.BaseErrorJSONResponse(c, utils.SystemError, message, http.StatusOK)
+		return
+	}
+	utils.SuccessJSONResponse(c, output)
+}
@@ -0,0 +1,58 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package handler  ...
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/apis/open/serializer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils"
+)
+
+// CommitDeadLetterList 查询超过最大重试次数仍然提交失败的环境
+func (r *ResourceHandler) CommitDeadLetterList(c *gin.Context) {
+	output := serializer.CommitDeadLetterListResponse(r.committer.ListDeadLetters())
+	utils.SuccessJSONResponse(c, output)
+}
+
+// CommitDeadLetterRedrive 重新提交一个或者所有死信
+func (r *ResourceHandler) CommitDeadLetterRedrive(c *gin.Context) {
+	var req serializer.CommitDeadLetterRedriveRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.BadRequestErrorJSONResponse(c, utils.ValidationErrorMessage(err))
+		return
+	}
+	if req.All {
+		count := r.committer.RedriveAll()
+		utils.SuccessJSONResponse(c, serializer.CommitDeadLetterRedriveResponse{Count: count})
+		return
+	}
+	if req.Key == "" {
+		utils.BadRequestErrorJSONResponse(c, "key is required when all is false")
+		return
+	}
+	if err := r.committer.Redrive(req.Key); err != nil {
+		utils.NotFoundJSONResponse(c, fmt.Sprintf("redrive %s err:%+v", req.Key, err.Error()))
+		return
+	}
+	utils.SuccessJSONResponse(c, serializer.CommitDeadLetterRedriveResponse{Count: 1})
+}
@@ -20,31 +20,66 @@
 package handler
 
 import (
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/apis/open/serializer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/archive"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/committer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/reconciler"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/leaderelection"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils/redactx"
 )
 
 // ResourceHandler resource api handler
 type ResourceHandler struct {
-	LeaderElector     *leaderelection.EtcdLeaderElector
-	apigwEtcdRegistry *registry.APIGWEtcdRegistry
+	LeaderElector     leaderelection.LeaderElector
+	apigwEtcdRegistry registry.APIGWRegistry
 	committer         *committer.Committer
-	apisixEtcdStore   *store.ApisixEtcdStore
+	apisixEtcdStore   store.ApisixStore
+	orphanCollector   *reconciler.OrphanCollector
+	shadowReporter    *store.ShadowReporter
+	synchronizer      *synchronizer.ApisixConfigSynchronizer
+	archiver          *archive.Archiver
 }
 
 // NewResourceApi constructor of resource handler
 func NewResourceApi(
-	leaderElector *leaderelection.EtcdLeaderElector,
-	registry *registry.APIGWEtcdRegistry,
+	leaderElector leaderelection.LeaderElector,
+	registry registry.APIGWRegistry,
 	committer *committer.Committer,
-	apiSixConfStore *store.ApisixEtcdStore,
+	apiSixConfStore store.ApisixStore,
+	orphanCollector *reconciler.OrphanCollector,
+	shadowReporter *store.ShadowReporter,
+	synchronizer *synchronizer.ApisixConfigSynchronizer,
+	archiver *archive.Archiver,
 ) *ResourceHandler {
 	return &ResourceHandler{
 		LeaderElector:     leaderElector,
 		apigwEtcdRegistry: registry,
 		committer:         committer,
 		apisixEtcdStore:   apiSixConfStore,
+		orphanCollector:   orphanCollector,
+		shadowReporter:    shadowReporter,
+		synchronizer:      synchronizer,
+		archiver:          archiver,
+	}
+}
+
+// redactConsumers 脱敏资源列表中 consumer 插件的凭证, 返回的数据是序列化后的拷贝, 不会修改缓存
+func redactConsumers(resources map[string]*serializer.StageScopedApisixResources) {
+	for _, stageResources := range resources {
+		if stageResources == nil {
+			continue
+		}
+		for _, consumer := range stageResources.Consumers {
+			conf, ok := consumer.(map[string]any)
+			if !ok {
+				continue
+			}
+			if plugins, ok := conf["plugins"].(map[string]any); ok {
+				redactx.ConsumerPlugins(plugins)
+			}
+		}
 	}
 }
@@ -0,0 +1,37 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package handler  ...
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/apis/open/serializer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils"
+)
+
+// ShadowReport 逐个环境对比影子配置与线上配置, 只返回存在差异的环境
+func (r *ResourceHandler) ShadowReport(c *gin.Context) {
+	if r.shadowReporter == nil {
+		utils.BadRequestErrorJSONResponse(c, "shadow mode is not enabled")
+		return
+	}
+	output := serializer.ShadowReportResponse(r.shadowReporter.Report())
+	utils.SuccessJSONResponse(c, output)
+}
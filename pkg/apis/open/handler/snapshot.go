@@ -0,0 +1,87 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package handler  ...
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/apis/open/serializer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils"
+)
+
+// ApisixSnapshotList 查询环境的发布快照
+func (r *ResourceHandler) ApisixSnapshotList(c *gin.Context) {
+	var req serializer.ApisixSnapshotListRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.BadRequestErrorJSONResponse(c, utils.ValidationErrorMessage(err))
+		return
+	}
+	if req.GatewayName == "" || req.StageName == "" {
+		utils.BadRequestErrorJSONResponse(c, "gateway_name and stage_name are required")
+		return
+	}
+	snapshots, err := r.synchronizer.ListSnapshots(c, req.GatewayName, req.StageName)
+	if err != nil {
+		snapshotErrorResponse(c, "list snapshots", err)
+		return
+	}
+	output := make(serializer.ApisixSnapshotListResponse, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		output = append(output, serializer.NewApisixSnapshotInfo(snapshot))
+	}
+	utils.SuccessJSONResponse(c, output)
+}
+
+// ApisixSnapshotRollback 将环境回滚到指定发布版本的快照
+func (r *ResourceHandler) ApisixSnapshotRollback(c *gin.Context) {
+	var req serializer.ApisixSnapshotRollbackRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.BadRequestErrorJSONResponse(c, utils.ValidationErrorMessage(err))
+		return
+	}
+	if req.GatewayName == "" || req.StageName == "" || req.PublishID == "" {
+		utils.BadRequestErrorJSONResponse(c, "gateway_name, stage_name and publish_id are required")
+		return
+	}
+	snapshot, err := r.synchronizer.Rollback(c, req.GatewayName, req.StageName, req.PublishID)
+	if err != nil {
+		snapshotErrorResponse(c, "rollback", err)
+		return
+	}
+	utils.SuccessJSONResponse(c, serializer.NewApisixSnapshotInfo(snapshot))
+}
+
+func snapshotErrorResponse(c *gin.Context, action string, err error) {
+	message := fmt.Sprintf("%s err:%+v", action, err.Error())
+	switch {
+	case errors.Is(err, synchronizer.ErrSnapshotDisabled):
+		utils.BadRequestErrorJSONResponse(c, message)
+	case errors.Is(err, store.ErrSnapshotNotFound):
+		utils.NotFoundJSONResponse(c, message)
+	default:
+		utils.BaseErrorJSONResponse(c, utils.SystemError, message, http.StatusOK)
+	}
+}
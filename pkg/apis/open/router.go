@@ -23,28 +23,51 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/apis/open/handler"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/archive"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/committer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/reconciler"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/leaderelection"
 )
 
 // Register registers the API routes
 func Register(
 	r *gin.RouterGroup,
-	leaderElector *leaderelection.EtcdLeaderElector,
-	registry *registry.APIGWEtcdRegistry,
+	leaderElector leaderelection.LeaderElector,
+	registry registry.APIGWRegistry,
 	committer *committer.Committer,
-	apisixConfStore *store.ApisixEtcdStore,
+	apisixConfStore store.ApisixStore,
+	orphanCollector *reconciler.OrphanCollector,
+	shadowReporter *store.ShadowReporter,
+	synchronizer *synchronizer.ApisixConfigSynchronizer,
+	archiver *archive.Archiver,
 ) {
 	// register resource api
-	resourceApi := handler.NewResourceApi(leaderElector, registry, committer, apisixConfStore)
+	resourceApi := handler.NewResourceApi(
+		leaderElector, registry, committer, apisixConfStore, orphanCollector, shadowReporter, synchronizer, archiver,
+	)
 	r.GET("/leader/", resourceApi.GetLeader)
 	r.POST("/apigw/resources/", resourceApi.ApigwList)
 	r.POST("/apigw/resources/count/", resourceApi.ApigwStageResourceCount)
 	r.POST("/apigw/resources/current-version/", resourceApi.ApigwStageCurrentVersion)
+	r.GET("/apigw/origins/", resourceApi.ApigwOriginList)
 
 	r.POST("/apisix/resources/", resourceApi.ApisixList)
 	r.POST("/apisix/resources/count/", resourceApi.ApisixStageResourceCount)
 	r.POST("/apisix/resources/current-version/", resourceApi.ApisixStageCurrentVersion)
+	r.GET("/apisix/orphans/", resourceApi.ApisixOrphanList)
+	r.GET("/apisix/targets/", resourceApi.ApisixTargetList)
+
+	r.GET("/commit/dead-letters/", resourceApi.CommitDeadLetterList)
+	r.POST("/commit/dead-letters/redrive/", resourceApi.CommitDeadLetterRedrive)
+
+	r.GET("/shadow/report/", resourceApi.ShadowReport)
+
+	r.POST("/apisix/snapshots/", resourceApi.ApisixSnapshotList)
+	r.POST("/apisix/snapshots/rollback/", resourceApi.ApisixSnapshotRollback)
+
+	r.POST("/apisix/export/", resourceApi.ApisixExport)
+	r.POST("/apisix/import/", resourceApi.ApisixImport)
 }
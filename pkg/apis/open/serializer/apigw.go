@@ -19,7 +19,10 @@
 // Package serializer ...
 package serializer
 
-import "github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+import (
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+)
 
 // ApigwListInfo apigw 资源列表
 type ApigwListInfo map[string]*StageScopedApisixResources
@@ -44,3 +47,6 @@ type ApigwListResourceCountResponse struct {
 
 // ApigwListCurrentVersionInfoResponse apigw 环境发布版本信息
 type ApigwListCurrentVersionInfoResponse *entity.ReleaseInfo
+
+// ApigwOriginListResponse 控制面的状态列表
+type ApigwOriginListResponse []*registry.OriginStatus
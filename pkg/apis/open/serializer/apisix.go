@@ -19,6 +19,11 @@
 // Package serializer ...
 package serializer
 
+import (
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/reconciler"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
+)
+
 // ApisixListInfo apisix 资源列表
 type ApisixListInfo map[string]*StageScopedApisixResources
 
@@ -36,3 +41,9 @@ type ApisixListResourceCountResponse struct {
 
 // ApisixListCurrentVersionInfoResponse apisix 环境发布版本信息
 type ApisixListCurrentVersionInfoResponse map[string]any
+
+// ApisixOrphanListResponse apisix 孤儿环境列表
+type ApisixOrphanListResponse []*reconciler.OrphanStage
+
+// ApisixTargetListResponse apisix 集群的同步状态列表
+type ApisixTargetListResponse []*synchronizer.TargetStatus
@@ -0,0 +1,38 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package serializer ...
+package serializer
+
+import "encoding/json"
+
+// ApisixExportRequest 导出 apisix 配置, 按网关或环境过滤时不导出全局资源
+type ApisixExportRequest struct {
+	GatewayName string `json:"gateway_name,omitempty"`
+	StageName   string `json:"stage_name,omitempty"`
+	Redact      bool   `json:"redact,omitempty"`
+}
+
+// ApisixImportRequest 恢复 apisix 配置, dry_run 为 true 时只返回将要产生的变更
+type ApisixImportRequest struct {
+	Archive     json.RawMessage `json:"archive"`
+	GatewayName string          `json:"gateway_name,omitempty"`
+	StageName   string          `json:"stage_name,omitempty"`
+	KeyPrefix   string          `json:"key_prefix,omitempty"`
+	DryRun      bool            `json:"dry_run,omitempty"`
+}
@@ -0,0 +1,36 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package serializer ...
+package serializer
+
+import "github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/committer"
+
+// CommitDeadLetterListResponse 提交失败的死信列表
+type CommitDeadLetterListResponse []*committer.DeadLetter
+
+// CommitDeadLetterRedriveRequest 重新提交死信, all 为 true 时重新提交所有死信
+type CommitDeadLetterRedriveRequest struct {
+	Key string `json:"key,omitempty"`
+	All bool   `json:"all,omitempty"`
+}
+
+// CommitDeadLetterRedriveResponse 重新提交的死信数量
+type CommitDeadLetterRedriveResponse struct {
+	Count int `json:"count"`
+}
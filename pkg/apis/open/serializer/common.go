@@ -23,6 +23,12 @@ package serializer
 type StageScopedApisixResources struct {
 	Routes         map[string]any `json:"routes,omitempty"`
 	Services       map[string]any `json:"services,omitempty"`
+	Upstreams      map[string]any `json:"upstreams,omitempty"`
+	PluginConfigs  map[string]any `json:"plugin_configs,omitempty"`
+	Consumers      map[string]any `json:"consumers,omitempty"`
+	ConsumerGroups map[string]any `json:"consumer_groups,omitempty"`
 	PluginMetadata map[string]any `json:"plugin_metadata,omitempty"`
 	Ssl            map[string]any `json:"ssl,omitempty"`
+	StreamRoutes   map[string]any `json:"stream_routes,omitempty"`
+	Protos         map[string]any `json:"protos,omitempty"`
 }
@@ -0,0 +1,74 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package serializer ...
+package serializer
+
+import (
+	"time"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+)
+
+// ApisixSnapshotListRequest 查询环境的发布快照
+type ApisixSnapshotListRequest struct {
+	GatewayName string `json:"gateway_name"`
+	StageName   string `json:"stage_name"`
+}
+
+// ApisixSnapshotInfo 发布快照概要, 不返回完整的资源配置
+type ApisixSnapshotInfo struct {
+	PublishID          string    `json:"publish_id"`
+	CreatedAt          time.Time `json:"created_at"`
+	RouteCount         int       `json:"route_count"`
+	ServiceCount       int       `json:"service_count"`
+	UpstreamCount      int       `json:"upstream_count"`
+	PluginConfigCount  int       `json:"plugin_config_count"`
+	ConsumerCount      int       `json:"consumer_count"`
+	ConsumerGroupCount int       `json:"consumer_group_count"`
+	SSLCount           int       `json:"ssl_count"`
+	StreamRouteCount   int       `json:"stream_route_count"`
+	ProtoCount         int       `json:"proto_count"`
+}
+
+// NewApisixSnapshotInfo ...
+func NewApisixSnapshotInfo(snapshot *store.Snapshot) *ApisixSnapshotInfo {
+	return &ApisixSnapshotInfo{
+		PublishID:          snapshot.PublishID,
+		CreatedAt:          snapshot.CreatedAt,
+		RouteCount:         len(snapshot.Resources.Routes),
+		ServiceCount:       len(snapshot.Resources.Services),
+		UpstreamCount:      len(snapshot.Resources.Upstreams),
+		PluginConfigCount:  len(snapshot.Resources.PluginConfigs),
+		ConsumerCount:      len(snapshot.Resources.Consumers),
+		ConsumerGroupCount: len(snapshot.Resources.ConsumerGroups),
+		SSLCount:           len(snapshot.Resources.SSLs),
+		StreamRouteCount:   len(snapshot.Resources.StreamRoutes),
+		ProtoCount:         len(snapshot.Resources.Protos),
+	}
+}
+
+// ApisixSnapshotListResponse 发布快照列表, 最近应用的在前
+type ApisixSnapshotListResponse []*ApisixSnapshotInfo
+
+// ApisixSnapshotRollbackRequest 将环境回滚到指定发布版本的快照
+type ApisixSnapshotRollbackRequest struct {
+	GatewayName string `json:"gateway_name"`
+	StageName   string `json:"stage_name"`
+	PublishID   string `json:"publish_id"`
+}
@@ -22,6 +22,7 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 
 	"gopkg.in/h2non/gentleman.v2"
 
@@ -67,25 +68,65 @@ func sendAndDecodeResp(result any) RequestOption {
 			_ = resp.Close()
 		}()
 
-		// decode common resp
-		var res utils.CommonResp
-		err = json.Unmarshal(resp.Bytes(), &res)
-		if err != nil {
-			return fmt.Errorf("unmarshal http resp err: %w", err)
+		err = decodeResp(resp, result)
+		return err
+	}
+}
+
+// sendAndDecodeRespWithETag 带上 If-None-Match 发送请求, 304 时 notModified 为 true 且不解析结果,
+// 否则解析结果并将 etag 更新为响应的 ETag
+func sendAndDecodeRespWithETag(result any, etag *string, notModified *bool) RequestOption {
+	return func(request *gentleman.Request) error {
+		if *etag != "" {
+			request.SetHeader("If-None-Match", *etag)
 		}
-		if res.Error.Code != "" {
-			return fmt.Errorf("code: %s,msg: %s", res.Error.Code, res.Error.Message)
+		resp, err := request.Send()
+		if err != nil {
+			logging.GetLogger().Errorf("do http request fail: %+v", err)
+			return fmt.Errorf("send http fail: %w", err)
 		}
+		defer func() {
+			_ = resp.Close()
+		}()
 
-		// decode resp
-		if result != nil {
-			var resultByte []byte
-			resultByte, err = json.Marshal(res.Data)
-			if err != nil {
-				return fmt.Errorf("marshal http result data err: %w", err)
-			}
-			return json.Unmarshal(resultByte, &result)
+		if resp.StatusCode == http.StatusNotModified {
+			*notModified = true
+			return nil
+		}
+		// 拉取的结果会覆盖本地的缓存, 错误的响应不能当作空结果
+		if !resp.Ok {
+			err = fmt.Errorf("http status: %d", resp.StatusCode)
+		} else {
+			err = decodeResp(resp, result)
 		}
+		if err != nil {
+			logging.GetLogger().Errorf("do http request fail: %+v", err)
+			return err
+		}
+		*etag = resp.Header.Get("ETag")
 		return nil
 	}
 }
+
+// decodeResp decode common resp and the data into result
+func decodeResp(resp *gentleman.Response, result any) error {
+	var res utils.CommonResp
+	err := json.Unmarshal(resp.Bytes(), &res)
+	if err != nil {
+		return fmt.Errorf("unmarshal http resp err: %w", err)
+	}
+	if res.Error.Code != "" {
+		return fmt.Errorf("code: %s,msg: %s", res.Error.Code, res.Error.Message)
+	}
+
+	// decode resp
+	if result != nil {
+		var resultByte []byte
+		resultByte, err = json.Marshal(res.Data)
+		if err != nil {
+			return fmt.Errorf("marshal http result data err: %w", err)
+		}
+		return json.Unmarshal(resultByte, &result)
+	}
+	return nil
+}
@@ -22,10 +22,13 @@ package client
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"gopkg.in/h2non/gentleman.v2"
 	"gopkg.in/h2non/gentleman.v2/plugins/body"
+	"gopkg.in/h2non/gentleman.v2/plugins/timeout"
 	"gopkg.in/h2non/gentleman.v2/plugins/url"
 
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
@@ -33,6 +36,10 @@ import (
 
 const (
 	reportPublishEventURL = "/api/v1/micro-gateway/:micro_gateway_instance_id/release/:publish_id/events/"
+	listReleasesURL       = "/api/v1/micro-gateway/:micro_gateway_instance_id/releases/"
+	getStageResourcesURL  = "/api/v1/micro-gateway/:micro_gateway_instance_id/releases/" +
+		":gateway_name/:stage_name/resources/"
+	listGlobalResourcesURL = "/api/v1/micro-gateway/:micro_gateway_instance_id/global-resources/"
 )
 
 var coreAPIClient *CoreAPIClient
@@ -48,7 +55,7 @@ type CoreAPIClient struct {
 // InitCoreAPIClient init core api client
 func InitCoreAPIClient(cfg *config.Config) {
 	coreOnce.Do(func() {
-		coreAPIClient = newCoreAPIClient(cfg.EventReporter.CoreAPIHost, cfg.Auth.ID, cfg.Auth.Secret)
+		coreAPIClient = NewCoreAPIClient(cfg.EventReporter.CoreAPIHost, cfg.Auth.ID, cfg.Auth.Secret, 0)
 	})
 }
 
@@ -57,10 +64,13 @@ func GetCoreAPIClient() *CoreAPIClient {
 	return coreAPIClient
 }
 
-// NewCoreAPIClient New core_api client with instance_id and instance_secret
-func newCoreAPIClient(host, instanceID, instanceSecret string) *CoreAPIClient {
+// NewCoreAPIClient New core_api client with instance_id and instance_secret, timeout 为 0 时不限制请求时间
+func NewCoreAPIClient(host, instanceID, instanceSecret string, requestTimeout time.Duration) *CoreAPIClient {
 	cli := gentleman.New()
 	cli.URL(host)
+	if requestTimeout > 0 {
+		cli.Use(timeout.Request(requestTimeout))
+	}
 
 	// set instance
 	cli.SetHeader("X-Bk-Micro-Gateway-Instance-Id", instanceID)
@@ -84,3 +94,40 @@ func (c *CoreAPIClient) ReportPublishEvent(ctx context.Context, req *ReportEvent
 	request.Use(body.JSON(req))
 	return c.doHttpRequest(request, sendAndDecodeResp(nil))
 }
+
+// ListReleases 查询各环境最新的发布, etag 与上一次相同时返回 NotModified
+func (c *CoreAPIClient) ListReleases(ctx context.Context, etag string) (*ListReleasesResp, error) {
+	request := c.client.Request()
+	request.Path(listReleasesURL)
+	request.Method(http.MethodGet)
+	request.Use(url.Param("micro_gateway_instance_id", c.microGatewayInstanceID))
+	res := &ListReleasesResp{ETag: etag}
+	return res, c.doHttpRequest(request, sendAndDecodeRespWithETag(&res.Releases, &res.ETag, &res.NotModified))
+}
+
+// GetStageResources 下载环境指定发布版本的 apisix 资源
+func (c *CoreAPIClient) GetStageResources(
+	ctx context.Context,
+	gatewayName, stageName string,
+	publishID int,
+) (*StageResourcesResp, error) {
+	request := c.client.Request()
+	request.Path(getStageResourcesURL)
+	request.Method(http.MethodGet)
+	request.Use(url.Param("micro_gateway_instance_id", c.microGatewayInstanceID))
+	request.Use(url.Param("gateway_name", gatewayName))
+	request.Use(url.Param("stage_name", stageName))
+	request.SetQuery("publish_id", strconv.Itoa(publishID))
+	var res StageResourcesResp
+	return &res, c.doHttpRequest(request, sendAndDecodeResp(&res))
+}
+
+// ListGlobalResources 查询全局资源, etag 与上一次相同时返回 NotModified
+func (c *CoreAPIClient) ListGlobalResources(ctx context.Context, etag string) (*GlobalResourcesResp, error) {
+	request := c.client.Request()
+	request.Path(listGlobalResourcesURL)
+	request.Method(http.MethodGet)
+	request.Use(url.Param("micro_gateway_instance_id", c.microGatewayInstanceID))
+	res := &GlobalResourcesResp{ETag: etag}
+	return res, c.doHttpRequest(request, sendAndDecodeRespWithETag(&res, &res.ETag, &res.NotModified))
+}
@@ -20,6 +20,8 @@
 package client
 
 import (
+	"encoding/json"
+
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
 )
 
@@ -33,3 +35,34 @@ type ReportEventReq struct {
 	Detail        map[string]any       `json:"detail"`
 	Ts            int64                `json:"ts"`
 }
+
+// MicroGatewayRelease 环境最新的发布, 删除的环境以 publish_id 为 -2 的发布返回
+type MicroGatewayRelease struct {
+	GatewayName string `json:"gateway_name"`
+	StageName   string `json:"stage_name"`
+	APIVersion  string `json:"api_version"`
+	PublishID   int    `json:"publish_id"`
+	// Release 与 etcd 中 _bk_release 相同的发布信息
+	Release json.RawMessage `json:"release"`
+}
+
+// ListReleasesResp 各环境最新的发布, NotModified 时 Releases 为空
+type ListReleasesResp struct {
+	Releases    []*MicroGatewayRelease `json:"-"`
+	ETag        string                 `json:"-"`
+	NotModified bool                   `json:"-"`
+}
+
+// StageResourcesResp 环境发布版本的 apisix 资源, Resources 按资源类型分组, 每个资源为带 id 的原生 apisix 配置
+type StageResourcesResp struct {
+	PublishID int                          `json:"publish_id"`
+	Resources map[string][]json.RawMessage `json:"resources"`
+}
+
+// GlobalResourcesResp 全局资源, 如 plugin_metadata
+type GlobalResourcesResp struct {
+	APIVersion  string                       `json:"api_version"`
+	Resources   map[string][]json.RawMessage `json:"resources"`
+	ETag        string                       `json:"-"`
+	NotModified bool                         `json:"-"`
+}
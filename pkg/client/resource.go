@@ -20,6 +20,7 @@
 package client
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
@@ -42,6 +43,10 @@ const (
 	ResourceApisixURL               = "/v1/open/apisix/resources/"
 	ResourceApisixCountURL          = "/v1/open/apisix/resources/count/"
 	ResourceApisixCurrentVersionURL = "/v1/open/apisix/resources/current-version/"
+	ApisixSnapshotURL               = "/v1/open/apisix/snapshots/"
+	ApisixSnapshotRollbackURL       = "/v1/open/apisix/snapshots/rollback/"
+	ApisixExportURL                 = "/v1/open/apisix/export/"
+	ApisixImportURL                 = "/v1/open/apisix/import/"
 )
 
 // ResourceClient is a client for the resource API.
@@ -178,6 +183,46 @@ func (r *ResourceClient) ApisixStageCurrentVersion(
 	return res, r.doHttpRequest(request, sendAndDecodeResp(&res))
 }
 
+// ApisixSnapshotList apisix 环境发布快照列表
+func (r *ResourceClient) ApisixSnapshotList(req *ApisixSnapshotRequest) (ApisixSnapshotListResponse, error) {
+	request := r.client.Request()
+	request.Path(ApisixSnapshotURL)
+	request.Method(http.MethodPost)
+	request.Use(body.JSON(req))
+	var res ApisixSnapshotListResponse
+	return res, r.doHttpRequest(request, sendAndDecodeResp(&res))
+}
+
+// ApisixSnapshotRollback apisix 环境回滚到指定发布版本的快照
+func (r *ResourceClient) ApisixSnapshotRollback(req *ApisixSnapshotRequest) (*ApisixSnapshotInfo, error) {
+	request := r.client.Request()
+	request.Path(ApisixSnapshotRollbackURL)
+	request.Method(http.MethodPost)
+	request.Use(body.JSON(req))
+	var res ApisixSnapshotInfo
+	return &res, r.doHttpRequest(request, sendAndDecodeResp(&res))
+}
+
+// ApisixExport 导出 apisix 当前配置, 返回归档的原始内容
+func (r *ResourceClient) ApisixExport(req *ApisixExportRequest) (json.RawMessage, error) {
+	request := r.client.Request()
+	request.Path(ApisixExportURL)
+	request.Method(http.MethodPost)
+	request.Use(body.JSON(req))
+	var res json.RawMessage
+	return res, r.doHttpRequest(request, sendAndDecodeResp(&res))
+}
+
+// ApisixImport 将归档恢复到 apisix
+func (r *ResourceClient) ApisixImport(req *ApisixImportRequest) (*ApisixImportResponse, error) {
+	request := r.client.Request()
+	request.Path(ApisixImportURL)
+	request.Method(http.MethodPost)
+	request.Use(body.JSON(req))
+	var res ApisixImportResponse
+	return &res, r.doHttpRequest(request, sendAndDecodeResp(&res))
+}
+
 // GetHostFromLeaderName eg: in:somename-ip1,ip2 out: http://ip1:port
 func GetHostFromLeaderName(leader string) string {
 	// format somename-ip1,ip2,ip3
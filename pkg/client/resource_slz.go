@@ -19,14 +19,25 @@
 // Package client ...
 package client
 
-import "github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+)
 
 // StageScopedApisixResources apisix resource
 type StageScopedApisixResources struct {
 	Routes         map[string]entity.Route          `json:"routes,omitempty"`
 	Services       map[string]entity.Service        `json:"services,omitempty"`
+	Upstreams      map[string]entity.Upstream       `json:"upstreams,omitempty"`
+	PluginConfigs  map[string]entity.PluginConfig   `json:"plugin_configs,omitempty"`
+	Consumers      map[string]entity.Consumer       `json:"consumers,omitempty"`
+	ConsumerGroups map[string]entity.ConsumerGroup  `json:"consumer_groups,omitempty"`
 	PluginMetadata map[string]entity.PluginMetadata `json:"plugin_metadata,omitempty"`
 	Ssl            map[string]entity.SSL            `json:"ssl,omitempty"`
+	StreamRoutes   map[string]entity.StreamRoute    `json:"stream_routes,omitempty"`
+	Protos         map[string]entity.Proto          `json:"protos,omitempty"`
 }
 
 // ApigwListInfo apigw 资源列表
@@ -70,3 +81,53 @@ type ApisixListResourceCountResponse struct {
 
 // ApisixListCurrentVersionInfoResponse apisix 环境发布版本信息
 type ApisixListCurrentVersionInfoResponse map[string]any
+
+// ApisixSnapshotRequest apisix snapshot api req, publish_id is only required by rollback
+type ApisixSnapshotRequest struct {
+	GatewayName string `json:"gateway_name"`
+	StageName   string `json:"stage_name"`
+	PublishID   string `json:"publish_id,omitempty"`
+}
+
+// ApisixSnapshotInfo apisix 环境发布快照概要
+type ApisixSnapshotInfo struct {
+	PublishID    string    `json:"publish_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	RouteCount   int       `json:"route_count"`
+	ServiceCount int       `json:"service_count"`
+	SSLCount     int       `json:"ssl_count"`
+}
+
+// ApisixSnapshotListResponse apisix 环境发布快照列表
+type ApisixSnapshotListResponse []*ApisixSnapshotInfo
+
+// ApisixExportRequest apisix export api req, 按网关或环境过滤时不导出全局资源
+type ApisixExportRequest struct {
+	GatewayName string `json:"gateway_name,omitempty"`
+	StageName   string `json:"stage_name,omitempty"`
+	Redact      bool   `json:"redact,omitempty"`
+}
+
+// ApisixImportRequest apisix import api req
+type ApisixImportRequest struct {
+	Archive     json.RawMessage `json:"archive"`
+	GatewayName string          `json:"gateway_name,omitempty"`
+	StageName   string          `json:"stage_name,omitempty"`
+	KeyPrefix   string          `json:"key_prefix,omitempty"`
+	DryRun      bool            `json:"dry_run,omitempty"`
+}
+
+// ApisixStageDiff 恢复时环境资源的变更
+type ApisixStageDiff struct {
+	StageKey  string              `json:"stage_key"`
+	Put       map[string][]string `json:"put,omitempty"`
+	Delete    map[string][]string `json:"delete,omitempty"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// ApisixImportResponse apisix 恢复结果
+type ApisixImportResponse struct {
+	Prefix string             `json:"prefix"`
+	DryRun bool               `json:"dry_run"`
+	Diffs  []*ApisixStageDiff `json:"diffs"`
+}
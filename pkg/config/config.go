@@ -21,8 +21,10 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"strings"
 	"time"
 
@@ -35,6 +37,8 @@ import (
 const (
 	envPodName = "BK_GATEWAY_POD_NAME"
 	envPodIP   = "BK_GATEWAY_POD_IP"
+	// envPodNamespace kubernetes 来源没有配置 namespace 时使用 pod 所在的 namespace
+	envPodNamespace = "BK_GATEWAY_POD_NAMESPACE"
 )
 
 // ReleaseVersionResourceID 发布版本资源 ID
@@ -75,13 +79,134 @@ type VirtualStage struct {
 
 // Dashboard ...
 type Dashboard struct {
+	// Source 网关发布资源的来源, etcd、directory、kubernetes 或 core_api
+	Source string
+	Etcd   Etcd
+	// Origins 其他控制面的 etcd, 与 Etcd 一起 watch, 共用同一个 apisix; 同一个环境只能由一个控制面发布
+	Origins    []DashboardOrigin
+	Directory  Directory
+	Kubernetes Kubernetes
+	CoreAPI    CoreAPISource
+}
+
+// 网关发布资源的来源
+const (
+	// DashboardSourceEtcd 从 dashboard 的 etcd 读取
+	DashboardSourceEtcd = "etcd"
+	// DashboardSourceDirectory 从本地目录读取, 用于本地开发和离线部署
+	DashboardSourceDirectory = "directory"
+	// DashboardSourceKubernetes 从 kubernetes 中带标签的 ConfigMap 读取, 使用 Lease 选主
+	DashboardSourceKubernetes = "kubernetes"
+	// DashboardSourceCoreAPI 从 core api 拉取, 用于无法访问 dashboard etcd 的网络隔离环境
+	DashboardSourceCoreAPI = "core_api"
+)
+
+// DefaultDashboardOrigin Dashboard.Etcd 对应的控制面名称
+const DefaultDashboardOrigin = "default"
+
+// DashboardOrigin 一个控制面的 etcd, 没有配置 endpoints 时使用 Dashboard.Etcd 的 etcd
+type DashboardOrigin struct {
+	Name string
 	Etcd Etcd
 }
 
+// Directory 本地目录来源, 目录布局与 dashboard etcd 中 key prefix 之后的部分一致
+type Directory struct {
+	Path string
+	// Interval 扫描目录的间隔
+	Interval time.Duration
+}
+
+// Kubernetes kubernetes 来源, 读取 Namespace 下匹配 LabelSelector 的 ConfigMap
+type Kubernetes struct {
+	Namespace     string
+	LabelSelector string
+	// Interval 对比 ConfigMap 变更的间隔, ConfigMap 从 informer 缓存读取
+	Interval time.Duration
+	// LeaseName 选主使用的 Lease 名称, 位于 Namespace 下
+	LeaseName string
+}
+
+// CoreAPISource core api 拉取来源, 地址和认证信息复用 EventReporter.CoreAPIHost 和 Auth
+type CoreAPISource struct {
+	// Interval 拉取最新发布的间隔
+	Interval time.Duration
+	// Timeout 单个请求的超时时间
+	Timeout time.Duration
+	// Backoff 拉取失败后第一次重试的等待时间, 之后每次失败翻倍, 最长为 MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// apisix 配置的存储后端
+const (
+	// ApisixBackendEtcd 直接写入 apisix etcd
+	ApisixBackendEtcd = "etcd"
+	// ApisixBackendAdminAPI 通过 apisix admin api 写入, 适用于没有 apisix etcd 权限的部署
+	ApisixBackendAdminAPI = "admin_api"
+	// ApisixBackendStandalone 渲染为 apisix standalone 模式 (config_provider: yaml) 的 apisix.yaml
+	ApisixBackendStandalone = "standalone"
+)
+
+// Standalone ...
+type Standalone struct {
+	// Path of the rendered apisix.yaml with all the stages
+	Path string
+	// GatewayDir writes one more file <GatewayDir>/<gateway>.yaml for each gateway if not empty,
+	// including the global plugin metadata and the virtual stage
+	GatewayDir string
+}
+
+// AdminAPI ...
+type AdminAPI struct {
+	// Addr of the apisix admin api, e.g. http://127.0.0.1:9180
+	Addr   string
+	APIKey string
+	// Timeout of each admin api request
+	Timeout time.Duration
+	// RefreshInterval of listing all the resources to refresh the local cache
+	RefreshInterval time.Duration
+}
+
 // Apisix ...
 type Apisix struct {
+	// Backend etcd, admin_api or standalone, defaults to etcd
+	Backend      string
 	Etcd         Etcd
+	AdminAPI     AdminAPI
+	Standalone   Standalone
 	VirtualStage VirtualStage
+	// Name of the apisix cluster configured by Etcd, used in the target status, metrics and release events
+	Name string
+	// Targets the other apisix clusters (e.g. the data planes in other availability zones),
+	// every stage is synchronized to Etcd and all the Targets unless placed by Placement
+	Targets []ApisixTarget
+	// Placement rules of the stages, the first matched rule decides the clusters of a stage,
+	// stages matching no rule are synchronized to all the clusters
+	Placement []PlacementRule
+}
+
+// PlacementRule ...
+type PlacementRule struct {
+	// Gateway and Stage are shell patterns of path.Match, empty matches all
+	Gateway string
+	Stage   string
+	// Labels in key=value of the release labels, e.g. apisix-version=3.13.X, all of them should match
+	Labels []string
+	// Targets names of the clusters, Apisix.Name or the names in Apisix.Targets
+	Targets []string
+}
+
+// ApisixTarget ...
+type ApisixTarget struct {
+	Name string
+	// Backend defaults to Apisix.Backend
+	Backend string
+	// Etcd of the target, KeyPrefix defaults to Apisix.Etcd.KeyPrefix
+	Etcd Etcd
+	// Timeout and RefreshInterval default to Apisix.AdminAPI
+	AdminAPI   AdminAPI
+	Standalone Standalone
 }
 
 // Operator ...
@@ -94,9 +219,9 @@ type Operator struct {
 	AgentCommitTimeWindow        time.Duration
 	AgentConcurrencyLimit        int
 
-	// etcd put interval
+	// 写入被依赖的资源后, 等待 watch 看到写入的最长时间, 超时后继续写入引用方
 	EtcdPutInterval time.Duration
-	// etcd delete interval
+	// 删除引用方后, 等待 watch 看到删除的最长时间, 超时后继续删除被依赖的资源
 	EtcdDelInterval time.Duration
 	// etcd sync timeout
 	EtcdSyncTimeout time.Duration
@@ -106,6 +231,85 @@ type Operator struct {
 	CommitResourceChanSize int
 	// WatchEventChanSize is the buffer size for watch event channel from etcd registry
 	WatchEventChanSize int
+
+	// DriftReconcile periodic drift detection between apigw etcd and apisix etcd
+	DriftReconcile DriftReconcile
+	// OrphanCollect collect the stages left in apisix etcd which are not published in apigw etcd
+	OrphanCollect OrphanCollect
+	// CommitRetry retry policy of the failed stage commits
+	CommitRetry CommitRetry
+	// Shadow run the whole pipeline without touching the live apisix etcd
+	Shadow Shadow
+	// Snapshot keep the applied configs of each stage for rollback
+	Snapshot Snapshot
+	// Export periodic exports of the apisix state to a local directory
+	Export Export
+}
+
+// DriftReconcile ...
+type DriftReconcile struct {
+	// Enable full reads of apigw etcd and apisix etcd every interval, defaults to false
+	Enable bool
+	// reconcile interval, a random jitter in [0, Jitter) will be added to each round
+	Interval time.Duration
+	Jitter   time.Duration
+	// re-apply the desired config when drift detected, only report metrics if false
+	Heal bool
+}
+
+// OrphanCollect ...
+type OrphanCollect struct {
+	Enable   bool
+	Interval time.Duration
+	// only delete the orphan stage which has been orphaned longer than GracePeriod
+	GracePeriod time.Duration
+	// delete the orphan stages, only report them if false
+	Delete bool
+}
+
+// CommitRetry ...
+type CommitRetry struct {
+	// the failed stage is moved to the dead letter list after MaxAttempts retries
+	MaxAttempts int
+	// the n-th retry waits BaseDelay * 2^(n-1) with jitter, at most MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// the commit waits at most TargetWaitTimeout for the apisix targets other than the primary one,
+	// the slower writes go on in background and are reported as pending
+	TargetWaitTimeout time.Duration
+	// etcd key prefix of the pending retries and dead letters in apisix etcd, must not be under Apisix.Etcd.KeyPrefix;
+	// they are reloaded by the next leader, only kept in memory if empty or the apisix backend is not etcd
+	KeyPrefix string
+}
+
+// Shadow ...
+type Shadow struct {
+	Enable bool
+	// write to KeyPrefix instead of Apisix.Etcd.KeyPrefix, only record the diffs if empty
+	KeyPrefix string
+}
+
+// Snapshot ...
+type Snapshot struct {
+	Enable bool
+	// etcd key prefix of the snapshots in apisix etcd, must not be under Apisix.Etcd.KeyPrefix
+	KeyPrefix string
+	// keep the latest MaxCount snapshots of each stage
+	MaxCount int
+	// rollback to the previous snapshot when apisix fails to load the release (version probe failed or timeout)
+	AutoRollback bool
+}
+
+// Export ...
+type Export struct {
+	Enable   bool
+	Interval time.Duration
+	// local directory of the archive files
+	Dir string
+	// keep the latest Retention archive files in Dir
+	Retention int
+	// redact the secrets (ssl private keys, plugin passwords and tokens) in the archive files
+	Redact bool
 }
 
 // VersionProbe ...
@@ -205,14 +409,38 @@ func newDefaultConfig() *Config {
 			AuthPassword: "DebugModel@bk",
 		},
 		Dashboard: Dashboard{
+			Source: DashboardSourceEtcd,
 			Etcd: Etcd{
 				KeyPrefix: "/bk-gateway-apigw/default",
 			},
+			Directory: Directory{
+				Interval: time.Second,
+			},
+			Kubernetes: Kubernetes{
+				LabelSelector: "gateway.bk.tencent.com/operator-source=true",
+				Interval:      time.Second,
+				LeaseName:     "bk-gateway-operator-leader",
+			},
+			CoreAPI: CoreAPISource{
+				Interval:   5 * time.Second,
+				Timeout:    10 * time.Second,
+				Backoff:    5 * time.Second,
+				MaxBackoff: 2 * time.Minute,
+			},
 		},
 		Apisix: Apisix{
 			Etcd: Etcd{
 				KeyPrefix: "/apisix",
 			},
+			Backend: ApisixBackendEtcd,
+			AdminAPI: AdminAPI{
+				Timeout:         10 * time.Second,
+				RefreshInterval: 30 * time.Second,
+			},
+			Standalone: Standalone{
+				Path: "/usr/local/apisix/conf/apisix.yaml",
+			},
+			Name: "default",
 			VirtualStage: VirtualStage{
 				FileLoggerLogPath: "/usr/local/apisix/logs/access.log",
 				VirtualGateway:    "-",
@@ -247,6 +475,34 @@ func newDefaultConfig() *Config {
 			// Default channel buffer sizes
 			CommitResourceChanSize: 100,
 			WatchEventChanSize:     100,
+
+			DriftReconcile: DriftReconcile{
+				Enable:   false,
+				Interval: 5 * time.Minute,
+				Jitter:   30 * time.Second,
+				Heal:     false,
+			},
+			OrphanCollect: OrphanCollect{
+				Enable:      true,
+				Interval:    10 * time.Minute,
+				GracePeriod: time.Hour,
+				Delete:      false,
+			},
+			CommitRetry: CommitRetry{
+				MaxAttempts:       5,
+				BaseDelay:         2 * time.Second,
+				MaxDelay:          5 * time.Minute,
+				TargetWaitTimeout: 5 * time.Second,
+				KeyPrefix:         "/bk-gateway-operator/commit-retries",
+			},
+			Snapshot: Snapshot{
+				KeyPrefix: "/bk-gateway-operator/snapshots",
+				MaxCount:  10,
+			},
+			Export: Export{
+				Interval:  time.Hour,
+				Retention: 24,
+			},
 		},
 		Sentry: Sentry{
 			ReportLevel: 2,
@@ -281,18 +537,189 @@ func Load(v *viper.Viper) (*Config, error) {
 
 	cfg.init()
 
+	if err := cfg.validateDashboardSource(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateApisixBackend(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateApisixTargets(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
+// validateDashboardSource 目录和 kubernetes 来源需要配置读取的位置和间隔
+func (c *Config) validateDashboardSource() error {
+	if c.Dashboard.Source != DashboardSourceEtcd && len(c.Dashboard.Origins) > 0 {
+		return fmt.Errorf("dashboard origins are not supported by the %s source", c.Dashboard.Source)
+	}
+	switch c.Dashboard.Source {
+	case DashboardSourceEtcd:
+		return c.validateDashboardOrigins()
+	case DashboardSourceDirectory:
+		if c.Dashboard.Directory.Path == "" {
+			return errors.New("dashboard directory path is empty")
+		}
+		if c.Dashboard.Directory.Interval <= 0 {
+			return fmt.Errorf("dashboard directory interval %s is invalid", c.Dashboard.Directory.Interval)
+		}
+	case DashboardSourceKubernetes:
+		if c.Dashboard.Kubernetes.Namespace == "" || c.Dashboard.Kubernetes.LeaseName == "" {
+			return errors.New("dashboard kubernetes namespace and lease name should not be empty")
+		}
+		if c.Dashboard.Kubernetes.Interval <= 0 {
+			return fmt.Errorf("dashboard kubernetes interval %s is invalid", c.Dashboard.Kubernetes.Interval)
+		}
+	case DashboardSourceCoreAPI:
+		if c.EventReporter.CoreAPIHost == "" {
+			return errors.New("eventReporter coreAPIHost should not be empty for the core_api source")
+		}
+		pull := c.Dashboard.CoreAPI
+		if pull.Interval <= 0 || pull.Timeout <= 0 || pull.Backoff <= 0 || pull.MaxBackoff < pull.Backoff {
+			return fmt.Errorf("dashboard core api interval %s, timeout %s or backoff %s-%s is invalid",
+				pull.Interval, pull.Timeout, pull.Backoff, pull.MaxBackoff)
+		}
+	default:
+		return fmt.Errorf("unknown dashboard source %q, should be one of %s, %s, %s, %s", c.Dashboard.Source,
+			DashboardSourceEtcd, DashboardSourceDirectory, DashboardSourceKubernetes, DashboardSourceCoreAPI)
+	}
+	return nil
+}
+
+// validateDashboardOrigins 控制面名称会作为指标和环境归属的标识, 不能为空或重复; 同一个 etcd 的 prefix 不能重复
+func (c *Config) validateDashboardOrigins() error {
+	names := map[string]struct{}{DefaultDashboardOrigin: {}}
+	prefixes := map[string]string{c.Dashboard.Etcd.Endpoints + c.Dashboard.Etcd.KeyPrefix: DefaultDashboardOrigin}
+	for _, origin := range c.Dashboard.Origins {
+		if origin.Name == "" {
+			return errors.New("dashboard origin name is empty")
+		}
+		if _, ok := names[origin.Name]; ok {
+			return fmt.Errorf("dashboard origin name %s is duplicated", origin.Name)
+		}
+		names[origin.Name] = struct{}{}
+		if origin.Etcd.KeyPrefix == "" {
+			return fmt.Errorf("dashboard origin %s key prefix is empty", origin.Name)
+		}
+		key := origin.Etcd.Endpoints + origin.Etcd.KeyPrefix
+		if other, ok := prefixes[key]; ok {
+			return fmt.Errorf("dashboard origin %s watches the same prefix as %s", origin.Name, other)
+		}
+		prefixes[key] = origin.Name
+	}
+	return nil
+}
+
+// validateApisixBackend admin api 和 standalone 后端没有 apisix etcd, 不支持影子模式和快照
+func (c *Config) validateApisixBackend() error {
+	backends := map[string]ApisixTarget{c.Apisix.Name: {
+		Backend:    c.Apisix.Backend,
+		AdminAPI:   c.Apisix.AdminAPI,
+		Standalone: c.Apisix.Standalone,
+	}}
+	for _, target := range c.Apisix.Targets {
+		backends[target.Name] = target
+	}
+	for name, target := range backends {
+		switch target.Backend {
+		case ApisixBackendEtcd:
+		case ApisixBackendAdminAPI:
+			if target.AdminAPI.Addr == "" {
+				return fmt.Errorf("apisix admin api addr of %s is empty", name)
+			}
+		case ApisixBackendStandalone:
+			if target.Standalone.Path == "" {
+				return fmt.Errorf("apisix standalone path of %s is empty", name)
+			}
+		default:
+			return fmt.Errorf("apisix backend %s of %s is not supported, should be one of %s, %s and %s",
+				target.Backend, name, ApisixBackendEtcd, ApisixBackendAdminAPI, ApisixBackendStandalone)
+		}
+	}
+	if c.Apisix.Backend == ApisixBackendEtcd {
+		return nil
+	}
+	if c.Operator.Shadow.Enable || c.Operator.Snapshot.Enable {
+		return fmt.Errorf("shadow mode and snapshot are not supported by the apisix %s backend", c.Apisix.Backend)
+	}
+	return nil
+}
+
+// validateApisixTargets 集群名称会作为指标和事件中的标识, 不能为空或重复; 放置规则只能引用已配置的集群
+func (c *Config) validateApisixTargets() error {
+	names := map[string]struct{}{c.Apisix.Name: {}}
+	for _, target := range c.Apisix.Targets {
+		if target.Name == "" {
+			return errors.New("apisix target name is empty")
+		}
+		if _, ok := names[target.Name]; ok {
+			return fmt.Errorf("apisix target name %s is duplicated", target.Name)
+		}
+		names[target.Name] = struct{}{}
+	}
+
+	for i, rule := range c.Apisix.Placement {
+		if len(rule.Targets) == 0 {
+			return fmt.Errorf("apisix placement rule %d has no targets", i)
+		}
+		for _, name := range rule.Targets {
+			if _, ok := names[name]; !ok {
+				return fmt.Errorf("apisix placement rule %d refers to unknown target %s", i, name)
+			}
+		}
+		for _, pattern := range []string{rule.Gateway, rule.Stage} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("apisix placement rule %d has invalid pattern %s: %w", i, pattern, err)
+			}
+		}
+		for _, label := range rule.Labels {
+			if key, _, ok := strings.Cut(label, "="); !ok || key == "" {
+				return fmt.Errorf("apisix placement rule %d has invalid label %s, should be key=value", i, label)
+			}
+		}
+	}
+	return nil
+}
+
 func (c *Config) init() {
 	hostName, _ := os.Hostname()
 	InstanceName = envx.Get(envPodName, hostName+"_"+utils.GetGeneratedUUID())
 	InstanceIP = envx.Get(envPodIP, "127.0.0.1")
+	if c.Dashboard.Kubernetes.Namespace == "" {
+		c.Dashboard.Kubernetes.Namespace = envx.Get(envPodNamespace, "")
+	}
 
 	DefaultStageKey = GenStagePrimaryKey(c.Operator.DefaultGateway, c.Operator.DefaultStage)
 	VirtualStageKey = GenStagePrimaryKey(c.Apisix.VirtualStage.VirtualGateway, c.Apisix.VirtualStage.VirtualStage)
 
 	c.Apisix.Etcd.KeyPrefix = strings.TrimSuffix(c.Apisix.Etcd.KeyPrefix, "/")
+	for i := range c.Dashboard.Origins {
+		origin := &c.Dashboard.Origins[i]
+		if origin.Etcd.Endpoints == "" {
+			keyPrefix := origin.Etcd.KeyPrefix
+			origin.Etcd = c.Dashboard.Etcd
+			origin.Etcd.KeyPrefix = keyPrefix
+		}
+		origin.Etcd.KeyPrefix = strings.TrimSuffix(origin.Etcd.KeyPrefix, "/")
+	}
+	for i := range c.Apisix.Targets {
+		target := &c.Apisix.Targets[i]
+		if target.Etcd.KeyPrefix == "" {
+			target.Etcd.KeyPrefix = c.Apisix.Etcd.KeyPrefix
+		}
+		target.Etcd.KeyPrefix = strings.TrimSuffix(target.Etcd.KeyPrefix, "/")
+		if target.Backend == "" {
+			target.Backend = c.Apisix.Backend
+		}
+		if target.AdminAPI.Timeout == 0 {
+			target.AdminAPI.Timeout = c.Apisix.AdminAPI.Timeout
+		}
+		if target.AdminAPI.RefreshInterval == 0 {
+			target.AdminAPI.RefreshInterval = c.Apisix.AdminAPI.RefreshInterval
+		}
+	}
 	c.Dashboard.Etcd.KeyPrefix = strings.TrimSuffix(c.Dashboard.Etcd.KeyPrefix, "/")
 
 	if c.Debug {
@@ -31,6 +31,10 @@ const ApigwGlobalResourcePrefixFormat = "%s/%s/global/"
 // GlobalResourceKey ...
 const GlobalResourceKey = "global_resource"
 
+// ManagedIDPrefix operator 管理的 global_rule, stream_route 和 proto 的 id 前缀; 这些资源在 apisix 中没有 labels,
+// 只能通过 id 区分, 没有这个前缀的资源由其他方式创建, operator 不会写入和删除
+const ManagedIDPrefix = "bk-apigw."
+
 // APISIXResource ...
 type APISIXResource string
 
@@ -53,7 +57,15 @@ const (
 var SupportEventResourceTypeMap = map[APISIXResource]bool{
 	Route:          true,
 	Service:        true,
+	Upstream:       true,
+	PluginConfig:   true,
+	Consumer:       true,
+	ConsumerGroup:  true,
 	PluginMetadata: true,
+	GlobalRule:     true,
+	StreamRoute:    true,
+	Proto:          true,
+	SSL:            true,
 	BkRelease:      true,
 }
 
@@ -62,13 +74,25 @@ var SupportResourceTypeMap = map[APISIXResource]bool{
 	Service:        true,
 	SSL:            true,
 	PluginMetadata: true,
-	Upstream:       false,
-	PluginConfig:   false,
-	Consumer:       false,
-	ConsumerGroup:  false,
-	GlobalRule:     false,
-	Proto:          false,
-	StreamRoute:    false,
+	Upstream:       true,
+	PluginConfig:   true,
+	Consumer:       true,
+	ConsumerGroup:  true,
+	GlobalRule:     true,
+	Proto:          true,
+	StreamRoute:    true,
+}
+
+// GlobalResourceTypeMap 全局资源, 发布在 /{prefix}/{api_version}/global/ 下, 不依赖于 gateway 和 stage;
+// 其中 plugin_metadata 也可以发布在环境下, 随环境一起同步, 写入 apisix 时覆盖同名的全局配置
+var GlobalResourceTypeMap = map[APISIXResource]bool{
+	PluginMetadata: true,
+	GlobalRule:     true,
+}
+
+// GlobalOnlyResourceTypeMap 只能发布为全局资源的类型, 发布在环境下时不支持
+var GlobalOnlyResourceTypeMap = map[APISIXResource]bool{
+	GlobalRule: true,
 }
 
 // ResourceTypeList ...
@@ -106,6 +130,11 @@ func (a APISIXResource) String() string {
 	return string(a)
 }
 
+// IsGlobal 是否是全局资源类型
+func (a APISIXResource) IsGlobal() bool {
+	return GlobalResourceTypeMap[a]
+}
+
 const (
 	// SkippedValueEtcdInitDir indicates the init_dir
 	// etcd event will be skipped.
@@ -121,8 +150,14 @@ const (
 
 	ApisixResourceTypeRoutes         = "routes"
 	ApisixResourceTypeServices       = "services"
+	ApisixResourceTypeUpstreams      = "upstreams"
+	ApisixResourceTypePluginConfigs  = "plugin_configs"
+	ApisixResourceTypeConsumers      = "consumers"
+	ApisixResourceTypeConsumerGroups = "consumer_groups"
+	ApisixResourceTypeGlobalRules    = "global_rules"
 	ApisixResourceTypeSSL            = "ssls"
 	ApisixResourceTypeProtos         = "protos"
+	ApisixResourceTypeStreamRoutes   = "stream_routes"
 	ApisixResourceTypePluginMetadata = "plugin_metadata"
 
 	SyncSleepSeconds = 5 * time.Second
@@ -23,6 +23,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/spf13/cast"
 	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.uber.org/zap"
 
@@ -37,7 +38,7 @@ import (
 
 // EventAgent ...
 type EventAgent struct {
-	apigwRegistry *registry.APIGWEtcdRegistry
+	apigwRegistry registry.APIGWRegistry
 	commitChan    chan []*entity.ReleaseInfo
 
 	synchronizer *synchronizer.ApisixConfigSynchronizer
@@ -51,7 +52,7 @@ type EventAgent struct {
 
 // NewEventAgent ...
 func NewEventAgent(
-	resourceRegistry *registry.APIGWEtcdRegistry,
+	resourceRegistry registry.APIGWRegistry,
 	commitCh chan []*entity.ReleaseInfo,
 	synchronizer *synchronizer.ApisixConfigSynchronizer,
 	stageTimer *timer.ReleaseTimer,
@@ -72,6 +73,10 @@ func (w *EventAgent) SetKeepAliveChan(keepAliveChan <-chan struct{}) {
 
 // Run ...
 func (w *EventAgent) Run(ctx context.Context) {
+	// 成为 leader 后先全量同步一次，补齐没有 leader 期间发布的环境
+	if !w.resync(ctx) {
+		return
+	}
 	watchCh, watchCancel := w.createWatchChannel(ctx)
 
 	ticker := time.NewTicker(commitTimeWindow) // 窗口定时器
@@ -88,6 +93,11 @@ func (w *EventAgent) Run(ctx context.Context) {
 				// stop last watch loop
 				watchCancel()
 
+				// watch 异常退出(如 revision 已被压缩)时, 中间的事件可能已经丢失, 需要全量同步后再恢复 watch
+				if !w.resync(ctx) {
+					return
+				}
+
 				// reset watch channel
 				watchCh, watchCancel = w.createWatchChannel(ctx)
 
@@ -136,10 +146,69 @@ func (w *EventAgent) createWatchChannel(ctx context.Context) (<-chan *entity.Res
 	return watchCh, cancel
 }
 
+// resync 全量同步，失败时重试直到成功或者 agent 停止，返回 false 表示 agent 已停止
+func (w *EventAgent) resync(ctx context.Context) bool {
+	for {
+		err := w.fullSync(ctx)
+		if err == nil {
+			return true
+		}
+		w.logger.Errorw("full sync failed, will retry", "err", err)
+
+		select {
+		case <-time.After(constant.SyncSleepSeconds):
+		case <-w.keepAliveChan:
+			w.logger.Debugw("keep alive trigger")
+			return false
+		case <-ctx.Done():
+			w.logger.Infow("gateway agent stopped, stop full sync")
+			return false
+		}
+	}
+}
+
+// fullSync 查询所有已发布的环境, 连同全局资源一起提交给 committer, 并从查询时的 revision 之后开始 watch
+func (w *EventAgent) fullSync(ctx context.Context) error {
+	releaseList, revision, err := w.apigwRegistry.ListReleaseInfos(ctx)
+	if err != nil {
+		return err
+	}
+
+	apiVersion := defaultAPIVersion
+	if len(releaseList) > 0 {
+		apiVersion = releaseList[0].APIVersion
+	}
+	// 全局资源不依赖于 stage 的发布信息, 也需要同步一次
+	globalRelease := &entity.ReleaseInfo{
+		ResourceMetadata: entity.ResourceMetadata{
+			ID:         constant.GlobalResourceKey,
+			Kind:       constant.PluginMetadata,
+			APIVersion: apiVersion,
+			Labels:     &entity.LabelInfo{},
+			Ctx:        ctx,
+		},
+		Ctx: ctx,
+	}
+
+	w.logger.Infow("full sync all published stages", "count", len(releaseList), "revision", revision)
+	w.commitChan <- append(releaseList, globalRelease)
+
+	w.apigwRegistry.SetCurrentRevision(revision + 1)
+	return nil
+}
+
 func (w *EventAgent) handleEvent(event *entity.ResourceMetadata) {
-	// Note：跳过删除事件:删除事件的 release_info 信息还是上次的，无法获取到最新的 release_info
+	// Note：删除事件的 release_info 信息还是上次的，无法获取到最新的 release_info, 只触发环境的同步, 不上报发布事件
 	if event.Op == mvccpb.DELETE && !event.IsGlobalResource() {
-		w.logger.Debugw("skip delete event", "event", event)
+		if event.IsEmpty() {
+			w.logger.Debugw("skip empty delete event", "event", event)
+			return
+		}
+		w.logger.Debugw("Receive delete event", "gatewayName",
+			event.Labels.Gateway, "stageName", event.Labels.Stage)
+		releaseInfo := event.GetReleaseInfo()
+		releaseInfo.PublishId = cast.ToInt(constant.NoNeedReportPublishID)
+		w.resourceTimer.Touch(releaseInfo)
 		return
 	}
 	// trace
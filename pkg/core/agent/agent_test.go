@@ -24,6 +24,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/spf13/cast"
 	"go.etcd.io/etcd/api/v3/mvccpb"
 
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
@@ -85,18 +86,22 @@ var _ = Describe("EventAgent", func() {
 		})
 
 		Context("when event is delete operation", func() {
-			It("should skip delete event for non-global resource", func() {
+			It("should update timer without reporting for delete event of non-global resource", func() {
 				event := &entity.ResourceMetadata{
 					Labels: &entity.LabelInfo{
-						Gateway: "test-gateway",
-						Stage:   "test-stage",
+						Gateway:   "test-gateway",
+						Stage:     "test-stage",
+						PublishId: "1",
 					},
 					Op:   mvccpb.DELETE,
 					Kind: constant.Route,
+					Ctx:  context.Background(),
 				}
 
 				agent.handleEvent(event)
-				// Timer should not be updated for delete events
+				// 删除事件中的发布信息是上一次的, 不能用于上报
+				Eventually(releaseTimer.ListReleaseForCommit, 5*time.Second, 100*time.Millisecond).
+					Should(ConsistOf(HaveField("PublishId", cast.ToInt(constant.NoNeedReportPublishID))))
 			})
 		})
 
@@ -391,7 +396,7 @@ var _ = Describe("EventAgent", func() {
 			}
 		})
 
-		It("should skip delete events for non-global resources", func() {
+		It("should keep the pending release when a resource of the stage is deleted", func() {
 			// PUT event first
 			putEvent := &entity.ResourceMetadata{
 				ID: "route-1",
@@ -429,6 +434,7 @@ var _ = Describe("EventAgent", func() {
 			case releases := <-commitChan:
 				// Should only have 1 release from PUT event
 				Expect(releases).To(HaveLen(1))
+				Expect(releases[0].PublishId).To(Equal(1))
 			case <-time.After(time.Second):
 				Fail("should receive releases from commit channel")
 			}
@@ -28,6 +28,9 @@ import (
 
 var commitTimeWindow = 5 * time.Second
 
+// defaultAPIVersion 没有任何环境发布时, 全局资源使用的 api version
+const defaultAPIVersion = "v2"
+
 // Init ...
 func Init(cfg *config.Config) {
 	commitTimeWindow = cfg.Operator.AgentCommitTimeWindow
@@ -47,6 +47,12 @@ func (t *CacheTimer) Update(offset time.Duration) {
 	t.ShouldCommitTime = time.Now().Add(offset)
 }
 
+// Delay 推迟到 offset 之后提交, 强制提交的时间窗口也从 offset 之后开始计算
+func (t *CacheTimer) Delay(offset time.Duration) {
+	t.ShouldCommitTime = time.Now().Add(offset)
+	t.CachedTime = t.ShouldCommitTime
+}
+
 // ReleaseTimer ...
 type ReleaseTimer struct {
 	releaseTimer sync.Map
@@ -57,6 +63,15 @@ func NewReleaseTimer() *ReleaseTimer {
 	return &ReleaseTimer{}
 }
 
+// ReleaseCacheKey 发布信息在 timer 中的 key, 环境资源按环境维度合并, 全局资源合并为一个
+func ReleaseCacheKey(releaseInfo *entity.ReleaseInfo) string {
+	// 全局资源, 环境级别的插件元数据按环境合并
+	if releaseInfo.IsGlobalResource() {
+		return constant.GlobalResourceKey
+	}
+	return releaseInfo.GetReleaseID()
+}
+
 // Update ...
 func (t *ReleaseTimer) Update(releaseInfo *entity.ReleaseInfo) {
 	// trace
@@ -65,11 +80,7 @@ func (t *ReleaseTimer) Update(releaseInfo *entity.ReleaseInfo) {
 	defer span.End()
 
 	var timer *CacheTimer
-	cacheKey := releaseInfo.GetReleaseID()
-	// 全局资源
-	if releaseInfo.Kind == constant.PluginMetadata {
-		cacheKey = constant.GlobalResourceKey
-	}
+	cacheKey := ReleaseCacheKey(releaseInfo)
 	timerInterface, ok := t.releaseTimer.Load(cacheKey)
 	if !ok {
 		timer = &CacheTimer{ReleaseInfo: releaseInfo}
@@ -85,7 +96,6 @@ func (t *ReleaseTimer) Update(releaseInfo *entity.ReleaseInfo) {
 			// end old releaseInfo trace
 			_, span := trace.StartTrace(timer.ReleaseInfo.Ctx, "timer.Replace")
 			span.End()
-
 			timer.ReleaseInfo = releaseInfo
 			timer.Update(eventsWaitingTimeWindow)
 		}
@@ -93,6 +103,24 @@ func (t *ReleaseTimer) Update(releaseInfo *entity.ReleaseInfo) {
 	t.releaseTimer.Store(cacheKey, timer)
 }
 
+// Retry 在 delay 之后重新提交失败的发布, 如果已经有新的事件在等待提交, 以新的事件为准
+func (t *ReleaseTimer) Retry(releaseInfo *entity.ReleaseInfo, delay time.Duration) {
+	timer := &CacheTimer{ReleaseInfo: releaseInfo}
+	timer.Delay(delay)
+	t.releaseTimer.LoadOrStore(ReleaseCacheKey(releaseInfo), timer)
+}
+
+// Touch 触发 releaseInfo 所在环境的同步; 已经有等待提交的发布时只推迟提交时间, 不替换其发布信息
+func (t *ReleaseTimer) Touch(releaseInfo *entity.ReleaseInfo) {
+	timer := &CacheTimer{ReleaseInfo: releaseInfo}
+	timer.Reset(eventsWaitingTimeWindow)
+	if actual, loaded := t.releaseTimer.LoadOrStore(ReleaseCacheKey(releaseInfo), timer); loaded {
+		if cached, ok := actual.(*CacheTimer); ok {
+			cached.Update(eventsWaitingTimeWindow)
+		}
+	}
+}
+
 // ListReleaseForCommit ...
 func (t *ReleaseTimer) ListReleaseForCommit() []*entity.ReleaseInfo {
 	releaseInfos := make([]*entity.ReleaseInfo, 0)
@@ -126,6 +126,31 @@ var _ = Describe("Timer", func() {
 			gomega.Expect(stageList).To(gomega.HaveLen(1))
 		})
 
+		It("should commit stage PluginMetadata with the stage", func() {
+			stagePluginMetadata := entity.ReleaseInfo{
+				ResourceMetadata: entity.ResourceMetadata{
+					Labels: &entity.LabelInfo{
+						Gateway: "gateway1",
+						Stage:   "stage1",
+					},
+					ID:   "file-logger",
+					Kind: constant.PluginMetadata,
+					Ctx:  context.Background(),
+				},
+			}
+			globalPluginMetadata := entity.ReleaseInfo{
+				ResourceMetadata: entity.ResourceMetadata{
+					Labels: &entity.LabelInfo{},
+					ID:     "file-logger",
+					Kind:   constant.PluginMetadata,
+					Ctx:    context.Background(),
+				},
+			}
+
+			gomega.Expect(ReleaseCacheKey(&stagePluginMetadata)).To(gomega.Equal("bk.release.gateway1.stage1"))
+			gomega.Expect(ReleaseCacheKey(&globalPluginMetadata)).To(gomega.Equal(constant.GlobalResourceKey))
+		})
+
 		It("should handle multiple releases", func() {
 			stageInfo1 := entity.ReleaseInfo{
 				ResourceMetadata: entity.ResourceMetadata{
@@ -220,5 +245,47 @@ var _ = Describe("Timer", func() {
 			// Reset
 			forceUpdateTimeWindow = 30 * time.Second
 		})
+
+		It("should not force commit a delayed retry before the delay", func() {
+			forceUpdateTimeWindow = 50 * time.Millisecond
+
+			stageTimer.Retry(&stageInfo, 200*time.Millisecond)
+			time.Sleep(100 * time.Millisecond)
+			gomega.Expect(stageTimer.ListReleaseForCommit()).To(gomega.HaveLen(0))
+
+			time.Sleep(150 * time.Millisecond)
+			gomega.Expect(stageTimer.ListReleaseForCommit()).To(gomega.HaveLen(1))
+
+			// Reset
+			forceUpdateTimeWindow = 30 * time.Second
+		})
+
+		It("should keep the pending event when retry", func() {
+			eventsWaitingTimeWindow = 10 * time.Millisecond
+			stageTimer.Update(&stageInfo)
+			stageTimer.Retry(&stageInfo, time.Hour)
+
+			time.Sleep(20 * time.Millisecond)
+			gomega.Expect(stageTimer.ListReleaseForCommit()).To(gomega.HaveLen(1))
+		})
+
+		It("should keep the pending release info when touch", func() {
+			stageTimer.Update(&stageInfo)
+			touched := stageInfo
+			touched.PublishId = -1
+			stageTimer.Touch(&touched)
+
+			time.Sleep(150 * time.Millisecond)
+			releases := stageTimer.ListReleaseForCommit()
+			gomega.Expect(releases).To(gomega.HaveLen(1))
+			gomega.Expect(releases[0].PublishId).To(gomega.Equal(1))
+
+			// 没有等待提交的发布时以 touch 的发布信息提交
+			stageTimer.Touch(&touched)
+			time.Sleep(150 * time.Millisecond)
+			releases = stageTimer.ListReleaseForCommit()
+			gomega.Expect(releases).To(gomega.HaveLen(1))
+			gomega.Expect(releases[0].PublishId).To(gomega.Equal(-1))
+		})
 	})
 })
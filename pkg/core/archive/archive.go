@@ -0,0 +1,280 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package archive exports the apisix state managed by operator into a portable archive, and restores it
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"go.uber.org/zap"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+)
+
+// archiveVersion 归档格式的版本, 格式不兼容时递增
+const archiveVersion = 1
+
+var (
+	// ErrRedactedArchive 脱敏后的归档缺少密钥, 不能用于恢复
+	ErrRedactedArchive = errors.New("archive is redacted, secrets can not be restored")
+	// ErrUnsupportedVersion 归档格式版本不支持
+	ErrUnsupportedVersion = errors.New("unsupported archive version")
+	// ErrPrefixNotSupported 只有 etcd 后端支持导入到其他前缀
+	ErrPrefixNotSupported = errors.New("import to another key prefix is only supported by the etcd backend")
+)
+
+// Archive operator 管理的 apisix 配置归档, 环境资源按 stage key 分组
+type Archive struct {
+	Version   int                                    `json:"version"`
+	CreatedAt time.Time                              `json:"created_at"`
+	Prefix    string                                 `json:"prefix"`
+	Redacted  bool                                   `json:"redacted"`
+	Stages    map[string]*entity.ApisixStageResource `json:"stages"`
+	// Global 按网关或环境过滤时不导出全局资源
+	Global *entity.ApisixGlobalResource `json:"global,omitempty"`
+}
+
+// Decode 读取归档, 并补全 plugin metadata 的元数据
+func Decode(r io.Reader) (*Archive, error) {
+	archive := &Archive{}
+	if err := json.NewDecoder(r).Decode(archive); err != nil {
+		return nil, fmt.Errorf("decode archive failed: %w", err)
+	}
+	if archive.Version != archiveVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, archive.Version)
+	}
+	if archive.Stages == nil {
+		archive.Stages = make(map[string]*entity.ApisixStageResource)
+	}
+	if archive.Global != nil {
+		// plugin metadata 序列化后只保留配置本身, 需要从配置中解析出 id 等元数据
+		for id, pm := range archive.Global.PluginMetadata {
+			for _, raw := range pm.PluginMetadataConf {
+				if err := json.Unmarshal(raw, &pm.ResourceMetadata); err != nil {
+					return nil, fmt.Errorf("decode plugin metadata %s failed: %w", id, err)
+				}
+			}
+			pm.ID = id
+		}
+	}
+	return archive, nil
+}
+
+// Filter 按网关和环境过滤, 为空时不过滤
+type Filter struct {
+	Gateway string
+	Stage   string
+}
+
+func (f Filter) isEmpty() bool {
+	return f.Gateway == "" && f.Stage == ""
+}
+
+func (f Filter) match(conf *entity.ApisixStageResource) bool {
+	if f.isEmpty() {
+		return true
+	}
+	gatewayName, stageName := stageOwner(conf)
+	return (f.Gateway == "" || f.Gateway == gatewayName) && (f.Stage == "" || f.Stage == stageName)
+}
+
+// stageOwner 从资源的 label 中获取环境所属的网关和环境
+func stageOwner(conf *entity.ApisixStageResource) (string, string) {
+	for _, resource := range conf.Resources() {
+		return resource.GetGatewayName(), resource.GetStageName()
+	}
+	return "", ""
+}
+
+// ExportOptions ...
+type ExportOptions struct {
+	Filter
+	// Redact 将 ssl 私钥、插件中的密码等敏感字段替换为 redactedValue
+	Redact bool
+}
+
+// ImportOptions ...
+type ImportOptions struct {
+	Filter
+	// Prefix 恢复到其他的 apisix etcd 前缀, 为空时恢复到当前前缀
+	Prefix string
+	// DryRun 只返回将要产生的变更, 不写入 apisix etcd
+	DryRun bool
+}
+
+// ImportResult 恢复的结果, 只包含存在变更的环境
+type ImportResult struct {
+	Prefix string             `json:"prefix"`
+	DryRun bool               `json:"dry_run"`
+	Diffs  []*store.StageDiff `json:"diffs"`
+}
+
+// Archiver 导出和恢复 apisix 配置
+type Archiver struct {
+	store        store.ApisixStore
+	synchronizer *synchronizer.ApisixConfigSynchronizer
+
+	cfg config.Export
+
+	logger *zap.SugaredLogger
+}
+
+// NewArchiver ...
+func NewArchiver(
+	apisixStore store.ApisixStore,
+	syncer *synchronizer.ApisixConfigSynchronizer,
+	cfg config.Export,
+) *Archiver {
+	return &Archiver{
+		store:        apisixStore,
+		synchronizer: syncer,
+		cfg:          cfg,
+		logger:       logging.GetLogger().Named("archiver"),
+	}
+}
+
+// Export 导出当前 apisix etcd 前缀下的配置
+func (a *Archiver) Export(opts ExportOptions) (*Archive, error) {
+	archive := &Archive{
+		Version:   archiveVersion,
+		CreatedAt: time.Now(),
+		Prefix:    a.store.Prefix(),
+		Stages:    make(map[string]*entity.ApisixStageResource),
+	}
+	for stageKey, conf := range a.store.GetAll() {
+		if opts.match(conf) {
+			archive.Stages[stageKey] = conf
+		}
+	}
+	if opts.isEmpty() {
+		archive.Global = a.store.GetGlobal()
+	}
+
+	// 缓存中的资源是共享的, 通过序列化得到一份独立的拷贝, 脱敏时不会修改缓存
+	bytes, err := json.Marshal(archive)
+	if err != nil {
+		return nil, fmt.Errorf("encode archive failed: %w", err)
+	}
+	archive, err = Decode(strings.NewReader(string(bytes)))
+	if err != nil {
+		return nil, err
+	}
+	if opts.Redact {
+		redact(archive)
+	}
+	return archive, nil
+}
+
+// Import 将归档恢复到 apisix etcd, 归档中的环境会整体替换目标中的同名环境, 其他环境不受影响
+func (a *Archiver) Import(ctx context.Context, archive *Archive, opts ImportOptions) (*ImportResult, error) {
+	if archive.Redacted && !opts.DryRun {
+		return nil, ErrRedactedArchive
+	}
+
+	target := a.store
+	if opts.Prefix != "" && strings.TrimRight(opts.Prefix, "/") != a.store.Prefix() {
+		// 导入到其他前缀需要使用同一个 apisix etcd, admin api 后端不支持
+		etcdStore, ok := a.store.(*store.ApisixEtcdStore)
+		if !ok {
+			return nil, ErrPrefixNotSupported
+		}
+		var err error
+		target, err = etcdStore.Fork(ctx, opts.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("create store of prefix %s failed: %w", opts.Prefix, err)
+		}
+		defer target.Close()
+	}
+	result := &ImportResult{Prefix: target.Prefix(), DryRun: opts.DryRun, Diffs: make([]*store.StageDiff, 0)}
+
+	stageKeys := make([]string, 0, len(archive.Stages))
+	for stageKey, conf := range archive.Stages {
+		if opts.match(conf) {
+			stageKeys = append(stageKeys, stageKey)
+		}
+	}
+	sort.Strings(stageKeys)
+
+	for _, stageKey := range stageKeys {
+		conf := archive.Stages[stageKey]
+		diff := target.Diff(stageKey, conf)
+		if diff.IsEmpty() {
+			continue
+		}
+		result.Diffs = append(result.Diffs, diff)
+		if opts.DryRun {
+			continue
+		}
+		if err := a.restoreStage(ctx, target, stageKey, conf); err != nil {
+			return result, fmt.Errorf("restore stage %s failed: %w", stageKey, err)
+		}
+	}
+
+	if archive.Global == nil || !opts.isEmpty() {
+		return result, nil
+	}
+	diff := target.DiffGlobal(archive.Global)
+	if diff.IsEmpty() {
+		return result, nil
+	}
+	result.Diffs = append(result.Diffs, diff)
+	if opts.DryRun {
+		return result, nil
+	}
+	if err := a.restoreGlobal(ctx, target, archive.Global); err != nil {
+		return result, fmt.Errorf("restore global resources failed: %w", err)
+	}
+	return result, nil
+}
+
+// restoreStage 恢复到当前前缀时通过 synchronizer 写入, 与正常发布共用同一把锁
+func (a *Archiver) restoreStage(
+	ctx context.Context,
+	target store.ApisixStore,
+	stageKey string,
+	conf *entity.ApisixStageResource,
+) error {
+	gatewayName, stageName := stageOwner(conf)
+	if target != a.store || config.GenStagePrimaryKey(gatewayName, stageName) != stageKey {
+		return target.Alter(ctx, stageKey, conf)
+	}
+	return a.synchronizer.Sync(ctx, gatewayName, stageName, conf)
+}
+
+func (a *Archiver) restoreGlobal(
+	ctx context.Context,
+	target store.ApisixStore,
+	conf *entity.ApisixGlobalResource,
+) error {
+	if target != a.store {
+		return target.AlterGlobal(ctx, conf)
+	}
+	return a.synchronizer.SyncGlobal(ctx, conf)
+}
@@ -0,0 +1,250 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package archive_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	json "github.com/json-iterator/go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/archive"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/tests/util"
+)
+
+var _ = Describe("Archiver", func() {
+	var (
+		etcd      *embed.Etcd
+		client    *clientv3.Client
+		etcdStore *store.ApisixEtcdStore
+		archiver  *archive.Archiver
+		ctx       context.Context
+		dir       string
+	)
+
+	newStageConf := func(gatewayName string) *entity.ApisixStageResource {
+		labels := &entity.LabelInfo{Gateway: gatewayName, Stage: "prod", PublishId: "1"}
+		conf := entity.NewEmptyApisixConfiguration()
+		conf.Routes[gatewayName+"-route"] = &entity.Route{
+			ResourceMetadata: entity.ResourceMetadata{ID: gatewayName + "-route", Labels: labels},
+			URI:              "/" + gatewayName,
+			Plugins: map[string]any{
+				"basic-auth": map[string]any{"username": "admin", "password": "123456"},
+			},
+		}
+		conf.SSLs[gatewayName+"-ssl"] = &entity.SSL{
+			ResourceMetadata: entity.ResourceMetadata{ID: gatewayName + "-ssl", Labels: labels},
+			Cert:             "cert",
+			Key:              "private key",
+			Snis:             []string{gatewayName + ".example.com"},
+		}
+		conf.Consumers[gatewayName+"-app"] = &entity.Consumer{
+			ResourceMetadata: entity.ResourceMetadata{Labels: labels},
+			Username:         gatewayName + "-app",
+			Plugins: map[string]any{
+				"key-auth": map[string]any{"key": "app-key"},
+			},
+		}
+		return conf
+	}
+
+	newGlobalConf := func() *entity.ApisixGlobalResource {
+		conf := entity.NewEmptyApisixGlobalResource()
+		conf.PluginMetadata["bk-concurrency-limit"] = &entity.PluginMetadata{
+			ResourceMetadata: entity.ResourceMetadata{ID: "bk-concurrency-limit"},
+			PluginMetadataConf: entity.PluginMetadataConf{
+				"bk-concurrency-limit": []byte(`{"id":"bk-concurrency-limit","secret":"abc"}`),
+			},
+		}
+		return conf
+	}
+
+	countKeys := func(prefix string) int64 {
+		resp, err := client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+		Expect(err).ShouldNot(HaveOccurred())
+		return resp.Count
+	}
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		metric.InitMetric(prometheus.NewRegistry())
+		synchronizer.Init(&config.Config{})
+		dir = GinkgoT().TempDir()
+
+		client, etcd, err = util.StartEmbedEtcdClient(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		etcdStore, err = store.NewApisixEtcdStore(ctx, client, "/apisix",
+			10*time.Millisecond, 10*time.Millisecond, 5*time.Second)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		syncer := synchronizer.NewSynchronizer(etcdStore, "/healthz")
+		archiver = archive.NewArchiver(etcdStore, syncer, config.Export{Dir: dir, Retention: 2})
+
+		Expect(syncer.Sync(ctx, "gw-a", "prod", newStageConf("gw-a"))).To(Succeed())
+		Expect(syncer.Sync(ctx, "gw-b", "prod", newStageConf("gw-b"))).To(Succeed())
+		Expect(syncer.SyncGlobal(ctx, newGlobalConf())).To(Succeed())
+		// 同步全局资源时还会写入 operator 的虚拟环境; 缓存按资源类型分别 watch, 需要等待每个环境的所有资源
+		Eventually(func() int {
+			return len(etcdStore.GetAll()) + len(etcdStore.GetGlobal().PluginMetadata)
+		}, 5*time.Second, 20*time.Millisecond).Should(Equal(4))
+		for _, gatewayName := range []string{"gw-a", "gw-b"} {
+			Eventually(func() int {
+				return len(etcdStore.Get(config.GenStagePrimaryKey(gatewayName, "prod")).Resources())
+			}, 5*time.Second, 20*time.Millisecond).Should(Equal(3))
+		}
+	})
+
+	AfterEach(func() {
+		etcdStore.Close()
+		client.Close()
+		etcd.Close()
+		_ = os.RemoveAll(etcd.Config().Dir)
+	})
+
+	It("should export the stages matching the filter without global resources", func() {
+		output, err := archiver.Export(archive.ExportOptions{Filter: archive.Filter{Gateway: "gw-a"}})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(output.Prefix).To(Equal("/apisix"))
+		Expect(output.Stages).To(HaveLen(1))
+		Expect(output.Stages).To(HaveKey(config.GenStagePrimaryKey("gw-a", "prod")))
+		Expect(output.Global).To(BeNil())
+
+		output, err = archiver.Export(archive.ExportOptions{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(output.Stages).To(HaveLen(3))
+		Expect(output.Global.PluginMetadata).To(HaveKey("bk-concurrency-limit"))
+	})
+
+	It("should redact the secrets without changing the store", func() {
+		output, err := archiver.Export(archive.ExportOptions{Redact: true})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(output.Redacted).To(BeTrue())
+
+		conf := output.Stages[config.GenStagePrimaryKey("gw-a", "prod")]
+		Expect(conf.SSLs["gw-a-ssl"].Key).To(Equal("******"))
+		Expect(conf.SSLs["gw-a-ssl"].Cert).To(Equal("cert"))
+		Expect(conf.Routes["gw-a-route"].Plugins["basic-auth"]).To(Equal(
+			map[string]any{"username": "admin", "password": "******"}))
+		Expect(conf.Consumers["gw-a-app"].Plugins["key-auth"]).To(Equal(map[string]any{"key": "******"}))
+		Expect(string(output.Global.PluginMetadata["bk-concurrency-limit"].
+			PluginMetadataConf["bk-concurrency-limit"])).To(ContainSubstring(`"secret":"******"`))
+
+		cached := etcdStore.Get(config.GenStagePrimaryKey("gw-a", "prod"))
+		Expect(cached.SSLs["gw-a-ssl"].Key).To(Equal("private key"))
+		Expect(cached.Consumers["gw-a-app"].Plugins["key-auth"]).To(Equal(map[string]any{"key": "app-key"}))
+	})
+
+	It("should decode the archive written by export", func() {
+		output, err := archiver.Export(archive.ExportOptions{})
+		Expect(err).ShouldNot(HaveOccurred())
+		bytes, err := json.Marshal(output)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		decoded, err := archive.Decode(strings.NewReader(string(bytes)))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(decoded.Stages).To(HaveLen(3))
+		Expect(decoded.Global.PluginMetadata["bk-concurrency-limit"].ID).To(Equal("bk-concurrency-limit"))
+
+		_, err = archive.Decode(strings.NewReader(`{"version":100}`))
+		Expect(err).To(MatchError(archive.ErrUnsupportedVersion))
+	})
+
+	It("should only show the diff in dry run", func() {
+		output, err := archiver.Export(archive.ExportOptions{})
+		Expect(err).ShouldNot(HaveOccurred())
+		stageKey := config.GenStagePrimaryKey("gw-a", "prod")
+		output.Stages[stageKey].Routes["gw-a-route"].URI = "/changed"
+
+		result, err := archiver.Import(ctx, output, archive.ImportOptions{DryRun: true})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(result.DryRun).To(BeTrue())
+		Expect(result.Diffs).To(HaveLen(1))
+		Expect(result.Diffs[0].StageKey).To(Equal(stageKey))
+		Expect(result.Diffs[0].Put).To(Equal(map[string][]string{"routes": {"gw-a-route"}}))
+		Expect(etcdStore.Get(stageKey).Routes["gw-a-route"].URI).To(Equal("/gw-a"))
+	})
+
+	It("should restore to another prefix", func() {
+		output, err := archiver.Export(archive.ExportOptions{})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		result, err := archiver.Import(ctx, output, archive.ImportOptions{
+			Filter: archive.Filter{Gateway: "gw-b"},
+			Prefix: "/apisix-restore",
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(result.Prefix).To(Equal("/apisix-restore"))
+		Expect(result.Diffs).To(HaveLen(1))
+		Expect(countKeys("/apisix-restore/routes/")).To(Equal(int64(1)))
+		Expect(countKeys("/apisix-restore/ssls/")).To(Equal(int64(1)))
+		Expect(countKeys("/apisix-restore/consumers/gw-b-app")).To(Equal(int64(1)))
+		Expect(countKeys("/apisix-restore/plugin_metadata/")).To(BeZero())
+	})
+
+	It("should refuse to restore a redacted archive", func() {
+		output, err := archiver.Export(archive.ExportOptions{Redact: true})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		_, err = archiver.Import(ctx, output, archive.ImportOptions{Prefix: "/apisix-restore"})
+		Expect(err).To(MatchError(archive.ErrRedactedArchive))
+
+		result, err := archiver.Import(ctx, output, archive.ImportOptions{Prefix: "/apisix-restore", DryRun: true})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(result.Diffs).To(HaveLen(4))
+		Expect(countKeys("/apisix-restore/")).To(BeZero())
+	})
+
+	It("should keep the latest archives in dir", func() {
+		for _, name := range []string{"apisix-20200101T000000Z.json", "apisix-20200102T000000Z.json", "other.json"} {
+			Expect(os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o600)).To(Succeed())
+		}
+
+		path, err := archiver.ExportToDir()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		entries, err := os.ReadDir(dir)
+		Expect(err).ShouldNot(HaveOccurred())
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		Expect(names).To(ConsistOf("apisix-20200102T000000Z.json", filepath.Base(path), "other.json"))
+
+		file, err := os.Open(path)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer file.Close()
+		decoded, err := archive.Decode(file)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(decoded.Stages).To(HaveLen(3))
+	})
+})
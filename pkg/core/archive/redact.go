@@ -0,0 +1,89 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package archive
+
+import (
+	json "github.com/json-iterator/go"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils/redactx"
+)
+
+// redact 脱敏 ssl 私钥、上游 mTLS 私钥以及插件配置中的敏感字段
+func redact(archive *Archive) {
+	archive.Redacted = true
+	for _, conf := range archive.Stages {
+		for _, route := range conf.Routes {
+			redactx.Plugins(route.Plugins)
+			redactUpstream(route.Upstream)
+		}
+		for _, service := range conf.Services {
+			redactx.Plugins(service.Plugins)
+			redactUpstream(service.Upstream)
+		}
+		for _, upstream := range conf.Upstreams {
+			redactUpstream(&upstream.UpstreamDef)
+		}
+		for _, pluginConfig := range conf.PluginConfigs {
+			redactx.Plugins(pluginConfig.Plugins)
+		}
+		for _, consumer := range conf.Consumers {
+			redactx.ConsumerPlugins(consumer.Plugins)
+		}
+		for _, consumerGroup := range conf.ConsumerGroups {
+			redactx.Plugins(consumerGroup.Plugins)
+		}
+		for _, ssl := range conf.SSLs {
+			if ssl.Key != "" {
+				ssl.Key = redactx.Mask
+			}
+			for i := range ssl.Keys {
+				ssl.Keys[i] = redactx.Mask
+			}
+		}
+		for _, streamRoute := range conf.StreamRoutes {
+			redactx.Plugins(streamRoute.Plugins)
+			redactUpstream(streamRoute.Upstream)
+		}
+	}
+	if archive.Global == nil {
+		return
+	}
+	for _, globalRule := range archive.Global.GlobalRules {
+		redactx.Plugins(globalRule.Plugins)
+	}
+	for _, pm := range archive.Global.PluginMetadata {
+		for name, raw := range pm.PluginMetadataConf {
+			var conf map[string]any
+			if err := json.Unmarshal(raw, &conf); err != nil {
+				continue
+			}
+			redactx.Value(conf)
+			if bytes, err := json.Marshal(conf); err == nil {
+				pm.PluginMetadataConf[name] = bytes
+			}
+		}
+	}
+}
+
+func redactUpstream(upstream *entity.UpstreamDef) {
+	if upstream != nil && upstream.TLS != nil && upstream.TLS.ClientKey != "" {
+		upstream.TLS.ClientKey = redactx.Mask
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package archive
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	json "github.com/json-iterator/go"
+)
+
+const (
+	archiveFilePrefix = "apisix-"
+	archiveFileSuffix = ".json"
+)
+
+// Run 按 interval 周期导出到本地目录, 直到 ctx 结束
+func (a *Archiver) Run(ctx context.Context) {
+	a.logger.Infow("scheduled export started",
+		"interval", a.cfg.Interval, "dir", a.cfg.Dir, "retention", a.cfg.Retention)
+	for {
+		select {
+		case <-time.After(a.cfg.Interval):
+			path, err := a.ExportToDir()
+			if err != nil {
+				a.logger.Errorw("scheduled export failed", "err", err)
+				continue
+			}
+			a.logger.Infow("scheduled export finished", "path", path)
+		case <-ctx.Done():
+			a.logger.Infow("scheduled export stopped")
+			return
+		}
+	}
+}
+
+// ExportToDir 导出全部配置到本地目录, 并清理超出保留数量的旧归档, 返回归档文件路径
+func (a *Archiver) ExportToDir() (string, error) {
+	archive, err := a.Export(ExportOptions{Redact: a.cfg.Redact})
+	if err != nil {
+		return "", err
+	}
+	bytes, err := json.Marshal(archive)
+	if err != nil {
+		return "", fmt.Errorf("encode archive failed: %w", err)
+	}
+	// 未脱敏的归档包含私钥, 只允许当前用户读写
+	if err = os.MkdirAll(a.cfg.Dir, 0o700); err != nil {
+		return "", fmt.Errorf("create archive dir failed: %w", err)
+	}
+
+	name := archiveFilePrefix + archive.CreatedAt.UTC().Format("20060102T150405Z") + archiveFileSuffix
+	path := filepath.Join(a.cfg.Dir, name)
+	// 先写临时文件再重命名, 避免留下不完整的归档
+	tmpPath := filepath.Join(a.cfg.Dir, "."+name+".tmp")
+	if err = os.WriteFile(tmpPath, bytes, 0o600); err != nil {
+		return "", fmt.Errorf("write archive failed: %w", err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("rename archive failed: %w", err)
+	}
+
+	if err = a.pruneDir(); err != nil {
+		a.logger.Errorw("prune archives failed", "err", err, "dir", a.cfg.Dir)
+	}
+	return path, nil
+}
+
+// pruneDir 删除超出保留数量的旧归档, 文件名中的时间保证了按名称排序即按时间排序
+func (a *Archiver) pruneDir() error {
+	if a.cfg.Retention <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(a.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, archiveFilePrefix) && strings.HasSuffix(name, archiveFileSuffix) {
+			names = append(names, name)
+		}
+	}
+	if len(names) <= a.cfg.Retention {
+		return nil
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-a.cfg.Retention] {
+		if err = os.Remove(filepath.Join(a.cfg.Dir, name)); err != nil {
+			return err
+		}
+		a.logger.Debugw("delete expired archive", "name", name)
+	}
+	return nil
+}
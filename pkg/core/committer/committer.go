@@ -26,6 +26,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/agent/timer"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
@@ -37,11 +38,9 @@ import (
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils"
 )
 
-const maxStageRetryCount = 3
-
 // Committer ...
 type Committer struct {
-	apigwEtcdRegistry  *registry.APIGWEtcdRegistry
+	apigwEtcdRegistry  registry.APIGWRegistry
 	commitResourceChan chan []*entity.ReleaseInfo
 
 	synchronizer *synchronizer.ApisixConfigSynchronizer
@@ -53,12 +52,20 @@ type Committer struct {
 	// Gateway stage dimension
 	gatewayStageChanMap     map[string]chan struct{}
 	gatewayStageChanMapLock *sync.RWMutex
+
+	// 超过最大重试次数的发布, key 与 release timer 的 key 一致
+	deadLetters    map[string]*DeadLetter
+	deadLettersMux sync.RWMutex
+
+	// 重试和死信的持久化, 为空时只保存在内存中; persisted 为已经写入 retryStore 的 key
+	retryStore *RetryStore
+	persisted  map[string]struct{}
 }
 
 // NewCommitter 创建 Committer
 // commitChanSize: buffer size for commit resource channel, use 0 for unbuffered (not recommended)
 func NewCommitter(
-	apigwEtcdRegistry *registry.APIGWEtcdRegistry,
+	apigwEtcdRegistry registry.APIGWRegistry,
 	synchronizer *synchronizer.ApisixConfigSynchronizer,
 	releaseTimer *timer.ReleaseTimer,
 	commitChanSize int,
@@ -80,6 +87,8 @@ func NewCommitter(
 			map[string]chan struct{},
 		), // Map for storing gateway stage channels
 		gatewayStageChanMapLock: &sync.RWMutex{},
+		deadLetters:             make(map[string]*DeadLetter),
+		persisted:               make(map[string]struct{}),
 	}
 }
 
@@ -140,7 +149,7 @@ func (c *Committer) commitGroup(ctx context.Context, releaseInfoList []*entity.R
 	for _, resourceInfo := range releaseInfoList {
 		wg.Add(1)
 		tmpResourceInfo := resourceInfo
-		// 判断是否是 global 资源：PluginMetadata 且 Stage 为空
+		// 判断是否是 global 资源：全局资源类型且 Stage 为空
 		if tmpResourceInfo.IsGlobalResource() {
 			// Global 资源需要单独处理
 			utils.GoroutineWithRecovery(ctx, func() {
@@ -222,7 +231,7 @@ func (c *Committer) commitStage(ctx context.Context, si *entity.ReleaseInfo, sta
 	if err != nil {
 		c.logger.Error(err, "get native apisix configuration failed", "stageInfo", si)
 		// retry
-		c.retryStage(si)
+		c.retryStage(si, err)
 		span.RecordError(err)
 		eventreporter.ReportParseConfigurationFailureEvent(ctx, si, err)
 		// 释放 channel
@@ -234,6 +243,7 @@ func (c *Committer) commitStage(ctx context.Context, si *entity.ReleaseInfo, sta
 	eventreporter.ReportApplyConfigurationDoingEvent(ctx, si)
 
 	span.AddEvent("committer.Sync")
+	stageKey := config.GenStagePrimaryKey(si.GetGatewayName(), si.GetStageName())
 	err = c.synchronizer.Sync(
 		ctx,
 		si.GetGatewayName(),
@@ -243,30 +253,25 @@ func (c *Committer) commitStage(ctx context.Context, si *entity.ReleaseInfo, sta
 	if err != nil {
 		c.logger.Error(err, "sync apisix configuration failed", "stageInfo", si)
 		// retry
-		c.retryStage(si)
+		c.retryStage(si, err)
 		span.RecordError(err)
-		eventreporter.ReportApplyConfigurationFailureEvent(ctx, si, err)
+		eventreporter.ReportApplyConfigurationFailureEvent(ctx, si, err, c.synchronizer.TargetResults(stageKey))
 		// 释放 channel
 		<-stageChan
 		stageChannelReleased = true
 		return
 	}
-	// eventrepoter.ReportApplyConfigurationSuccessEvent(ctx, stage) // 可以由事件之前的关系推断出来
+	c.resolveDeadLetter(si)
+	// 只有一个 apisix 集群时可以由事件之前的关系推断出来, 多个集群时上报各集群的同步结果
+	if targets := c.synchronizer.TargetResults(stageKey); targets != nil {
+		eventreporter.ReportApplyConfigurationSuccessEvent(ctx, si, targets)
+	}
 	// Mark as released since ReportLoadConfigurationResultEvent will handle it
 	stageChannelReleased = true
 	eventreporter.ReportLoadConfigurationResultEvent(ctx, si, stageChan)
 	c.logger.Infow("commit stage success", "stageInfo", si)
 }
 
-func (c *Committer) retryStage(si *entity.ReleaseInfo) {
-	if si.RetryCount >= maxStageRetryCount {
-		c.logger.Errorw("too many retries", "stageInfo", si)
-		return
-	}
-	si.RetryCount++
-	c.releaseTimer.Update(si)
-}
-
 // GetStageReleaseNativeApisixConfiguration 直接从 etcd 获取原生 apisix 配置
 func (c *Committer) GetStageReleaseNativeApisixConfiguration(
 	ctx context.Context,
@@ -312,7 +317,7 @@ func (c *Committer) commitGlobalResource(ctx context.Context, si *entity.Release
 	if err != nil {
 		c.logger.Error(err, "get native global apisix configuration failed", "globalInfo", si)
 		// retry
-		c.retryStage(si)
+		c.retryStage(si, err)
 		span.RecordError(err)
 		return
 	}
@@ -324,10 +329,11 @@ func (c *Committer) commitGlobalResource(ctx context.Context, si *entity.Release
 	if err != nil {
 		c.logger.Error(err, "sync global apisix configuration failed", "globalInfo", si)
 		// retry
-		c.retryStage(si)
+		c.retryStage(si, err)
 		span.RecordError(err)
 		return
 	}
+	c.resolveDeadLetter(si)
 	c.logger.Infow("commit global resource success", "globalInfo", si)
 }
 
@@ -20,16 +20,28 @@ package committer
 
 import (
 	"context"
+	"errors"
+	"os"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
 
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/agent/timer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/tests/util"
 )
 
+var errSync = errors.New("sync failed")
+
 var _ = Describe("Committer", func() {
 	var (
 		committer    *Committer
@@ -156,7 +168,7 @@ var _ = Describe("Committer", func() {
 				},
 			}
 
-			committer.retryStage(releaseInfo)
+			committer.retryStage(releaseInfo, errSync)
 			Expect(releaseInfo.RetryCount).To(Equal(int64(1)))
 		})
 
@@ -172,10 +184,174 @@ var _ = Describe("Committer", func() {
 				},
 			}
 
-			committer.retryStage(releaseInfo)
+			committer.retryStage(releaseInfo, errSync)
 			// RetryCount should not increase beyond max
 			Expect(releaseInfo.RetryCount).To(Equal(int64(maxStageRetryCount)))
 		})
+
+		It("should schedule the retry with backoff instead of the events waiting window", func() {
+			releaseInfo := &entity.ReleaseInfo{
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:     "test-release",
+					Labels: &entity.LabelInfo{Gateway: "test-gateway", Stage: "test-stage"},
+				},
+			}
+
+			committer.retryStage(releaseInfo, errSync)
+			Expect(releaseTimer.ListReleaseForCommit()).To(BeEmpty())
+		})
+	})
+
+	Describe("dead letters", func() {
+		var releaseInfo *entity.ReleaseInfo
+
+		BeforeEach(func() {
+			releaseInfo = &entity.ReleaseInfo{
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:         "test-release",
+					Labels:     &entity.LabelInfo{Gateway: "test-gateway", Stage: "test-stage"},
+					RetryCount: maxStageRetryCount,
+				},
+				PublishId: 10,
+			}
+			committer.retryStage(releaseInfo, errSync)
+		})
+
+		It("should move the stage to the dead letter list after max retries", func() {
+			letters := committer.ListDeadLetters()
+			Expect(letters).To(HaveLen(1))
+			Expect(letters[0].Key).To(Equal("bk.release.test-gateway.test-stage"))
+			Expect(letters[0].Gateway).To(Equal("test-gateway"))
+			Expect(letters[0].Stage).To(Equal("test-stage"))
+			Expect(letters[0].PublishID).To(Equal(10))
+			Expect(letters[0].Attempts).To(Equal(maxStageRetryCount + 1))
+			Expect(letters[0].LastError).To(Equal(errSync.Error()))
+		})
+
+		It("should remove the dead letter after commit success", func() {
+			committer.resolveDeadLetter(releaseInfo)
+			Expect(committer.ListDeadLetters()).To(BeEmpty())
+		})
+
+		It("should redrive one dead letter", func() {
+			Expect(committer.Redrive("not-exist")).To(MatchError(ErrDeadLetterNotFound))
+			Expect(committer.Redrive("bk.release.test-gateway.test-stage")).To(Succeed())
+			Expect(committer.ListDeadLetters()).To(BeEmpty())
+			Expect(releaseInfo.RetryCount).To(BeZero())
+			Eventually(releaseTimer.ListReleaseForCommit, 5*time.Second, 100*time.Millisecond).Should(HaveLen(1))
+		})
+
+		It("should redrive all dead letters", func() {
+			globalRelease := &entity.ReleaseInfo{
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:         "global-plugin",
+					Kind:       constant.PluginMetadata,
+					RetryCount: maxStageRetryCount,
+				},
+			}
+			committer.retryStage(globalRelease, errSync)
+			Expect(committer.ListDeadLetters()).To(HaveLen(2))
+
+			Expect(committer.RedriveAll()).To(Equal(2))
+			Expect(committer.ListDeadLetters()).To(BeEmpty())
+		})
+	})
+
+	Describe("retry store", func() {
+		var (
+			etcd       *embed.Etcd
+			client     *clientv3.Client
+			retryStore *RetryStore
+		)
+
+		BeforeEach(func() {
+			var err error
+			client, etcd, err = util.StartEmbedEtcdClient(context.Background())
+			Expect(err).ShouldNot(HaveOccurred())
+			retryStore = NewRetryStore(client, "/bk-gateway-operator/commit-retries", 5*time.Second)
+			committer.EnableRetryStore(retryStore)
+		})
+
+		AfterEach(func() {
+			client.Close()
+			etcd.Close()
+			_ = os.RemoveAll(etcd.Config().Dir)
+		})
+
+		It("should restore the retries and dead letters in the next leader", func() {
+			ctx := context.Background()
+			metric.InitMetric(prometheus.NewRegistry())
+			// 控制面中环境的资源, 恢复的重试需要带上 api 版本才能查询到
+			routeKey := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/route/test-gateway.test-stage.1"
+			_, err := client.Put(ctx, routeKey, `{"id": "test-gateway.test-stage.1", "uri": "/test",
+				"upstream": {"type": "roundrobin", "nodes": [{"host": "1.1.1.1", "port": 80, "weight": 1}]},
+				"labels": {"gateway.bk.tencent.com/gateway": "test-gateway",
+					"gateway.bk.tencent.com/stage": "test-stage",
+					"gateway.bk.tencent.com/apisix-version": "3.13.0"}}`)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			stageRelease := &entity.ReleaseInfo{
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:         "test-release",
+					APIVersion: "v2",
+					Labels:     &entity.LabelInfo{Gateway: "test-gateway", Stage: "test-stage"},
+				},
+				PublishId: 10,
+			}
+			committer.retryStage(stageRelease, errSync)
+			globalRelease := &entity.ReleaseInfo{
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:         "global-plugin",
+					APIVersion: "v2",
+					Kind:       constant.PluginMetadata,
+					RetryCount: maxStageRetryCount,
+				},
+			}
+			committer.retryStage(globalRelease, errSync)
+
+			records, err := retryStore.List(context.Background())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(records).To(HaveLen(2))
+
+			apisixStore, err := store.NewApisixEtcdStore(ctx, client, "/apisix",
+				10*time.Millisecond, 10*time.Millisecond, 5*time.Second)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer apisixStore.Close()
+			nextTimer := timer.NewReleaseTimer()
+			next := NewCommitter(registry.NewAPIGWEtcdRegistry(client, "/bk-gateway-apigw", 100),
+				synchronizer.NewSynchronizer(apisixStore, "/healthz"), nextTimer, 100)
+			next.EnableRetryStore(retryStore)
+			Expect(next.Restore(context.Background())).To(Succeed())
+
+			letters := next.ListDeadLetters()
+			Expect(letters).To(HaveLen(1))
+			Expect(letters[0].Key).To(Equal(constant.GlobalResourceKey))
+			Expect(letters[0].releaseInfo.IsGlobalResource()).To(BeTrue())
+			Expect(letters[0].releaseInfo.APIVersion).To(Equal("v2"))
+
+			var restored []*entity.ReleaseInfo
+			Eventually(func() []*entity.ReleaseInfo {
+				restored = append(restored, nextTimer.ListReleaseForCommit()...)
+				return restored
+			}, 10*time.Second, 100*time.Millisecond).Should(HaveLen(1))
+			Expect(restored[0].GetReleaseID()).To(Equal("bk.release.test-gateway.test-stage"))
+			Expect(restored[0].RetryCount).To(Equal(int64(1)))
+			Expect(restored[0].PublishId).To(Equal(10))
+			Expect(restored[0].APIVersion).To(Equal("v2"))
+
+			// 恢复的重试写入控制面中环境的资源, 而不是空配置; 提交成功后删除持久化的记录
+			conf, err := next.GetStageReleaseNativeApisixConfiguration(ctx, restored[0])
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(conf.Routes).To(HaveKey("test-gateway.test-stage.1"))
+			next.commitGroup(ctx, restored)
+			resp, err := client.Get(ctx, "/apisix/routes/test-gateway.test-stage.1")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resp.Kvs).To(HaveLen(1))
+			next.resolveDeadLetter(letters[0].releaseInfo)
+			records, err = retryStore.List(context.Background())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(records).To(BeEmpty())
+		})
 	})
 
 	Describe("Run", func() {
@@ -717,12 +893,12 @@ var _ = Describe("Committer", func() {
 			}
 
 			// retryStage should work for global resources too
-			committer.retryStage(globalRelease)
+			committer.retryStage(globalRelease, errSync)
 			Expect(globalRelease.RetryCount).To(Equal(int64(1)))
 
 			// Should respect max retry count
 			globalRelease.RetryCount = maxStageRetryCount
-			committer.retryStage(globalRelease)
+			committer.retryStage(globalRelease, errSync)
 			Expect(globalRelease.RetryCount).To(Equal(int64(maxStageRetryCount)))
 		})
 	})
@@ -0,0 +1,47 @@
+// Package committer ...
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+// Package committer ...
+package committer
+
+import (
+	"time"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils"
+)
+
+var (
+	// maxStageRetryCount 超过最大重试次数后进入死信列表
+	maxStageRetryCount int64 = 3
+	// retryBackoff 提交失败后重试的等待时间
+	retryBackoff = utils.Backoff{BaseDelay: 2 * time.Second, MaxDelay: 5 * time.Minute}
+)
+
+// Init ...
+func Init(cfg *config.Config) {
+	if cfg.Operator.CommitRetry.MaxAttempts > 0 {
+		maxStageRetryCount = int64(cfg.Operator.CommitRetry.MaxAttempts)
+	}
+	if cfg.Operator.CommitRetry.BaseDelay > 0 {
+		retryBackoff.BaseDelay = cfg.Operator.CommitRetry.BaseDelay
+	}
+	if cfg.Operator.CommitRetry.MaxDelay > 0 {
+		retryBackoff.MaxDelay = cfg.Operator.CommitRetry.MaxDelay
+	}
+}
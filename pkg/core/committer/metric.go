@@ -30,3 +30,30 @@ func ReportResourceConvertedMetric(gateway, stage, resType string, numbers int)
 
 	metric.ResourceConvertedCounter.WithLabelValues(gateway, stage, resType).Add(float64(numbers))
 }
+
+// ReportCommitRetryMetric ...
+func ReportCommitRetryMetric(gateway, stage string) {
+	if metric.CommitRetryCounter == nil {
+		return
+	}
+
+	metric.CommitRetryCounter.WithLabelValues(gateway, stage).Inc()
+}
+
+// ReportDeadLetterMetric ...
+func ReportDeadLetterMetric(gateway, stage string) {
+	if metric.CommitDeadLetterGauge == nil {
+		return
+	}
+
+	metric.CommitDeadLetterGauge.WithLabelValues(gateway, stage).Set(1)
+}
+
+// DeleteDeadLetterMetric ...
+func DeleteDeadLetterMetric(gateway, stage string) {
+	if metric.CommitDeadLetterGauge == nil {
+		return
+	}
+
+	metric.CommitDeadLetterGauge.DeleteLabelValues(gateway, stage)
+}
@@ -0,0 +1,115 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package committer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	json "github.com/json-iterator/go"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+)
+
+// RetryRecord 持久化的重试或死信, DeadLetter 不为空时为死信
+type RetryRecord struct {
+	Release *entity.ReleaseInfo `json:"release"`
+	// ReleaseInfo 序列化时不包含 kind、api 版本、来源和重试次数, 单独保存;
+	// api 版本为空时查询不到环境的资源, 会把空配置当作期望的状态写入 apisix
+	Kind       constant.APISIXResource `json:"kind"`
+	APIVersion string                  `json:"api_version"`
+	Origin     string                  `json:"origin,omitempty"`
+	RetryCount int64                   `json:"retry_count"`
+	DeadLetter *DeadLetter             `json:"dead_letter,omitempty"`
+}
+
+// RetryStore 在 etcd 的独立前缀下保存等待重试的发布和死信, 切换 leader 后由新的 leader 加载
+//
+//	{prefix}/{key}: 重试记录, key 与 release timer 的 key 一致
+type RetryStore struct {
+	client  *clientv3.Client
+	prefix  string
+	timeout time.Duration
+
+	logger *zap.SugaredLogger
+}
+
+// NewRetryStore ...
+func NewRetryStore(client *clientv3.Client, prefix string, timeout time.Duration) *RetryStore {
+	return &RetryStore{
+		client:  client,
+		prefix:  strings.TrimRight(prefix, "/") + "/",
+		timeout: timeout,
+		logger:  logging.GetLogger().Named("retry-store"),
+	}
+}
+
+// Save 保存重试记录, 同一个 key 覆盖写入
+func (s *RetryStore) Save(ctx context.Context, key string, record *RetryRecord) error {
+	bytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal retry record failed: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err = s.client.Put(ctx, s.prefix+key, string(bytes))
+	return err
+}
+
+// Delete 提交成功后删除重试记录
+func (s *RetryStore) Delete(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.client.Delete(ctx, s.prefix+key)
+	return err
+}
+
+// List 返回全部重试记录, key 与 release timer 的 key 一致
+func (s *RetryStore) List(ctx context.Context) (map[string]*RetryRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	resp, err := s.client.Get(ctx, s.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("list retry records failed: %w", err)
+	}
+	records := make(map[string]*RetryRecord, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		record := &RetryRecord{}
+		if err = json.Unmarshal(kv.Value, record); err != nil || record.Release == nil {
+			s.logger.Errorw("unmarshal retry record failed", "key", string(kv.Key), "err", err)
+			continue
+		}
+		if record.APIVersion == "" {
+			// 没有 api 版本的记录无法查询到环境的资源, 重试会清空环境, 跳过
+			s.logger.Errorw("retry record without api version", "key", string(kv.Key))
+			continue
+		}
+		records[strings.TrimPrefix(string(kv.Key), s.prefix)] = record
+	}
+	return records, nil
+}
@@ -0,0 +1,217 @@
+// Package committer ...
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+// Package committer ...
+package committer
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/agent/timer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+)
+
+// ErrDeadLetterNotFound ...
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter 超过最大重试次数仍然提交失败的发布
+type DeadLetter struct {
+	// Key 与 release timer 的 key 一致, 环境资源为 stage key, 全局资源为 global_resource
+	Key       string    `json:"key"`
+	Gateway   string    `json:"gateway_name"`
+	Stage     string    `json:"stage_name"`
+	PublishID int       `json:"publish_id"`
+	Attempts  int64     `json:"attempts"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+
+	releaseInfo *entity.ReleaseInfo
+}
+
+func (c *Committer) retryStage(si *entity.ReleaseInfo, err error) {
+	if si.RetryCount >= maxStageRetryCount {
+		c.logger.Errorw("too many retries, move to dead letter list", "stageInfo", si, "err", err)
+		c.addDeadLetter(si, err)
+		return
+	}
+	si.RetryCount++
+	delay := retryBackoff.Delay(si.RetryCount)
+	c.logger.Warnw("commit failed, retry later", "stageInfo", si, "attempt", si.RetryCount, "delay", delay)
+	ReportCommitRetryMetric(si.GetGatewayName(), si.GetStageName())
+	c.releaseTimer.Retry(si, delay)
+	c.persist(si, nil)
+}
+
+func (c *Committer) addDeadLetter(si *entity.ReleaseInfo, err error) {
+	letter := &DeadLetter{
+		Key:         timer.ReleaseCacheKey(si),
+		Gateway:     si.GetGatewayName(),
+		Stage:       si.GetStageName(),
+		PublishID:   si.PublishId,
+		Attempts:    si.RetryCount + 1,
+		FailedAt:    time.Now(),
+		releaseInfo: si,
+	}
+	if err != nil {
+		letter.LastError = err.Error()
+	}
+
+	c.deadLettersMux.Lock()
+	c.deadLetters[letter.Key] = letter
+	c.deadLettersMux.Unlock()
+	ReportDeadLetterMetric(letter.Gateway, letter.Stage)
+	c.persist(si, letter)
+}
+
+// resolveDeadLetter 提交成功后移出死信列表, 并删除持久化的重试记录
+func (c *Committer) resolveDeadLetter(si *entity.ReleaseInfo) {
+	key := timer.ReleaseCacheKey(si)
+	c.deadLettersMux.Lock()
+	letter, ok := c.deadLetters[key]
+	if ok {
+		delete(c.deadLetters, letter.Key)
+	}
+	_, persisted := c.persisted[key]
+	delete(c.persisted, key)
+	c.deadLettersMux.Unlock()
+	if ok {
+		DeleteDeadLetterMetric(letter.Gateway, letter.Stage)
+	}
+	if persisted {
+		if err := c.retryStore.Delete(context.Background(), key); err != nil {
+			c.logger.Errorw("delete retry record failed", "key", key, "err", err)
+		}
+	}
+}
+
+// EnableRetryStore 持久化等待重试的发布和死信, 需要在 Restore 和 Run 之前调用
+func (c *Committer) EnableRetryStore(retryStore *RetryStore) {
+	c.retryStore = retryStore
+}
+
+// persist 将等待重试的发布或者死信写入 retryStore, 失败时只保留在内存中
+func (c *Committer) persist(si *entity.ReleaseInfo, letter *DeadLetter) {
+	if c.retryStore == nil {
+		return
+	}
+	key := timer.ReleaseCacheKey(si)
+	record := &RetryRecord{
+		Release:    si,
+		Kind:       si.Kind,
+		APIVersion: si.APIVersion,
+		Origin:     si.Origin,
+		RetryCount: si.RetryCount,
+		DeadLetter: letter,
+	}
+	if err := c.retryStore.Save(context.Background(), key, record); err != nil {
+		c.logger.Errorw("save retry record failed", "key", key, "err", err)
+		return
+	}
+	c.deadLettersMux.Lock()
+	c.persisted[key] = struct{}{}
+	c.deadLettersMux.Unlock()
+}
+
+// Restore 成为 leader 后加载之前的 leader 留下的重试和死信, 重试按照已经重试的次数继续等待
+func (c *Committer) Restore(ctx context.Context) error {
+	if c.retryStore == nil {
+		return nil
+	}
+	records, err := c.retryStore.List(ctx)
+	if err != nil {
+		return err
+	}
+	for key, record := range records {
+		si := record.Release
+		si.Kind = record.Kind
+		si.APIVersion = record.APIVersion
+		si.Origin = record.Origin
+		si.RetryCount = record.RetryCount
+		si.Ctx = ctx
+
+		c.deadLettersMux.Lock()
+		c.persisted[key] = struct{}{}
+		if letter := record.DeadLetter; letter != nil {
+			letter.releaseInfo = si
+			c.deadLetters[key] = letter
+		}
+		c.deadLettersMux.Unlock()
+
+		if record.DeadLetter != nil {
+			ReportDeadLetterMetric(record.DeadLetter.Gateway, record.DeadLetter.Stage)
+			continue
+		}
+		c.releaseTimer.Retry(si, retryBackoff.Delay(si.RetryCount))
+	}
+	c.logger.Infow("restore the commit retries", "count", len(records))
+	return nil
+}
+
+// ListDeadLetters 查询死信列表
+func (c *Committer) ListDeadLetters() []*DeadLetter {
+	c.deadLettersMux.RLock()
+	letters := make([]*DeadLetter, 0, len(c.deadLetters))
+	for _, letter := range c.deadLetters {
+		letters = append(letters, letter)
+	}
+	c.deadLettersMux.RUnlock()
+
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].Key < letters[j].Key
+	})
+	return letters
+}
+
+// Redrive 将一个死信重新放回提交队列, 重新计算重试次数
+func (c *Committer) Redrive(key string) error {
+	c.deadLettersMux.Lock()
+	letter, ok := c.deadLetters[key]
+	if ok {
+		delete(c.deadLetters, key)
+	}
+	c.deadLettersMux.Unlock()
+	if !ok {
+		return ErrDeadLetterNotFound
+	}
+	c.redrive(letter)
+	return nil
+}
+
+// RedriveAll 将所有死信重新放回提交队列, 返回重新提交的数量
+func (c *Committer) RedriveAll() int {
+	c.deadLettersMux.Lock()
+	letters := c.deadLetters
+	c.deadLetters = make(map[string]*DeadLetter)
+	c.deadLettersMux.Unlock()
+
+	for _, letter := range letters {
+		c.redrive(letter)
+	}
+	return len(letters)
+}
+
+func (c *Committer) redrive(letter *DeadLetter) {
+	c.logger.Infow("redrive dead letter", "key", letter.Key, "attempts", letter.Attempts)
+	DeleteDeadLetterMetric(letter.Gateway, letter.Stage)
+	// 持久化的死信在重新提交成功或者再次失败时更新
+	letter.releaseInfo.RetryCount = 0
+	c.releaseTimer.Update(letter.releaseInfo)
+}
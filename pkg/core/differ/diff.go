@@ -101,9 +101,19 @@ func normalizeServiceNodes(service *entity.Service) *entity.Service {
 	return &normalized
 }
 
+// normalizeUpstreamNodes: Normalize Nodes field of Upstream
+func normalizeUpstreamNodes(upstream *entity.Upstream) *entity.Upstream {
+	if upstream == nil {
+		return upstream
+	}
+	normalized := *upstream
+	normalized.Nodes = normalizeNodesValue(upstream.Nodes)
+	return &normalized
+}
+
 // ignoreApisixMetadata: ignore some members of apisixMetadata
 var ignoreApisixMetadataCmpOpt = cmpopts.IgnoreFields(entity.ResourceMetadata{},
-	"Labels", "Ctx", "RetryCount", "APIVersion", "Kind", "ApisixVersion", "Op",
+	"Labels", "Ctx", "RetryCount", "APIVersion", "Kind", "ApisixVersion", "Op", "Origin",
 )
 
 // CmpReporter ...
@@ -160,7 +170,13 @@ func (d *ConfigDiffer) Diff(
 	toDelete = &entity.ApisixStageResource{}
 	put.Routes, toDelete.Routes = d.DiffRoutes(old.Routes, new.Routes)
 	put.Services, toDelete.Services = d.DiffServices(old.Services, new.Services)
+	put.Upstreams, toDelete.Upstreams = d.DiffUpstreams(old.Upstreams, new.Upstreams)
+	put.PluginConfigs, toDelete.PluginConfigs = d.DiffPluginConfigs(old.PluginConfigs, new.PluginConfigs)
+	put.Consumers, toDelete.Consumers = d.DiffConsumers(old.Consumers, new.Consumers)
+	put.ConsumerGroups, toDelete.ConsumerGroups = d.DiffConsumerGroups(old.ConsumerGroups, new.ConsumerGroups)
 	put.SSLs, toDelete.SSLs = d.DiffSSLs(old.SSLs, new.SSLs)
+	put.StreamRoutes, toDelete.StreamRoutes = d.DiffStreamRoutes(old.StreamRoutes, new.StreamRoutes)
+	put.Protos, toDelete.Protos = d.DiffProtos(old.Protos, new.Protos)
 	return put, toDelete
 }
 
@@ -177,6 +193,7 @@ func (d *ConfigDiffer) DiffGlobal(
 	put = &entity.ApisixGlobalResource{}
 	toDelete = &entity.ApisixGlobalResource{}
 	put.PluginMetadata, toDelete.PluginMetadata = d.DiffPluginMetadatas(old.PluginMetadata, new.PluginMetadata)
+	put.GlobalRules, toDelete.GlobalRules = d.DiffGlobalRules(old.GlobalRules, new.GlobalRules)
 	return put, toDelete
 }
 
@@ -257,6 +274,145 @@ func (d *ConfigDiffer) DiffServices(
 	return putList, deleteList
 }
 
+// DiffUpstreams 对比两个 Upstream map，返回需要 put 和 delete 的 Upstream
+func (d *ConfigDiffer) DiffUpstreams(
+	old map[string]*entity.Upstream,
+	new map[string]*entity.Upstream,
+) (putList, deleteList map[string]*entity.Upstream) {
+	oldResMap := make(map[string]*entity.Upstream)
+	putList = make(map[string]*entity.Upstream)
+	deleteList = make(map[string]*entity.Upstream)
+	maps.Copy(oldResMap, old)
+	for key, newRes := range new {
+		oldRes, ok := oldResMap[key]
+		if !ok {
+			putList[key] = newRes
+			continue
+		}
+		// Normalize Nodes fields before comparison
+		normalizedOld := normalizeUpstreamNodes(oldRes)
+		normalizedNew := normalizeUpstreamNodes(newRes)
+		if !cmp.Equal(
+			normalizedOld,
+			normalizedNew,
+			cmp.Transformer("transformerMap", transformMap),
+			ignoreApisixMetadataCmpOpt,
+			cmp.Reporter(&CmpReporter{
+				Gateway:      newRes.GetReleaseInfo().GetGatewayName(),
+				Stage:        newRes.GetReleaseInfo().GetStageName(),
+				ResourceType: constant.ApisixResourceTypeUpstreams,
+			}),
+		) {
+			putList[key] = newRes
+		}
+		delete(oldResMap, key)
+	}
+	maps.Copy(deleteList, oldResMap)
+	return putList, deleteList
+}
+
+// DiffPluginConfigs 对比两个 PluginConfig map，返回需要 put 和 delete 的 PluginConfig
+func (d *ConfigDiffer) DiffPluginConfigs(
+	old map[string]*entity.PluginConfig,
+	new map[string]*entity.PluginConfig,
+) (putList, deleteList map[string]*entity.PluginConfig) {
+	oldResMap := make(map[string]*entity.PluginConfig)
+	putList = make(map[string]*entity.PluginConfig)
+	deleteList = make(map[string]*entity.PluginConfig)
+	maps.Copy(oldResMap, old)
+	for key, newRes := range new {
+		oldRes, ok := oldResMap[key]
+		if !ok {
+			putList[key] = newRes
+			continue
+		}
+		if !cmp.Equal(
+			oldRes,
+			newRes,
+			cmp.Transformer("transformerMap", transformMap),
+			ignoreApisixMetadataCmpOpt,
+			cmp.Reporter(&CmpReporter{
+				Gateway:      newRes.GetReleaseInfo().GetGatewayName(),
+				Stage:        newRes.GetReleaseInfo().GetStageName(),
+				ResourceType: constant.ApisixResourceTypePluginConfigs,
+			}),
+		) {
+			putList[key] = newRes
+		}
+		delete(oldResMap, key)
+	}
+	maps.Copy(deleteList, oldResMap)
+	return putList, deleteList
+}
+
+// DiffConsumers 对比两个 Consumer map，返回需要 put 和 delete 的 Consumer
+func (d *ConfigDiffer) DiffConsumers(
+	old map[string]*entity.Consumer,
+	new map[string]*entity.Consumer,
+) (putList, deleteList map[string]*entity.Consumer) {
+	oldResMap := make(map[string]*entity.Consumer)
+	putList = make(map[string]*entity.Consumer)
+	deleteList = make(map[string]*entity.Consumer)
+	maps.Copy(oldResMap, old)
+	for key, newRes := range new {
+		oldRes, ok := oldResMap[key]
+		if !ok {
+			putList[key] = newRes
+			continue
+		}
+		if !cmp.Equal(
+			oldRes,
+			newRes,
+			cmp.Transformer("transformerMap", transformMap),
+			ignoreApisixMetadataCmpOpt,
+			cmp.Reporter(&CmpReporter{
+				Gateway:      newRes.GetReleaseInfo().GetGatewayName(),
+				Stage:        newRes.GetReleaseInfo().GetStageName(),
+				ResourceType: constant.ApisixResourceTypeConsumers,
+			}),
+		) {
+			putList[key] = newRes
+		}
+		delete(oldResMap, key)
+	}
+	maps.Copy(deleteList, oldResMap)
+	return putList, deleteList
+}
+
+// DiffConsumerGroups 对比两个 ConsumerGroup map，返回需要 put 和 delete 的 ConsumerGroup
+func (d *ConfigDiffer) DiffConsumerGroups(
+	old map[string]*entity.ConsumerGroup,
+	new map[string]*entity.ConsumerGroup,
+) (putList, deleteList map[string]*entity.ConsumerGroup) {
+	oldResMap := make(map[string]*entity.ConsumerGroup)
+	putList = make(map[string]*entity.ConsumerGroup)
+	deleteList = make(map[string]*entity.ConsumerGroup)
+	maps.Copy(oldResMap, old)
+	for key, newRes := range new {
+		oldRes, ok := oldResMap[key]
+		if !ok {
+			putList[key] = newRes
+			continue
+		}
+		if !cmp.Equal(
+			oldRes,
+			newRes,
+			cmp.Transformer("transformerMap", transformMap),
+			ignoreApisixMetadataCmpOpt,
+			cmp.Reporter(&CmpReporter{
+				Gateway:      newRes.GetReleaseInfo().GetGatewayName(),
+				Stage:        newRes.GetReleaseInfo().GetStageName(),
+				ResourceType: constant.ApisixResourceTypeConsumerGroups,
+			}),
+		) {
+			putList[key] = newRes
+		}
+		delete(oldResMap, key)
+	}
+	maps.Copy(deleteList, oldResMap)
+	return putList, deleteList
+}
+
 // DiffPluginMetadatas 对比两个 PluginMetadata map，返回需要 put 和 delete 的 PluginMetadata
 func (d *ConfigDiffer) DiffPluginMetadatas(
 	old map[string]*entity.PluginMetadata,
@@ -284,6 +440,36 @@ func (d *ConfigDiffer) DiffPluginMetadatas(
 	return putList, deleteList
 }
 
+// DiffGlobalRules 对比两个 GlobalRule map，返回需要 put 和 delete 的 GlobalRule
+func (d *ConfigDiffer) DiffGlobalRules(
+	old map[string]*entity.GlobalRule,
+	new map[string]*entity.GlobalRule,
+) (putList, deleteList map[string]*entity.GlobalRule) {
+	oldResMap := make(map[string]*entity.GlobalRule)
+	putList = make(map[string]*entity.GlobalRule)
+	deleteList = make(map[string]*entity.GlobalRule)
+	maps.Copy(oldResMap, old)
+	for key, newRes := range new {
+		oldRes, ok := oldResMap[key]
+		if !ok {
+			putList[key] = newRes
+			continue
+		}
+		// 全局资源没有网关和环境, 不需要上报差异
+		if !cmp.Equal(
+			oldRes,
+			newRes,
+			cmp.Transformer("transformerMap", transformMap),
+			ignoreApisixMetadataCmpOpt,
+		) {
+			putList[key] = newRes
+		}
+		delete(oldResMap, key)
+	}
+	maps.Copy(deleteList, oldResMap)
+	return putList, deleteList
+}
+
 // DiffSSLs 对比两个 SSL map，返回需要 put 和 delete 的 SSL
 func (d *ConfigDiffer) DiffSSLs(
 	old map[string]*entity.SSL,
@@ -314,3 +500,70 @@ func (d *ConfigDiffer) DiffSSLs(
 	maps.Copy(deleteList, oldResMap)
 	return putList, deleteList
 }
+
+// DiffStreamRoutes 对比两个 StreamRoute map，返回需要 put 和 delete 的 StreamRoute
+func (d *ConfigDiffer) DiffStreamRoutes(
+	old map[string]*entity.StreamRoute,
+	new map[string]*entity.StreamRoute,
+) (putList, deleteList map[string]*entity.StreamRoute) {
+	oldResMap := make(map[string]*entity.StreamRoute)
+	putList = make(map[string]*entity.StreamRoute)
+	deleteList = make(map[string]*entity.StreamRoute)
+	maps.Copy(oldResMap, old)
+	for key, newRes := range new {
+		oldRes, ok := oldResMap[key]
+		if !ok {
+			putList[key] = newRes
+			continue
+		}
+		if !cmp.Equal(
+			oldRes,
+			newRes,
+			cmp.Transformer("transformerMap", transformMap),
+			ignoreApisixMetadataCmpOpt,
+			cmp.Reporter(&CmpReporter{
+				Gateway:      newRes.GetReleaseInfo().GetGatewayName(),
+				Stage:        newRes.GetReleaseInfo().GetStageName(),
+				ResourceType: constant.ApisixResourceTypeStreamRoutes,
+			}),
+		) {
+			putList[key] = newRes
+		}
+		delete(oldResMap, key)
+	}
+	maps.Copy(deleteList, oldResMap)
+	return putList, deleteList
+}
+
+// DiffProtos 对比两个 Proto map，返回需要 put 和 delete 的 Proto
+func (d *ConfigDiffer) DiffProtos(
+	old map[string]*entity.Proto,
+	new map[string]*entity.Proto,
+) (putList, deleteList map[string]*entity.Proto) {
+	oldResMap := make(map[string]*entity.Proto)
+	putList = make(map[string]*entity.Proto)
+	deleteList = make(map[string]*entity.Proto)
+	maps.Copy(oldResMap, old)
+	for key, newRes := range new {
+		oldRes, ok := oldResMap[key]
+		if !ok {
+			putList[key] = newRes
+			continue
+		}
+		if !cmp.Equal(
+			oldRes,
+			newRes,
+			ignoreApisixMetadataCmpOpt,
+			cmp.Reporter(&CmpReporter{
+				Gateway:      newRes.GetReleaseInfo().GetGatewayName(),
+				Stage:        newRes.GetReleaseInfo().GetStageName(),
+				ResourceType: constant.ApisixResourceTypeProtos,
+			}),
+		) {
+			putList[key] = newRes
+		}
+		delete(oldResMap, key)
+	}
+	maps.Copy(deleteList, oldResMap)
+	return putList, deleteList
+}
@@ -199,6 +199,33 @@ var _ = Describe("configDiffer", func() {
 		})
 	})
 
+	Describe("diffGlobal", func() {
+		globalRule := func(id string, plugins map[string]any) *entity.GlobalRule {
+			return &entity.GlobalRule{
+				ResourceMetadata: entity.ResourceMetadata{ID: id, Kind: constant.GlobalRule},
+				Plugins:          plugins,
+			}
+		}
+
+		It("diff GlobalRules", func() {
+			differ = NewConfigDiffer()
+			old := entity.NewEmptyApisixGlobalResource()
+			old.GlobalRules["1"] = globalRule("1", map[string]any{"prometheus": map[string]any{}})
+			old.GlobalRules["2"] = globalRule("2", map[string]any{"cors": map[string]any{}})
+			new := entity.NewEmptyApisixGlobalResource()
+			new.GlobalRules["1"] = globalRule("1", map[string]any{"prometheus": map[string]any{"prefer_name": true}})
+			new.GlobalRules["3"] = globalRule("3", map[string]any{"cors": map[string]any{}})
+
+			put, del := differ.DiffGlobal(old, new)
+			Expect(put.GlobalRules).To(HaveLen(2))
+			Expect(put.GlobalRules).To(HaveKey("1"))
+			Expect(put.GlobalRules).To(HaveKey("3"))
+			Expect(del.GlobalRules).To(HaveLen(1))
+			Expect(del.GlobalRules).To(HaveKey("2"))
+			Expect(put.PluginMetadata).To(BeEmpty())
+		})
+	})
+
 	Describe("diffServices", func() {
 		var (
 			newServices map[string]*entity.Service
@@ -288,6 +315,201 @@ var _ = Describe("configDiffer", func() {
 		})
 	})
 
+	Describe("diffUpstreams", func() {
+		upstream := func(id string, nodes any) *entity.Upstream {
+			return &entity.Upstream{UpstreamDef: entity.UpstreamDef{
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:     id,
+					Kind:   constant.Upstream,
+					Labels: &entity.LabelInfo{Gateway: "test-gateway", Stage: "test-stage"},
+				},
+				Type:  "roundrobin",
+				Nodes: nodes,
+			}}
+		}
+
+		It("diff Upstreams", func() {
+			differ = NewConfigDiffer()
+			oldUpstreams := map[string]*entity.Upstream{
+				"upstream-1": upstream("upstream-1", []map[string]any{{"host": "1.1.1.1", "port": 80, "weight": 1}}),
+				"upstream-2": upstream("upstream-2", []map[string]any{{"host": "2.2.2.2", "port": 80, "weight": 1}}),
+				"upstream-3": upstream("upstream-3", nil),
+			}
+			newUpstreams := map[string]*entity.Upstream{
+				// 节点值的类型不同但内容相同, 不需要更新
+				"upstream-1": upstream("upstream-1", []any{
+					map[string]any{"host": "1.1.1.1", "port": 80.0, "weight": 1},
+				}),
+				"upstream-2": upstream("upstream-2", []map[string]any{{"host": "2.2.2.3", "port": 80, "weight": 1}}),
+				"upstream-4": upstream("upstream-4", nil),
+			}
+
+			put, del := differ.DiffUpstreams(oldUpstreams, newUpstreams)
+			Expect(put).To(HaveLen(2))
+			Expect(put).To(HaveKey("upstream-2"))
+			Expect(put).To(HaveKey("upstream-4"))
+			Expect(del).To(HaveLen(1))
+			Expect(del).To(HaveKey("upstream-3"))
+		})
+	})
+
+	Describe("diffPluginConfigs", func() {
+		pluginConfig := func(id string, plugins map[string]any) *entity.PluginConfig {
+			return &entity.PluginConfig{
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:     id,
+					Kind:   constant.PluginConfig,
+					Labels: &entity.LabelInfo{Gateway: "test-gateway", Stage: "test-stage"},
+				},
+				Plugins: plugins,
+			}
+		}
+
+		It("diff PluginConfigs", func() {
+			differ = NewConfigDiffer()
+			oldPluginConfigs := map[string]*entity.PluginConfig{
+				"plugin-config-1": pluginConfig("plugin-config-1", map[string]any{"cors": map[string]any{}}),
+				"plugin-config-2": pluginConfig("plugin-config-2", map[string]any{"cors": map[string]any{}}),
+				"plugin-config-3": pluginConfig("plugin-config-3", map[string]any{"cors": map[string]any{}}),
+			}
+			newPluginConfigs := map[string]*entity.PluginConfig{
+				"plugin-config-1": pluginConfig("plugin-config-1", map[string]any{"cors": map[string]any{}}),
+				"plugin-config-2": pluginConfig("plugin-config-2", map[string]any{
+					"cors": map[string]any{"allow_origins": "*"},
+				}),
+				"plugin-config-4": pluginConfig("plugin-config-4", map[string]any{"cors": map[string]any{}}),
+			}
+
+			put, del := differ.DiffPluginConfigs(oldPluginConfigs, newPluginConfigs)
+			Expect(put).To(HaveLen(2))
+			Expect(put).To(HaveKey("plugin-config-2"))
+			Expect(put).To(HaveKey("plugin-config-4"))
+			Expect(del).To(HaveLen(1))
+			Expect(del).To(HaveKey("plugin-config-3"))
+		})
+	})
+
+	Describe("diffConsumers", func() {
+		consumer := func(username string, plugins map[string]any) *entity.Consumer {
+			return &entity.Consumer{
+				ResourceMetadata: entity.ResourceMetadata{
+					Kind:   constant.Consumer,
+					Labels: &entity.LabelInfo{Gateway: "test-gateway", Stage: "test-stage"},
+				},
+				Username: username,
+				Plugins:  plugins,
+			}
+		}
+
+		It("diff Consumers", func() {
+			differ = NewConfigDiffer()
+			oldConsumers := map[string]*entity.Consumer{
+				"app-1": consumer("app-1", map[string]any{"key-auth": map[string]any{"key": "key-1"}}),
+				"app-2": consumer("app-2", map[string]any{"key-auth": map[string]any{"key": "key-2"}}),
+			}
+			newConsumers := map[string]*entity.Consumer{
+				"app-1": consumer("app-1", map[string]any{"key-auth": map[string]any{"key": "key-1"}}),
+				"app-3": consumer("app-3", map[string]any{"key-auth": map[string]any{"key": "key-3"}}),
+			}
+
+			put, del := differ.DiffConsumers(oldConsumers, newConsumers)
+			Expect(put).To(HaveLen(1))
+			Expect(put).To(HaveKey("app-3"))
+			Expect(del).To(HaveLen(1))
+			Expect(del).To(HaveKey("app-2"))
+		})
+
+		It("diff ConsumerGroups", func() {
+			differ = NewConfigDiffer()
+			group := func(id string, count int) *entity.ConsumerGroup {
+				return &entity.ConsumerGroup{
+					ResourceMetadata: entity.ResourceMetadata{
+						ID:     id,
+						Kind:   constant.ConsumerGroup,
+						Labels: &entity.LabelInfo{Gateway: "test-gateway", Stage: "test-stage"},
+					},
+					Plugins: map[string]any{"limit-count": map[string]any{"count": count}},
+				}
+			}
+
+			put, del := differ.DiffConsumerGroups(
+				map[string]*entity.ConsumerGroup{"group-1": group("group-1", 10)},
+				map[string]*entity.ConsumerGroup{"group-1": group("group-1", 20)},
+			)
+			Expect(put).To(HaveKey("group-1"))
+			Expect(del).To(BeEmpty())
+		})
+	})
+
+	Describe("diffStreamRoutes", func() {
+		streamRoute := func(id string, port int) *entity.StreamRoute {
+			return &entity.StreamRoute{
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:     id,
+					Kind:   constant.StreamRoute,
+					Labels: &entity.LabelInfo{Gateway: "test-gateway", Stage: "test-stage"},
+				},
+				ServerPort: port,
+				UpstreamID: "upstream-1",
+			}
+		}
+
+		It("diff StreamRoutes", func() {
+			differ = NewConfigDiffer()
+			oldStreamRoutes := map[string]*entity.StreamRoute{
+				"tcp-1": streamRoute("tcp-1", 9100),
+				"tcp-2": streamRoute("tcp-2", 9101),
+				"tcp-3": streamRoute("tcp-3", 9102),
+			}
+			// 写入 apisix 后 stream route 只能根据 id 还原网关和环境, 其他 labels 的差异不需要更新
+			unchanged := streamRoute("tcp-1", 9100)
+			unchanged.Labels.PublishId = "10"
+			newStreamRoutes := map[string]*entity.StreamRoute{
+				"tcp-1": unchanged,
+				"tcp-2": streamRoute("tcp-2", 9200),
+				"tcp-4": streamRoute("tcp-4", 9103),
+			}
+
+			put, del := differ.DiffStreamRoutes(oldStreamRoutes, newStreamRoutes)
+			Expect(put).To(HaveLen(2))
+			Expect(put).To(HaveKey("tcp-2"))
+			Expect(put).To(HaveKey("tcp-4"))
+			Expect(del).To(HaveLen(1))
+			Expect(del).To(HaveKey("tcp-3"))
+		})
+	})
+
+	Describe("diffProtos", func() {
+		proto := func(id, content string) *entity.Proto {
+			return &entity.Proto{
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:     id,
+					Kind:   constant.Proto,
+					Labels: &entity.LabelInfo{Gateway: "test-gateway", Stage: "test-stage"},
+				},
+				Content: content,
+			}
+		}
+
+		It("diff Protos", func() {
+			differ = NewConfigDiffer()
+			oldProtos := map[string]*entity.Proto{
+				"proto-1": proto("proto-1", `syntax = "proto3"; package a;`),
+				"proto-2": proto("proto-2", `syntax = "proto3"; package b;`),
+			}
+			newProtos := map[string]*entity.Proto{
+				"proto-1": proto("proto-1", `syntax = "proto3"; package a;`),
+				"proto-3": proto("proto-3", `syntax = "proto3"; package c;`),
+			}
+
+			put, del := differ.DiffProtos(oldProtos, newProtos)
+			Expect(put).To(HaveLen(1))
+			Expect(put).To(HaveKey("proto-3"))
+			Expect(del).To(HaveLen(1))
+			Expect(del).To(HaveKey("proto-2"))
+		})
+	})
+
 	Describe("diffRoutes", func() {
 		var (
 			newRoutes map[string]*entity.Route
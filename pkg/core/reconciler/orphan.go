@@ -0,0 +1,216 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package reconciler ...
+package reconciler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+// OrphanStage apisix etcd 中存在, 但是 apigw etcd 中已经没有对应发布信息的环境
+type OrphanStage struct {
+	Gateway   string         `json:"gateway_name"`
+	Stage     string         `json:"stage_name"`
+	StageKey  string         `json:"stage_key"`
+	Resources map[string]int `json:"resources"`
+	FirstSeen time.Time      `json:"first_seen"`
+}
+
+// OrphanCollector 定时清理 apisix etcd 中的孤儿环境
+type OrphanCollector struct {
+	apigwRegistry registry.APIGWRegistry
+	store         store.ApisixStore
+	synchronizer  *synchronizer.ApisixConfigSynchronizer
+
+	interval    time.Duration
+	gracePeriod time.Duration
+	delete      bool
+
+	// stage key -> 第一次发现为孤儿环境的时间
+	firstSeenMux sync.Mutex
+	firstSeen    map[string]time.Time
+
+	logger *zap.SugaredLogger
+}
+
+// NewOrphanCollector ...
+func NewOrphanCollector(
+	apigwRegistry registry.APIGWRegistry,
+	apisixStore store.ApisixStore,
+	syncer *synchronizer.ApisixConfigSynchronizer,
+	cfg config.OrphanCollect,
+) *OrphanCollector {
+	return &OrphanCollector{
+		apigwRegistry: apigwRegistry,
+		store:         apisixStore,
+		synchronizer:  syncer,
+		interval:      cfg.Interval,
+		gracePeriod:   cfg.GracePeriod,
+		delete:        cfg.Delete,
+		firstSeen:     make(map[string]time.Time),
+		logger:        logging.GetLogger().Named("orphan-collector"),
+	}
+}
+
+// Run 周期执行孤儿环境回收, 直到 ctx 结束
+func (c *OrphanCollector) Run(ctx context.Context) {
+	c.logger.Infow("orphan collector started",
+		"interval", c.interval, "gracePeriod", c.gracePeriod, "delete", c.delete)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := c.Collect(ctx); err != nil {
+				c.logger.Errorw("collect orphan stages failed", "err", err)
+			}
+		case <-ctx.Done():
+			c.logger.Infow("orphan collector stopped")
+			return
+		}
+	}
+}
+
+// ListOrphans 查询当前的孤儿环境, 只查询不删除
+func (c *OrphanCollector) ListOrphans(ctx context.Context) ([]*OrphanStage, error) {
+	return c.findOrphans(ctx)
+}
+
+// Collect 查询孤儿环境, 开启删除时会删除超过宽限期的孤儿环境, 返回当前的孤儿环境列表
+func (c *OrphanCollector) Collect(ctx context.Context) ([]*OrphanStage, error) {
+	orphans, err := c.findOrphans(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metric.ResetOrphanMetric()
+	for _, orphan := range orphans {
+		for resourceType, count := range orphan.Resources {
+			metric.ReportOrphanMetric(orphan.Gateway, orphan.Stage, resourceType, count)
+		}
+		c.logger.Warnw("orphan stage found",
+			"stageKey", orphan.StageKey, "resources", orphan.Resources, "firstSeen", orphan.FirstSeen)
+
+		if !c.delete || time.Since(orphan.FirstSeen) < c.gracePeriod {
+			continue
+		}
+		// 同步一份空配置, 复用 store 的 diff 逻辑删除该环境下的所有资源
+		err := c.synchronizer.Sync(ctx, orphan.Gateway, orphan.Stage, entity.NewEmptyApisixConfiguration())
+		metric.ReportOrphanCleanupMetric(orphan.Gateway, orphan.Stage, err)
+		if err != nil {
+			c.logger.Errorw("delete orphan stage failed", "err", err, "stageKey", orphan.StageKey)
+			continue
+		}
+		c.logger.Infow("delete orphan stage success", "stageKey", orphan.StageKey)
+	}
+	return orphans, nil
+}
+
+func (c *OrphanCollector) findOrphans(ctx context.Context) ([]*OrphanStage, error) {
+	// 先读取 apisix 的缓存, 再读取发布信息: 发布信息总是先于资源写入 apisix, 避免把刚发布的环境误判为孤儿
+	stageResources := c.store.GetAll()
+	releaseList, _, err := c.apigwRegistry.ListReleaseInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 发布信息解析失败的环境不在 releaseList 中, 只根据 key 判断, 不能当作孤儿删除
+	published, err := c.apigwRegistry.ListReleaseStageKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, release := range releaseList {
+		// 删除环境的发布信息不算作已发布
+		if release.IsDeleteRelease() {
+			delete(published, release.GetStageKey())
+			continue
+		}
+		published[release.GetStageKey()] = struct{}{}
+	}
+
+	now := time.Now()
+	c.firstSeenMux.Lock()
+	defer c.firstSeenMux.Unlock()
+
+	orphans := make([]*OrphanStage, 0)
+	current := make(map[string]time.Time)
+	for stageKey, resources := range stageResources {
+		if stageKey == config.VirtualStageKey {
+			continue
+		}
+		if _, ok := published[stageKey]; ok {
+			continue
+		}
+		gatewayName, stageName := stageNameOf(resources)
+		// 没有网关标签的资源不是 operator 管理的, 不做处理
+		if gatewayName == "" {
+			continue
+		}
+		firstSeen, ok := c.firstSeen[stageKey]
+		if !ok {
+			firstSeen = now
+		}
+		current[stageKey] = firstSeen
+		orphans = append(orphans, &OrphanStage{
+			Gateway:  gatewayName,
+			Stage:    stageName,
+			StageKey: stageKey,
+			Resources: map[string]int{
+				constant.ApisixResourceTypeRoutes:         len(resources.Routes),
+				constant.ApisixResourceTypeServices:       len(resources.Services),
+				constant.ApisixResourceTypeUpstreams:      len(resources.Upstreams),
+				constant.ApisixResourceTypePluginConfigs:  len(resources.PluginConfigs),
+				constant.ApisixResourceTypeConsumers:      len(resources.Consumers),
+				constant.ApisixResourceTypeConsumerGroups: len(resources.ConsumerGroups),
+				constant.ApisixResourceTypeSSL:            len(resources.SSLs),
+				constant.ApisixResourceTypeStreamRoutes:   len(resources.StreamRoutes),
+				constant.ApisixResourceTypeProtos:         len(resources.Protos),
+			},
+			FirstSeen: firstSeen,
+		})
+	}
+	// 重新发布或者已经删除的环境不再追踪
+	c.firstSeen = current
+
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].StageKey < orphans[j].StageKey
+	})
+	return orphans, nil
+}
+
+// stageNameOf 从环境下任意一个资源的标签中获取网关和环境名
+func stageNameOf(resources *entity.ApisixStageResource) (string, string) {
+	for _, resource := range resources.Resources() {
+		return resource.GetGatewayName(), resource.GetStageName()
+	}
+	return "", ""
+}
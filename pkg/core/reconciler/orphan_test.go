@@ -0,0 +1,157 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package reconciler_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/reconciler"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+var _ = Describe("OrphanCollector", func() {
+	var (
+		ctx         context.Context
+		etcd        *embed.Etcd
+		client      *clientv3.Client
+		apisixStore *store.ApisixEtcdStore
+		apigwReg    *registry.APIGWEtcdRegistry
+		syncer      *synchronizer.ApisixConfigSynchronizer
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		metric.InitMetric(prometheus.NewRegistry())
+
+		etcd, client, err = startTestEtcd()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// prod 已发布, old 在 apigw 中已经没有发布信息
+		putRelease(ctx, client, "gw", "prod")
+		for _, stage := range []string{"prod", "old"} {
+			id := "gw." + stage + ".1"
+			_, err = client.Put(ctx, apisixPrefix+"/routes/"+id, routeValue("gw", stage, id, "/foo"))
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		apisixStore, err = store.NewApisixEtcdStore(
+			ctx, client, apisixPrefix, 10*time.Millisecond, 10*time.Millisecond, 5*time.Second)
+		Expect(err).ShouldNot(HaveOccurred())
+		apigwReg = registry.NewAPIGWEtcdRegistry(client, apigwPrefix, 100)
+		syncer = synchronizer.NewSynchronizer(apisixStore, "/healthz")
+	})
+
+	AfterEach(func() {
+		apisixStore.Close()
+		client.Close()
+		etcd.Close()
+		_ = os.RemoveAll(etcd.Config().Dir)
+	})
+
+	It("should list the stages without release", func() {
+		c := reconciler.NewOrphanCollector(apigwReg, apisixStore, syncer, config.OrphanCollect{})
+
+		orphans, err := c.ListOrphans(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(orphans).To(HaveLen(1))
+		Expect(orphans[0].Gateway).To(Equal("gw"))
+		Expect(orphans[0].Stage).To(Equal("old"))
+		Expect(orphans[0].StageKey).To(Equal(config.GenStagePrimaryKey("gw", "old")))
+		Expect(orphans[0].Resources[constant.ApisixResourceTypeRoutes]).To(Equal(1))
+
+		// 再次查询时保留第一次发现的时间
+		again, err := c.ListOrphans(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(again[0].FirstSeen).To(Equal(orphans[0].FirstSeen))
+	})
+
+	It("should not delete the orphan stage in report only mode", func() {
+		c := reconciler.NewOrphanCollector(apigwReg, apisixStore, syncer, config.OrphanCollect{})
+
+		orphans, err := c.Collect(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(orphans).To(HaveLen(1))
+
+		resp, err := client.Get(ctx, apisixPrefix+"/routes/gw.old.1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.Kvs).To(HaveLen(1))
+	})
+
+	It("should not delete the orphan stage within the grace period", func() {
+		c := reconciler.NewOrphanCollector(apigwReg, apisixStore, syncer, config.OrphanCollect{
+			Delete:      true,
+			GracePeriod: time.Hour,
+		})
+
+		_, err := c.Collect(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		resp, err := client.Get(ctx, apisixPrefix+"/routes/gw.old.1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.Kvs).To(HaveLen(1))
+	})
+
+	It("should delete the orphan stage after the grace period", func() {
+		c := reconciler.NewOrphanCollector(apigwReg, apisixStore, syncer, config.OrphanCollect{
+			Delete: true,
+		})
+
+		_, err := c.Collect(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		resp, err := client.Get(ctx, apisixPrefix+"/routes/gw.old.1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.Kvs).To(BeEmpty())
+		resp, err = client.Get(ctx, apisixPrefix+"/routes/gw.prod.1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.Kvs).To(HaveLen(1))
+	})
+
+	It("should not delete the stage whose release can not be parsed", func() {
+		key := fmt.Sprintf("%s/v2/gateway/gw/old/_bk_release/bk.release.gw.old", apigwPrefix)
+		_, err := client.Put(ctx, key, "not a json")
+		Expect(err).ShouldNot(HaveOccurred())
+		c := reconciler.NewOrphanCollector(apigwReg, apisixStore, syncer, config.OrphanCollect{
+			Delete: true,
+		})
+
+		orphans, err := c.Collect(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(orphans).To(BeEmpty())
+
+		resp, err := client.Get(ctx, apisixPrefix+"/routes/gw.old.1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.Kvs).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,237 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package reconciler periodically compares the published apigw config with apisix etcd and heals the drift
+package reconciler
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/differ"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+// StageDrift 单个环境的漂移统计, key 为 apisix 资源类型, value 为不一致的资源数量
+type StageDrift struct {
+	Gateway string
+	Stage   string
+	Drift   map[string]int
+	Healed  bool
+}
+
+// Total ...
+func (d *StageDrift) Total() int {
+	total := 0
+	for _, count := range d.Drift {
+		total += count
+	}
+	return total
+}
+
+// DriftReconciler 定时对比 apigw etcd 中已发布的配置与 apisix etcd 中的实际配置
+type DriftReconciler struct {
+	apigwRegistry registry.APIGWRegistry
+	store         store.ApisixStore
+	synchronizer  *synchronizer.ApisixConfigSynchronizer
+	differ        *differ.ConfigDiffer
+
+	interval time.Duration
+	jitter   time.Duration
+	heal     bool
+
+	// 上一轮上报过指标的环境, 用于清理已下线环境的指标
+	reportedStages map[string]*StageDrift
+
+	logger *zap.SugaredLogger
+}
+
+// NewDriftReconciler ...
+func NewDriftReconciler(
+	apigwRegistry registry.APIGWRegistry,
+	apisixStore store.ApisixStore,
+	syncer *synchronizer.ApisixConfigSynchronizer,
+	cfg config.DriftReconcile,
+) *DriftReconciler {
+	return &DriftReconciler{
+		apigwRegistry:  apigwRegistry,
+		store:          apisixStore,
+		synchronizer:   syncer,
+		differ:         differ.NewConfigDiffer(),
+		interval:       cfg.Interval,
+		jitter:         cfg.Jitter,
+		heal:           cfg.Heal,
+		reportedStages: make(map[string]*StageDrift),
+		logger:         logging.GetLogger().Named("drift-reconciler"),
+	}
+}
+
+// Run 按 interval + 随机 jitter 周期执行对账, 直到 ctx 结束
+func (r *DriftReconciler) Run(ctx context.Context) {
+	r.logger.Infow("drift reconciler started", "interval", r.interval, "jitter", r.jitter, "heal", r.heal)
+	for {
+		select {
+		case <-time.After(r.nextInterval()):
+			if _, err := r.Reconcile(ctx); err != nil {
+				r.logger.Errorw("reconcile drift failed", "err", err)
+			}
+		case <-ctx.Done():
+			r.logger.Infow("drift reconciler stopped")
+			return
+		}
+	}
+}
+
+func (r *DriftReconciler) nextInterval() time.Duration {
+	if r.jitter <= 0 {
+		return r.interval
+	}
+	// 加入随机抖动, 避免多个实例/环境同时对 etcd 发起全量读取
+	return r.interval + rand.N(r.jitter)
+}
+
+// Reconcile 对所有已发布的环境执行一次对账, 返回存在漂移的环境列表
+func (r *DriftReconciler) Reconcile(ctx context.Context) ([]*StageDrift, error) {
+	releaseList, _, err := r.apigwRegistry.ListReleaseInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	currentStages := make(map[string]*StageDrift, len(releaseList))
+	var driftList []*StageDrift
+	for _, release := range releaseList {
+		if release.IsDeleteRelease() {
+			continue
+		}
+		drift, err := r.reconcileStage(ctx, release)
+		if err != nil {
+			// 单个环境失败不影响其他环境的对账
+			r.logger.Errorw("reconcile stage failed", "err", err, "stageKey", release.GetStageKey())
+			continue
+		}
+		currentStages[release.GetStageKey()] = drift
+		if drift.Total() > 0 {
+			driftList = append(driftList, drift)
+		}
+	}
+
+	// 已下线的环境, 清理对应的指标
+	for stageKey, drift := range r.reportedStages {
+		if _, ok := currentStages[stageKey]; !ok {
+			metric.DeleteDriftMetric(drift.Gateway, drift.Stage)
+		}
+	}
+	r.reportedStages = currentStages
+
+	r.logger.Infow("reconcile drift finished", "stageCount", len(releaseList), "driftStageCount", len(driftList))
+	return driftList, nil
+}
+
+func (r *DriftReconciler) reconcileStage(ctx context.Context, release *entity.ReleaseInfo) (*StageDrift, error) {
+	gatewayName, stageName := release.GetGatewayName(), release.GetStageName()
+
+	desired, err := r.apigwRegistry.ListStageResources(release)
+	if err != nil {
+		return nil, err
+	}
+	actual := r.store.Get(release.GetStageKey())
+
+	// 环境没有放置在主集群上时, 主集群上不应该有该环境的资源
+	expected := desired
+	if !r.synchronizer.IsPlacedOnPrimary(gatewayName, stageName, release.Labels) {
+		expected = entity.NewEmptyApisixConfiguration()
+	}
+
+	put, toDelete := r.differ.Diff(actual, expected)
+	drift := &StageDrift{
+		Gateway: gatewayName,
+		Stage:   stageName,
+		Drift: map[string]int{
+			constant.ApisixResourceTypeRoutes:         len(put.Routes) + len(toDelete.Routes),
+			constant.ApisixResourceTypeServices:       len(put.Services) + len(toDelete.Services),
+			constant.ApisixResourceTypeUpstreams:      len(put.Upstreams) + len(toDelete.Upstreams),
+			constant.ApisixResourceTypePluginConfigs:  len(put.PluginConfigs) + len(toDelete.PluginConfigs),
+			constant.ApisixResourceTypeConsumers:      len(put.Consumers) + len(toDelete.Consumers),
+			constant.ApisixResourceTypeConsumerGroups: len(put.ConsumerGroups) + len(toDelete.ConsumerGroups),
+			constant.ApisixResourceTypeSSL:            len(put.SSLs) + len(toDelete.SSLs),
+			constant.ApisixResourceTypeStreamRoutes:   len(put.StreamRoutes) + len(toDelete.StreamRoutes),
+			constant.ApisixResourceTypeProtos:         len(put.Protos) + len(toDelete.Protos),
+			// 环境的插件元数据合并到全局资源中写入, 不受放置规则影响
+			constant.ApisixResourceTypePluginMetadata: r.pluginMetadataDrift(release.GetStageKey(), desired),
+		},
+	}
+	for resourceType, count := range drift.Drift {
+		metric.ReportDriftMetric(gatewayName, stageName, resourceType, count)
+	}
+
+	if drift.Total() == 0 {
+		return drift, nil
+	}
+	r.logger.Warnw("apisix config drift detected",
+		"gateway", gatewayName, "stage", stageName, "drift", drift.Drift)
+
+	if !r.heal {
+		return drift, nil
+	}
+	// 通过 synchronizer 重新应用期望的配置, 与 committer 共用同一把锁, 不会与正常发布并发写入
+	err = r.synchronizer.Sync(ctx, gatewayName, stageName, desired)
+	if err == nil && drift.Drift[constant.ApisixResourceTypePluginMetadata] > 0 {
+		// 插件元数据没有变化时环境同步不会重新写入全局资源, 需要单独修复
+		err = r.synchronizer.ResyncGlobal(ctx)
+	}
+	metric.ReportDriftHealMetric(gatewayName, stageName, err)
+	if err != nil {
+		r.logger.Errorw("heal drift failed", "err", err, "gateway", gatewayName, "stage", stageName)
+		return drift, nil
+	}
+	drift.Healed = true
+	r.logger.Infow("heal drift success", "gateway", gatewayName, "stage", stageName)
+	return drift, nil
+}
+
+// pluginMetadataDrift 对比环境的插件元数据与 apisix 中的全局插件元数据, 返回不一致的数量;
+// 同名插件的元数据由其他环境写入时不对比
+func (r *DriftReconciler) pluginMetadataDrift(stageKey string, desired *entity.ApisixStageResource) int {
+	if len(desired.PluginMetadata) == 0 {
+		return 0
+	}
+	actual := r.store.GetGlobal().PluginMetadata
+	expected := make(map[string]*entity.PluginMetadata, len(desired.PluginMetadata))
+	current := make(map[string]*entity.PluginMetadata, len(desired.PluginMetadata))
+	for name, pm := range desired.PluginMetadata {
+		if owner := r.synchronizer.PluginMetadataOwner(name); owner != "" && owner != stageKey {
+			continue
+		}
+		expected[name] = pm
+		if actualPM, ok := actual[name]; ok {
+			current[name] = actualPM
+		}
+	}
+	put, _ := r.differ.DiffPluginMetadatas(current, expected)
+	return len(put)
+}
@@ -0,0 +1,264 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package reconciler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/reconciler"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+const (
+	apigwPrefix  = "/bk-gateway-apigw"
+	apisixPrefix = "/apisix"
+)
+
+var _ = Describe("DriftReconciler", func() {
+	var (
+		ctx         context.Context
+		etcd        *embed.Etcd
+		client      *clientv3.Client
+		apisixStore *store.ApisixEtcdStore
+		apigwReg    *registry.APIGWEtcdRegistry
+		syncer      *synchronizer.ApisixConfigSynchronizer
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		metric.InitMetric(prometheus.NewRegistry())
+
+		etcd, client, err = startTestEtcd()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		apisixStore, err = store.NewApisixEtcdStore(
+			ctx, client, apisixPrefix, 10*time.Millisecond, 10*time.Millisecond, 5*time.Second)
+		Expect(err).ShouldNot(HaveOccurred())
+		apigwReg = registry.NewAPIGWEtcdRegistry(client, apigwPrefix, 100)
+		syncer = synchronizer.NewSynchronizer(apisixStore, "/healthz")
+
+		putRelease(ctx, client, "gw", "prod")
+		putRoute(ctx, client, "gw", "prod", "gw.prod.1", "/foo")
+	})
+
+	AfterEach(func() {
+		apisixStore.Close()
+		client.Close()
+		etcd.Close()
+		_ = os.RemoveAll(etcd.Config().Dir)
+	})
+
+	It("should only report drift when heal is disabled", func() {
+		r := reconciler.NewDriftReconciler(apigwReg, apisixStore, syncer, config.DriftReconcile{})
+
+		driftList, err := r.Reconcile(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(driftList).To(HaveLen(1))
+		Expect(driftList[0].Gateway).To(Equal("gw"))
+		Expect(driftList[0].Stage).To(Equal("prod"))
+		Expect(driftList[0].Drift[constant.ApisixResourceTypeRoutes]).To(Equal(1))
+		Expect(driftList[0].Healed).To(BeFalse())
+		Expect(testutil.ToFloat64(
+			metric.DriftResourceGauge.WithLabelValues("gw", "prod", constant.ApisixResourceTypeRoutes),
+		)).To(Equal(float64(1)))
+
+		resp, err := client.Get(ctx, apisixPrefix+"/routes/gw.prod.1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.Kvs).To(BeEmpty())
+	})
+
+	It("should heal the drift when heal is enabled", func() {
+		r := reconciler.NewDriftReconciler(apigwReg, apisixStore, syncer, config.DriftReconcile{Heal: true})
+
+		driftList, err := r.Reconcile(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(driftList).To(HaveLen(1))
+		Expect(driftList[0].Healed).To(BeTrue())
+
+		resp, err := client.Get(ctx, apisixPrefix+"/routes/gw.prod.1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.Kvs).To(HaveLen(1))
+
+		Eventually(func() int {
+			return len(apisixStore.Get(config.GenStagePrimaryKey("gw", "prod")).Routes)
+		}, 5*time.Second, 50*time.Millisecond).Should(Equal(1))
+
+		// 修改 apisix 中的路由, 模拟人工改动, 下一轮对账应该恢复
+		_, err = client.Put(ctx, apisixPrefix+"/routes/gw.prod.1", routeValue("gw", "prod", "gw.prod.1", "/bar"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Eventually(func() string {
+			return apisixStore.Get(config.GenStagePrimaryKey("gw", "prod")).Routes["gw.prod.1"].URI
+		}, 5*time.Second, 50*time.Millisecond).Should(Equal("/bar"))
+
+		driftList, err = r.Reconcile(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(driftList).To(HaveLen(1))
+		Expect(driftList[0].Healed).To(BeTrue())
+		Eventually(func() string {
+			return apisixStore.Get(config.GenStagePrimaryKey("gw", "prod")).Routes["gw.prod.1"].URI
+		}, 5*time.Second, 50*time.Millisecond).Should(Equal("/foo"))
+
+		driftList, err = r.Reconcile(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(driftList).To(BeEmpty())
+	})
+
+	It("should report and heal the drift of the stage plugin metadata", func() {
+		putPluginMetadata(ctx, client, "gw", "prod", "file-logger", "/logs/stage.log")
+		Expect(syncer.SyncGlobal(ctx, entity.NewEmptyApisixGlobalResource())).To(Succeed())
+		r := reconciler.NewDriftReconciler(apigwReg, apisixStore, syncer, config.DriftReconcile{Heal: true})
+		pluginMetadataPath := func() string {
+			pm, ok := apisixStore.GetGlobal().PluginMetadata["file-logger"]
+			if !ok {
+				return ""
+			}
+			var value map[string]any
+			Expect(json.Unmarshal(pm.PluginMetadataConf["file-logger"], &value)).To(Succeed())
+			return value["path"].(string)
+		}
+
+		driftList, err := r.Reconcile(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(driftList).To(HaveLen(1))
+		Expect(driftList[0].Drift[constant.ApisixResourceTypePluginMetadata]).To(Equal(1))
+		Expect(driftList[0].Healed).To(BeTrue())
+		Eventually(pluginMetadataPath, 5*time.Second, 50*time.Millisecond).Should(Equal("/logs/stage.log"))
+
+		// 修改 apisix 中的插件元数据, 模拟人工改动, 下一轮对账应该恢复
+		_, err = client.Put(ctx, apisixPrefix+"/plugin_metadata/file-logger",
+			`{"id":"file-logger","path":"/logs/manual.log"}`)
+		Expect(err).ShouldNot(HaveOccurred())
+		Eventually(pluginMetadataPath, 5*time.Second, 50*time.Millisecond).Should(Equal("/logs/manual.log"))
+
+		driftList, err = r.Reconcile(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(driftList).To(HaveLen(1))
+		Expect(driftList[0].Drift[constant.ApisixResourceTypePluginMetadata]).To(Equal(1))
+		Expect(driftList[0].Drift[constant.ApisixResourceTypeRoutes]).To(BeZero())
+		Eventually(pluginMetadataPath, 5*time.Second, 50*time.Millisecond).Should(Equal("/logs/stage.log"))
+
+		driftList, err = r.Reconcile(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(driftList).To(BeEmpty())
+	})
+})
+
+func putRelease(ctx context.Context, client *clientv3.Client, gateway, stage string) {
+	key := fmt.Sprintf("%s/v2/gateway/%s/%s/_bk_release/bk.release.%s.%s",
+		apigwPrefix, gateway, stage, gateway, stage)
+	value, _ := json.Marshal(map[string]any{
+		"id": fmt.Sprintf("bk.release.%s.%s", gateway, stage),
+		"labels": map[string]any{
+			"gateway.bk.tencent.com/gateway":        gateway,
+			"gateway.bk.tencent.com/stage":          stage,
+			"gateway.bk.tencent.com/publish-id":     "1",
+			"gateway.bk.tencent.com/apisix-version": "3.13.0",
+		},
+		"publish_id":     1,
+		"apisix_version": "3.13.0",
+	})
+	_, err := client.Put(ctx, key, string(value))
+	Expect(err).ShouldNot(HaveOccurred())
+}
+
+func putRoute(ctx context.Context, client *clientv3.Client, gateway, stage, id, uri string) {
+	key := fmt.Sprintf("%s/v2/gateway/%s/%s/route/%s", apigwPrefix, gateway, stage, id)
+	_, err := client.Put(ctx, key, routeValue(gateway, stage, id, uri))
+	Expect(err).ShouldNot(HaveOccurred())
+}
+
+func putPluginMetadata(ctx context.Context, client *clientv3.Client, gateway, stage, name, path string) {
+	key := fmt.Sprintf("%s/v2/gateway/%s/%s/plugin_metadata/%s", apigwPrefix, gateway, stage, name)
+	value, _ := json.Marshal(map[string]any{
+		"id":   name,
+		"path": path,
+		"labels": map[string]any{
+			"gateway.bk.tencent.com/gateway":        gateway,
+			"gateway.bk.tencent.com/stage":          stage,
+			"gateway.bk.tencent.com/apisix-version": "3.13.0",
+		},
+	})
+	_, err := client.Put(ctx, key, string(value))
+	Expect(err).ShouldNot(HaveOccurred())
+}
+
+func routeValue(gateway, stage, id, uri string) string {
+	value, _ := json.Marshal(map[string]any{
+		"id":   id,
+		"name": id,
+		"uri":  uri,
+		"labels": map[string]any{
+			"gateway.bk.tencent.com/gateway":        gateway,
+			"gateway.bk.tencent.com/stage":          stage,
+			"gateway.bk.tencent.com/apisix-version": "3.13.0",
+		},
+		"upstream": map[string]any{
+			"type":  "roundrobin",
+			"nodes": []map[string]any{{"host": "1.1.1.1", "port": 80, "weight": 1}},
+		},
+	})
+	return string(value)
+}
+
+// startTestEtcd starts an embedded etcd for testing
+func startTestEtcd() (*embed.Etcd, *clientv3.Client, error) {
+	cfg := embed.NewConfig()
+	cfg.Dir, _ = os.MkdirTemp("", "etcd-reconciler-test")
+	cfg.LogLevel = "error"
+
+	// Use random ports to avoid conflicts
+	cfg.ListenClientUrls = []url.URL{{Scheme: "http", Host: "localhost:0"}}
+	cfg.ListenPeerUrls = []url.URL{{Scheme: "http", Host: "localhost:0"}}
+
+	etcd, err := embed.StartEtcd(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	select {
+	case <-etcd.Server.ReadyNotify():
+		client, err := clientv3.New(clientv3.Config{
+			Endpoints:   []string{etcd.Clients[0].Addr().String()},
+			DialTimeout: time.Second,
+		})
+		return etcd, client, err
+	case <-time.After(30 * time.Second):
+		etcd.Close()
+		return nil, nil, fmt.Errorf("etcd server took too long to start")
+	}
+}
@@ -24,16 +24,19 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	json "github.com/json-iterator/go"
 	"github.com/rotisserie/eris"
 	"github.com/tidwall/sjson"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	v3rpc "go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/validator"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
@@ -42,6 +45,32 @@ import (
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/trace"
 )
 
+// APIGWRegistry 网关发布资源的来源, 资源按照
+// /{prefix}/{api_version}/gateway/{gateway_name}/{stage_name}/{kind}/{id} 的布局组织
+type APIGWRegistry interface {
+	// Watch 返回资源变更事件的 channel, ctx 结束后关闭
+	Watch(ctx context.Context) <-chan *entity.ResourceMetadata
+	// ListReleaseInfos 查询所有环境的发布信息, 同时返回从哪里开始 watch 不会遗漏事件的 revision
+	ListReleaseInfos(ctx context.Context) ([]*entity.ReleaseInfo, int64, error)
+	// ListReleaseStageKeys 只根据 key 查询有发布信息的环境, 包括发布信息解析失败的环境
+	ListReleaseStageKeys(ctx context.Context) (map[string]struct{}, error)
+	// SetCurrentRevision 设置下一次 Watch 开始的 revision
+	SetCurrentRevision(revision int64)
+	ListStageResources(stageRelease *entity.ReleaseInfo) (*entity.ApisixStageResource, error)
+	ListGlobalResources(releaseInfo *entity.ReleaseInfo) (*entity.ApisixGlobalResource, error)
+	GetStageResourceByID(resourceID string, stageRelease *entity.ReleaseInfo) (*entity.ApisixStageResource, error)
+	Count(stageRelease *entity.ReleaseInfo) (int64, error)
+	StageReleaseVersion(stageRelease *entity.ReleaseInfo) (*entity.ReleaseInfo, error)
+}
+
+var (
+	_ APIGWRegistry = (*APIGWEtcdRegistry)(nil)
+	_ APIGWRegistry = (*APIGWDirRegistry)(nil)
+	_ APIGWRegistry = (*APIGWKubeRegistry)(nil)
+	_ APIGWRegistry = (*APIGWHTTPRegistry)(nil)
+	_ APIGWRegistry = (*APIGWMultiRegistry)(nil)
+)
+
 // APIGWEtcdRegistry implements the Register interface using etcd as the main storage.
 type APIGWEtcdRegistry struct {
 	etcdClient *clientv3.Client
@@ -50,7 +79,8 @@ type APIGWEtcdRegistry struct {
 
 	keyPrefix string
 
-	currentRevision int64
+	// nextRevision 下一次 Watch 开始的 revision, 由 Watch 取走后清零, 运行中的 watch 只使用自己的 revision
+	nextRevision atomic.Int64
 
 	// watchEventChanSize is the buffer size for watch event channel
 	watchEventChanSize int
@@ -97,10 +127,11 @@ func (r *APIGWEtcdRegistry) Watch(ctx context.Context) <-chan *entity.ResourceMe
 	retCh := make(chan *entity.ResourceMetadata, r.watchEventChanSize)
 	var etcdWatchCh clientv3.WatchChan
 	needCreateChan := true
+	// 取走全量同步设置的 revision, 上一次 watch 的协程退出时不会覆盖它
+	currentRevision := r.nextRevision.Swap(0)
 	go func() {
 		defer func() {
 			cancel() // Ensure watchCtx is cancelled when goroutine exits
-			r.currentRevision = 0
 			close(retCh)
 		}()
 
@@ -111,7 +142,7 @@ func (r *APIGWEtcdRegistry) Watch(ctx context.Context) <-chan *entity.ResourceMe
 					strings.TrimSuffix(r.keyPrefix, "/")+"/",
 					clientv3.WithPrefix(),
 					clientv3.WithPrevKV(),
-					clientv3.WithRev(r.currentRevision),
+					clientv3.WithRev(currentRevision),
 				)
 				needCreateChan = false
 			}
@@ -123,7 +154,7 @@ func (r *APIGWEtcdRegistry) Watch(ctx context.Context) <-chan *entity.ResourceMe
 						nil,
 						"Watch etcd registry failed: channel break, will recover from cached revision",
 						"revision",
-						r.currentRevision,
+						currentRevision,
 					)
 					time.Sleep(time.Second * 5)
 					needCreateChan = true
@@ -147,7 +178,7 @@ func (r *APIGWEtcdRegistry) Watch(ctx context.Context) <-chan *entity.ResourceMe
 							event.Err(),
 							"Watch etcd registry failed: other error, will recover from cached revision",
 							"revision",
-							r.currentRevision,
+							currentRevision,
 						)
 						time.Sleep(time.Second * 5)
 						needCreateChan = true
@@ -164,7 +195,7 @@ func (r *APIGWEtcdRegistry) Watch(ctx context.Context) <-chan *entity.ResourceMe
 						continue
 					}
 					retCh <- metadata
-					r.currentRevision = event.Header.Revision
+					currentRevision = event.Header.Revision
 				}
 
 			case <-ctx.Done():
@@ -239,6 +270,7 @@ func (r *APIGWEtcdRegistry) handleEvent(event *clientv3.Event) (*entity.Resource
 			span.RecordError(err)
 			return nil, err
 		}
+		setStageFromKey(string(event.PrevKv.Key), &metadata)
 		metadata.Ctx = eventCtx
 		metadata.Op = event.Type
 		return &metadata, nil
@@ -246,6 +278,21 @@ func (r *APIGWEtcdRegistry) handleEvent(event *clientv3.Event) (*entity.Resource
 	return nil, fmt.Errorf("err unknown event type: %s", event.Type)
 }
 
+// setStageFromKey 删除事件中的 labels 是上一次写入的值, 所属的环境以 key 为准
+// /{prefix}/{api_version}/gateway/{gateway_name}/{stage_name}/{kind}/{id}
+func setStageFromKey(key string, metadata *entity.ResourceMetadata) {
+	matches := strings.Split(strings.TrimPrefix(key, "/"), "/")
+	if len(matches) < 7 || matches[len(matches)-5] != "gateway" {
+		return
+	}
+	labels := entity.LabelInfo{}
+	if metadata.Labels != nil {
+		labels = *metadata.Labels
+	}
+	labels.Gateway, labels.Stage = matches[len(matches)-4], matches[len(matches)-3]
+	metadata.Labels = &labels
+}
+
 // extractResourceMetadata 解析 etcd key 和 value，返回资源元数据，复杂度比较高：todo 优化
 func (r *APIGWEtcdRegistry) extractResourceMetadata(key string, value []byte) (entity.ResourceMetadata, error) {
 	// /{self.prefix}/{self.api_version}/gateway/{gateway_name}/{stage_name}/route/bk-default.default.-1
@@ -278,7 +325,7 @@ func (r *APIGWEtcdRegistry) extractResourceMetadata(key string, value []byte) (e
 	}
 
 	// /bk-gateway-apigw/v2/global/plugin_metadata/bk-concurrency-limit
-	if resourceKind == constant.PluginMetadata && len(matches) == 5 {
+	if resourceKind.IsGlobal() && len(matches) == 5 {
 		ret.ID = matches[len(matches)-1]
 		ret.Kind = resourceKind
 		ret.Name = matches[len(matches)-1]
@@ -353,7 +400,7 @@ func (r *APIGWEtcdRegistry) ValueToStageResource(resp *clientv3.GetResponse) (*e
 		if resourceKind == constant.BkRelease {
 			continue
 		}
-		if !constant.SupportResourceTypeMap[resourceKind] {
+		if !constant.SupportResourceTypeMap[resourceKind] || constant.GlobalOnlyResourceTypeMap[resourceKind] {
 			r.logger.Errorf("resource kind not support, key: %s", kv.Key)
 			continue
 		}
@@ -363,7 +410,41 @@ func (r *APIGWEtcdRegistry) ValueToStageResource(resp *clientv3.GetResponse) (*e
 			return nil, err
 		}
 		// 校验配置 schema
-		err = validator.ValidateApisixJsonSchema(resourceMetadata.Labels.ApisixVersion, resourceKind, kv.Value)
+		schemaValue := kv.Value
+		if resourceKind == constant.ConsumerGroup {
+			// consumer_group 的 id 写在配置中, 但 apisix schema 中没有定义 id 字段, 校验前移除
+			schemaValue, err = sjson.DeleteBytes(kv.Value, "id")
+			if err != nil {
+				r.logger.Errorf("delete consumer group id failed: %v, key: %s", err, kv.Key)
+				return nil, err
+			}
+		}
+		if resourceKind == constant.PluginMetadata {
+			// 与全局的插件元数据相同, 写入 apisix 时不带 labels, 校验前移除
+			schemaValue, err = sjson.DeleteBytes(kv.Value, "labels")
+			if err != nil {
+				r.logger.Errorf("delete plugin metadata labels failed: %v, key: %s", err, kv.Key)
+				return nil, err
+			}
+		}
+		if resourceKind == constant.StreamRoute || resourceKind == constant.Proto {
+			// stream_route 和 proto 的 schema 中没有 labels 字段, 写入 apisix 时也不带 labels, 校验前移除
+			schemaValue, err = sjson.DeleteBytes(kv.Value, "labels")
+			if err != nil {
+				r.logger.Errorf("delete %s labels failed: %v, key: %s", resourceKind, err, kv.Key)
+				return nil, err
+			}
+			// 从 apisix 读取时根据 id 还原所属的环境, id 必须以 bk-apigw.{gateway}.{stage}. 开头
+			labels := entity.StageLabelsFromID(resourceMetadata.ID)
+			if labels == nil || labels.Gateway != resourceMetadata.GetGatewayName() ||
+				labels.Stage != resourceMetadata.GetStageName() {
+				r.logger.Errorf("%s id should start with %s{gateway}.{stage}., key: %s",
+					resourceKind, constant.ManagedIDPrefix, kv.Key)
+				return nil, eris.Errorf("%s id %s should start with %s{gateway}.{stage}.",
+					resourceKind, resourceMetadata.ID, constant.ManagedIDPrefix)
+			}
+		}
+		err = validator.ValidateApisixJsonSchema(resourceMetadata.Labels.ApisixVersion, resourceKind, schemaValue)
 		if err != nil {
 			r.logger.Errorf("validate apisix json schema failed: %v, key: %s", err, kv.Key)
 			return nil, err
@@ -388,15 +469,60 @@ func (r *APIGWEtcdRegistry) ValueToStageResource(resp *clientv3.GetResponse) (*e
 			}
 			service.ResourceMetadata = resourceMetadata
 			ret.Services[service.GetID()] = &service
-		// case constant.Proto:
-		//	var proto entity.Proto
-		//	err := json.Unmarshal(kv.Value, &proto)
-		//	if err != nil {
-		//		r.logger.Errorf("unmarshal etcd value failed:%v, key: %s", err, kv.Key)
-		//		return nil, err
-		//	}
-		//	proto.ResourceMetadata = resourceMetadata
-		//	ret.Protos[proto.GetID()] = &proto
+		case constant.Upstream:
+			var upstream entity.Upstream
+			err := json.Unmarshal(kv.Value, &upstream)
+			if err != nil {
+				r.logger.Errorf("unmarshal etcd value failed: %v, key: %s", err, kv.Key)
+				return nil, err
+			}
+			upstream.ResourceMetadata = resourceMetadata
+			ret.Upstreams[upstream.GetID()] = &upstream
+		case constant.PluginConfig:
+			var pluginConfig entity.PluginConfig
+			err := json.Unmarshal(kv.Value, &pluginConfig)
+			if err != nil {
+				r.logger.Errorf("unmarshal etcd value failed: %v, key: %s", err, kv.Key)
+				return nil, err
+			}
+			pluginConfig.ResourceMetadata = resourceMetadata
+			ret.PluginConfigs[pluginConfig.GetID()] = &pluginConfig
+		case constant.Consumer:
+			var consumer entity.Consumer
+			err := json.Unmarshal(kv.Value, &consumer)
+			if err != nil {
+				r.logger.Errorf("unmarshal etcd value failed: %v, key: %s", err, kv.Key)
+				return nil, err
+			}
+			consumer.ResourceMetadata = resourceMetadata
+			ret.Consumers[consumer.GetID()] = &consumer
+		case constant.ConsumerGroup:
+			var consumerGroup entity.ConsumerGroup
+			err := json.Unmarshal(kv.Value, &consumerGroup)
+			if err != nil {
+				r.logger.Errorf("unmarshal etcd value failed: %v, key: %s", err, kv.Key)
+				return nil, err
+			}
+			consumerGroup.ResourceMetadata = resourceMetadata
+			ret.ConsumerGroups[consumerGroup.GetID()] = &consumerGroup
+		case constant.StreamRoute:
+			var streamRoute entity.StreamRoute
+			err := json.Unmarshal(kv.Value, &streamRoute)
+			if err != nil {
+				r.logger.Errorf("unmarshal etcd value failed: %v, key: %s", err, kv.Key)
+				return nil, err
+			}
+			streamRoute.ResourceMetadata = resourceMetadata
+			ret.StreamRoutes[streamRoute.GetID()] = &streamRoute
+		case constant.Proto:
+			var proto entity.Proto
+			err := json.Unmarshal(kv.Value, &proto)
+			if err != nil {
+				r.logger.Errorf("unmarshal etcd value failed: %v, key: %s", err, kv.Key)
+				return nil, err
+			}
+			proto.ResourceMetadata = resourceMetadata
+			ret.Protos[proto.GetID()] = &proto
 		case constant.SSL:
 			var ssl entity.SSL
 			err := json.Unmarshal(kv.Value, &ssl)
@@ -407,6 +533,14 @@ func (r *APIGWEtcdRegistry) ValueToStageResource(resp *clientv3.GetResponse) (*e
 			ssl.ResourceMetadata = resourceMetadata
 			ssl.Status = constant.StatusEnable
 			ret.SSLs[ssl.GetID()] = &ssl
+		case constant.PluginMetadata:
+			metadata := &entity.PluginMetadata{
+				ResourceMetadata: resourceMetadata,
+				PluginMetadataConf: entity.PluginMetadataConf{
+					resourceMetadata.GetID(): schemaValue,
+				},
+			}
+			ret.PluginMetadata[metadata.GetID()] = metadata
 		}
 	}
 	return ret, nil
@@ -465,15 +599,21 @@ func (r *APIGWEtcdRegistry) ValueToGlobalResource(resp *clientv3.GetResponse) (*
 			r.logger.Error(err, "extract resource metadata failed", "key", string(kv.Key))
 			return nil, err
 		}
+		if resourceKind == constant.GlobalRule && !strings.HasPrefix(resourceMetadata.ID, constant.ManagedIDPrefix) {
+			// 没有前缀的 global rule 写入 apisix 后无法和其他方式创建的区分, 不做同步
+			r.logger.Errorf("global rule id should start with %s, key: %s", constant.ManagedIDPrefix, kv.Key)
+			continue
+		}
+		// Delete labels field, 全局资源写入 apisix 时不带 labels
+		rawConfig, _ := sjson.DeleteBytes(kv.Value, "labels")
 		// Validate configuration schema
-		err = validator.ValidateApisixJsonSchema(resourceMetadata.ApisixVersion, resourceKind, kv.Value)
+		err = validator.ValidateApisixJsonSchema(resourceMetadata.ApisixVersion, resourceKind, rawConfig)
 		if err != nil {
 			r.logger.Error(err, "validate apisix json schema failed", "key", string(kv.Key))
 			return nil, err
 		}
-		if resourceKind == constant.PluginMetadata {
-			// Delete labels field
-			rawConfig, _ := sjson.DeleteBytes(kv.Value, "labels")
+		switch resourceKind {
+		case constant.PluginMetadata:
 			metadata := &entity.PluginMetadata{
 				ResourceMetadata: resourceMetadata,
 				PluginMetadataConf: entity.PluginMetadataConf{
@@ -481,6 +621,20 @@ func (r *APIGWEtcdRegistry) ValueToGlobalResource(resp *clientv3.GetResponse) (*
 				},
 			}
 			ret.PluginMetadata[metadata.GetID()] = metadata
+		case constant.GlobalRule:
+			var globalRule entity.GlobalRule
+			if err = json.Unmarshal(rawConfig, &globalRule); err != nil {
+				r.logger.Error(err, "unmarshal etcd value failed", "key", string(kv.Key))
+				return nil, err
+			}
+			// global_rule 的 schema 中只有 id, 其他元数据不能写入 apisix
+			globalRule.ResourceMetadata = entity.ResourceMetadata{
+				ID:         resourceMetadata.ID,
+				Kind:       resourceMetadata.Kind,
+				APIVersion: resourceMetadata.APIVersion,
+				Ctx:        resourceMetadata.Ctx,
+			}
+			ret.GlobalRules[globalRule.GetID()] = &globalRule
 		}
 	}
 	return ret, nil
@@ -565,18 +719,94 @@ func (r *APIGWEtcdRegistry) StageReleaseVersion(stageRelease *entity.ReleaseInfo
 	return ret, nil
 }
 
+// ListReleaseInfos 查询 key prefix 下所有环境的发布信息，同时返回本次查询对应的 etcd revision，
+// 从 revision+1 开始 watch 可以保证不会遗漏查询之后的事件
+func (r *APIGWEtcdRegistry) ListReleaseInfos(ctx context.Context) ([]*entity.ReleaseInfo, int64, error) {
+	// /{prefix}/{api_version}/gateway/{gateway_name}/{stage_name}/_bk_release/bk.release.{gateway_name}.{stage_name}
+	startedTime := time.Now()
+	// 发布信息分散在各环境的目录下, 一次读取整个前缀, 所有发布信息来自同一个 revision 的快照
+	resp, err := r.etcdClient.Get(
+		ctx,
+		strings.TrimSuffix(r.keyPrefix, "/")+"/",
+		clientv3.WithPrefix(),
+	)
+	if err != nil {
+		metric.ReportRegistryAction(constant.BkRelease.String(), metric.ActionList, metric.ResultFail, startedTime)
+		r.logger.Error(err, "list etcd values failed", "keyPrefix", r.keyPrefix)
+		return nil, 0, err
+	}
+	revision := resp.Header.Revision
+
+	releaseList := make([]*entity.ReleaseInfo, 0)
+	for _, kv := range resp.Kvs {
+		if _, ok := releaseStageKey(string(kv.Key)); !ok {
+			continue
+		}
+		release, err := r.kvToStageReleaseInfo(kv)
+		if err != nil {
+			// 单个发布信息异常不影响其他环境的同步
+			r.logger.Errorf("parse release info failed: %v, key: %s", err, kv.Key)
+			continue
+		}
+		release.Ctx = release.ResourceMetadata.Ctx
+		releaseList = append(releaseList, release)
+	}
+	metric.ReportRegistryAction(constant.BkRelease.String(), metric.ActionList, metric.ResultSuccess, startedTime)
+	return releaseList, revision, nil
+}
+
+// ListReleaseStageKeys 只查询 key, 返回有发布信息的环境的 stage key
+func (r *APIGWEtcdRegistry) ListReleaseStageKeys(ctx context.Context) (map[string]struct{}, error) {
+	resp, err := r.etcdClient.Get(
+		ctx,
+		strings.TrimSuffix(r.keyPrefix, "/")+"/",
+		clientv3.WithPrefix(),
+		clientv3.WithKeysOnly(),
+	)
+	if err != nil {
+		r.logger.Error(err, "list etcd keys failed", "keyPrefix", r.keyPrefix)
+		return nil, err
+	}
+	stageKeys := make(map[string]struct{})
+	for _, kv := range resp.Kvs {
+		if stageKey, ok := releaseStageKey(string(kv.Key)); ok {
+			stageKeys[stageKey] = struct{}{}
+		}
+	}
+	return stageKeys, nil
+}
+
+// releaseStageKey 发布信息的 key 对应的 stage key, 不是发布信息的 key 时返回 false
+// /{prefix}/{api_version}/gateway/{gateway_name}/{stage_name}/_bk_release/bk.release.{gateway_name}.{stage_name}
+func releaseStageKey(key string) (string, bool) {
+	matches := strings.Split(strings.TrimPrefix(key, "/"), "/")
+	if len(matches) < 7 || constant.APISIXResource(matches[len(matches)-2]) != constant.BkRelease {
+		return "", false
+	}
+	return config.GenStagePrimaryKey(matches[len(matches)-4], matches[len(matches)-3]), true
+}
+
+// SetCurrentRevision 设置下一次 Watch 开始的 revision
+func (r *APIGWEtcdRegistry) SetCurrentRevision(revision int64) {
+	r.nextRevision.Store(revision)
+}
+
 // ValueToStageReleaseInfo ...
 func (r *APIGWEtcdRegistry) ValueToStageReleaseInfo(resp *clientv3.GetResponse) (*entity.ReleaseInfo, error) {
+	return r.kvToStageReleaseInfo(resp.Kvs[0])
+}
+
+func (r *APIGWEtcdRegistry) kvToStageReleaseInfo(kv *mvccpb.KeyValue) (*entity.ReleaseInfo, error) {
 	// /{prefix}/{api_version}/gateway/{gateway_name}/{stage_name}/_bk_release/bk.release.{gateway_name}.{stage_name}
 	var release *entity.ReleaseInfo
-	err := json.Unmarshal(resp.Kvs[0].Value, &release)
+	err := json.Unmarshal(kv.Value, &release)
 	if err != nil {
-		r.logger.Errorf("unmarshal etcd value failed:%v, key: %s", err, resp.Kvs[0].Key)
+		r.logger.Errorf("unmarshal etcd value failed:%v, key: %s", err, kv.Key)
 		return nil, err
 	}
-	resourceMetadata, err := r.extractResourceMetadata(string(resp.Kvs[0].Key), resp.Kvs[0].Value)
+	resourceMetadata, err := r.extractResourceMetadata(string(kv.Key), kv.Value)
 	if err != nil {
-		r.logger.Errorf("extract resource metadata failed:%v, key: %s", err, resp.Kvs[0].Key)
+		r.logger.Errorf("extract resource metadata failed:%v, key: %s", err, kv.Key)
 		return nil, err
 	}
 	release.ResourceMetadata = resourceMetadata
@@ -0,0 +1,151 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+)
+
+// dirFileExt 只读取 json 文件, 忽略编辑器的临时文件等
+const dirFileExt = ".json"
+
+// dirRacyWindow 修改时间在这个时间窗口内的文件总是重新读取,
+// 避免文件系统的时间精度不够时, 同一时间内大小不变的修改被缓存掩盖
+const dirRacyWindow = 2 * time.Second
+
+// dirFile 缓存的文件内容
+type dirFile struct {
+	modTime time.Time
+	size    int64
+	value   []byte
+}
+
+// APIGWDirRegistry 从本地目录读取网关发布的资源, 用于本地开发和离线部署
+// 目录布局与 etcd 一致: {root}/{api_version}/gateway/{gateway_name}/{stage_name}/{kind}/{id}.json,
+// 全局资源为 {root}/{api_version}/global/{kind}/{name}.json, kind 为 plugin_metadata 或 global_rule;
+// 通过定期扫描目录发现变更, 挂载的 ConfigMap、NFS 等文件系统上也能工作, 发布只需要把文件放到对应的位置;
+// 扫描时只读取修改时间或大小变化的文件, 其余文件使用缓存的内容
+type APIGWDirRegistry struct {
+	*snapshotRegistry
+
+	root string
+
+	mux   sync.Mutex
+	files map[string]*dirFile
+}
+
+// NewAPIGWDirRegistry creates a new APIGWDirRegistry reading the tree under root
+func NewAPIGWDirRegistry(root string, interval time.Duration, watchEventChanSize int) *APIGWDirRegistry {
+	r := &APIGWDirRegistry{root: root, files: make(map[string]*dirFile)}
+	r.snapshotRegistry = newSnapshotRegistry(
+		r.scan, interval, watchEventChanSize, logging.GetLogger().Named("dir-registry"))
+	return r
+}
+
+// scan 读取 root 下 dir 目录中的所有 json 文件, 返回 etcd key 到文件内容的映射
+func (r *APIGWDirRegistry) scan(_ context.Context, dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	seen := make(map[string]struct{})
+	base := filepath.Join(r.root, filepath.FromSlash(dir))
+	err := filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 目录不存在时视为没有资源
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && p != base {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || filepath.Ext(d.Name()) != dirFileExt {
+			return nil
+		}
+		rel, err := filepath.Rel(r.root, p)
+		if err != nil {
+			return err
+		}
+		value, err := r.read(p)
+		if err != nil {
+			// 扫描期间被删除的文件视为不存在
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		seen[p] = struct{}{}
+		files[snapshotKey(strings.TrimSuffix(filepath.ToSlash(rel), dirFileExt))] = value
+		return nil
+	})
+	if err == nil {
+		r.prune(base, seen)
+	}
+	return files, err
+}
+
+// read 读取文件内容, 修改时间和大小都没有变化时返回缓存的内容
+func (r *APIGWDirRegistry) read(p string) ([]byte, error) {
+	// ConfigMap 中的文件是符号链接, 需要读取链接目标的修改时间
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	r.mux.Lock()
+	cached, ok := r.files[p]
+	r.mux.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() &&
+		time.Since(info.ModTime()) > dirRacyWindow {
+		return cached.value, nil
+	}
+
+	value, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	r.mux.Lock()
+	r.files[p] = &dirFile{modTime: info.ModTime(), size: info.Size(), value: value}
+	r.mux.Unlock()
+	return value, nil
+}
+
+// prune 清理 base 目录下已经不存在的文件的缓存
+func (r *APIGWDirRegistry) prune(base string, seen map[string]struct{}) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	for p := range r.files {
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		if p == base || strings.HasPrefix(p, base+string(filepath.Separator)) {
+			delete(r.files, p)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+var _ = Describe("APIGWDirRegistry", func() {
+	var (
+		root     string
+		registry *APIGWDirRegistry
+		ctx      context.Context
+	)
+
+	writeFile := func(rel string, value any) {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		Expect(os.MkdirAll(filepath.Dir(p), 0o755)).To(Succeed())
+		content, err := json.Marshal(value)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(os.WriteFile(p, content, 0o644)).To(Succeed())
+	}
+	writeRelease := func(stage string) {
+		writeFile("v2/gateway/test-gateway/"+stage+"/_bk_release/bk.release.test-gateway."+stage+".json",
+			testReleaseValue(stage))
+	}
+	writeRoute := func(stage, id string) {
+		writeFile("v2/gateway/test-gateway/"+stage+"/route/"+id+".json", testRouteValue(stage, id))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		metric.InitMetric(prometheus.NewRegistry())
+		root = GinkgoT().TempDir()
+		registry = NewAPIGWDirRegistry(root, 20*time.Millisecond, 100)
+	})
+
+	Describe("ListReleaseInfos", func() {
+		It("should list the releases of the stages", func() {
+			writeRelease("prod")
+			writeRelease("test")
+			writeRoute("prod", "test-gateway.prod.1")
+			// non json files should be ignored
+			Expect(os.WriteFile(filepath.Join(root, "v2", "README"), []byte("docs"), 0o644)).To(Succeed())
+
+			releaseList, revision, err := registry.ListReleaseInfos(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(revision).To(BeZero())
+			Expect(releaseList).To(HaveLen(2))
+			for _, release := range releaseList {
+				Expect(release.Kind).To(Equal(constant.BkRelease))
+				Expect(release.APIVersion).To(Equal("v2"))
+				Expect(release.GetGatewayName()).To(Equal("test-gateway"))
+				Expect(release.PublishId).To(Equal(10))
+				Expect(release.Ctx).NotTo(BeNil())
+			}
+		})
+
+		It("should list the stage keys of the releases which can not be parsed", func() {
+			writeRelease("prod")
+			writeFile("v2/gateway/test-gateway/test/_bk_release/bk.release.test-gateway.test.json", "broken")
+
+			releaseList, _, err := registry.ListReleaseInfos(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(releaseList).To(HaveLen(1))
+			stageKeys, err := registry.ListReleaseStageKeys(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(stageKeys).To(Equal(map[string]struct{}{
+				"bk.release.test-gateway.prod": {},
+				"bk.release.test-gateway.test": {},
+			}))
+		})
+
+		It("should return empty list when the directory does not exist", func() {
+			registry = NewAPIGWDirRegistry(filepath.Join(root, "missing"), time.Second, 100)
+			releaseList, _, err := registry.ListReleaseInfos(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(releaseList).To(BeEmpty())
+		})
+	})
+
+	Describe("stage resources", func() {
+		It("should read the resources of the stage", func() {
+			writeRelease("prod")
+			writeRoute("prod", "test-gateway.prod.1")
+			writeRoute("prod", "test-gateway.prod.2")
+			writeRoute("test", "test-gateway.test.1")
+			release := createReleaseInfo(ctx, "v2", "test-gateway", "prod", constant.Route)
+
+			resources, err := registry.ListStageResources(release)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.Routes).To(HaveLen(2))
+			Expect(resources.Routes).To(HaveKey("test-gateway.prod.1"))
+
+			count, err := registry.Count(release)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(count).To(Equal(int64(2)))
+
+			resources, err = registry.GetStageResourceByID("test-gateway.prod.2", release)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.Routes).To(HaveKey("test-gateway.prod.2"))
+
+			_, err = registry.GetStageResourceByID("test-gateway.prod.3", release)
+			Expect(err).To(HaveOccurred())
+
+			version, err := registry.StageReleaseVersion(release)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(version.PublishId).To(Equal(10))
+		})
+
+		It("should return empty resources when the stage has no files", func() {
+			release := createReleaseInfo(ctx, "v2", "non-existent", "non-existent", constant.Route)
+			resources, err := registry.ListStageResources(release)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.Routes).To(BeEmpty())
+
+			global, err := registry.ListGlobalResources(release)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(global.PluginMetadata).To(BeEmpty())
+		})
+	})
+
+	Describe("Watch", func() {
+		It("should emit the changes of the files with the release last", func() {
+			writeRoute("prod", "test-gateway.prod.1")
+			_, _, err := registry.ListReleaseInfos(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			watchCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			eventCh := registry.Watch(watchCtx)
+
+			receive := func() *entity.ResourceMetadata {
+				var event *entity.ResourceMetadata
+				Eventually(eventCh, 2*time.Second).Should(Receive(&event))
+				return event
+			}
+
+			writeRoute("prod", "test-gateway.prod.2")
+			writeRelease("prod")
+			event := receive()
+			Expect(event.Kind).To(Equal(constant.Route))
+			Expect(event.Op).To(Equal(mvccpb.PUT))
+			event = receive()
+			Expect(event.Kind).To(Equal(constant.BkRelease))
+			Expect(event.GetStageName()).To(Equal("prod"))
+
+			Expect(os.Remove(filepath.Join(root, "v2/gateway/test-gateway/prod/route/test-gateway.prod.1.json"))).
+				To(Succeed())
+			event = receive()
+			Expect(event.Kind).To(Equal(constant.Route))
+			Expect(event.Op).To(Equal(mvccpb.DELETE))
+			Expect(event.GetStageName()).To(Equal("prod"))
+			Consistently(eventCh, 100*time.Millisecond).ShouldNot(Receive())
+
+			cancel()
+			Eventually(eventCh).Should(BeClosed())
+		})
+
+		It("should take the stage of the delete event from the key", func() {
+			writeFile("v2/gateway/test-gateway/prod/plugin_metadata/bk-concurrency-limit.json",
+				map[string]any{"id": "bk-concurrency-limit", "conn": 100})
+			_, _, err := registry.ListReleaseInfos(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			watchCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			eventCh := registry.Watch(watchCtx)
+
+			Expect(os.Remove(filepath.Join(root,
+				"v2/gateway/test-gateway/prod/plugin_metadata/bk-concurrency-limit.json"))).To(Succeed())
+			var event *entity.ResourceMetadata
+			Eventually(eventCh, 2*time.Second).Should(Receive(&event))
+			Expect(event.Kind).To(Equal(constant.PluginMetadata))
+			Expect(event.Op).To(Equal(mvccpb.DELETE))
+			Expect(event.GetGatewayName()).To(Equal("test-gateway"))
+			Expect(event.GetStageName()).To(Equal("prod"))
+			Expect(event.IsGlobalResource()).To(BeFalse())
+		})
+	})
+
+	Describe("scan", func() {
+		It("should only read the files whose modification time or size changed", func() {
+			writeRoute("prod", "test-gateway.prod.1")
+			p := filepath.Join(root, "v2/gateway/test-gateway/prod/route/test-gateway.prod.1.json")
+			key := snapshotKey("v2/gateway/test-gateway/prod/route/test-gateway.prod.1")
+			modTime := time.Now().Add(-time.Minute)
+			Expect(os.Chtimes(p, modTime, modTime)).To(Succeed())
+
+			files, err := registry.scan(ctx, "v2")
+			Expect(err).ShouldNot(HaveOccurred())
+			content := files[key]
+			Expect(content).NotTo(BeEmpty())
+
+			// 修改时间和大小都没有变化时使用缓存的内容
+			changed := bytes.ReplaceAll(content, []byte("prod.1"), []byte("prod.2"))
+			Expect(os.WriteFile(p, changed, 0o644)).To(Succeed())
+			Expect(os.Chtimes(p, modTime, modTime)).To(Succeed())
+			files, err = registry.scan(ctx, "v2")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(files[key]).To(Equal(content))
+
+			modTime = modTime.Add(time.Second)
+			Expect(os.Chtimes(p, modTime, modTime)).To(Succeed())
+			files, err = registry.scan(ctx, "v2/gateway/test-gateway/prod")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(files[key]).To(Equal(changed))
+
+			// 删除的文件同时清理缓存
+			Expect(os.Remove(p)).To(Succeed())
+			files, err = registry.scan(ctx, "v2/gateway/test-gateway/prod/route")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(files).To(BeEmpty())
+			Expect(registry.files).To(BeEmpty())
+		})
+	})
+})
+
+func testReleaseValue(stage string) map[string]any {
+	return map[string]any{
+		"id": "bk.release.test-gateway." + stage,
+		"labels": map[string]any{
+			"gateway.bk.tencent.com/gateway":    "test-gateway",
+			"gateway.bk.tencent.com/stage":      stage,
+			"gateway.bk.tencent.com/publish-id": "10",
+		},
+		"publish_id": 10,
+	}
+}
+
+func testRouteValue(stage, id string) map[string]any {
+	return map[string]any{
+		"id":   id,
+		"name": id,
+		"uri":  "/" + id,
+		"labels": map[string]any{
+			"gateway.bk.tencent.com/gateway":        "test-gateway",
+			"gateway.bk.tencent.com/stage":          stage,
+			"gateway.bk.tencent.com/apisix-version": "3.13.0",
+		},
+		"upstream": map[string]any{
+			"type":  "roundrobin",
+			"nodes": []map[string]any{{"host": "1.1.1.1", "port": 80, "weight": 1}},
+		},
+	}
+}
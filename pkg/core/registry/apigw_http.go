@@ -0,0 +1,227 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/client"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+)
+
+const httpDefaultAPIVersion = "v2"
+
+// httpStage 缓存的环境资源, publishID 为下载时的发布版本
+type httpStage struct {
+	publishID int
+	files     map[string][]byte
+}
+
+// APIGWHTTPRegistry 从 core api 拉取网关发布的资源, 用于无法访问 dashboard etcd 的网络隔离环境
+// 每次读取先用 ETag 查询各环境最新的发布, 只有发布版本变化的环境才重新下载资源;
+// 拉取失败时按指数退避重试, 期间使用上一次拉取成功的资源
+type APIGWHTTPRegistry struct {
+	*snapshotRegistry
+
+	client     *client.CoreAPIClient
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	cacheMux     sync.Mutex
+	releasesETag string
+	globalETag   string
+	stages       map[string]*httpStage
+	global       map[string][]byte
+	loaded       bool
+	refreshedAt  time.Time
+	failures     int
+	nextAttempt  time.Time
+	lastErr      error
+}
+
+// NewAPIGWHTTPRegistry creates a new APIGWHTTPRegistry pulling the releases from the core api
+func NewAPIGWHTTPRegistry(
+	coreAPIClient *client.CoreAPIClient,
+	pullConfig *config.CoreAPISource,
+	watchEventChanSize int,
+) *APIGWHTTPRegistry {
+	r := &APIGWHTTPRegistry{
+		client:     coreAPIClient,
+		backoff:    pullConfig.Backoff,
+		maxBackoff: pullConfig.MaxBackoff,
+		stages:     make(map[string]*httpStage),
+		global:     make(map[string][]byte),
+	}
+	r.snapshotRegistry = newSnapshotRegistry(
+		r.load, pullConfig.Interval, watchEventChanSize, logging.GetLogger().Named("http-registry"))
+	return r
+}
+
+// load 拉取最新的资源后返回 prefix 下的资源; 拉取失败时如果已经拉取成功过, 使用缓存的资源
+func (r *APIGWHTTPRegistry) load(ctx context.Context, prefix string) (map[string][]byte, error) {
+	r.cacheMux.Lock()
+	defer r.cacheMux.Unlock()
+
+	if err := r.refresh(ctx); err != nil {
+		if !r.loaded {
+			return nil, err
+		}
+		r.logger.Warnw("pull releases from core api failed, use the cached resources", "err", err)
+	}
+
+	keyPrefix := snapshotKey(prefix) + "/"
+	files := make(map[string][]byte)
+	collect := func(stageFiles map[string][]byte) {
+		for key, value := range stageFiles {
+			if prefix == "" || strings.HasPrefix(key, keyPrefix) {
+				files[key] = value
+			}
+		}
+	}
+	for _, stage := range r.stages {
+		collect(stage.files)
+	}
+	collect(r.global)
+	return files, nil
+}
+
+// refresh 拉取最新的发布和资源, 同一个间隔内的多次读取 (如 Watch 之后的提交) 只拉取一次; 退避期间直接返回上一次的错误
+func (r *APIGWHTTPRegistry) refresh(ctx context.Context) error {
+	now := time.Now()
+	if r.lastErr != nil && now.Before(r.nextAttempt) {
+		return r.lastErr
+	}
+	if r.lastErr == nil && r.loaded && now.Sub(r.refreshedAt) < r.interval/2 {
+		return nil
+	}
+
+	err := r.pull(ctx)
+	if err != nil {
+		r.failures++
+		backoff := r.backoff << min(r.failures-1, 16)
+		if backoff <= 0 || backoff > r.maxBackoff {
+			backoff = r.maxBackoff
+		}
+		r.nextAttempt = now.Add(backoff)
+		r.lastErr = err
+		return err
+	}
+	r.failures = 0
+	r.lastErr = nil
+	r.loaded = true
+	r.refreshedAt = now
+	return nil
+}
+
+// pull 查询发布和全局资源, 环境的资源下载失败时不更新 ETag, 下一次重新查询
+func (r *APIGWHTTPRegistry) pull(ctx context.Context) error {
+	releases, err := r.client.ListReleases(ctx, r.releasesETag)
+	if err != nil {
+		return fmt.Errorf("list releases failed: %w", err)
+	}
+	if !releases.NotModified {
+		if err = r.updateStages(ctx, releases.Releases); err != nil {
+			return err
+		}
+		r.releasesETag = releases.ETag
+	}
+
+	global, err := r.client.ListGlobalResources(ctx, r.globalETag)
+	if err != nil {
+		return fmt.Errorf("list global resources failed: %w", err)
+	}
+	if !global.NotModified {
+		apiVersion := global.APIVersion
+		if apiVersion == "" {
+			apiVersion = httpDefaultAPIVersion
+		}
+		r.global = r.resourceFiles(path.Join(apiVersion, "global"), global.Resources)
+		r.globalETag = global.ETag
+	}
+	return nil
+}
+
+// updateStages 下载发布版本变化的环境的资源, 删除不再返回的环境
+func (r *APIGWHTTPRegistry) updateStages(ctx context.Context, releases []*client.MicroGatewayRelease) error {
+	current := make(map[string]struct{}, len(releases))
+	for _, release := range releases {
+		stageKey := config.GenStagePrimaryKey(release.GatewayName, release.StageName)
+		current[stageKey] = struct{}{}
+		if stage, ok := r.stages[stageKey]; ok && stage.publishID == release.PublishID {
+			continue
+		}
+
+		apiVersion := release.APIVersion
+		if apiVersion == "" {
+			apiVersion = httpDefaultAPIVersion
+		}
+		dir := path.Join(apiVersion, "gateway", release.GatewayName, release.StageName)
+		files := make(map[string][]byte)
+		// 删除的环境没有资源, 只保留发布信息
+		if release.PublishID != cast.ToInt(constant.DeletePublishID) {
+			resources, err := r.client.GetStageResources(ctx, release.GatewayName, release.StageName, release.PublishID)
+			if err != nil {
+				return fmt.Errorf("get resources of %s failed: %w", stageKey, err)
+			}
+			if resources.PublishID != release.PublishID {
+				return fmt.Errorf("get resources of %s failed: publish id %d is not the expected %d",
+					stageKey, resources.PublishID, release.PublishID)
+			}
+			files = r.resourceFiles(dir, resources.Resources)
+		}
+		files[snapshotKey(path.Join(dir, constant.BkRelease.String(), stageKey))] = release.Release
+		r.stages[stageKey] = &httpStage{publishID: release.PublishID, files: files}
+		r.logger.Infow("pull stage resources from core api",
+			"stage", stageKey, "publishID", release.PublishID, "count", len(files)-1)
+	}
+	for stageKey := range r.stages {
+		if _, ok := current[stageKey]; !ok {
+			delete(r.stages, stageKey)
+		}
+	}
+	return nil
+}
+
+// resourceFiles 将按类型分组的资源转换为 etcd key 到资源的映射, 没有 id 的资源会被忽略
+func (r *APIGWHTTPRegistry) resourceFiles(dir string, resources map[string][]json.RawMessage) map[string][]byte {
+	files := make(map[string][]byte)
+	for kind, items := range resources {
+		for _, item := range items {
+			var metadata struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(item, &metadata); err != nil || metadata.ID == "" {
+				r.logger.Warnw("resource without id, skip it", "kind", kind, "resource", string(item))
+				continue
+			}
+			files[snapshotKey(path.Join(dir, kind, metadata.ID))] = item
+		}
+	}
+	return files
+}
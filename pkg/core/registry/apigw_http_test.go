@@ -0,0 +1,222 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/client"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+// fakeCoreAPI core api 的替身, 发布信息和全局资源支持 ETag
+type fakeCoreAPI struct {
+	mux sync.Mutex
+	// stages 各环境的发布版本和路由 id
+	stages    map[string]int
+	routes    map[string][]string
+	failing   bool
+	requests  map[string]int
+	downloads map[string]int
+}
+
+func newFakeCoreAPI() *fakeCoreAPI {
+	return &fakeCoreAPI{
+		stages:    map[string]int{"prod": 10},
+		routes:    map[string][]string{"prod": {"test-gateway.prod.1", "test-gateway.prod.2"}},
+		requests:  make(map[string]int),
+		downloads: make(map[string]int),
+	}
+}
+
+func (f *fakeCoreAPI) writeData(w http.ResponseWriter, req *http.Request, data any, withETag bool) {
+	content, _ := json.Marshal(map[string]any{"data": data})
+	if withETag {
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256(content))
+		if req.Header.Get("If-None-Match") == etag {
+			f.requests["not-modified"]++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+	}
+	_, _ = w.Write(content)
+}
+
+func (f *fakeCoreAPI) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.requests[req.URL.Path]++
+	if f.failing {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	switch req.URL.Path {
+	case "/api/v1/micro-gateway/instance/releases/":
+		releases := make([]*client.MicroGatewayRelease, 0)
+		for stage, publishID := range f.stages {
+			release := testReleaseValue(stage)
+			release["publish_id"] = publishID
+			release["labels"].(map[string]any)["gateway.bk.tencent.com/publish-id"] = strconv.Itoa(publishID)
+			content, _ := json.Marshal(release)
+			releases = append(releases, &client.MicroGatewayRelease{
+				GatewayName: "test-gateway",
+				StageName:   stage,
+				PublishID:   publishID,
+				Release:     content,
+			})
+		}
+		f.writeData(w, req, releases, true)
+	case "/api/v1/micro-gateway/instance/releases/test-gateway/prod/resources/":
+		f.downloads["prod"]++
+		routes := make([]any, 0)
+		for _, id := range f.routes["prod"] {
+			routes = append(routes, testRouteValue("prod", id))
+		}
+		f.writeData(w, req, map[string]any{
+			"publish_id": f.stages["prod"],
+			"resources":  map[string]any{"route": routes},
+		}, false)
+	case "/api/v1/micro-gateway/instance/global-resources/":
+		f.writeData(w, req, map[string]any{"resources": map[string]any{}}, true)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var _ = Describe("APIGWHTTPRegistry", func() {
+	var (
+		fake     *fakeCoreAPI
+		server   *httptest.Server
+		registry *APIGWHTTPRegistry
+		ctx      context.Context
+		release  *entity.ReleaseInfo
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		metric.InitMetric(prometheus.NewRegistry())
+		fake = newFakeCoreAPI()
+		server = httptest.NewServer(fake)
+		DeferCleanup(server.Close)
+
+		registry = NewAPIGWHTTPRegistry(
+			client.NewCoreAPIClient(server.URL, "instance", "secret", time.Second),
+			&config.CoreAPISource{
+				Interval:   20 * time.Millisecond,
+				Backoff:    200 * time.Millisecond,
+				MaxBackoff: time.Second,
+			},
+			100,
+		)
+		release = createReleaseInfo(ctx, "v2", "test-gateway", "prod", constant.Route)
+	})
+
+	It("should pull the releases and download the stage only when the publish id changes", func() {
+		releaseList, _, err := registry.ListReleaseInfos(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(releaseList).To(HaveLen(1))
+		Expect(releaseList[0].PublishId).To(Equal(10))
+
+		time.Sleep(30 * time.Millisecond)
+		resources, err := registry.ListStageResources(release)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resources.Routes).To(HaveLen(2))
+
+		fake.mux.Lock()
+		Expect(fake.downloads["prod"]).To(Equal(1))
+		Expect(fake.requests["not-modified"]).To(Equal(2))
+		fake.mux.Unlock()
+	})
+
+	It("should emit the changes of the pulled releases", func() {
+		_, _, err := registry.ListReleaseInfos(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		eventCh := registry.Watch(watchCtx)
+		receive := func() *entity.ResourceMetadata {
+			var event *entity.ResourceMetadata
+			Eventually(eventCh, 2*time.Second).Should(Receive(&event))
+			return event
+		}
+
+		fake.mux.Lock()
+		fake.stages["prod"] = 11
+		fake.routes["prod"] = []string{"test-gateway.prod.1"}
+		fake.mux.Unlock()
+
+		event := receive()
+		Expect(event.Kind).To(Equal(constant.Route))
+		Expect(event.Op).To(Equal(mvccpb.DELETE))
+		Expect(event.ID).To(Equal("test-gateway.prod.2"))
+		event = receive()
+		Expect(event.Kind).To(Equal(constant.BkRelease))
+		Expect(event.Op).To(Equal(mvccpb.PUT))
+		Expect(event.GetReleaseInfo().PublishId).To(Equal(11))
+		Consistently(eventCh, 100*time.Millisecond).ShouldNot(Receive())
+
+		version, err := registry.StageReleaseVersion(release)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(version.PublishId).To(Equal(11))
+	})
+
+	It("should use the cached resources and back off when the core api fails", func() {
+		_, _, err := registry.ListReleaseInfos(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		fake.mux.Lock()
+		fake.failing = true
+		fake.mux.Unlock()
+		for i := 0; i < 5; i++ {
+			time.Sleep(20 * time.Millisecond)
+			resources, err := registry.ListStageResources(release)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.Routes).To(HaveLen(2))
+		}
+
+		fake.mux.Lock()
+		defer fake.mux.Unlock()
+		// 第一次失败后在退避期间不会再请求
+		Expect(fake.requests["/api/v1/micro-gateway/instance/releases/"]).To(Equal(2))
+	})
+
+	It("should fail when the core api has never succeeded", func() {
+		fake.failing = true
+		_, _, err := registry.ListReleaseInfos(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+})
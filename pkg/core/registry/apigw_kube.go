@@ -0,0 +1,138 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package registry
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+)
+
+// ConfigMap 上描述资源归属的 label 和 annotation
+const (
+	// KubeLabelGateway 环境资源所属的网关, 没有网关和环境 label 的 ConfigMap 保存全局资源
+	KubeLabelGateway = "gateway.bk.tencent.com/gateway"
+	// KubeLabelStage 环境资源所属的环境
+	KubeLabelStage = "gateway.bk.tencent.com/stage"
+	// KubeAnnotationAPIVersion 资源的 api version, 默认为 v2
+	KubeAnnotationAPIVersion = "gateway.bk.tencent.com/api-version"
+
+	kubeDefaultAPIVersion = "v2"
+	kubeDataKeyExt        = ".json"
+)
+
+// APIGWKubeRegistry 从 kubernetes 中带标签的 ConfigMap 读取网关发布的资源
+// 一个环境的资源可以拆分到多个 ConfigMap 中 (单个 ConfigMap 不能超过 1MiB), 通过网关和环境 label 归属到环境;
+// data 的 key 为 {kind}.{id}.json, value 为与 etcd 中相同的资源 json,
+// 如 route.gw.prod.1.json, _bk_release.bk.release.gw.prod.json;
+// 全局资源的 ConfigMap 不带网关和环境 label, 如 plugin_metadata.bk-concurrency-limit.json, global_rule.1.json
+type APIGWKubeRegistry struct {
+	*snapshotRegistry
+
+	client    client.Reader
+	namespace string
+	selector  labels.Selector
+}
+
+// NewAPIGWKubeRegistry creates a new APIGWKubeRegistry reading the ConfigMaps matching the selector in namespace
+func NewAPIGWKubeRegistry(
+	reader client.Reader,
+	namespace string,
+	selector labels.Selector,
+	interval time.Duration,
+	watchEventChanSize int,
+) *APIGWKubeRegistry {
+	r := &APIGWKubeRegistry{
+		client:    reader,
+		namespace: namespace,
+		selector:  selector,
+	}
+	r.snapshotRegistry = newSnapshotRegistry(
+		r.list, interval, watchEventChanSize, logging.GetLogger().Named("kube-registry"))
+	return r
+}
+
+// list 查询所有 ConfigMap 并转换为 etcd key 到资源的映射, 只返回 prefix 下的资源
+func (r *APIGWKubeRegistry) list(ctx context.Context, prefix string) (map[string][]byte, error) {
+	configMaps := &corev1.ConfigMapList{}
+	err := r.client.List(ctx, configMaps, client.InNamespace(r.namespace), client.MatchingLabelsSelector{
+		Selector: r.selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keyPrefix := snapshotKey(prefix)
+	files := make(map[string][]byte)
+	owners := make(map[string]string)
+	for i := range configMaps.Items {
+		cm := &configMaps.Items[i]
+		dir, ok := r.configMapPrefix(cm)
+		if !ok {
+			r.logger.Warnw("configmap should have both gateway and stage labels or neither, skip it",
+				"configmap", cm.Name)
+			continue
+		}
+		for dataKey, value := range cm.Data {
+			kind, id, ok := strings.Cut(strings.TrimSuffix(dataKey, kubeDataKeyExt), ".")
+			if !ok || !strings.HasSuffix(dataKey, kubeDataKeyExt) {
+				r.logger.Warnw("invalid data key of configmap, should be {kind}.{id}.json, skip it",
+					"configmap", cm.Name, "key", dataKey)
+				continue
+			}
+			key := snapshotKey(path.Join(dir, kind, id))
+			if prefix != "" && !strings.HasPrefix(key, keyPrefix+"/") {
+				continue
+			}
+			// 同一个资源出现在多个 ConfigMap 中时, 按名称排序后最后一个生效
+			if owner, ok := owners[key]; ok {
+				r.logger.Warnw("duplicated resource in configmaps", "key", key, "configmaps", []string{owner, cm.Name})
+				if owner > cm.Name {
+					continue
+				}
+			}
+			owners[key] = cm.Name
+			files[key] = []byte(value)
+		}
+	}
+	return files, nil
+}
+
+// configMapPrefix ConfigMap 中资源的 prefix, 不包含 kind; 只有网关或环境其中一个 label 时返回 false
+func (r *APIGWKubeRegistry) configMapPrefix(cm *corev1.ConfigMap) (string, bool) {
+	apiVersion := cm.Annotations[KubeAnnotationAPIVersion]
+	if apiVersion == "" {
+		apiVersion = kubeDefaultAPIVersion
+	}
+	gateway, stage := cm.Labels[KubeLabelGateway], cm.Labels[KubeLabelStage]
+	switch {
+	case gateway == "" && stage == "":
+		return path.Join(apiVersion, "global"), true
+	case gateway == "" || stage == "":
+		return "", false
+	}
+	return path.Join(apiVersion, "gateway", gateway, stage), true
+}
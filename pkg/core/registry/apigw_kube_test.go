@@ -0,0 +1,143 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+var _ = Describe("APIGWKubeRegistry", func() {
+	var (
+		kubeClient client.Client
+		registry   *APIGWKubeRegistry
+		ctx        context.Context
+	)
+
+	toJSON := func(value any) string {
+		content, err := json.Marshal(value)
+		Expect(err).ShouldNot(HaveOccurred())
+		return string(content)
+	}
+	newConfigMap := func(namespace, name string, stageLabels, data map[string]string) *corev1.ConfigMap {
+		cmLabels := map[string]string{"gateway.bk.tencent.com/operator-source": "true"}
+		for key, value := range stageLabels {
+			cmLabels[key] = value
+		}
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: cmLabels},
+			Data:       data,
+		}
+	}
+	prodLabels := map[string]string{KubeLabelGateway: "test-gateway", KubeLabelStage: "prod"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		metric.InitMetric(prometheus.NewRegistry())
+
+		ignored := newConfigMap("blueking", "not-selected", prodLabels, map[string]string{
+			"route.test-gateway.prod.9.json": toJSON(testRouteValue("prod", "test-gateway.prod.9")),
+		})
+		delete(ignored.Labels, "gateway.bk.tencent.com/operator-source")
+		kubeClient = fake.NewClientBuilder().WithObjects(
+			newConfigMap("blueking", "test-gateway-prod", prodLabels, map[string]string{
+				"_bk_release.bk.release.test-gateway.prod.json": toJSON(testReleaseValue("prod")),
+				"route.test-gateway.prod.1.json":                toJSON(testRouteValue("prod", "test-gateway.prod.1")),
+				"invalid":                                       "{}",
+			}),
+			newConfigMap("blueking", "test-gateway-prod-2", prodLabels, map[string]string{
+				"route.test-gateway.prod.2.json": toJSON(testRouteValue("prod", "test-gateway.prod.2")),
+			}),
+			newConfigMap("other", "test-gateway-prod", prodLabels, map[string]string{
+				"route.test-gateway.prod.8.json": toJSON(testRouteValue("prod", "test-gateway.prod.8")),
+			}),
+			ignored,
+		).Build()
+
+		selector, err := labels.Parse("gateway.bk.tencent.com/operator-source=true")
+		Expect(err).ShouldNot(HaveOccurred())
+		registry = NewAPIGWKubeRegistry(kubeClient, "blueking", selector, 20*time.Millisecond, 100)
+	})
+
+	It("should list the releases and the resources of the selected configmaps", func() {
+		releaseList, _, err := registry.ListReleaseInfos(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(releaseList).To(HaveLen(1))
+		Expect(releaseList[0].GetStageName()).To(Equal("prod"))
+		Expect(releaseList[0].APIVersion).To(Equal("v2"))
+
+		release := createReleaseInfo(ctx, "v2", "test-gateway", "prod", constant.Route)
+		resources, err := registry.ListStageResources(release)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resources.Routes).To(HaveLen(2))
+		Expect(resources.Routes).To(HaveKey("test-gateway.prod.1"))
+		Expect(resources.Routes).To(HaveKey("test-gateway.prod.2"))
+
+		version, err := registry.StageReleaseVersion(release)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(version.PublishId).To(Equal(10))
+	})
+
+	It("should emit the changes of the configmaps", func() {
+		_, _, err := registry.ListReleaseInfos(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		eventCh := registry.Watch(watchCtx)
+		receive := func() *entity.ResourceMetadata {
+			var event *entity.ResourceMetadata
+			Eventually(eventCh, 2*time.Second).Should(Receive(&event))
+			return event
+		}
+
+		Expect(kubeClient.Delete(ctx, newConfigMap("blueking", "test-gateway-prod-2", nil, nil))).To(Succeed())
+		event := receive()
+		Expect(event.Kind).To(Equal(constant.Route))
+		Expect(event.Op).To(Equal(mvccpb.DELETE))
+		Expect(event.GetStageName()).To(Equal("prod"))
+
+		Expect(kubeClient.Create(ctx, newConfigMap("blueking", "global", nil, map[string]string{
+			"plugin_metadata.bk-concurrency-limit.json": toJSON(map[string]any{
+				"id":     "bk-concurrency-limit",
+				"labels": map[string]any{"gateway.bk.tencent.com/apisix-version": "3.13.0"},
+			}),
+		}))).To(Succeed())
+		event = receive()
+		Expect(event.Kind).To(Equal(constant.PluginMetadata))
+		Expect(event.Op).To(Equal(mvccpb.PUT))
+		Expect(event.Name).To(Equal("bk-concurrency-limit"))
+		Consistently(eventCh, 100*time.Millisecond).ShouldNot(Receive())
+	})
+})
@@ -0,0 +1,373 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.uber.org/zap"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+// Origin 一个控制面的资源来源
+type Origin struct {
+	Name     string
+	Prefix   string
+	Registry APIGWRegistry
+}
+
+// OriginConflict 被拒绝的环境, 环境已经由其他控制面发布; Owner 为空时环境没有拥有者, 所有控制面的发布都被拒绝
+type OriginConflict struct {
+	StageKey  string    `json:"stage_key"`
+	Owner     string    `json:"owner"`
+	RefusedAt time.Time `json:"refused_at"`
+}
+
+// OriginStatus 控制面的状态
+type OriginStatus struct {
+	Name        string            `json:"name"`
+	Prefix      string            `json:"prefix"`
+	Stages      int               `json:"stages"`
+	Events      int64             `json:"events"`
+	LastEventAt *time.Time        `json:"last_event_at,omitempty"`
+	Conflicts   []*OriginConflict `json:"conflicts"`
+}
+
+type originState struct {
+	*Origin
+
+	revision    int64
+	events      int64
+	lastEventAt time.Time
+	conflicts   map[string]*OriginConflict
+}
+
+// APIGWMultiRegistry 同时 watch 多个控制面, 多个控制面共用同一个 apisix
+// 每个环境只能由一个控制面发布: 先发布的控制面拥有环境, 其他控制面发布同名的环境会被拒绝, 直到拥有者删除环境;
+// 全量同步时拥有者仍然发布的环境保持归属; 其余的环境只有一个控制面发布时归属该控制面, 多个控制面发布时无法判断谁先发布
+// (如重启后), 拒绝所有控制面的发布并上报冲突, 直到下次全量同步时只剩一个控制面发布;
+// 全局资源合并所有控制面, 同名时靠前的控制面优先
+type APIGWMultiRegistry struct {
+	origins []*originState
+	byName  map[string]*originState
+
+	mux sync.Mutex
+	// owners 环境到拥有者控制面名称的映射, 名称为空表示环境由多个控制面发布, 所有控制面的事件都被拒绝
+	owners map[string]string
+
+	watchEventChanSize int
+
+	logger *zap.SugaredLogger
+}
+
+// NewAPIGWMultiRegistry creates a new APIGWMultiRegistry, the first origin is the primary one
+func NewAPIGWMultiRegistry(origins []*Origin, watchEventChanSize int) *APIGWMultiRegistry {
+	if watchEventChanSize <= 0 {
+		watchEventChanSize = 100
+	}
+	r := &APIGWMultiRegistry{
+		byName:             make(map[string]*originState, len(origins)),
+		owners:             make(map[string]string),
+		watchEventChanSize: watchEventChanSize,
+		logger:             logging.GetLogger().Named("multi-registry"),
+	}
+	for _, origin := range origins {
+		state := &originState{Origin: origin, conflicts: make(map[string]*OriginConflict)}
+		r.origins = append(r.origins, state)
+		r.byName[origin.Name] = state
+	}
+	return r
+}
+
+// Watch 合并所有控制面的事件, 并标记事件的来源; 任一控制面的 watch 退出时全部退出, 由 agent 全量同步后重新 watch
+func (r *APIGWMultiRegistry) Watch(ctx context.Context) <-chan *entity.ResourceMetadata {
+	watchCtx, cancel := context.WithCancel(ctx)
+	retCh := make(chan *entity.ResourceMetadata, r.watchEventChanSize)
+
+	var wg sync.WaitGroup
+	for _, origin := range r.origins {
+		originCh := origin.Registry.Watch(watchCtx)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cancel()
+			for event := range originCh {
+				// 退出时继续读取, 避免阻塞控制面的 watch 协程
+				if watchCtx.Err() != nil || !r.accept(origin, event) {
+					continue
+				}
+				select {
+				case retCh <- event:
+				case <-watchCtx.Done():
+				}
+			}
+			r.logger.Infow("watch of origin stopped", "origin", origin.Name)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(retCh)
+	}()
+	return retCh
+}
+
+// accept 标记事件的来源, 拒绝其他控制面拥有的环境的事件
+func (r *APIGWMultiRegistry) accept(origin *originState, event *entity.ResourceMetadata) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	defer r.reportStatus()
+
+	event.Origin = origin.Name
+	origin.events++
+	origin.lastEventAt = time.Now()
+	if event.Labels == nil || event.IsGlobalResource() {
+		metric.ReportOriginEvent(origin.Name, metric.ResultSuccess)
+		return true
+	}
+
+	stageKey := event.GetStageKey()
+	owner, ok := r.owners[stageKey]
+	switch {
+	case !ok && event.Op != mvccpb.DELETE:
+		r.owners[stageKey] = origin.Name
+	case ok && owner != origin.Name:
+		if _, refused := origin.conflicts[stageKey]; !refused {
+			r.logger.Errorw("stage is owned by another origin, refuse the events of it",
+				"stage", stageKey, "origin", origin.Name, "owner", owner)
+		}
+		origin.conflicts[stageKey] = &OriginConflict{StageKey: stageKey, Owner: owner, RefusedAt: time.Now()}
+		metric.ReportOriginEvent(origin.Name, metric.ResultFail)
+		return false
+	}
+
+	// 拥有者删除环境后释放归属, 其他控制面之后可以发布同名的环境
+	if event.Kind == constant.BkRelease && (event.Op == mvccpb.DELETE || event.IsDeleteRelease()) {
+		delete(r.owners, stageKey)
+		for _, other := range r.origins {
+			delete(other.conflicts, stageKey)
+		}
+	}
+	metric.ReportOriginEvent(origin.Name, metric.ResultSuccess)
+	return true
+}
+
+// ListReleaseInfos 查询所有控制面的发布信息并重新计算环境的归属, 被拒绝的环境不会返回;
+// 各控制面的 revision 由 SetCurrentRevision 分别设置, 返回的 revision 总是 0
+func (r *APIGWMultiRegistry) ListReleaseInfos(ctx context.Context) ([]*entity.ReleaseInfo, int64, error) {
+	lists := make([][]*entity.ReleaseInfo, len(r.origins))
+	revisions := make([]int64, len(r.origins))
+	for i, origin := range r.origins {
+		releases, revision, err := origin.Registry.ListReleaseInfos(ctx)
+		if err != nil {
+			return nil, 0, fmt.Errorf("list releases of origin %s failed: %w", origin.Name, err)
+		}
+		lists[i], revisions[i] = releases, revision
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	// 拥有者仍然发布的环境保持归属, 其余的环境只有一个控制面发布时归属该控制面, 否则没有拥有者
+	publishers := make(map[string][]string)
+	for i, origin := range r.origins {
+		for _, release := range lists[i] {
+			stageKey := release.GetStageKey()
+			publishers[stageKey] = append(publishers[stageKey], origin.Name)
+		}
+	}
+	owners := make(map[string]string, len(publishers))
+	for stageKey, names := range publishers {
+		switch {
+		case slices.Contains(names, r.owners[stageKey]):
+			owners[stageKey] = r.owners[stageKey]
+		case len(names) == 1:
+			owners[stageKey] = names[0]
+		default:
+			r.logger.Errorw("stage is published by multiple origins and has no owner, refuse all of them",
+				"stage", stageKey, "origins", names)
+			owners[stageKey] = ""
+		}
+	}
+
+	now := time.Now()
+	releaseList := make([]*entity.ReleaseInfo, 0)
+	for i, origin := range r.origins {
+		origin.revision = revisions[i]
+		origin.conflicts = make(map[string]*OriginConflict)
+		for _, release := range lists[i] {
+			stageKey := release.GetStageKey()
+			owner := owners[stageKey]
+			if owner != origin.Name {
+				r.logger.Errorw("stage is owned by another origin, refuse the release of it",
+					"stage", stageKey, "origin", origin.Name, "owner", owner)
+				origin.conflicts[stageKey] = &OriginConflict{StageKey: stageKey, Owner: owner, RefusedAt: now}
+				continue
+			}
+			release.Origin = origin.Name
+			releaseList = append(releaseList, release)
+		}
+	}
+	r.owners = owners
+	r.reportStatus()
+	return releaseList, 0, nil
+}
+
+// ListReleaseStageKeys 返回所有控制面有发布信息的环境, 包括被拒绝的环境
+func (r *APIGWMultiRegistry) ListReleaseStageKeys(ctx context.Context) (map[string]struct{}, error) {
+	stageKeys := make(map[string]struct{})
+	for _, origin := range r.origins {
+		keys, err := origin.Registry.ListReleaseStageKeys(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list release keys of origin %s failed: %w", origin.Name, err)
+		}
+		maps.Copy(stageKeys, keys)
+	}
+	return stageKeys, nil
+}
+
+// SetCurrentRevision revision 为 ListReleaseInfos 返回的 0 加上的偏移, 应用到各控制面自己的 revision 上
+func (r *APIGWMultiRegistry) SetCurrentRevision(revision int64) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	for _, origin := range r.origins {
+		origin.Registry.SetCurrentRevision(origin.revision + revision)
+	}
+}
+
+// registryOf 发布信息所属控制面的 registry, 没有来源时 (如 open api 查询) 按环境的归属查找, 都没有时使用第一个控制面
+func (r *APIGWMultiRegistry) registryOf(release *entity.ReleaseInfo) APIGWRegistry {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	name := release.Origin
+	if name == "" {
+		name = r.owners[release.GetStageKey()]
+	}
+	if origin, ok := r.byName[name]; ok {
+		return origin.Registry
+	}
+	return r.origins[0].Registry
+}
+
+// ListStageResources retrieves the stage resources for a given release
+func (r *APIGWMultiRegistry) ListStageResources(stageRelease *entity.ReleaseInfo) (*entity.ApisixStageResource, error) {
+	return r.registryOf(stageRelease).ListStageResources(stageRelease)
+}
+
+// ListGlobalResources 合并所有控制面的全局资源, 同名时靠前的控制面优先
+func (r *APIGWMultiRegistry) ListGlobalResources(
+	releaseInfo *entity.ReleaseInfo,
+) (*entity.ApisixGlobalResource, error) {
+	ret := entity.NewEmptyApisixGlobalResource()
+	for _, origin := range r.origins {
+		resources, err := origin.Registry.ListGlobalResources(releaseInfo)
+		if err != nil {
+			return nil, fmt.Errorf("list global resources of origin %s failed: %w", origin.Name, err)
+		}
+		for name, pluginMetadata := range resources.PluginMetadata {
+			if _, ok := ret.PluginMetadata[name]; ok {
+				r.logger.Warnw("plugin metadata is defined by multiple origins, ignore it",
+					"name", name, "origin", origin.Name)
+				continue
+			}
+			ret.PluginMetadata[name] = pluginMetadata
+		}
+		for id, globalRule := range resources.GlobalRules {
+			if _, ok := ret.GlobalRules[id]; ok {
+				r.logger.Warnw("global rule is defined by multiple origins, ignore it",
+					"id", id, "origin", origin.Name)
+				continue
+			}
+			ret.GlobalRules[id] = globalRule
+		}
+	}
+	return ret, nil
+}
+
+// GetStageResourceByID 根据资源 ID 查询资源信息
+func (r *APIGWMultiRegistry) GetStageResourceByID(
+	resourceID string,
+	stageRelease *entity.ReleaseInfo,
+) (*entity.ApisixStageResource, error) {
+	return r.registryOf(stageRelease).GetStageResourceByID(resourceID, stageRelease)
+}
+
+// Count 查询资源数量
+func (r *APIGWMultiRegistry) Count(stageRelease *entity.ReleaseInfo) (int64, error) {
+	return r.registryOf(stageRelease).Count(stageRelease)
+}
+
+// StageReleaseVersion 查询环境版本信息
+func (r *APIGWMultiRegistry) StageReleaseVersion(stageRelease *entity.ReleaseInfo) (*entity.ReleaseInfo, error) {
+	return r.registryOf(stageRelease).StageReleaseVersion(stageRelease)
+}
+
+// reportStatus 上报各控制面的环境数量和冲突数量, 需要持有锁
+func (r *APIGWMultiRegistry) reportStatus() {
+	stages := make(map[string]int, len(r.origins))
+	for _, owner := range r.owners {
+		stages[owner]++
+	}
+	for _, origin := range r.origins {
+		metric.ReportOriginStatus(origin.Name, stages[origin.Name], len(origin.conflicts))
+	}
+}
+
+// OriginStatus 返回所有控制面的状态, 按配置的顺序
+func (r *APIGWMultiRegistry) OriginStatus() []*OriginStatus {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	stages := make(map[string]int, len(r.origins))
+	for _, owner := range r.owners {
+		stages[owner]++
+	}
+	statuses := make([]*OriginStatus, 0, len(r.origins))
+	for _, origin := range r.origins {
+		status := &OriginStatus{
+			Name:      origin.Name,
+			Prefix:    origin.Prefix,
+			Stages:    stages[origin.Name],
+			Events:    origin.events,
+			Conflicts: make([]*OriginConflict, 0, len(origin.conflicts)),
+		}
+		if !origin.lastEventAt.IsZero() {
+			lastEventAt := origin.lastEventAt
+			status.LastEventAt = &lastEventAt
+		}
+		for _, conflict := range origin.conflicts {
+			status.Conflicts = append(status.Conflicts, conflict)
+		}
+		sort.Slice(status.Conflicts, func(i, j int) bool {
+			return status.Conflicts[i].StageKey < status.Conflicts[j].StageKey
+		})
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
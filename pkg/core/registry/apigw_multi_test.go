@@ -0,0 +1,177 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+var _ = Describe("APIGWMultiRegistry", func() {
+	var (
+		primaryRoot string
+		edgeRoot    string
+		registry    *APIGWMultiRegistry
+		ctx         context.Context
+	)
+
+	writeFile := func(root, rel string, value any) {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		Expect(os.MkdirAll(filepath.Dir(p), 0o755)).To(Succeed())
+		content, err := json.Marshal(value)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(os.WriteFile(p, content, 0o644)).To(Succeed())
+	}
+	releasePath := func(stage string) string {
+		return "v2/gateway/test-gateway/" + stage + "/_bk_release/bk.release.test-gateway." + stage + ".json"
+	}
+	writeRelease := func(root, stage string) {
+		writeFile(root, releasePath(stage), testReleaseValue(stage))
+	}
+	writeRoute := func(root, stage, id string) {
+		writeFile(root, "v2/gateway/test-gateway/"+stage+"/route/"+id+".json", testRouteValue(stage, id))
+	}
+	stageNames := func(releaseList []*entity.ReleaseInfo) map[string]string {
+		origins := make(map[string]string)
+		for _, release := range releaseList {
+			origins[release.GetStageName()] = release.Origin
+		}
+		return origins
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		metric.InitMetric(prometheus.NewRegistry())
+		primaryRoot = GinkgoT().TempDir()
+		edgeRoot = GinkgoT().TempDir()
+		primary := NewAPIGWDirRegistry(primaryRoot, 20*time.Millisecond, 100)
+		edge := NewAPIGWDirRegistry(edgeRoot, 20*time.Millisecond, 100)
+		registry = NewAPIGWMultiRegistry([]*Origin{
+			{Name: "default", Prefix: primaryRoot, Registry: primary},
+			{Name: "edge", Prefix: edgeRoot, Registry: edge},
+		}, 100)
+	})
+
+	Describe("ListReleaseInfos", func() {
+		It("should tag the releases with the origin and refuse the stages published by multiple origins", func() {
+			writeRelease(primaryRoot, "prod")
+			writeRoute(primaryRoot, "prod", "test-gateway.prod.1")
+			writeRelease(edgeRoot, "prod")
+			writeRoute(edgeRoot, "prod", "test-gateway.prod.2")
+			writeRelease(edgeRoot, "edge")
+			writeRoute(edgeRoot, "edge", "test-gateway.edge.1")
+
+			// 没有记录过归属 (如重启后) 时无法判断谁先发布, 所有控制面的发布都被拒绝
+			releaseList, revision, err := registry.ListReleaseInfos(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(revision).To(BeZero())
+			Expect(stageNames(releaseList)).To(Equal(map[string]string{"edge": "edge"}))
+
+			statuses := registry.OriginStatus()
+			Expect(statuses).To(HaveLen(2))
+			Expect(statuses[0].Stages).To(BeZero())
+			Expect(statuses[1].Stages).To(Equal(1))
+			for _, status := range statuses {
+				Expect(status.Conflicts).To(HaveLen(1))
+				Expect(status.Conflicts[0].StageKey).To(Equal("bk.release.test-gateway.prod"))
+				Expect(status.Conflicts[0].Owner).To(BeEmpty())
+			}
+
+			// 没有来源的查询按环境的归属路由
+			release := createReleaseInfo(ctx, "v2", "test-gateway", "edge", constant.Route)
+			resources, err := registry.ListStageResources(release)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.Routes).To(HaveLen(1))
+			Expect(resources.Routes).To(HaveKey("test-gateway.edge.1"))
+
+			// 只剩一个控制面发布后归属该控制面
+			Expect(os.Remove(filepath.Join(edgeRoot, filepath.FromSlash(releasePath("prod"))))).To(Succeed())
+			releaseList, _, err = registry.ListReleaseInfos(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(stageNames(releaseList)).To(Equal(map[string]string{"prod": "default", "edge": "edge"}))
+			Expect(registry.OriginStatus()[1].Conflicts).To(BeEmpty())
+		})
+
+		It("should keep the owner of the stage across full syncs", func() {
+			writeRelease(edgeRoot, "prod")
+			_, _, err := registry.ListReleaseInfos(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			writeRelease(primaryRoot, "prod")
+			releaseList, _, err := registry.ListReleaseInfos(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(stageNames(releaseList)).To(Equal(map[string]string{"prod": "edge"}))
+			Expect(registry.OriginStatus()[0].Conflicts).To(HaveLen(1))
+		})
+	})
+
+	Describe("Watch", func() {
+		It("should refuse the events of the stages owned by other origins", func() {
+			writeRelease(primaryRoot, "prod")
+			_, _, err := registry.ListReleaseInfos(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			watchCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			eventCh := registry.Watch(watchCtx)
+			receive := func() *entity.ResourceMetadata {
+				var event *entity.ResourceMetadata
+				Eventually(eventCh, 2*time.Second).Should(Receive(&event))
+				return event
+			}
+
+			writeRoute(edgeRoot, "prod", "test-gateway.prod.2")
+			writeRoute(edgeRoot, "edge", "test-gateway.edge.1")
+			event := receive()
+			Expect(event.GetStageName()).To(Equal("edge"))
+			Expect(event.Origin).To(Equal("edge"))
+			Consistently(eventCh, 100*time.Millisecond).ShouldNot(Receive())
+			Expect(registry.OriginStatus()[1].Conflicts).To(HaveLen(1))
+
+			// 拥有者删除环境后其他控制面可以发布
+			Expect(os.Remove(filepath.Join(primaryRoot, filepath.FromSlash(releasePath("prod"))))).To(Succeed())
+			event = receive()
+			Expect(event.Kind).To(Equal(constant.BkRelease))
+			Expect(event.Op).To(Equal(mvccpb.DELETE))
+			Expect(event.Origin).To(Equal("default"))
+
+			writeRoute(edgeRoot, "prod", "test-gateway.prod.3")
+			event = receive()
+			Expect(event.GetStageName()).To(Equal("prod"))
+			Expect(event.Origin).To(Equal("edge"))
+			Expect(registry.OriginStatus()[1].Conflicts).To(BeEmpty())
+			Expect(registry.OriginStatus()[1].Stages).To(Equal(2))
+
+			cancel()
+			Eventually(eventCh).Should(BeClosed())
+		})
+	})
+})
@@ -0,0 +1,340 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package registry
+
+import (
+	"context"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+// snapshotKeyPrefix 来源中的资源转换为 etcd key 时使用的前缀, 复用 etcd 布局的解析
+const snapshotKeyPrefix = "/bk-gateway-apigw"
+
+// snapshotLoader 读取 prefix 下的所有资源, 返回 etcd key 到资源内容的映射;
+// prefix 为 key prefix 之后的部分, 如 v2/gateway/{gateway_name}/{stage_name}, 为空时读取全部资源
+type snapshotLoader func(ctx context.Context, prefix string) (map[string][]byte, error)
+
+// snapshotRegistry 没有 watch 能力的来源的公共实现: 每次查询都读取来源中的全量资源,
+// 通过定期对比两次读取的结果产生变更事件, 目录和 kubernetes 来源只需要提供 snapshotLoader
+type snapshotRegistry struct {
+	load     snapshotLoader
+	interval time.Duration
+
+	// parser 复用 etcd registry 对 key 和 value 的解析
+	parser *APIGWEtcdRegistry
+
+	// files 最近一次读取到的资源, 作为 Watch 的起点
+	mux   sync.Mutex
+	files map[string][]byte
+
+	watchEventChanSize int
+
+	logger *zap.SugaredLogger
+}
+
+func newSnapshotRegistry(
+	load snapshotLoader,
+	interval time.Duration,
+	watchEventChanSize int,
+	logger *zap.SugaredLogger,
+) *snapshotRegistry {
+	if watchEventChanSize <= 0 {
+		watchEventChanSize = 100
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &snapshotRegistry{
+		load:               load,
+		interval:           interval,
+		parser:             NewAPIGWEtcdRegistry(nil, snapshotKeyPrefix, watchEventChanSize),
+		watchEventChanSize: watchEventChanSize,
+		logger:             logger,
+	}
+}
+
+// snapshotKey 将 key prefix 之后的部分转换为 etcd key
+func snapshotKey(rel string) string {
+	return snapshotKeyPrefix + "/" + rel
+}
+
+// getResponse 将资源转换为 etcd 的查询结果, 按 key 排序
+func getResponse(files map[string][]byte) *clientv3.GetResponse {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	resp := &clientv3.GetResponse{Count: int64(len(keys))}
+	for _, key := range keys {
+		resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(key), Value: files[key]})
+	}
+	return resp
+}
+
+// stagePrefix 环境资源的 prefix
+func stagePrefix(release *entity.ReleaseInfo) string {
+	return path.Join(release.APIVersion, "gateway", release.Labels.Gateway, release.Labels.Stage)
+}
+
+// releaseCtx 查询使用发布信息的 ctx, 没有时使用 context.Background()
+func releaseCtx(release *entity.ReleaseInfo) context.Context {
+	if release.Ctx != nil {
+		return release.Ctx
+	}
+	return context.Background()
+}
+
+// Watch 定期读取全量资源, 与上一次读取的结果对比后产生变更事件; 同一次对比中发布信息的事件在最后, 与发布时的写入顺序一致
+func (r *snapshotRegistry) Watch(ctx context.Context) <-chan *entity.ResourceMetadata {
+	retCh := make(chan *entity.ResourceMetadata, r.watchEventChanSize)
+	go func() {
+		defer close(retCh)
+
+		r.mux.Lock()
+		if r.files == nil {
+			files, err := r.load(ctx, "")
+			if err != nil {
+				r.logger.Errorw("load resources failed", "err", err)
+			}
+			r.files = files
+		}
+		r.mux.Unlock()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				r.logger.Infow("stop snapshot watch loop canceled by context")
+				return
+			case <-ticker.C:
+			}
+
+			files, err := r.load(ctx, "")
+			if err != nil {
+				// 读取失败时保留上一次的结果, 下一次读取重新对比
+				r.logger.Errorw("load resources failed", "err", err)
+				continue
+			}
+			r.mux.Lock()
+			events := r.diff(r.files, files)
+			r.files = files
+			r.mux.Unlock()
+
+			for _, event := range events {
+				select {
+				case retCh <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return retCh
+}
+
+// diff 对比两次读取的结果, 返回变更事件
+func (r *snapshotRegistry) diff(old, current map[string][]byte) []*entity.ResourceMetadata {
+	type change struct {
+		key   string
+		value []byte
+		op    mvccpb.Event_EventType
+	}
+	changes := make([]change, 0)
+	for key, value := range current {
+		if oldValue, ok := old[key]; !ok || string(oldValue) != string(value) {
+			changes = append(changes, change{key: key, value: value, op: mvccpb.PUT})
+		}
+	}
+	for key, value := range old {
+		if _, ok := current[key]; !ok {
+			changes = append(changes, change{key: key, value: value, op: mvccpb.DELETE})
+		}
+	}
+	isRelease := func(key string) bool {
+		return strings.Contains(key, "/"+constant.BkRelease.String()+"/")
+	}
+	slices.SortFunc(changes, func(a, b change) int {
+		if isRelease(a.key) != isRelease(b.key) {
+			if isRelease(a.key) {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.key, b.key)
+	})
+
+	events := make([]*entity.ResourceMetadata, 0, len(changes))
+	for _, c := range changes {
+		metadata, err := r.parser.extractResourceMetadata(c.key, c.value)
+		if err != nil {
+			r.logger.Errorw("parse resource failed, skip it", "key", c.key, "err", err)
+			continue
+		}
+		if c.op == mvccpb.DELETE {
+			setStageFromKey(c.key, &metadata)
+		}
+		metadata.Op = c.op
+		events = append(events, &metadata)
+	}
+	return events
+}
+
+// ListReleaseInfos 查询所有环境的发布信息, 同时将本次读取的结果作为 Watch 的起点, revision 总是 0
+func (r *snapshotRegistry) ListReleaseInfos(ctx context.Context) ([]*entity.ReleaseInfo, int64, error) {
+	startedTime := time.Now()
+	files, err := r.load(ctx, "")
+	if err != nil {
+		metric.ReportRegistryAction(constant.BkRelease.String(), metric.ActionList, metric.ResultFail, startedTime)
+		r.logger.Error(err, "load resources failed")
+		return nil, 0, err
+	}
+	r.mux.Lock()
+	r.files = files
+	r.mux.Unlock()
+
+	releaseList := make([]*entity.ReleaseInfo, 0)
+	for _, kv := range getResponse(files).Kvs {
+		if _, ok := releaseStageKey(string(kv.Key)); !ok {
+			continue
+		}
+		release, err := r.parser.ValueToStageReleaseInfo(&clientv3.GetResponse{Kvs: []*mvccpb.KeyValue{kv}})
+		if err != nil {
+			// 单个发布信息异常不影响其他环境的同步
+			r.logger.Errorf("parse release info failed: %v, key: %s", err, kv.Key)
+			continue
+		}
+		release.Ctx = release.ResourceMetadata.Ctx
+		releaseList = append(releaseList, release)
+	}
+	metric.ReportRegistryAction(constant.BkRelease.String(), metric.ActionList, metric.ResultSuccess, startedTime)
+	return releaseList, 0, nil
+}
+
+// ListReleaseStageKeys 只根据 key 返回有发布信息的环境的 stage key
+func (r *snapshotRegistry) ListReleaseStageKeys(ctx context.Context) (map[string]struct{}, error) {
+	files, err := r.load(ctx, "")
+	if err != nil {
+		r.logger.Error(err, "load resources failed")
+		return nil, err
+	}
+	stageKeys := make(map[string]struct{})
+	for key := range files {
+		if stageKey, ok := releaseStageKey(key); ok {
+			stageKeys[stageKey] = struct{}{}
+		}
+	}
+	return stageKeys, nil
+}
+
+// SetCurrentRevision 来源没有 revision, Watch 从最近一次 ListReleaseInfos 读取的结果开始对比
+func (r *snapshotRegistry) SetCurrentRevision(revision int64) {}
+
+// ListStageResources retrieves the stage resources for a given release
+func (r *snapshotRegistry) ListStageResources(stageRelease *entity.ReleaseInfo) (*entity.ApisixStageResource, error) {
+	files, err := r.load(releaseCtx(stageRelease), stagePrefix(stageRelease))
+	if err != nil {
+		r.logger.Error(err, "load resources failed", "stageRelease", stageRelease.GetID())
+		return nil, err
+	}
+	if len(files) == 0 {
+		return entity.NewEmptyApisixConfiguration(), nil
+	}
+	return r.parser.ValueToStageResource(getResponse(files))
+}
+
+// ListGlobalResources ...
+func (r *snapshotRegistry) ListGlobalResources(releaseInfo *entity.ReleaseInfo) (*entity.ApisixGlobalResource, error) {
+	startedTime := time.Now()
+	files, err := r.load(releaseCtx(releaseInfo), path.Join(releaseInfo.APIVersion, "global"))
+	if err != nil {
+		metric.ReportRegistryAction(releaseInfo.Kind.String(), metric.ActionGet, metric.ResultFail, startedTime)
+		r.logger.Error(err, "load resources failed", "apiVersion", releaseInfo.APIVersion)
+		return nil, err
+	}
+	if len(files) == 0 {
+		return entity.NewEmptyApisixGlobalResource(), nil
+	}
+	ret, err := r.parser.ValueToGlobalResource(getResponse(files))
+	if err != nil {
+		return nil, err
+	}
+	metric.ReportRegistryAction(releaseInfo.Kind.String(), metric.ActionGet, metric.ResultSuccess, startedTime)
+	return ret, nil
+}
+
+// loadOne 读取环境下的一个资源
+func (r *snapshotRegistry) loadOne(stageRelease *entity.ReleaseInfo, kind, id string) (*clientv3.GetResponse, error) {
+	kindPrefix := path.Join(stagePrefix(stageRelease), kind)
+	files, err := r.load(releaseCtx(stageRelease), kindPrefix)
+	if err != nil {
+		return nil, err
+	}
+	key := snapshotKey(path.Join(kindPrefix, id))
+	value, ok := files[key]
+	if !ok {
+		r.logger.Errorf("empty resource value key: %s", key)
+		return nil, eris.Errorf("resource not found: %s", key)
+	}
+	return getResponse(map[string][]byte{key: value}), nil
+}
+
+// GetStageResourceByID 根据资源 ID 查询资源信息
+func (r *snapshotRegistry) GetStageResourceByID(
+	resourceID string,
+	stageRelease *entity.ReleaseInfo,
+) (*entity.ApisixStageResource, error) {
+	resp, err := r.loadOne(stageRelease, stageRelease.Kind.String(), resourceID)
+	if err != nil {
+		return nil, err
+	}
+	return r.parser.ValueToStageResource(resp)
+}
+
+// Count 查询资源数量
+func (r *snapshotRegistry) Count(stageRelease *entity.ReleaseInfo) (int64, error) {
+	files, err := r.load(releaseCtx(stageRelease), path.Join(stagePrefix(stageRelease), stageRelease.Kind.String()))
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(files)), nil
+}
+
+// StageReleaseVersion 查询环境版本信息
+func (r *snapshotRegistry) StageReleaseVersion(stageRelease *entity.ReleaseInfo) (*entity.ReleaseInfo, error) {
+	resp, err := r.loadOne(stageRelease, constant.BkRelease.String(), stageRelease.GetReleaseID())
+	if err != nil {
+		return nil, err
+	}
+	return r.parser.ValueToStageReleaseInfo(resp)
+}
@@ -140,6 +140,43 @@ var _ = Describe("APIGWEtcdRegistry", func() {
 			})
 		})
 
+		Context("when parsing ssl", func() {
+			It("should extract ssl correctly", func() {
+				key := "/bk-gateway-apigw/v2/gateway/bk-default/default/ssl/bk-default.default.test-ssl"
+				value := []byte(`{
+					"id": "bk-default.default.test-ssl",
+					"labels": {
+						"gateway.bk.tencent.com/gateway": "bk-default",
+						"gateway.bk.tencent.com/stage": "default"
+					}
+				}`)
+
+				metadata, err := registry.extractResourceMetadata(key, value)
+				Expect(err).To(BeNil())
+				Expect(metadata.Kind).To(Equal(constant.SSL))
+				Expect(metadata.GetReleaseID()).To(Equal("bk.release.bk-default.default"))
+			})
+		})
+
+		Context("when parsing stage plugin_metadata", func() {
+			It("should extract the stage of plugin_metadata", func() {
+				key := "/bk-gateway-apigw/v2/gateway/bk-default/default/plugin_metadata/file-logger"
+				value := []byte(`{
+					"id": "file-logger",
+					"labels": {
+						"gateway.bk.tencent.com/gateway": "bk-default",
+						"gateway.bk.tencent.com/stage": "default"
+					}
+				}`)
+
+				metadata, err := registry.extractResourceMetadata(key, value)
+				Expect(err).To(BeNil())
+				Expect(metadata.Kind).To(Equal(constant.PluginMetadata))
+				Expect(metadata.IsGlobalResource()).To(BeFalse())
+				Expect(metadata.GetReleaseID()).To(Equal("bk.release.bk-default.default"))
+			})
+		})
+
 		Context("when key is empty", func() {
 			It("should return error", func() {
 				key := ""
@@ -365,6 +402,34 @@ var _ = Describe("APIGWEtcdRegistry with EmbedEtcd", func() {
 				Fail("timeout waiting for watch event")
 			}
 		})
+
+		It("should start from the revision set while the previous watch is stopping", func() {
+			// 事件通道只有一个缓冲, 第二个事件会阻塞上一次 watch 的协程, 直到通道被读取
+			watchRegistry := NewAPIGWEtcdRegistry(client, "/bk-gateway-apigw", 1)
+			watchCtx, cancel := context.WithCancel(ctx)
+			firstCh := watchRegistry.Watch(watchCtx)
+			time.Sleep(100 * time.Millisecond)
+			for _, name := range []string{"route-1", "route-2"} {
+				_, err := client.Put(ctx, "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/route/"+name,
+					fmt.Sprintf(`{"id": %q, "name": %q}`, name, name))
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			time.Sleep(100 * time.Millisecond)
+			cancel()
+
+			routeKey := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/route/missed-route"
+			putResp, err := client.Put(ctx, routeKey, `{"id": "missed-route", "name": "missed-route"}`)
+			Expect(err).ShouldNot(HaveOccurred())
+			watchRegistry.SetCurrentRevision(putResp.Header.Revision)
+			// 上一次 watch 退出时不会覆盖全量同步设置的 revision
+			Eventually(firstCh, 2*time.Second).Should(BeClosed())
+
+			watchCtx, cancel = context.WithCancel(ctx)
+			defer cancel()
+			var event *entity.ResourceMetadata
+			Eventually(watchRegistry.Watch(watchCtx), 2*time.Second).Should(Receive(&event))
+			Expect(event.Name).To(Equal("missed-route"))
+		})
 	})
 
 	Describe("StageReleaseVersion", func() {
@@ -377,6 +442,51 @@ var _ = Describe("APIGWEtcdRegistry with EmbedEtcd", func() {
 		})
 	})
 
+	Describe("ListReleaseInfos", func() {
+		It("should list all the stage releases with the snapshot revision", func() {
+			for _, stage := range []string{"prod", "test"} {
+				releaseKey := fmt.Sprintf(
+					"/bk-gateway-apigw/v2/gateway/test-gateway/%s/_bk_release/bk.release.test-gateway.%s",
+					stage, stage,
+				)
+				releaseValue := map[string]any{
+					"id": "bk.release.test-gateway." + stage,
+					"labels": map[string]any{
+						"gateway.bk.tencent.com/gateway":    "test-gateway",
+						"gateway.bk.tencent.com/stage":      stage,
+						"gateway.bk.tencent.com/publish-id": "10",
+					},
+					"publish_id": 10,
+				}
+				releaseBytes, _ := json.Marshal(releaseValue)
+				_, err := client.Put(ctx, releaseKey, string(releaseBytes))
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			// resources of the stage should not be listed
+			routeKey := "/bk-gateway-apigw/v2/gateway/test-gateway/prod/route/test-gateway.prod.1"
+			putResp, err := client.Put(ctx, routeKey, `{"id": "test-gateway.prod.1"}`)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			releaseList, revision, err := registry.ListReleaseInfos(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(revision).To(Equal(putResp.Header.Revision))
+			Expect(releaseList).To(HaveLen(2))
+			for _, release := range releaseList {
+				Expect(release.Kind).To(Equal(constant.BkRelease))
+				Expect(release.APIVersion).To(Equal("v2"))
+				Expect(release.GetGatewayName()).To(Equal("test-gateway"))
+				Expect(release.PublishId).To(Equal(10))
+				Expect(release.Ctx).NotTo(BeNil())
+			}
+		})
+
+		It("should return empty list when no release exists", func() {
+			releaseList, _, err := registry.ListReleaseInfos(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(releaseList).To(BeEmpty())
+		})
+	})
+
 	Describe("GetStageResourceByID", func() {
 		It("should return error when resource not found", func() {
 			releaseInfo := createReleaseInfo(ctx, "v2", "test-gateway", "test-stage", constant.Route)
@@ -413,6 +523,290 @@ var _ = Describe("APIGWEtcdRegistry with EmbedEtcd", func() {
 			Expect(resources.SSLs).To(HaveLen(0))
 		})
 
+		It("should parse upstream resources", func() {
+			upstreamKey := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/upstream/test-gateway.test-stage.1"
+			upstreamValue := map[string]any{
+				"id":   "test-gateway.test-stage.1",
+				"name": "test-upstream",
+				"type": "roundrobin",
+				"nodes": []map[string]any{
+					{"host": "1.1.1.1", "port": 80, "weight": 1},
+				},
+				"labels": map[string]any{
+					"gateway.bk.tencent.com/gateway":        "test-gateway",
+					"gateway.bk.tencent.com/stage":          "test-stage",
+					"gateway.bk.tencent.com/apisix-version": "3.13.0",
+				},
+			}
+			upstreamBytes, _ := json.Marshal(upstreamValue)
+			_, err := client.Put(ctx, upstreamKey, string(upstreamBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, upstreamKey)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resources, err := registry.ValueToStageResource(resp)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.Upstreams).To(HaveLen(1))
+			upstream := resources.Upstreams["test-gateway.test-stage.1"]
+			Expect(upstream.Kind).To(Equal(constant.Upstream))
+			Expect(upstream.Type).To(Equal("roundrobin"))
+			Expect(upstream.GetStageName()).To(Equal("test-stage"))
+		})
+
+		It("should parse plugin config resources", func() {
+			key := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/plugin_config/test-gateway.test-stage.1"
+			value := map[string]any{
+				"id": "test-gateway.test-stage.1",
+				"plugins": map[string]any{
+					"proxy-rewrite": map[string]any{"uri": "/test"},
+				},
+				"labels": map[string]any{
+					"gateway.bk.tencent.com/gateway":        "test-gateway",
+					"gateway.bk.tencent.com/stage":          "test-stage",
+					"gateway.bk.tencent.com/apisix-version": "3.13.0",
+				},
+			}
+			valueBytes, _ := json.Marshal(value)
+			_, err := client.Put(ctx, key, string(valueBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resources, err := registry.ValueToStageResource(resp)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.PluginConfigs).To(HaveLen(1))
+			Expect(resources.PluginConfigs["test-gateway.test-stage.1"].Plugins).To(HaveKey("proxy-rewrite"))
+		})
+
+		It("should reject plugin config resources without plugins", func() {
+			key := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/plugin_config/test-gateway.test-stage.2"
+			value := map[string]any{
+				"id":      "test-gateway.test-stage.2",
+				"plugins": map[string]any{},
+				"labels": map[string]any{
+					"gateway.bk.tencent.com/gateway":        "test-gateway",
+					"gateway.bk.tencent.com/stage":          "test-stage",
+					"gateway.bk.tencent.com/apisix-version": "3.13.0",
+				},
+			}
+			valueBytes, _ := json.Marshal(value)
+			_, err := client.Put(ctx, key, string(valueBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = registry.ValueToStageResource(resp)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should parse consumer and consumer group resources", func() {
+			labels := map[string]any{
+				"gateway.bk.tencent.com/gateway":        "test-gateway",
+				"gateway.bk.tencent.com/stage":          "test-stage",
+				"gateway.bk.tencent.com/apisix-version": "3.13.0",
+			}
+			prefix := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/"
+			groupBytes, _ := json.Marshal(map[string]any{
+				"id":      "test-gateway.test-stage.1",
+				"plugins": map[string]any{"limit-count": map[string]any{"count": 10, "time_window": 60}},
+				"labels":  labels,
+			})
+			_, err := client.Put(ctx, prefix+"consumer_group/test-gateway.test-stage.1", string(groupBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+			consumerBytes, _ := json.Marshal(map[string]any{
+				"username": "test-gateway-test-stage-app",
+				"group_id": "test-gateway.test-stage.1",
+				"plugins":  map[string]any{"key-auth": map[string]any{"key": "app-key"}},
+				"labels":   labels,
+			})
+			_, err = client.Put(ctx, prefix+"consumer/test-gateway-test-stage-app", string(consumerBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, prefix, clientv3.WithPrefix())
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resources, err := registry.ValueToStageResource(resp)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.ConsumerGroups).To(HaveKey("test-gateway.test-stage.1"))
+			Expect(resources.Consumers).To(HaveKey("test-gateway-test-stage-app"))
+			consumer := resources.Consumers["test-gateway-test-stage-app"]
+			Expect(consumer.GroupID).To(Equal("test-gateway.test-stage.1"))
+			Expect(consumer.GetStageName()).To(Equal("test-stage"))
+		})
+
+		It("should reject consumer resources with unknown fields", func() {
+			key := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/consumer/test-app"
+			value := map[string]any{
+				"id":       "test-app",
+				"username": "test-app",
+				"labels": map[string]any{
+					"gateway.bk.tencent.com/gateway":        "test-gateway",
+					"gateway.bk.tencent.com/stage":          "test-stage",
+					"gateway.bk.tencent.com/apisix-version": "3.13.0",
+				},
+			}
+			valueBytes, _ := json.Marshal(value)
+			_, err := client.Put(ctx, key, string(valueBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = registry.ValueToStageResource(resp)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should parse stream route resources", func() {
+			key := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/stream_route/" +
+				"bk-apigw.test-gateway.test-stage.tcp"
+			value := map[string]any{
+				"id":          "bk-apigw.test-gateway.test-stage.tcp",
+				"server_addr": "127.0.0.1",
+				"server_port": 9100,
+				"upstream_id": "test-gateway.test-stage.1",
+				"labels": map[string]any{
+					"gateway.bk.tencent.com/gateway":        "test-gateway",
+					"gateway.bk.tencent.com/stage":          "test-stage",
+					"gateway.bk.tencent.com/apisix-version": "3.13.0",
+				},
+			}
+			valueBytes, _ := json.Marshal(value)
+			_, err := client.Put(ctx, key, string(valueBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resources, err := registry.ValueToStageResource(resp)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.StreamRoutes).To(HaveKey("bk-apigw.test-gateway.test-stage.tcp"))
+			streamRoute := resources.StreamRoutes["bk-apigw.test-gateway.test-stage.tcp"]
+			Expect(streamRoute.ServerPort).To(Equal(9100))
+			Expect(streamRoute.GetStageName()).To(Equal("test-stage"))
+		})
+
+		It("should skip global rules published under a stage", func() {
+			key := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/global_rule/test-gateway.test-stage.rule"
+			value := map[string]any{
+				"id": "test-gateway.test-stage.rule",
+				"labels": map[string]any{
+					"gateway.bk.tencent.com/gateway":        "test-gateway",
+					"gateway.bk.tencent.com/stage":          "test-stage",
+					"gateway.bk.tencent.com/apisix-version": "3.13.0",
+				},
+			}
+			valueBytes, _ := json.Marshal(value)
+			_, err := client.Put(ctx, key, string(valueBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			// global_rule 只能发布为全局资源, 环境下的不做校验, 直接跳过
+			resources, err := registry.ValueToStageResource(resp)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.Resources()).To(BeEmpty())
+		})
+
+		It("should reject stream route resources whose id is not prefixed by bk-apigw and the stage", func() {
+			key := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/stream_route/test-gateway.test-stage.tcp"
+			value := map[string]any{
+				"id":          "test-gateway.test-stage.tcp",
+				"server_port": 9100,
+				"upstream_id": "test-gateway.test-stage.1",
+				"labels": map[string]any{
+					"gateway.bk.tencent.com/gateway":        "test-gateway",
+					"gateway.bk.tencent.com/stage":          "test-stage",
+					"gateway.bk.tencent.com/apisix-version": "3.13.0",
+				},
+			}
+			valueBytes, _ := json.Marshal(value)
+			_, err := client.Put(ctx, key, string(valueBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = registry.ValueToStageResource(resp)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should parse stage plugin metadata without labels", func() {
+			key := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/plugin_metadata/file-logger"
+			value := map[string]any{
+				"id":   "file-logger",
+				"path": "/logs/stage.log",
+				"labels": map[string]any{
+					"gateway.bk.tencent.com/gateway":        "test-gateway",
+					"gateway.bk.tencent.com/stage":          "test-stage",
+					"gateway.bk.tencent.com/apisix-version": "3.13.0",
+				},
+			}
+			valueBytes, _ := json.Marshal(value)
+			_, err := client.Put(ctx, key, string(valueBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resources, err := registry.ValueToStageResource(resp)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.PluginMetadata).To(HaveKey("file-logger"))
+			pluginMetadata := resources.PluginMetadata["file-logger"]
+			Expect(pluginMetadata.GetStageName()).To(Equal("test-stage"))
+			Expect(pluginMetadata.IsGlobalResource()).To(BeFalse())
+			Expect(string(pluginMetadata.PluginMetadataConf["file-logger"])).NotTo(ContainSubstring("labels"))
+		})
+
+		It("should parse proto resources", func() {
+			key := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/proto/bk-apigw.test-gateway.test-stage.greeter"
+			value := map[string]any{
+				"id":      "bk-apigw.test-gateway.test-stage.greeter",
+				"content": `syntax = "proto3"; package helloworld; message HelloRequest { string name = 1; }`,
+				"labels": map[string]any{
+					"gateway.bk.tencent.com/gateway":        "test-gateway",
+					"gateway.bk.tencent.com/stage":          "test-stage",
+					"gateway.bk.tencent.com/apisix-version": "3.13.0",
+				},
+			}
+			valueBytes, _ := json.Marshal(value)
+			_, err := client.Put(ctx, key, string(valueBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resources, err := registry.ValueToStageResource(resp)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.Protos).To(HaveKey("bk-apigw.test-gateway.test-stage.greeter"))
+			Expect(resources.Protos["bk-apigw.test-gateway.test-stage.greeter"].GetStageName()).To(Equal("test-stage"))
+		})
+
+		It("should reject proto resources which can not be compiled", func() {
+			key := "/bk-gateway-apigw/v2/gateway/test-gateway/test-stage/proto/bk-apigw.test-gateway.test-stage.broken"
+			value := map[string]any{
+				"id":      "bk-apigw.test-gateway.test-stage.broken",
+				"content": `syntax = "proto3"; message HelloRequest { string name = 1 }`,
+				"labels": map[string]any{
+					"gateway.bk.tencent.com/gateway":        "test-gateway",
+					"gateway.bk.tencent.com/stage":          "test-stage",
+					"gateway.bk.tencent.com/apisix-version": "3.13.0",
+				},
+			}
+			valueBytes, _ := json.Marshal(value)
+			_, err := client.Put(ctx, key, string(valueBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = registry.ValueToStageResource(resp)
+			Expect(err).To(HaveOccurred())
+		})
+
 		It("should return error for invalid key format", func() {
 			// Key with insufficient segments
 			invalidKey := "/bk-gateway-apigw/v2/gateway/test"
@@ -440,6 +834,56 @@ var _ = Describe("APIGWEtcdRegistry with EmbedEtcd", func() {
 			_, err = registry.ValueToGlobalResource(resp)
 			Expect(err).To(HaveOccurred())
 		})
+
+		It("should parse global rules without labels", func() {
+			key := "/bk-gateway-apigw/v2/global/global_rule/bk-apigw.1"
+			value := map[string]any{
+				"id":      "bk-apigw.1",
+				"plugins": map[string]any{"prometheus": map[string]any{}},
+				"labels": map[string]any{
+					"gateway.bk.tencent.com/apisix-version": "3.13.0",
+				},
+			}
+			valueBytes, _ := json.Marshal(value)
+			_, err := client.Put(ctx, key, string(valueBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resources, err := registry.ValueToGlobalResource(resp)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.GlobalRules).To(HaveKey("bk-apigw.1"))
+			globalRule := resources.GlobalRules["bk-apigw.1"]
+			Expect(globalRule.IsGlobalResource()).To(BeTrue())
+			Expect(globalRule.Plugins).To(HaveKey("prometheus"))
+
+			// 写入 apisix 的配置中只保留 global_rule schema 中的字段
+			raw, err := json.Marshal(globalRule)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(raw)).To(MatchJSON(`{"id":"bk-apigw.1","plugins":{"prometheus":{}}}`))
+		})
+
+		It("should skip global rules without the managed id prefix", func() {
+			key := "/bk-gateway-apigw/v2/global/global_rule/1"
+			value := map[string]any{
+				"id":      "1",
+				"plugins": map[string]any{"prometheus": map[string]any{}},
+				"labels": map[string]any{
+					"gateway.bk.tencent.com/apisix-version": "3.13.0",
+				},
+			}
+			valueBytes, _ := json.Marshal(value)
+			_, err := client.Put(ctx, key, string(valueBytes))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resources, err := registry.ValueToGlobalResource(resp)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources.GlobalRules).To(BeEmpty())
+		})
 	})
 })
 
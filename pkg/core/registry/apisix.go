@@ -44,8 +44,11 @@ type ApisixEtcdRegistry struct {
 
 	mux             sync.RWMutex
 	resources       map[string]entity.ApisixResource // resource id -> resource
+	revisions       map[string]int64                 // resource id -> etcd mod revision
 	syncTimeout     time.Duration
 	currentRevision int64
+	// revisionNotify 每次 currentRevision 前进时关闭并重建, 用于唤醒 WaitForRevision
+	revisionNotify chan struct{}
 
 	// ctx for controlling the lifecycle of incrSync goroutine
 	ctx    context.Context
@@ -66,12 +69,13 @@ func NewApisixEtcdRegistry(ctx context.Context, client *clientv3.Client, prefix
 	registryCtx, cancel := context.WithCancel(ctx)
 
 	apisixEtcdRegistry := &ApisixEtcdRegistry{
-		client:      client,
-		Prefix:      prefix,
-		logger:      logging.GetLogger().Named("etcd-resource-store"),
-		syncTimeout: syncTimeout,
-		ctx:         registryCtx,
-		cancel:      cancel,
+		client:         client,
+		Prefix:         prefix,
+		logger:         logging.GetLogger().Named("etcd-resource-store"),
+		syncTimeout:    syncTimeout,
+		revisionNotify: make(chan struct{}),
+		ctx:            registryCtx,
+		cancel:         cancel,
 	}
 
 	apisixEtcdRegistry.logger.Infow("Create etcd resource store", "Prefix", prefix)
@@ -111,6 +115,7 @@ func (e *ApisixEtcdRegistry) fullSync(ctx context.Context, syncTimeout time.Dura
 	defer e.mux.Unlock()
 
 	e.resources = make(map[string]entity.ApisixResource)
+	e.revisions = make(map[string]int64)
 
 	for i := range ret.Kvs {
 		resource, err := e.parseResource(ret.Kvs[i].Key, ret.Kvs[i].Value)
@@ -127,12 +132,50 @@ func (e *ApisixEtcdRegistry) fullSync(ctx context.Context, syncTimeout time.Dura
 		e.logger.Debugw("store resource", "key", string(ret.Kvs[i].Key), "resourceID",
 			resource.GetID())
 		e.resources[resource.GetID()] = resource
+		e.revisions[resource.GetID()] = ret.Kvs[i].ModRevision
 	}
 
-	e.currentRevision = ret.Header.Revision
+	e.advanceRevisionLocked(ret.Header.Revision)
 	return nil
 }
 
+// advanceRevisionLocked 更新 watch 已经同步到的 revision 并唤醒等待者, 调用方需持有写锁
+func (e *ApisixEtcdRegistry) advanceRevisionLocked(revision int64) {
+	if revision <= e.currentRevision {
+		return
+	}
+	e.currentRevision = revision
+	close(e.revisionNotify)
+	e.revisionNotify = make(chan struct{})
+}
+
+// WaitForRevision 等待缓存同步到指定的 etcd revision, 即 watch 已经看到该 revision 之前的所有写入
+// 在 timeout 内没有等到时返回 false
+func (e *ApisixEtcdRegistry) WaitForRevision(ctx context.Context, revision int64, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		e.mux.RLock()
+		current, notify := e.currentRevision, e.revisionNotify
+		e.mux.RUnlock()
+		if current >= revision {
+			return true
+		}
+		select {
+		case <-notify:
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// Refresh 重新全量同步缓存, 用于写入冲突后获取 etcd 中的最新数据
+func (e *ApisixEtcdRegistry) Refresh(ctx context.Context) error {
+	return e.fullSync(ctx, e.syncTimeout)
+}
+
 func (e *ApisixEtcdRegistry) parseResource(key, value []byte) (resource entity.ApisixResource, err error) {
 	if len(e.Prefix) == len(key) {
 		return nil, nil
@@ -148,40 +191,60 @@ func (e *ApisixEtcdRegistry) parseResource(key, value []byte) (resource entity.A
 		return nil, fmt.Errorf("invalid Prefix key: %s", e.Prefix)
 	}
 	resourceType := parts[len(parts)-1]
+	resource, err = UnmarshalApisixResource(resourceType, value)
+	if err != nil {
+		e.logger.Errorf("Unmarshal resource [key=%s,value=%s] from etcd failed: %v", key, value, err)
+		return nil, err
+	}
+	return resource, nil
+}
+
+// UnmarshalApisixResource 按资源类型解析 apisix 资源, plugin metadata 保留原始配置
+func UnmarshalApisixResource(resourceType string, value []byte) (resource entity.ApisixResource, err error) {
 	switch resourceType {
 	case constant.ApisixResourceTypeRoutes:
 		resource = &entity.Route{}
 	case constant.ApisixResourceTypeServices:
 		resource = &entity.Service{}
+	case constant.ApisixResourceTypeUpstreams:
+		resource = &entity.Upstream{}
+	case constant.ApisixResourceTypePluginConfigs:
+		resource = &entity.PluginConfig{}
+	case constant.ApisixResourceTypeConsumers:
+		resource = &entity.Consumer{}
+	case constant.ApisixResourceTypeConsumerGroups:
+		resource = &entity.ConsumerGroup{}
 	case constant.ApisixResourceTypeSSL:
 		resource = &entity.SSL{}
+	case constant.ApisixResourceTypeGlobalRules:
+		resource = &entity.GlobalRule{}
 	case constant.ApisixResourceTypeProtos:
 		resource = &entity.Proto{}
+	case constant.ApisixResourceTypeStreamRoutes:
+		resource = &entity.StreamRoute{}
 	case constant.ApisixResourceTypePluginMetadata:
 		var metadata entity.ResourceMetadata
-		err = json.Unmarshal(value, &metadata)
-		if err != nil {
-			e.logger.Errorf("Unmarshal resource [key=%s,value=%s] from etcd failed: %v",
-				key, value, err)
+		if err = json.Unmarshal(value, &metadata); err != nil {
 			return nil, fmt.Errorf("unmarshal resource from etcd failed: %w", err)
 		}
-		resource = &entity.PluginMetadata{
+		return &entity.PluginMetadata{
 			ResourceMetadata: metadata,
 			PluginMetadataConf: entity.PluginMetadataConf{
 				metadata.GetID(): value,
 			},
-		}
+		}, nil
 	default:
-		e.logger.Errorw("Unknown resource type", "resourceType", resourceType)
 		return nil, fmt.Errorf("unknown resource type: %s", resourceType)
 	}
-	if resourceType != constant.ApisixResourceTypePluginMetadata {
-		err = json.Unmarshal(value, resource)
-		if err != nil {
-			e.logger.Errorf("Unmarshal resource [key=%s,value=%s] from etcd failed: %v",
-				key, value, err)
-			return nil, fmt.Errorf("unmarshal resource from etcd failed: %w", err)
-		}
+	if err = json.Unmarshal(value, resource); err != nil {
+		return nil, fmt.Errorf("unmarshal resource from etcd failed: %w", err)
+	}
+	// apisix 中的 stream route 和 proto 没有 labels, 根据 id 还原所属的环境
+	switch r := resource.(type) {
+	case *entity.StreamRoute:
+		r.RestoreStageLabels()
+	case *entity.Proto:
+		r.RestoreStageLabels()
 	}
 	return resource, nil
 }
@@ -255,7 +318,9 @@ func (e *ApisixEtcdRegistry) incrSync() {
 					continue
 				}
 			}
-			e.currentRevision = event.Header.Revision
+			e.mux.Lock()
+			e.advanceRevisionLocked(event.Header.Revision)
+			e.mux.Unlock()
 		}
 	}
 }
@@ -283,7 +348,11 @@ func (e *ApisixEtcdRegistry) handlerEvent(event *clientv3.Event) error {
 			resource.GetID(),
 		)
 		e.mux.Lock()
-		e.resources[resource.GetID()] = resource
+		// Refresh 之后 watch 可能还会收到更旧的事件, 不能覆盖较新的缓存
+		if event.Kv.ModRevision >= e.revisions[resource.GetID()] {
+			e.resources[resource.GetID()] = resource
+			e.revisions[resource.GetID()] = event.Kv.ModRevision
+		}
 		e.mux.Unlock()
 	case clientv3.EventTypeDelete:
 		resource, err := e.parseResource(event.PrevKv.Key, event.PrevKv.Value)
@@ -306,7 +375,10 @@ func (e *ApisixEtcdRegistry) handlerEvent(event *clientv3.Event) error {
 			resource.GetID(),
 		)
 		e.mux.Lock()
-		delete(e.resources, resource.GetID())
+		if event.Kv.ModRevision >= e.revisions[resource.GetID()] {
+			delete(e.resources, resource.GetID())
+			delete(e.revisions, resource.GetID())
+		}
 		e.mux.Unlock()
 	}
 	return nil
@@ -329,6 +401,13 @@ func (e *ApisixEtcdRegistry) GetStageResources(stageName string) map[string]enti
 	return resources
 }
 
+// GetModRevision returns the etcd mod revision of the resource last seen by the cache, 0 if not exist
+func (e *ApisixEtcdRegistry) GetModRevision(id string) int64 {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+	return e.revisions[id]
+}
+
 // GetAllResources returns all resources from the registry
 func (e *ApisixEtcdRegistry) GetAllResources() map[string]entity.ApisixResource {
 	e.mux.RLock()
@@ -21,6 +21,7 @@ package registry
 import (
 	"context"
 	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -193,6 +194,43 @@ var _ = Describe("ApisixEtcdRegistry", func() {
 			})
 		})
 
+		Context("when parsing stream_routes", func() {
+			BeforeEach(func() {
+				registry = &ApisixEtcdRegistry{
+					Prefix:    "/apisix/stream_routes/",
+					resources: make(map[string]entity.ApisixResource),
+					mux:       sync.RWMutex{},
+					logger:    logging.GetLogger().Named("test-registry"),
+				}
+			})
+
+			It("should restore the stage labels from the id", func() {
+				key := []byte("/apisix/stream_routes/bk-apigw.test-gateway.test-stage.tcp")
+				value := []byte(`{"id": "bk-apigw.test-gateway.test-stage.tcp", "server_port": 9100}`)
+
+				resource, err := registry.parseResource(key, value)
+				Expect(err).To(BeNil())
+
+				streamRoute, ok := resource.(*entity.StreamRoute)
+				Expect(ok).To(BeTrue())
+				Expect(streamRoute.ServerPort).To(Equal(9100))
+				Expect(streamRoute.GetGatewayName()).To(Equal("test-gateway"))
+				Expect(streamRoute.GetStageName()).To(Equal("test-stage"))
+			})
+
+			It("should leave the labels empty for unmanaged stream routes", func() {
+				for _, id := range []string{"1", "redis.tcp.6379"} {
+					key := []byte("/apisix/stream_routes/" + id)
+					value := []byte(`{"id": "` + id + `", "server_port": 9100}`)
+
+					resource, err := registry.parseResource(key, value)
+					Expect(err).To(BeNil())
+					Expect(resource.GetGatewayName()).To(BeEmpty())
+					Expect(resource.GetStageName()).To(BeEmpty())
+				}
+			})
+		})
+
 		Context("when parsing unknown resource type", func() {
 			BeforeEach(func() {
 				registry = &ApisixEtcdRegistry{
@@ -302,6 +340,41 @@ var _ = Describe("ApisixEtcdRegistry", func() {
 		})
 	})
 
+	Describe("WaitForRevision", func() {
+		var registry *ApisixEtcdRegistry
+
+		BeforeEach(func() {
+			registry = &ApisixEtcdRegistry{
+				Prefix:          "/apisix/routes/",
+				currentRevision: 10,
+				revisionNotify:  make(chan struct{}),
+				logger:          logging.GetLogger().Named("test-registry"),
+			}
+		})
+
+		It("should return immediately when the revision has been seen", func() {
+			Expect(registry.WaitForRevision(context.Background(), 10, time.Millisecond)).To(BeTrue())
+		})
+
+		It("should return when the watch advances to the revision", func() {
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				registry.mux.Lock()
+				registry.advanceRevisionLocked(11)
+				registry.mux.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				registry.mux.Lock()
+				registry.advanceRevisionLocked(12)
+				registry.mux.Unlock()
+			}()
+			Expect(registry.WaitForRevision(context.Background(), 12, 5*time.Second)).To(BeTrue())
+		})
+
+		It("should return false after timeout", func() {
+			Expect(registry.WaitForRevision(context.Background(), 11, 10*time.Millisecond)).To(BeFalse())
+		})
+	})
+
 	Describe("Close", func() {
 		It("should cancel context when Close is called", func() {
 			ctx, cancel := context.WithCancel(context.Background())
@@ -35,15 +35,49 @@ import (
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils"
 )
 
-func initApisixEtcdStore(ctx context.Context, cfg *config.Config) (apisixStore *store.ApisixEtcdStore, err error) {
-	client, err := initApisixEtcdClient(cfg)
+// initApisixStore 按配置的后端创建主集群的 store, 只有 etcd 后端使用 prefix
+func initApisixStore(ctx context.Context, cfg *config.Config, prefix string) (store.ApisixStore, error) {
+	switch cfg.Apisix.Backend {
+	case config.ApisixBackendAdminAPI:
+		return store.NewApisixAdminStore(ctx, &cfg.Apisix.AdminAPI)
+	case config.ApisixBackendStandalone:
+		return store.NewApisixStandaloneStore(&cfg.Apisix.Standalone)
+	default:
+		return initApisixEtcdStore(ctx, cfg, prefix)
+	}
+}
+
+func initApisixEtcdStore(
+	ctx context.Context, cfg *config.Config, prefix string,
+) (apisixStore *store.ApisixEtcdStore, err error) {
+	return newApisixEtcdStore(ctx, cfg, &cfg.Apisix.Etcd, prefix)
+}
+
+// initApisixTargetStore 其他 apisix 集群的 store
+func initApisixTargetStore(
+	ctx context.Context, cfg *config.Config, target *config.ApisixTarget,
+) (store.ApisixStore, error) {
+	switch target.Backend {
+	case config.ApisixBackendAdminAPI:
+		return store.NewApisixAdminStore(ctx, &target.AdminAPI)
+	case config.ApisixBackendStandalone:
+		return store.NewApisixStandaloneStore(&target.Standalone)
+	default:
+		return newApisixEtcdStore(ctx, cfg, &target.Etcd, target.Etcd.KeyPrefix)
+	}
+}
+
+func newApisixEtcdStore(
+	ctx context.Context, cfg *config.Config, etcdConfig *config.Etcd, prefix string,
+) (apisixStore *store.ApisixEtcdStore, err error) {
+	client, err := createEtcdClient(etcdConfig)
 	if err != nil {
 		return nil, fmt.Errorf("init etcd client failed: %w", err)
 	}
 	apisixStore, err = store.NewApisixEtcdStore(
 		ctx,
 		client,
-		cfg.Apisix.Etcd.KeyPrefix,
+		prefix,
 		cfg.Operator.EtcdPutInterval,
 		cfg.Operator.EtcdDelInterval,
 		cfg.Operator.EtcdSyncTimeout,
@@ -23,18 +23,25 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cast"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
 
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/client"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/agent"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/agent/timer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/archive"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/committer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/reconciler"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/eventreporter"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/leaderelection"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
@@ -44,13 +51,22 @@ import (
 // EtcdAgentRunner ...
 type EtcdAgentRunner struct {
 	client            *clientv3.Client
-	apigwEtcdRegistry *registry.APIGWEtcdRegistry
-	leader            *leaderelection.EtcdLeaderElector
+	apigwEtcdRegistry registry.APIGWRegistry
+	leader            leaderelection.LeaderElector
 	synchronizer      *synchronizer.ApisixConfigSynchronizer
-	apisixEtcdstore   *store.ApisixEtcdStore
+	apisixStore       store.ApisixStore
+	// 其他 apisix 集群的 store, 由 synchronizer 同步
+	targetStores []store.ApisixStore
 
-	committer *committer.Committer
-	agent     *agent.EventAgent
+	// 影子模式下线上的 apisix 配置, 只读, 用于与影子配置对比
+	liveApisixEtcdStore *store.ApisixEtcdStore
+	shadowReporter      *store.ShadowReporter
+
+	committer  *committer.Committer
+	agent      *agent.EventAgent
+	reconciler *reconciler.DriftReconciler
+	collector  *reconciler.OrphanCollector
+	archiver   *archive.Archiver
 
 	cfg *config.Config
 
@@ -63,17 +79,22 @@ type EtcdAgentRunner struct {
 
 // NewEtcdAgentRunner ...
 func NewEtcdAgentRunner(ctx context.Context, cfg *config.Config) *EtcdAgentRunner {
-	client, err := initOperatorEtcdClient(cfg)
-	if err != nil {
-		fmt.Println(err, "Error creating apigwEtcdRegistry etcd client")
-		os.Exit(1)
+	// 只有 etcd 来源需要 dashboard 的 etcd
+	var etcdClient *clientv3.Client
+	if cfg.Dashboard.Source == config.DashboardSourceEtcd {
+		var err error
+		etcdClient, err = initOperatorEtcdClient(cfg)
+		if err != nil {
+			fmt.Println(err, "Error creating apigwEtcdRegistry etcd client")
+			os.Exit(1)
+		}
 	}
 
 	// Create a cancellable context for managing background goroutines
 	runnerCtx, cancel := context.WithCancel(ctx)
 
 	r := &EtcdAgentRunner{
-		client: client,
+		client: etcdClient,
 		cfg:    cfg,
 		logger: logging.GetLogger().Named("etcd-agent-runner"),
 		ctx:    runnerCtx,
@@ -88,21 +109,29 @@ func (r *EtcdAgentRunner) init() {
 	metric.InitMetric(prometheus.DefaultRegisterer)
 
 	// 2. init apigwEtcdRegistry
-	r.apigwEtcdRegistry = registry.NewAPIGWEtcdRegistry(
-		r.client,
-		r.cfg.Dashboard.Etcd.KeyPrefix,
-		r.cfg.Operator.WatchEventChanSize,
-	)
+	// 3. init leader election, the shadow instance elects separately and never competes with the live one
+	r.initRegistry()
 
-	r.leader, _ = leaderelection.NewEtcdLeaderElector(r.client, r.cfg.Dashboard.Etcd.KeyPrefix)
 	// 4. init output
-	apisixEtcdStore, err := initApisixEtcdStore(r.ctx, r.cfg)
+	apisixPrefix := r.cfg.Apisix.Etcd.KeyPrefix
+	if r.cfg.Operator.Shadow.Enable && r.cfg.Operator.Shadow.KeyPrefix != "" {
+		apisixPrefix = r.cfg.Operator.Shadow.KeyPrefix
+	}
+	apisixStore, err := initApisixStore(r.ctx, r.cfg, apisixPrefix)
 	if err != nil {
-		fmt.Println(err, "Error creating etcd apisixEtcdstore")
+		fmt.Println(err, "Error creating apisix store")
 		os.Exit(1)
 	}
-	r.apisixEtcdstore = apisixEtcdStore
-	r.synchronizer = synchronizer.NewSynchronizer(apisixEtcdStore, "/healthz")
+	r.apisixStore = apisixStore
+	if r.cfg.Operator.Shadow.Enable {
+		// 配置校验保证影子模式使用 etcd 后端
+		r.initShadow(apisixStore.(*store.ApisixEtcdStore)) //nolint:forcetypeassert
+	}
+	r.synchronizer = synchronizer.NewSynchronizer(apisixStore, "/healthz")
+	r.initTargets()
+	if r.cfg.Operator.Snapshot.Enable {
+		r.initSnapshot()
+	}
 
 	stageTimer := timer.NewReleaseTimer()
 	// 5. init committer
@@ -112,6 +141,9 @@ func (r *EtcdAgentRunner) init() {
 		stageTimer,
 		r.cfg.Operator.CommitResourceChanSize,
 	)
+	if r.cfg.Apisix.Backend == config.ApisixBackendEtcd && r.cfg.Operator.CommitRetry.KeyPrefix != "" {
+		r.initRetryStore()
+	}
 	commitChan := r.committer.GetCommitChan()
 
 	// 6. init agent
@@ -121,6 +153,211 @@ func (r *EtcdAgentRunner) init() {
 		r.synchronizer,
 		stageTimer,
 	)
+
+	// 7. init drift reconciler
+	if r.cfg.Operator.DriftReconcile.Enable {
+		r.reconciler = reconciler.NewDriftReconciler(
+			r.apigwEtcdRegistry,
+			r.apisixStore,
+			r.synchronizer,
+			r.cfg.Operator.DriftReconcile,
+		)
+	}
+
+	// 8. init orphan collector, the open api can list orphans even if the periodic collection is disabled
+	r.collector = reconciler.NewOrphanCollector(
+		r.apigwEtcdRegistry,
+		r.apisixStore,
+		r.synchronizer,
+		r.cfg.Operator.OrphanCollect,
+	)
+
+	// 9. init archiver, the open api can export and import even if the scheduled export is disabled
+	r.archiver = archive.NewArchiver(r.apisixStore, r.synchronizer, r.cfg.Operator.Export)
+}
+
+// initRegistry 根据配置的来源读取网关发布的资源, 并使用来源对应的方式选主;
+// 目录和 core api 来源没有可以选主的存储, 只能单实例运行
+func (r *EtcdAgentRunner) initRegistry() {
+	electionSuffix := ""
+	if r.cfg.Operator.Shadow.Enable {
+		electionSuffix = "-shadow"
+	}
+
+	switch r.cfg.Dashboard.Source {
+	case config.DashboardSourceDirectory:
+		r.logger.Infow("read the releases from directory",
+			"path", r.cfg.Dashboard.Directory.Path, "interval", r.cfg.Dashboard.Directory.Interval)
+		r.apigwEtcdRegistry = registry.NewAPIGWDirRegistry(
+			r.cfg.Dashboard.Directory.Path,
+			r.cfg.Dashboard.Directory.Interval,
+			r.cfg.Operator.WatchEventChanSize,
+		)
+	case config.DashboardSourceKubernetes:
+		kubeCfg := &r.cfg.Dashboard.Kubernetes
+		r.logger.Infow("read the releases from kubernetes configmaps",
+			"namespace", kubeCfg.Namespace, "selector", kubeCfg.LabelSelector, "lease", kubeCfg.LeaseName)
+		kubeRegistry, leader, err := initKubeSource(r.ctx, r.cfg, kubeCfg.LeaseName+electionSuffix)
+		if err != nil {
+			fmt.Println(err, "Error creating kubernetes source")
+			os.Exit(1)
+		}
+		r.apigwEtcdRegistry = kubeRegistry
+		r.leader = leader
+	case config.DashboardSourceCoreAPI:
+		r.logger.Infow("pull the releases from core api",
+			"host", r.cfg.EventReporter.CoreAPIHost, "interval", r.cfg.Dashboard.CoreAPI.Interval)
+		coreAPIClient := client.NewCoreAPIClient(
+			r.cfg.EventReporter.CoreAPIHost,
+			r.cfg.Auth.ID,
+			r.cfg.Auth.Secret,
+			r.cfg.Dashboard.CoreAPI.Timeout,
+		)
+		r.apigwEtcdRegistry = registry.NewAPIGWHTTPRegistry(
+			coreAPIClient,
+			&r.cfg.Dashboard.CoreAPI,
+			r.cfg.Operator.WatchEventChanSize,
+		)
+	default:
+		r.apigwEtcdRegistry = registry.NewAPIGWEtcdRegistry(
+			r.client,
+			r.cfg.Dashboard.Etcd.KeyPrefix,
+			r.cfg.Operator.WatchEventChanSize,
+		)
+		if len(r.cfg.Dashboard.Origins) > 0 {
+			r.initOrigins()
+		}
+		// 所有控制面共用 Dashboard.Etcd 上的选主
+		r.leader, _ = leaderelection.NewEtcdLeaderElector(r.client, r.cfg.Dashboard.Etcd.KeyPrefix+electionSuffix)
+	}
+}
+
+// initOrigins 同时 watch 其他控制面的 etcd, Dashboard.Etcd 为第一个控制面
+func (r *EtcdAgentRunner) initOrigins() {
+	origins := []*registry.Origin{{
+		Name:     config.DefaultDashboardOrigin,
+		Prefix:   r.cfg.Dashboard.Etcd.KeyPrefix,
+		Registry: r.apigwEtcdRegistry,
+	}}
+	for i := range r.cfg.Dashboard.Origins {
+		origin := &r.cfg.Dashboard.Origins[i]
+		originClient, err := createEtcdClient(&origin.Etcd)
+		if err != nil {
+			fmt.Println(err, "Error creating etcd client of dashboard origin", origin.Name)
+			os.Exit(1)
+		}
+		r.logger.Infow("watch the releases of dashboard origin",
+			"origin", origin.Name, "endpoints", origin.Etcd.Endpoints, "prefix", origin.Etcd.KeyPrefix)
+		originRegistry := registry.NewAPIGWEtcdRegistry(
+			originClient,
+			origin.Etcd.KeyPrefix,
+			r.cfg.Operator.WatchEventChanSize,
+		)
+		origins = append(origins, &registry.Origin{
+			Name:     origin.Name,
+			Prefix:   origin.Etcd.KeyPrefix,
+			Registry: originRegistry,
+		})
+	}
+	r.apigwEtcdRegistry = registry.NewAPIGWMultiRegistry(origins, r.cfg.Operator.WatchEventChanSize)
+}
+
+func (r *EtcdAgentRunner) initShadow(shadowStore *store.ApisixEtcdStore) {
+	if r.cfg.Operator.Shadow.KeyPrefix == "" {
+		r.logger.Infow("shadow mode enabled, only record the diffs")
+		shadowStore.EnableRecordOnly()
+		r.shadowReporter = store.NewShadowReporter(nil, shadowStore)
+		return
+	}
+
+	r.logger.Infow("shadow mode enabled", "shadowPrefix", r.cfg.Operator.Shadow.KeyPrefix)
+	liveStore, err := initApisixEtcdStore(r.ctx, r.cfg, r.cfg.Apisix.Etcd.KeyPrefix)
+	if err != nil {
+		fmt.Println(err, "Error creating live etcd apisixEtcdstore")
+		os.Exit(1)
+	}
+	r.liveApisixEtcdStore = liveStore
+	r.shadowReporter = store.NewShadowReporter(liveStore, shadowStore)
+}
+
+// initTargets 同步到其他的 apisix 集群并设置放置规则, 影子模式只写入影子前缀, 不同步其他集群
+func (r *EtcdAgentRunner) initTargets() {
+	if len(r.cfg.Apisix.Targets) == 0 && len(r.cfg.Apisix.Placement) == 0 {
+		return
+	}
+	if r.cfg.Operator.Shadow.Enable {
+		r.logger.Warnw("shadow mode enabled, skip the other apisix targets and placement rules",
+			"targets", len(r.cfg.Apisix.Targets), "placement", len(r.cfg.Apisix.Placement))
+		return
+	}
+	for i := range r.cfg.Apisix.Targets {
+		target := &r.cfg.Apisix.Targets[i]
+		targetStore, err := initApisixTargetStore(r.ctx, r.cfg, target)
+		if err != nil {
+			fmt.Println(err, "Error creating apisix store of target", target.Name)
+			os.Exit(1)
+		}
+		r.targetStores = append(r.targetStores, targetStore)
+		r.synchronizer.AddTarget(target.Name, targetStore)
+		r.logger.Infow("add apisix target",
+			"name", target.Name, "backend", target.Backend, "prefix", targetStore.Prefix())
+	}
+	if err := r.synchronizer.SetPlacement(r.cfg.Apisix.Placement); err != nil {
+		fmt.Println(err, "Error setting placement rules")
+		os.Exit(1)
+	}
+}
+
+func (r *EtcdAgentRunner) initSnapshot() {
+	snapshotClient, err := initApisixEtcdClient(r.cfg)
+	if err != nil {
+		fmt.Println(err, "Error creating snapshot etcd client")
+		os.Exit(1)
+	}
+	snapshots := store.NewSnapshotStore(
+		snapshotClient,
+		r.cfg.Operator.Snapshot.KeyPrefix,
+		r.cfg.Operator.Snapshot.MaxCount,
+		r.cfg.Operator.EtcdSyncTimeout,
+	)
+	if err = r.synchronizer.EnableSnapshot(r.ctx, snapshots); err != nil {
+		fmt.Println(err, "Error loading the rollback pins")
+		os.Exit(1)
+	}
+	if !r.cfg.Operator.Snapshot.AutoRollback {
+		return
+	}
+
+	eventreporter.SetLoadFailureHandler(func(ctx context.Context, release *entity.ReleaseInfo, err error) {
+		gatewayName, stageName := release.GetGatewayName(), release.GetStageName()
+		publishID := cast.ToString(release.PublishId)
+		r.logger.Warnw("apisix failed to load the release, rollback to the previous snapshot",
+			"gateway", gatewayName, "stage", stageName, "publishID", publishID, "err", err)
+		snapshot, err := r.synchronizer.RollbackToPrevious(ctx, gatewayName, stageName, publishID)
+		if err != nil {
+			r.logger.Errorw("auto rollback failed",
+				"gateway", gatewayName, "stage", stageName, "publishID", publishID, "err", err)
+			return
+		}
+		if snapshot != nil {
+			r.logger.Infow("auto rollback success",
+				"gateway", gatewayName, "stage", stageName, "publishID", snapshot.PublishID)
+		}
+	})
+}
+
+// initRetryStore 持久化等待重试的发布和死信, 影子模式单独选主, 也使用单独的前缀
+func (r *EtcdAgentRunner) initRetryStore() {
+	retryClient, err := initApisixEtcdClient(r.cfg)
+	if err != nil {
+		fmt.Println(err, "Error creating commit retry etcd client")
+		os.Exit(1)
+	}
+	retryPrefix := r.cfg.Operator.CommitRetry.KeyPrefix
+	if r.cfg.Operator.Shadow.Enable {
+		retryPrefix = strings.TrimRight(retryPrefix, "/") + "-shadow"
+	}
+	r.committer.EnableRetryStore(committer.NewRetryStore(retryClient, retryPrefix, r.cfg.Operator.EtcdSyncTimeout))
 }
 
 // Close releases all resources and stops background goroutines
@@ -128,8 +365,14 @@ func (r *EtcdAgentRunner) Close() {
 	if r.cancel != nil {
 		r.cancel()
 	}
-	if r.apisixEtcdstore != nil {
-		r.apisixEtcdstore.Close()
+	if r.apisixStore != nil {
+		r.apisixStore.Close()
+	}
+	if r.liveApisixEtcdStore != nil {
+		r.liveApisixEtcdStore.Close()
+	}
+	for _, targetStore := range r.targetStores {
+		targetStore.Close()
 	}
 	r.logger.Info("EtcdAgentRunner closed")
 }
@@ -140,8 +383,12 @@ func (r *EtcdAgentRunner) Run(ctx context.Context) {
 	httpServer := server.NewServer(
 		r.leader,
 		r.apigwEtcdRegistry,
-		r.apisixEtcdstore,
+		r.apisixStore,
 		r.committer,
+		r.collector,
+		r.shadowReporter,
+		r.synchronizer,
+		r.archiver,
 	)
 	httpServer.RegisterMetric(prometheus.DefaultGatherer)
 	if err := httpServer.Run(ctx, r.cfg); err != nil {
@@ -156,11 +403,28 @@ func (r *EtcdAgentRunner) Run(ctx context.Context) {
 		keepAliveChan = r.leader.WaitForLeading()
 	}
 
-	// 3. run committer
+	// 3. run committer, continue the retries and dead letters left by the previous leader
+	if err := r.committer.Restore(ctx); err != nil {
+		r.logger.Errorw("restore the commit retries failed", "err", err)
+	}
 	r.logger.Info("starting committer")
 	go r.committer.Run(ctx)
 
-	// 4. run agent
+	// 4. run drift reconciler
+	if r.reconciler != nil {
+		r.logger.Info("starting drift reconciler")
+		go r.reconciler.Run(ctx)
+	}
+	if r.cfg.Operator.OrphanCollect.Enable {
+		r.logger.Info("starting orphan collector")
+		go r.collector.Run(ctx)
+	}
+	if r.cfg.Operator.Export.Enable {
+		r.logger.Info("starting scheduled export")
+		go r.archiver.Run(ctx)
+	}
+
+	// 5. run agent
 	r.agent.SetKeepAliveChan(keepAliveChan)
 
 	r.logger.Info("starting etcd agent")
@@ -0,0 +1,90 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/leaderelection"
+)
+
+// initKubeSource 创建 kubernetes 来源: ConfigMap 从 informer 缓存读取, 定期对比不会请求 apiserver; 使用 Lease 选主
+func initKubeSource(
+	ctx context.Context, cfg *config.Config, leaseName string,
+) (*registry.APIGWKubeRegistry, *leaderelection.KubeLeaderElector, error) {
+	kubeCfg := &cfg.Dashboard.Kubernetes
+	restConfig, err := ctrlconfig.GetConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("load kubernetes config failed: %w", err)
+	}
+	selector, err := labels.Parse(kubeCfg.LabelSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse label selector %s failed: %w", kubeCfg.LabelSelector, err)
+	}
+
+	configMapCache, err := cache.New(restConfig, cache.Options{
+		Scheme:            clientgoscheme.Scheme,
+		DefaultNamespaces: map[string]cache.Config{kubeCfg.Namespace: {}},
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.ConfigMap{}: {Label: selector},
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("create configmap cache failed: %w", err)
+	}
+	if _, err = configMapCache.GetInformer(ctx, &corev1.ConfigMap{}); err != nil {
+		return nil, nil, fmt.Errorf("create configmap informer failed: %w", err)
+	}
+	go func() {
+		if err := configMapCache.Start(ctx); err != nil {
+			fmt.Println(err, "Error running configmap cache")
+		}
+	}()
+	if !configMapCache.WaitForCacheSync(ctx) {
+		return nil, nil, fmt.Errorf("wait for configmap cache sync failed")
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create kubernetes client failed: %w", err)
+	}
+	leader, err := leaderelection.NewKubeLeaderElector(clientset, kubeCfg.Namespace, leaseName)
+	if err != nil {
+		return nil, nil, err
+	}
+	kubeRegistry := registry.NewAPIGWKubeRegistry(
+		configMapCache,
+		kubeCfg.Namespace,
+		selector,
+		kubeCfg.Interval,
+		cfg.Operator.WatchEventChanSize,
+	)
+	return kubeRegistry, leader, nil
+}
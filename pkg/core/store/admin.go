@@ -0,0 +1,448 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"go.uber.org/zap"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/differ"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+const (
+	adminAPIPath      = "/apisix/admin/"
+	adminAPIKeyHeader = "X-API-KEY"
+)
+
+// adminListResponse apisix 3.x admin api list 接口的返回, 没有资源时 list 可能是空对象
+type adminListResponse struct {
+	Total int            `json:"total"`
+	List  adminListItems `json:"list"`
+}
+
+type adminListItem struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// adminListItems 兼容 list 为数组或空对象
+type adminListItems []adminListItem
+
+// UnmarshalJSON ...
+func (m *adminListItems) UnmarshalJSON(data []byte) error {
+	if !gjson.ParseBytes(data).IsArray() {
+		*m = nil
+		return nil
+	}
+	var items []adminListItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	*m = items
+	return nil
+}
+
+// ApisixAdminStore 通过 apisix admin api 读写 apisix 配置, 适用于没有 apisix etcd 权限的部署
+// 本地缓存定期通过 list 接口全量刷新, 写入成功后更新缓存;
+// admin api 没有事务, 写入失败时不回滚已经写入的资源, 由下一次同步与缓存重新 diff 后补齐
+type ApisixAdminStore struct {
+	addr   string
+	apiKey string
+	client *http.Client
+	differ *differ.ConfigDiffer
+
+	// alterMux 写入持有读锁, 全量刷新持有写锁, 避免刷新时覆盖写入后更新的缓存
+	alterMux sync.RWMutex
+
+	mux       sync.RWMutex
+	resources map[string]map[string]entity.ApisixResource // resource type -> resource id -> resource
+
+	refreshInterval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	logger *zap.SugaredLogger
+}
+
+// NewApisixAdminStore 创建 admin api store, 首次全量同步失败时返回错误
+func NewApisixAdminStore(
+	ctx context.Context,
+	adminAPI *config.AdminAPI,
+) (*ApisixAdminStore, error) {
+	storeCtx, cancel := context.WithCancel(ctx)
+	s := &ApisixAdminStore{
+		addr:            strings.TrimRight(adminAPI.Addr, "/"),
+		apiKey:          adminAPI.APIKey,
+		client:          &http.Client{Timeout: adminAPI.Timeout},
+		differ:          differ.NewConfigDiffer(),
+		resources:       make(map[string]map[string]entity.ApisixResource, len(apisixResourceTypes)),
+		refreshInterval: adminAPI.RefreshInterval,
+		ctx:             storeCtx,
+		cancel:          cancel,
+		logger:          logging.GetLogger().Named("admin-api-config-store"),
+	}
+	if err := s.Refresh(ctx); err != nil {
+		cancel()
+		return nil, fmt.Errorf("create admin api config store failed: %w", err)
+	}
+	if s.refreshInterval > 0 {
+		go s.run()
+	}
+
+	s.logger.Infow("Create admin api config store", "addr", s.addr)
+	return s, nil
+}
+
+// Prefix returns the address of the admin api
+func (s *ApisixAdminStore) Prefix() string {
+	return s.addr
+}
+
+// Close stops the refresh goroutine
+func (s *ApisixAdminStore) Close() {
+	s.cancel()
+	s.logger.Infow("ApisixAdminStore closed", "addr", s.addr)
+}
+
+func (s *ApisixAdminStore) run() {
+	ticker := time.NewTicker(s.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(s.ctx); err != nil {
+				s.logger.Errorw("Refresh admin api cache failed", "err", err, "addr", s.addr)
+			}
+		}
+	}
+}
+
+// Refresh 通过 list 接口全量刷新所有资源类型的缓存
+func (s *ApisixAdminStore) Refresh(ctx context.Context) error {
+	s.alterMux.Lock()
+	defer s.alterMux.Unlock()
+
+	resources := make(map[string]map[string]entity.ApisixResource, len(apisixResourceTypes))
+	for _, resourceType := range apisixResourceTypes {
+		items, err := s.list(ctx, resourceType)
+		if err != nil {
+			return err
+		}
+		resources[resourceType] = items
+	}
+
+	s.mux.Lock()
+	s.resources = resources
+	s.mux.Unlock()
+	return nil
+}
+
+func (s *ApisixAdminStore) list(ctx context.Context, resourceType string) (map[string]entity.ApisixResource, error) {
+	body, err := s.do(ctx, http.MethodGet, resourceType, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp adminListResponse
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshal %s list failed: %w", resourceType, err)
+	}
+
+	resources := make(map[string]entity.ApisixResource, len(resp.List))
+	for _, item := range resp.List {
+		value := []byte(item.Value)
+		// plugin metadata 的 id 为插件名称, 旧数据中可能没有 id 字段
+		if !gjson.GetBytes(value, "id").Exists() {
+			value, err = sjson.SetBytes(value, "id", path.Base(item.Key))
+			if err != nil {
+				return nil, fmt.Errorf("set id of %s failed: %w", item.Key, err)
+			}
+		}
+		resource, err := registry.UnmarshalApisixResource(resourceType, value)
+		if err != nil {
+			s.logger.Errorw("Parse resource from admin api failed", "err", err, "key", item.Key)
+			continue
+		}
+		resources[resource.GetID()] = resource
+	}
+	return resources, nil
+}
+
+// do 请求 admin api, 返回响应的 body
+func (s *ApisixAdminStore) do(
+	ctx context.Context,
+	method, resourceType, id string,
+	payload []byte,
+) ([]byte, error) {
+	url := s.addr + adminAPIPath + resourceType
+	if id != "" {
+		url += "/" + id
+	}
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(adminAPIKeyHeader, s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", method, url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s read body failed: %w", method, url, err)
+	}
+	// 删除不存在的资源视为成功, 缓存可能落后于 apisix
+	if method == http.MethodDelete && resp.StatusCode == http.StatusNotFound {
+		return body, nil
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("%s %s failed, status: %d, body: %s", method, url, resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+func (s *ApisixAdminStore) resourcesOf(resourceType string) map[string]entity.ApisixResource {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return maps.Clone(s.resources[resourceType])
+}
+
+// Get get a staged apisix configuration
+func (s *ApisixAdminStore) Get(stageKey string) *entity.ApisixStageResource {
+	if conf, ok := s.GetAll()[stageKey]; ok {
+		return conf
+	}
+	return entity.NewEmptyApisixConfiguration()
+}
+
+// GetAll get staged apisix configuration map, key is the stage key
+func (s *ApisixAdminStore) GetAll() map[string]*entity.ApisixStageResource {
+	configMap := make(map[string]*entity.ApisixStageResource)
+	stageConf := func(resource entity.ApisixResource) *entity.ApisixStageResource {
+		stageKey := resource.GetStageKey()
+		if _, ok := configMap[stageKey]; !ok {
+			configMap[stageKey] = entity.NewEmptyApisixConfiguration()
+		}
+		return configMap[stageKey]
+	}
+	for id, route := range s.resourcesOf(constant.ApisixResourceTypeRoutes) {
+		stageConf(route).Routes[id] = route.(*entity.Route) //nolint:forcetypeassert
+	}
+	for id, service := range s.resourcesOf(constant.ApisixResourceTypeServices) {
+		stageConf(service).Services[id] = service.(*entity.Service) //nolint:forcetypeassert
+	}
+	for id, upstream := range s.resourcesOf(constant.ApisixResourceTypeUpstreams) {
+		stageConf(upstream).Upstreams[id] = upstream.(*entity.Upstream) //nolint:forcetypeassert
+	}
+	for id, pluginConfig := range s.resourcesOf(constant.ApisixResourceTypePluginConfigs) {
+		stageConf(pluginConfig).PluginConfigs[id] = pluginConfig.(*entity.PluginConfig) //nolint:forcetypeassert
+	}
+	for id, consumer := range s.resourcesOf(constant.ApisixResourceTypeConsumers) {
+		stageConf(consumer).Consumers[id] = consumer.(*entity.Consumer) //nolint:forcetypeassert
+	}
+	for id, consumerGroup := range s.resourcesOf(constant.ApisixResourceTypeConsumerGroups) {
+		stageConf(consumerGroup).ConsumerGroups[id] = consumerGroup.(*entity.ConsumerGroup) //nolint:forcetypeassert
+	}
+	for id, ssl := range s.resourcesOf(constant.ApisixResourceTypeSSL) {
+		stageConf(ssl).SSLs[id] = ssl.(*entity.SSL) //nolint:forcetypeassert
+	}
+	for id, streamRoute := range s.resourcesOf(constant.ApisixResourceTypeStreamRoutes) {
+		stageConf(streamRoute).StreamRoutes[id] = streamRoute.(*entity.StreamRoute) //nolint:forcetypeassert
+	}
+	for id, proto := range s.resourcesOf(constant.ApisixResourceTypeProtos) {
+		stageConf(proto).Protos[id] = proto.(*entity.Proto) //nolint:forcetypeassert
+	}
+	return configMap
+}
+
+// GetGlobal 获取全局资源配置, 即没有 stage 标签的 plugin metadata 和 operator 管理的 global rule
+func (s *ApisixAdminStore) GetGlobal() *entity.ApisixGlobalResource {
+	ret := entity.NewEmptyApisixGlobalResource()
+	for id, pm := range s.resourcesOf(constant.ApisixResourceTypePluginMetadata) {
+		if pm.GetStageName() == "" {
+			ret.PluginMetadata[id] = pm.(*entity.PluginMetadata) //nolint:forcetypeassert
+		}
+	}
+	for id, globalRule := range s.resourcesOf(constant.ApisixResourceTypeGlobalRules) {
+		// 只对比 operator 管理的 global rule, 其他方式创建的不会被删除
+		if globalRule.(*entity.GlobalRule).IsManaged() { //nolint:forcetypeassert
+			ret.GlobalRules[id] = globalRule.(*entity.GlobalRule) //nolint:forcetypeassert
+		}
+	}
+	return ret
+}
+
+// Diff 对比环境的期望配置与缓存中的配置, 不写入 apisix
+func (s *ApisixAdminStore) Diff(stageKey string, conf *entity.ApisixStageResource) *StageDiff {
+	put, toDelete := s.differ.Diff(s.Get(stageKey), conf)
+	return newStageDiff(stageKey, put, toDelete)
+}
+
+// DiffGlobal 对比全局资源的期望配置与缓存中的配置, 不写入 apisix
+func (s *ApisixAdminStore) DiffGlobal(conf *entity.ApisixGlobalResource) *StageDiff {
+	return newGlobalDiff(s.differ.DiffGlobal(s.GetGlobal(), conf))
+}
+
+// Alter 写入环境配置, 被依赖的资源先写入, 引用方先删除
+func (s *ApisixAdminStore) Alter(ctx context.Context, stageKey string, conf *entity.ApisixStageResource) error {
+	st := time.Now()
+	err := s.alterStage(ctx, stageKey, conf)
+	metric.ReportStageConfigAlterMetric(stageKey, conf, st, err)
+	if err != nil {
+		s.logger.Errorw("Alter by stage failed", "err", err, "stage", stageKey)
+		return err
+	}
+	return nil
+}
+
+func (s *ApisixAdminStore) alterStage(ctx context.Context, stageKey string, conf *entity.ApisixStageResource) error {
+	s.alterMux.RLock()
+	defer s.alterMux.RUnlock()
+
+	putConf, deleteConf := s.differ.Diff(s.Get(stageKey), conf)
+	putNodes := stageResourceNodes(putConf)
+	deleteNodes := stageResourceNodes(deleteConf)
+	for _, level := range dependencyLevels(putNodes) {
+		for _, ref := range level {
+			if err := s.put(ctx, ref.resourceType, ref.id, putNodes[ref]); err != nil {
+				return err
+			}
+		}
+	}
+	deleteLevels := dependencyLevels(deleteNodes)
+	slices.Reverse(deleteLevels)
+	for _, level := range deleteLevels {
+		for _, ref := range level {
+			if err := s.delete(ctx, ref.resourceType, ref.id); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(putNodes)+len(deleteNodes) == 0 {
+		s.logger.Infof("%s has no change", stageKey)
+		return nil
+	}
+	s.logger.Infow("alter stage by admin api", "stage", stageKey, "put", len(putNodes), "delete", len(deleteNodes))
+	return nil
+}
+
+// AlterGlobal 写入全局资源配置
+func (s *ApisixAdminStore) AlterGlobal(ctx context.Context, conf *entity.ApisixGlobalResource) error {
+	st := time.Now()
+	err := s.alterGlobal(ctx, conf)
+	metric.ReportStageConfigAlterMetric(config.GenStagePrimaryKey("apigw", "global_resource"), nil, st, err)
+	if err != nil {
+		s.logger.Errorw("Alter global resource failed", "err", err)
+		return err
+	}
+	return nil
+}
+
+func (s *ApisixAdminStore) alterGlobal(ctx context.Context, conf *entity.ApisixGlobalResource) error {
+	s.alterMux.RLock()
+	defer s.alterMux.RUnlock()
+
+	putConf, deleteConf := s.differ.DiffGlobal(s.GetGlobal(), conf)
+	if putConf != nil {
+		for id, pm := range putConf.PluginMetadata {
+			if err := s.put(ctx, constant.ApisixResourceTypePluginMetadata, id, pm); err != nil {
+				return err
+			}
+		}
+		for id, globalRule := range putConf.GlobalRules {
+			if err := s.put(ctx, constant.ApisixResourceTypeGlobalRules, id, globalRule); err != nil {
+				return err
+			}
+		}
+	}
+	if deleteConf != nil {
+		for id := range deleteConf.PluginMetadata {
+			if err := s.delete(ctx, constant.ApisixResourceTypePluginMetadata, id); err != nil {
+				return err
+			}
+		}
+		for id := range deleteConf.GlobalRules {
+			if err := s.delete(ctx, constant.ApisixResourceTypeGlobalRules, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *ApisixAdminStore) put(ctx context.Context, resourceType, id string, resource entity.ApisixResource) error {
+	payload, err := marshalResource(resource)
+	if err != nil {
+		return fmt.Errorf("marshal %s %s failed: %w", resourceType, id, err)
+	}
+	if _, err = s.do(ctx, http.MethodPut, resourceType, id, payload); err != nil {
+		return err
+	}
+	s.logger.Debugw("Put resource by admin api", "resourceType", resourceType, "resourceID", id)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.resources[resourceType] == nil {
+		s.resources[resourceType] = make(map[string]entity.ApisixResource)
+	}
+	s.resources[resourceType][id] = resource
+	return nil
+}
+
+func (s *ApisixAdminStore) delete(ctx context.Context, resourceType, id string) error {
+	if _, err := s.do(ctx, http.MethodDelete, resourceType, id, nil); err != nil {
+		return err
+	}
+	s.logger.Debugw("Delete resource by admin api", "resourceType", resourceType, "resourceID", id)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.resources[resourceType], id)
+	return nil
+}
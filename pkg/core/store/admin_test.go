@@ -0,0 +1,244 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	json "github.com/json-iterator/go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+// fakeAdminAPI apisix admin api 的替身, 记录收到的写请求
+type fakeAdminAPI struct {
+	mux       sync.Mutex
+	resources map[string]map[string]json.RawMessage
+	writes    []string
+}
+
+func (f *fakeAdminAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(adminAPIKeyHeader) != "test-key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	resourceType, id, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, adminAPIPath), "/")
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.resources[resourceType] == nil {
+		f.resources[resourceType] = make(map[string]json.RawMessage)
+	}
+	switch r.Method {
+	case http.MethodGet:
+		// 与 apisix 一致, 没有资源时 list 为空对象
+		if len(f.resources[resourceType]) == 0 {
+			_, _ = w.Write([]byte(`{"total":0,"list":{}}`))
+			return
+		}
+		items := make([]adminListItem, 0, len(f.resources[resourceType]))
+		for id, value := range f.resources[resourceType] {
+			items = append(items, adminListItem{Key: "/apisix/" + resourceType + "/" + id, Value: value})
+		}
+		body, _ := json.Marshal(map[string]any{"total": len(items), "list": items})
+		_, _ = w.Write(body)
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.resources[resourceType][id] = body
+		f.writes = append(f.writes, fmt.Sprintf("PUT %s/%s", resourceType, id))
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if _, ok := f.resources[resourceType][id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.resources[resourceType], id)
+		f.writes = append(f.writes, fmt.Sprintf("DELETE %s/%s", resourceType, id))
+	}
+}
+
+func (f *fakeAdminAPI) put(resourceType, id, value string) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.resources[resourceType] == nil {
+		f.resources[resourceType] = make(map[string]json.RawMessage)
+	}
+	f.resources[resourceType][id] = json.RawMessage(value)
+}
+
+func (f *fakeAdminAPI) takeWrites() []string {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	writes := f.writes
+	f.writes = nil
+	return writes
+}
+
+var _ = Describe("ApisixAdminStore", func() {
+	var (
+		fake   *fakeAdminAPI
+		server *httptest.Server
+		ctx    context.Context
+		s      *ApisixAdminStore
+	)
+
+	labels := &entity.LabelInfo{Gateway: "gw", Stage: "prod"}
+	stageKey := config.GenStagePrimaryKey("gw", "prod")
+
+	newStore := func(apiKey string) (*ApisixAdminStore, error) {
+		return NewApisixAdminStore(ctx, &config.AdminAPI{
+			Addr:    server.URL,
+			APIKey:  apiKey,
+			Timeout: time.Second,
+		})
+	}
+
+	BeforeEach(func() {
+		if !metricInitialized {
+			metric.InitMetric(prometheus.NewRegistry())
+			metricInitialized = true
+		}
+		ctx = context.Background()
+		fake = &fakeAdminAPI{resources: make(map[string]map[string]json.RawMessage)}
+		fake.put("routes", "stale-route",
+			`{"id":"stale-route","uri":"/stale","labels":{"gateway.bk.tencent.com/gateway":"gw",`+
+				`"gateway.bk.tencent.com/stage":"prod"}}`)
+		fake.put("plugin_metadata", "file-logger", `{"log_format":{"host":"$host"}}`)
+		server = httptest.NewServer(fake)
+
+		var err error
+		s, err = newStore("test-key")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		s.Close()
+		server.Close()
+	})
+
+	It("should fail to create the store with a wrong api key", func() {
+		_, err := newStore("wrong-key")
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should load the resources by listing", func() {
+		Expect(s.Prefix()).To(Equal(server.URL))
+		Expect(s.Get(stageKey).Routes).To(HaveKey("stale-route"))
+		Expect(s.GetAll()).To(HaveKey(stageKey))
+		// 没有 id 的 plugin metadata 使用 key 中的插件名称
+		Expect(s.GetGlobal().PluginMetadata).To(HaveKey("file-logger"))
+	})
+
+	It("should alter the stage in dependency order", func() {
+		conf := entity.NewEmptyApisixConfiguration()
+		conf.Services["service-1"] = &entity.Service{
+			ResourceMetadata: entity.ResourceMetadata{ID: "service-1", Labels: labels},
+		}
+		conf.Routes["route-1"] = &entity.Route{
+			ResourceMetadata: entity.ResourceMetadata{ID: "route-1", Labels: labels},
+			URI:              "/v1",
+			ServiceID:        "service-1",
+		}
+		Expect(s.Diff(stageKey, conf).IsEmpty()).To(BeFalse())
+
+		Expect(s.Alter(ctx, stageKey, conf)).To(Succeed())
+		Expect(fake.takeWrites()).To(Equal([]string{
+			"PUT services/service-1",
+			"PUT routes/route-1",
+			"DELETE routes/stale-route",
+		}))
+		Expect(s.Get(stageKey).Routes).To(HaveKey("route-1"))
+		Expect(s.Get(stageKey).Routes).NotTo(HaveKey("stale-route"))
+
+		// 没有变更时不写入
+		Expect(s.Alter(ctx, stageKey, conf)).To(Succeed())
+		Expect(fake.takeWrites()).To(BeEmpty())
+
+		// 删除环境时引用方先删除
+		Expect(s.Alter(ctx, stageKey, entity.NewEmptyApisixConfiguration())).To(Succeed())
+		Expect(fake.takeWrites()).To(Equal([]string{
+			"DELETE routes/route-1",
+			"DELETE services/service-1",
+		}))
+	})
+
+	It("should alter the global plugin metadata", func() {
+		conf := entity.NewEmptyApisixGlobalResource()
+		conf.PluginMetadata["prometheus"] = &entity.PluginMetadata{
+			ResourceMetadata: entity.ResourceMetadata{ID: "prometheus"},
+			PluginMetadataConf: entity.PluginMetadataConf{
+				"prometheus": []byte(`{"id":"prometheus","prefer_name":true}`),
+			},
+		}
+		Expect(s.AlterGlobal(ctx, conf)).To(Succeed())
+		Expect(fake.takeWrites()).To(ConsistOf("PUT plugin_metadata/prometheus", "DELETE plugin_metadata/file-logger"))
+		Expect(s.GetGlobal().PluginMetadata).To(HaveLen(1))
+	})
+
+	It("should alter the global rules", func() {
+		conf := entity.NewEmptyApisixGlobalResource()
+		conf.GlobalRules["bk-apigw.1"] = &entity.GlobalRule{
+			ResourceMetadata: entity.ResourceMetadata{ID: "bk-apigw.1"},
+			Plugins:          map[string]any{"prometheus": map[string]any{}},
+		}
+		Expect(s.AlterGlobal(ctx, conf)).To(Succeed())
+		Expect(fake.takeWrites()).To(ConsistOf("PUT global_rules/bk-apigw.1", "DELETE plugin_metadata/file-logger"))
+		Expect(s.GetGlobal().GlobalRules).To(HaveKey("bk-apigw.1"))
+
+		Expect(s.AlterGlobal(ctx, entity.NewEmptyApisixGlobalResource())).To(Succeed())
+		Expect(fake.takeWrites()).To(Equal([]string{"DELETE global_rules/bk-apigw.1"}))
+	})
+
+	It("should keep the global rules not managed by the operator", func() {
+		fake.put("global_rules", "1", `{"id":"1","plugins":{"prometheus":{}}}`)
+		Expect(s.Refresh(ctx)).To(Succeed())
+		Expect(s.GetGlobal().GlobalRules).To(BeEmpty())
+
+		Expect(s.AlterGlobal(ctx, entity.NewEmptyApisixGlobalResource())).To(Succeed())
+		Expect(fake.takeWrites()).NotTo(ContainElement("DELETE global_rules/1"))
+	})
+
+	It("should pick up the changes made by others after refresh", func() {
+		fake.put("routes", "other-route",
+			`{"id":"other-route","uri":"/other","labels":{"gateway.bk.tencent.com/gateway":"gw",`+
+				`"gateway.bk.tencent.com/stage":"prod"}}`)
+		Expect(s.Get(stageKey).Routes).NotTo(HaveKey("other-route"))
+
+		Expect(s.Refresh(ctx)).To(Succeed())
+		Expect(s.Get(stageKey).Routes).To(HaveKey("other-route"))
+	})
+
+	It("should fail the alter when the admin api rejects the request", func() {
+		server.Close()
+		err := s.Alter(ctx, stageKey, entity.NewEmptyApisixConfiguration())
+		Expect(err).Should(HaveOccurred())
+	})
+})
@@ -0,0 +1,181 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"sync"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+)
+
+// ErrStreamRouteConflict 环境的 stream route 与数据面上已有的 stream route 匹配条件重叠
+var ErrStreamRouteConflict = errors.New("stream route conflict")
+
+// streamRouteMatch stream route 的匹配条件; apisix 按 server_addr, server_port, remote_addr 和 sni 选择 stream route,
+// 为空的条件匹配任意值, 匹配条件重叠的多个 stream route 只有一个会生效
+type streamRouteMatch struct {
+	serverAddr string
+	serverPort int
+	remoteAddr string
+	sni        string
+}
+
+func newStreamRouteMatch(streamRoute *entity.StreamRoute) streamRouteMatch {
+	return streamRouteMatch{
+		serverAddr: streamRoute.ServerAddr,
+		serverPort: streamRoute.ServerPort,
+		remoteAddr: streamRoute.RemoteAddr,
+		sni:        streamRoute.SNI,
+	}
+}
+
+func (m streamRouteMatch) String() string {
+	return fmt.Sprintf("server_addr=%q,server_port=%d,remote_addr=%q,sni=%q",
+		m.serverAddr, m.serverPort, m.remoteAddr, m.sni)
+}
+
+// overlaps 两个 stream route 是否可能匹配同一个连接: 每个条件都相同、其中一个为空或者地址段有交集
+func (m streamRouteMatch) overlaps(other streamRouteMatch) bool {
+	return (m.serverPort == 0 || other.serverPort == 0 || m.serverPort == other.serverPort) &&
+		addrOverlaps(m.serverAddr, other.serverAddr) &&
+		addrOverlaps(m.remoteAddr, other.remoteAddr) &&
+		(m.sni == "" || other.sni == "" || m.sni == other.sni)
+}
+
+// addrOverlaps 地址可以是 IP 或者 CIDR, 为空时匹配任意地址
+func addrOverlaps(a, b string) bool {
+	if a == "" || b == "" || a == b {
+		return true
+	}
+	pa, okA := parsePrefix(a)
+	pb, okB := parsePrefix(b)
+	return okA && okB && pa.Overlaps(pb)
+}
+
+func parsePrefix(addr string) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(addr); err == nil {
+		return prefix, true
+	}
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(ip, ip.BitLen()), true
+}
+
+// CheckStreamRouteConflict 检查环境的 stream route 是否与同一个数据面上其他环境 (包括不受管理的) 的 stream route 冲突,
+// 环境内的 stream route 之间也不能冲突; 写入前检查, 冲突时不写入任何资源
+func CheckStreamRouteConflict(s ApisixStore, stageKey string, conf *entity.ApisixStageResource) error {
+	return checkStreamRouteConflict(s.GetAll(), nil, stageKey, conf)
+}
+
+// checkStreamRouteConflict written 中的环境以 written 为准, 不使用 stages 中的缓存
+func checkStreamRouteConflict(
+	stages map[string]*entity.ApisixStageResource,
+	written map[string]map[string]streamRouteMatch,
+	stageKey string,
+	conf *entity.ApisixStageResource,
+) error {
+	if conf == nil || len(conf.StreamRoutes) == 0 {
+		return nil
+	}
+	others := make(map[string]streamRouteMatch)
+	for key, stage := range stages {
+		if _, ok := written[key]; ok || key == stageKey {
+			continue
+		}
+		for id, streamRoute := range stage.StreamRoutes {
+			others[id] = newStreamRouteMatch(streamRoute)
+		}
+	}
+	for key, matches := range written {
+		if key != stageKey {
+			maps.Copy(others, matches)
+		}
+	}
+
+	owners := slices.Sorted(maps.Keys(others))
+	for _, id := range slices.Sorted(maps.Keys(conf.StreamRoutes)) {
+		match := newStreamRouteMatch(conf.StreamRoutes[id])
+		for _, owner := range owners {
+			if match.overlaps(others[owner]) {
+				return fmt.Errorf("%w: %s (%s) overlaps with %s (%s)",
+					ErrStreamRouteConflict, id, match, owner, others[owner])
+			}
+		}
+		others[id] = match
+		owners = append(owners, id)
+	}
+	return nil
+}
+
+// StreamRouteGuard 串行化同一个数据面上环境 stream route 的冲突检查和写入, 避免并行同步的两个环境都通过检查;
+// store 的缓存可能由 watch 异步更新, 所以同时记录通过 guard 写入的 stream route, 检查时以其为准
+type StreamRouteGuard struct {
+	mux     sync.Mutex
+	written map[string]map[string]streamRouteMatch
+}
+
+// NewStreamRouteGuard ...
+func NewStreamRouteGuard() *StreamRouteGuard {
+	return &StreamRouteGuard{written: make(map[string]map[string]streamRouteMatch)}
+}
+
+// Alter 检查环境的 stream route 没有冲突后写入; 没有 stream route 的环境不会引起冲突, 不需要串行
+func (g *StreamRouteGuard) Alter(
+	ctx context.Context,
+	s ApisixStore,
+	stageKey string,
+	conf *entity.ApisixStageResource,
+) error {
+	if conf == nil || len(conf.StreamRoutes) == 0 {
+		g.mux.Lock()
+		_, ok := g.written[stageKey]
+		g.mux.Unlock()
+		if err := s.Alter(ctx, stageKey, conf); err != nil || !ok {
+			return err
+		}
+		g.mux.Lock()
+		delete(g.written, stageKey)
+		g.mux.Unlock()
+		return nil
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	if err := checkStreamRouteConflict(s.GetAll(), g.written, stageKey, conf); err != nil {
+		return err
+	}
+	// 写入时会修改资源, 先记录匹配条件
+	matches := make(map[string]streamRouteMatch, len(conf.StreamRoutes))
+	for id, streamRoute := range conf.StreamRoutes {
+		matches[id] = newStreamRouteMatch(streamRoute)
+	}
+	if err := s.Alter(ctx, stageKey, conf); err != nil {
+		return err
+	}
+	g.written[stageKey] = matches
+	return nil
+}
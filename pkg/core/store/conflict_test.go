@@ -0,0 +1,196 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+var _ = Describe("CheckStreamRouteConflict", func() {
+	var s *ApisixStandaloneStore
+
+	newStage := func(gateway, stage string, ports ...int) *entity.ApisixStageResource {
+		conf := entity.NewEmptyApisixConfiguration()
+		for i, port := range ports {
+			id := "bk-apigw." + gateway + "." + stage + ".tcp-" + string(rune('a'+i))
+			conf.StreamRoutes[id] = &entity.StreamRoute{
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:     id,
+					Labels: &entity.LabelInfo{Gateway: gateway, Stage: stage},
+				},
+				ServerAddr: "127.0.0.1",
+				ServerPort: port,
+				UpstreamID: "1",
+			}
+		}
+		return conf
+	}
+
+	BeforeEach(func() {
+		if !metricInitialized {
+			metric.InitMetric(prometheus.NewRegistry())
+			metricInitialized = true
+		}
+		var err error
+		s, err = NewApisixStandaloneStore(&config.Standalone{Path: filepath.Join(GinkgoT().TempDir(), "apisix.yaml")})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(s.Alter(context.Background(), config.GenStagePrimaryKey("gw", "prod"), newStage("gw", "prod", 9100))).
+			To(Succeed())
+	})
+
+	It("should pass when the stage has no stream routes", func() {
+		Expect(CheckStreamRouteConflict(s, config.GenStagePrimaryKey("gw", "test"), newStage("gw", "test"))).
+			To(Succeed())
+	})
+
+	It("should pass when the stage listens on other ports", func() {
+		Expect(CheckStreamRouteConflict(s, config.GenStagePrimaryKey("gw", "test"), newStage("gw", "test", 9101))).
+			To(Succeed())
+	})
+
+	It("should pass when the stage updates its own stream routes", func() {
+		Expect(CheckStreamRouteConflict(s, config.GenStagePrimaryKey("gw", "prod"), newStage("gw", "prod", 9100))).
+			To(Succeed())
+	})
+
+	It("should pass when the sni is different", func() {
+		prod := newStage("gw", "prod", 9100)
+		prod.StreamRoutes["bk-apigw.gw.prod.tcp-a"].SNI = "prod.example.com"
+		Expect(s.Alter(context.Background(), config.GenStagePrimaryKey("gw", "prod"), prod)).To(Succeed())
+
+		conf := newStage("gw", "test", 9100)
+		conf.StreamRoutes["bk-apigw.gw.test.tcp-a"].SNI = "test.example.com"
+		Expect(CheckStreamRouteConflict(s, config.GenStagePrimaryKey("gw", "test"), conf)).To(Succeed())
+	})
+
+	It("should fail when another stage listens on the same address", func() {
+		err := CheckStreamRouteConflict(s, config.GenStagePrimaryKey("gw", "test"), newStage("gw", "test", 9100))
+		Expect(errors.Is(err, ErrStreamRouteConflict)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("gw.prod.tcp-a"))
+	})
+
+	It("should fail when stream routes in the same stage conflict", func() {
+		err := CheckStreamRouteConflict(s, config.GenStagePrimaryKey("gw", "test"), newStage("gw", "test", 9101, 9101))
+		Expect(errors.Is(err, ErrStreamRouteConflict)).To(BeTrue())
+	})
+
+	It("should fail when the stage listens on all addresses of the same port", func() {
+		conf := newStage("gw", "test", 9100)
+		conf.StreamRoutes["bk-apigw.gw.test.tcp-a"].ServerAddr = ""
+		err := CheckStreamRouteConflict(s, config.GenStagePrimaryKey("gw", "test"), conf)
+		Expect(errors.Is(err, ErrStreamRouteConflict)).To(BeTrue())
+	})
+
+	It("should fail when the remote addr overlaps", func() {
+		prod := newStage("gw", "prod", 9100)
+		prod.StreamRoutes["bk-apigw.gw.prod.tcp-a"].RemoteAddr = "10.0.0.0/8"
+		Expect(s.Alter(context.Background(), config.GenStagePrimaryKey("gw", "prod"), prod)).To(Succeed())
+
+		conf := newStage("gw", "test", 9100)
+		conf.StreamRoutes["bk-apigw.gw.test.tcp-a"].RemoteAddr = "192.168.0.0/16"
+		Expect(CheckStreamRouteConflict(s, config.GenStagePrimaryKey("gw", "test"), conf)).To(Succeed())
+
+		conf.StreamRoutes["bk-apigw.gw.test.tcp-a"].RemoteAddr = "10.1.2.3"
+		err := CheckStreamRouteConflict(s, config.GenStagePrimaryKey("gw", "test"), conf)
+		Expect(errors.Is(err, ErrStreamRouteConflict)).To(BeTrue())
+
+		conf.StreamRoutes["bk-apigw.gw.test.tcp-a"].RemoteAddr = ""
+		err = CheckStreamRouteConflict(s, config.GenStagePrimaryKey("gw", "test"), conf)
+		Expect(errors.Is(err, ErrStreamRouteConflict)).To(BeTrue())
+	})
+
+	It("should fail when one of the sni is empty", func() {
+		conf := newStage("gw", "test", 9100)
+		conf.StreamRoutes["bk-apigw.gw.test.tcp-a"].SNI = "test.example.com"
+		Expect(s.Alter(context.Background(), config.GenStagePrimaryKey("gw", "test"), conf)).To(Succeed())
+
+		conf = newStage("gw", "dev", 9100)
+		conf.StreamRoutes["bk-apigw.gw.dev.tcp-a"].SNI = "dev.example.com"
+		err := CheckStreamRouteConflict(s, config.GenStagePrimaryKey("gw", "dev"), conf)
+		// prod 的 sni 为空, 与 dev 重叠
+		Expect(errors.Is(err, ErrStreamRouteConflict)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("gw.prod.tcp-a"))
+	})
+})
+
+// staleStore 模拟 watch 还没有更新缓存的 store, GetAll 始终返回创建时的配置
+type staleStore struct {
+	ApisixStore
+	stages map[string]*entity.ApisixStageResource
+}
+
+func (s *staleStore) GetAll() map[string]*entity.ApisixStageResource {
+	return s.stages
+}
+
+var _ = Describe("StreamRouteGuard", func() {
+	var (
+		s     *staleStore
+		guard *StreamRouteGuard
+	)
+
+	newStage := func(gateway, stage string, port int) *entity.ApisixStageResource {
+		conf := entity.NewEmptyApisixConfiguration()
+		id := "bk-apigw." + gateway + "." + stage + ".tcp"
+		conf.StreamRoutes[id] = &entity.StreamRoute{
+			ResourceMetadata: entity.ResourceMetadata{
+				ID:     id,
+				Labels: &entity.LabelInfo{Gateway: gateway, Stage: stage},
+			},
+			ServerPort: port,
+			UpstreamID: "1",
+		}
+		return conf
+	}
+
+	BeforeEach(func() {
+		if !metricInitialized {
+			metric.InitMetric(prometheus.NewRegistry())
+			metricInitialized = true
+		}
+		standalone, err := NewApisixStandaloneStore(
+			&config.Standalone{Path: filepath.Join(GinkgoT().TempDir(), "apisix.yaml")})
+		Expect(err).ShouldNot(HaveOccurred())
+		s = &staleStore{ApisixStore: standalone, stages: standalone.GetAll()}
+		guard = NewStreamRouteGuard()
+	})
+
+	It("should check against the stream routes written before the cache is updated", func() {
+		ctx := context.Background()
+		Expect(guard.Alter(ctx, s, config.GenStagePrimaryKey("gw", "prod"), newStage("gw", "prod", 9100))).To(Succeed())
+
+		err := guard.Alter(ctx, s, config.GenStagePrimaryKey("gw", "test"), newStage("gw", "test", 9100))
+		Expect(errors.Is(err, ErrStreamRouteConflict)).To(BeTrue())
+
+		// prod 删除 stream route 后端口可以被其他环境使用
+		Expect(guard.Alter(ctx, s, config.GenStagePrimaryKey("gw", "prod"), entity.NewEmptyApisixConfiguration())).
+			To(Succeed())
+		Expect(guard.Alter(ctx, s, config.GenStagePrimaryKey("gw", "test"), newStage("gw", "test", 9100))).To(Succeed())
+	})
+})
@@ -0,0 +1,177 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	"sort"
+
+	"github.com/spf13/cast"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+)
+
+// resourceRef 依赖图中的节点, 以资源类型和 id 唯一标识
+type resourceRef struct {
+	resourceType string
+	id           string
+}
+
+// stageResourceNodes 展开环境配置中的所有资源
+func stageResourceNodes(conf *entity.ApisixStageResource) map[resourceRef]entity.ApisixResource {
+	nodes := make(map[resourceRef]entity.ApisixResource)
+	if conf == nil {
+		return nodes
+	}
+	for id, route := range conf.Routes {
+		nodes[resourceRef{constant.ApisixResourceTypeRoutes, id}] = route
+	}
+	for id, service := range conf.Services {
+		nodes[resourceRef{constant.ApisixResourceTypeServices, id}] = service
+	}
+	for id, upstream := range conf.Upstreams {
+		nodes[resourceRef{constant.ApisixResourceTypeUpstreams, id}] = upstream
+	}
+	for id, pluginConfig := range conf.PluginConfigs {
+		nodes[resourceRef{constant.ApisixResourceTypePluginConfigs, id}] = pluginConfig
+	}
+	for id, consumer := range conf.Consumers {
+		nodes[resourceRef{constant.ApisixResourceTypeConsumers, id}] = consumer
+	}
+	for id, consumerGroup := range conf.ConsumerGroups {
+		nodes[resourceRef{constant.ApisixResourceTypeConsumerGroups, id}] = consumerGroup
+	}
+	for id, ssl := range conf.SSLs {
+		nodes[resourceRef{constant.ApisixResourceTypeSSL, id}] = ssl
+	}
+	for id, streamRoute := range conf.StreamRoutes {
+		nodes[resourceRef{constant.ApisixResourceTypeStreamRoutes, id}] = streamRoute
+	}
+	for id, proto := range conf.Protos {
+		nodes[resourceRef{constant.ApisixResourceTypeProtos, id}] = proto
+	}
+	return nodes
+}
+
+// resourceDependencies 返回资源通过 id 引用的其他资源
+func resourceDependencies(resource entity.ApisixResource) []resourceRef {
+	var deps []resourceRef
+	addDep := func(resourceType string, id any) {
+		if idStr := cast.ToString(id); idStr != "" {
+			deps = append(deps, resourceRef{resourceType, idStr})
+		}
+	}
+	addUpstreamDeps := func(upstream *entity.UpstreamDef, upstreamID any) {
+		addDep(constant.ApisixResourceTypeUpstreams, upstreamID)
+		if upstream != nil && upstream.TLS != nil {
+			addDep(constant.ApisixResourceTypeSSL, upstream.TLS.ClientCertId)
+		}
+	}
+	// grpc-transcode 插件通过 proto_id 引用 proto
+	addPluginDeps := func(plugins map[string]any) {
+		if conf, ok := plugins["grpc-transcode"].(map[string]any); ok {
+			addDep(constant.ApisixResourceTypeProtos, conf["proto_id"])
+		}
+	}
+
+	switch r := resource.(type) {
+	case *entity.Route:
+		addDep(constant.ApisixResourceTypeServices, r.ServiceID)
+		addDep(constant.ApisixResourceTypePluginConfigs, r.PluginConfigID)
+		addUpstreamDeps(r.Upstream, r.UpstreamID)
+		addPluginDeps(r.Plugins)
+	case *entity.Service:
+		addUpstreamDeps(r.Upstream, r.UpstreamID)
+		addPluginDeps(r.Plugins)
+	case *entity.PluginConfig:
+		addPluginDeps(r.Plugins)
+	case *entity.Upstream:
+		addUpstreamDeps(&r.UpstreamDef, nil)
+	case *entity.Consumer:
+		addDep(constant.ApisixResourceTypeConsumerGroups, r.GroupID)
+	case *entity.StreamRoute:
+		addDep(constant.ApisixResourceTypeServices, r.ServiceID)
+		addUpstreamDeps(r.Upstream, r.UpstreamID)
+	}
+	return deps
+}
+
+// dependencyLevels 按依赖关系对资源做拓扑分层: 第 0 层不依赖集合内的其他资源, 第 n 层只依赖前面各层的资源
+// 依赖集合外的资源不影响分层; 存在循环依赖时, 环上的资源放在最后一层
+func dependencyLevels(nodes map[resourceRef]entity.ApisixResource) [][]resourceRef {
+	inDegree := make(map[resourceRef]int, len(nodes))
+	dependents := make(map[resourceRef][]resourceRef)
+	for ref, resource := range nodes {
+		inDegree[ref] = 0
+		for _, dep := range resourceDependencies(resource) {
+			if _, ok := nodes[dep]; !ok || dep == ref {
+				continue
+			}
+			inDegree[ref]++
+			dependents[dep] = append(dependents[dep], ref)
+		}
+	}
+
+	var current []resourceRef
+	for ref, degree := range inDegree {
+		if degree == 0 {
+			current = append(current, ref)
+		}
+	}
+
+	levels := make([][]resourceRef, 0)
+	visited := 0
+	for len(current) > 0 {
+		sortResourceRefs(current)
+		levels = append(levels, current)
+		visited += len(current)
+
+		var next []resourceRef
+		for _, ref := range current {
+			for _, dependent := range dependents[ref] {
+				inDegree[dependent]--
+				if inDegree[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		current = next
+	}
+
+	if visited < len(nodes) {
+		var cycle []resourceRef
+		for ref, degree := range inDegree {
+			if degree > 0 {
+				cycle = append(cycle, ref)
+			}
+		}
+		sortResourceRefs(cycle)
+		levels = append(levels, cycle)
+	}
+	return levels
+}
+
+func sortResourceRefs(refs []resourceRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].resourceType != refs[j].resourceType {
+			return refs[i].resourceType < refs[j].resourceType
+		}
+		return refs[i].id < refs[j].id
+	})
+}
@@ -0,0 +1,189 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+)
+
+var _ = Describe("dependencyLevels", func() {
+	route := func(id string, serviceID any) *entity.Route {
+		return &entity.Route{ResourceMetadata: entity.ResourceMetadata{ID: id}, ServiceID: serviceID}
+	}
+	service := func(id, clientCertID string) *entity.Service {
+		svc := &entity.Service{ResourceMetadata: entity.ResourceMetadata{ID: id}}
+		if clientCertID != "" {
+			svc.Upstream = &entity.UpstreamDef{TLS: &entity.UpstreamTLS{ClientCertId: clientCertID}}
+		}
+		return svc
+	}
+	ssl := func(id string) *entity.SSL {
+		return &entity.SSL{ResourceMetadata: entity.ResourceMetadata{ID: id}}
+	}
+
+	It("should put the referenced resources before the routes", func() {
+		conf := &entity.ApisixStageResource{
+			Routes: map[string]*entity.Route{
+				"route-1": route("route-1", "service-1"),
+				"route-2": route("route-2", nil),
+			},
+			Services: map[string]*entity.Service{"service-1": service("service-1", "ssl-1")},
+			SSLs:     map[string]*entity.SSL{"ssl-1": ssl("ssl-1")},
+		}
+
+		levels := dependencyLevels(stageResourceNodes(conf))
+		Expect(levels).To(Equal([][]resourceRef{
+			{
+				{constant.ApisixResourceTypeRoutes, "route-2"},
+				{constant.ApisixResourceTypeSSL, "ssl-1"},
+			},
+			{{constant.ApisixResourceTypeServices, "service-1"}},
+			{{constant.ApisixResourceTypeRoutes, "route-1"}},
+		}))
+	})
+
+	It("should put the upstreams before the services and routes", func() {
+		upstream := &entity.Upstream{UpstreamDef: entity.UpstreamDef{
+			ResourceMetadata: entity.ResourceMetadata{ID: "upstream-1"},
+			TLS:              &entity.UpstreamTLS{ClientCertId: "ssl-1"},
+		}}
+		svc := service("service-1", "")
+		svc.UpstreamID = "upstream-1"
+		r := route("route-1", nil)
+		r.UpstreamID = "upstream-1"
+		conf := &entity.ApisixStageResource{
+			Routes:    map[string]*entity.Route{"route-1": r},
+			Services:  map[string]*entity.Service{"service-1": svc},
+			Upstreams: map[string]*entity.Upstream{"upstream-1": upstream},
+			SSLs:      map[string]*entity.SSL{"ssl-1": ssl("ssl-1")},
+		}
+
+		levels := dependencyLevels(stageResourceNodes(conf))
+		Expect(levels).To(Equal([][]resourceRef{
+			{{constant.ApisixResourceTypeSSL, "ssl-1"}},
+			{{constant.ApisixResourceTypeUpstreams, "upstream-1"}},
+			{
+				{constant.ApisixResourceTypeRoutes, "route-1"},
+				{constant.ApisixResourceTypeServices, "service-1"},
+			},
+		}))
+	})
+
+	It("should put the plugin configs before the routes", func() {
+		r := route("route-1", nil)
+		r.PluginConfigID = "plugin-config-1"
+		conf := &entity.ApisixStageResource{
+			Routes: map[string]*entity.Route{"route-1": r},
+			PluginConfigs: map[string]*entity.PluginConfig{
+				"plugin-config-1": {ResourceMetadata: entity.ResourceMetadata{ID: "plugin-config-1"}},
+			},
+		}
+
+		levels := dependencyLevels(stageResourceNodes(conf))
+		Expect(levels).To(Equal([][]resourceRef{
+			{{constant.ApisixResourceTypePluginConfigs, "plugin-config-1"}},
+			{{constant.ApisixResourceTypeRoutes, "route-1"}},
+		}))
+	})
+
+	It("should put the consumer groups before the consumers", func() {
+		conf := &entity.ApisixStageResource{
+			Consumers: map[string]*entity.Consumer{
+				"app": {Username: "app", GroupID: "group-1"},
+			},
+			ConsumerGroups: map[string]*entity.ConsumerGroup{
+				"group-1": {ResourceMetadata: entity.ResourceMetadata{ID: "group-1"}},
+			},
+		}
+
+		levels := dependencyLevels(stageResourceNodes(conf))
+		Expect(levels).To(Equal([][]resourceRef{
+			{{constant.ApisixResourceTypeConsumerGroups, "group-1"}},
+			{{constant.ApisixResourceTypeConsumers, "app"}},
+		}))
+	})
+
+	It("should put the upstreams and services before the stream routes", func() {
+		upstream := &entity.Upstream{UpstreamDef: entity.UpstreamDef{
+			ResourceMetadata: entity.ResourceMetadata{ID: "upstream-1"},
+		}}
+		conf := &entity.ApisixStageResource{
+			StreamRoutes: map[string]*entity.StreamRoute{
+				"stream-route-1": {
+					ResourceMetadata: entity.ResourceMetadata{ID: "stream-route-1"},
+					UpstreamID:       "upstream-1",
+				},
+				"stream-route-2": {
+					ResourceMetadata: entity.ResourceMetadata{ID: "stream-route-2"},
+					ServiceID:        "service-1",
+				},
+			},
+			Services:  map[string]*entity.Service{"service-1": service("service-1", "")},
+			Upstreams: map[string]*entity.Upstream{"upstream-1": upstream},
+		}
+
+		levels := dependencyLevels(stageResourceNodes(conf))
+		Expect(levels).To(Equal([][]resourceRef{
+			{
+				{constant.ApisixResourceTypeServices, "service-1"},
+				{constant.ApisixResourceTypeUpstreams, "upstream-1"},
+			},
+			{
+				{constant.ApisixResourceTypeStreamRoutes, "stream-route-1"},
+				{constant.ApisixResourceTypeStreamRoutes, "stream-route-2"},
+			},
+		}))
+	})
+
+	It("should put the protos before the routes referencing them by grpc-transcode", func() {
+		grpcRoute := route("route-1", "")
+		grpcRoute.Plugins = map[string]any{
+			"grpc-transcode": map[string]any{"proto_id": "proto-1", "service": "helloworld.Greeter"},
+		}
+		conf := &entity.ApisixStageResource{
+			Routes: map[string]*entity.Route{"route-1": grpcRoute},
+			Protos: map[string]*entity.Proto{
+				"proto-1": {ResourceMetadata: entity.ResourceMetadata{ID: "proto-1"}},
+			},
+		}
+
+		levels := dependencyLevels(stageResourceNodes(conf))
+		Expect(levels).To(Equal([][]resourceRef{
+			{{constant.ApisixResourceTypeProtos, "proto-1"}},
+			{{constant.ApisixResourceTypeRoutes, "route-1"}},
+		}))
+	})
+
+	It("should ignore the references outside the resource set", func() {
+		conf := &entity.ApisixStageResource{
+			Routes: map[string]*entity.Route{"route-1": route("route-1", "service-1")},
+		}
+
+		levels := dependencyLevels(stageResourceNodes(conf))
+		Expect(levels).To(Equal([][]resourceRef{{{constant.ApisixResourceTypeRoutes, "route-1"}}}))
+	})
+
+	It("should return empty levels for nil config", func() {
+		Expect(dependencyLevels(stageResourceNodes(nil))).To(BeEmpty())
+	})
+})
@@ -0,0 +1,193 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/differ"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+)
+
+// StageDiff 一个环境的期望配置与已有配置之间的差异, key 为资源类型, value 为资源 id 列表
+type StageDiff struct {
+	// StageKey 环境资源为 stage key, 全局资源为 global_resource
+	StageKey  string              `json:"stage_key"`
+	Put       map[string][]string `json:"put,omitempty"`
+	Delete    map[string][]string `json:"delete,omitempty"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// IsEmpty ...
+func (d *StageDiff) IsEmpty() bool {
+	return len(d.Put) == 0 && len(d.Delete) == 0
+}
+
+func newStageDiff(stageKey string, put, toDelete *entity.ApisixStageResource) *StageDiff {
+	return &StageDiff{
+		StageKey:  stageKey,
+		Put:       stageResourceIDs(put),
+		Delete:    stageResourceIDs(toDelete),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func newGlobalDiff(put, toDelete *entity.ApisixGlobalResource) *StageDiff {
+	return &StageDiff{
+		StageKey:  constant.GlobalResourceKey,
+		Put:       globalResourceIDs(put),
+		Delete:    globalResourceIDs(toDelete),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// Diff 对比环境的期望配置与缓存中的配置, 不写入 apisix etcd
+func (s *ApisixEtcdStore) Diff(stageKey string, conf *entity.ApisixStageResource) *StageDiff {
+	put, toDelete := s.differ.Diff(s.Get(stageKey), conf)
+	return newStageDiff(stageKey, put, toDelete)
+}
+
+// DiffGlobal 对比全局资源的期望配置与缓存中的配置, 不写入 apisix etcd
+func (s *ApisixEtcdStore) DiffGlobal(conf *entity.ApisixGlobalResource) *StageDiff {
+	return newGlobalDiff(s.differ.DiffGlobal(s.GetGlobal(), conf))
+}
+
+// shadowRecorder 只记录模式下, 记录每个环境最后一次将要写入 apisix etcd 的变更
+type shadowRecorder struct {
+	mux     sync.RWMutex
+	records map[string]*StageDiff
+}
+
+func (r *shadowRecorder) record(diff *StageDiff) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	// 没有变更说明与线上配置一致
+	if diff.IsEmpty() {
+		delete(r.records, diff.StageKey)
+		return
+	}
+	r.records[diff.StageKey] = diff
+}
+
+func (r *shadowRecorder) list() []*StageDiff {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	return sortStageDiffs(slices.Collect(maps.Values(r.records)))
+}
+
+// EnableRecordOnly 开启只记录模式: 照常计算变更, 但是不写入 apisix etcd, 用于影子模式
+func (s *ApisixEtcdStore) EnableRecordOnly() {
+	s.recorder = &shadowRecorder{records: make(map[string]*StageDiff)}
+}
+
+// ShadowReporter 逐个环境对比影子配置与线上配置
+type ShadowReporter struct {
+	live   *ApisixEtcdStore
+	shadow *ApisixEtcdStore
+	differ *differ.ConfigDiffer
+}
+
+// NewShadowReporter live 为 nil 时, shadow 为只记录模式的 store, 报告其记录的变更
+func NewShadowReporter(live, shadow *ApisixEtcdStore) *ShadowReporter {
+	return &ShadowReporter{
+		live:   live,
+		shadow: shadow,
+		differ: differ.NewConfigDiffer(),
+	}
+}
+
+// Report 返回影子配置与线上配置存在差异的环境
+func (r *ShadowReporter) Report() []*StageDiff {
+	if r.live == nil {
+		if r.shadow.recorder == nil {
+			return []*StageDiff{}
+		}
+		return r.shadow.recorder.list()
+	}
+
+	liveStages := r.live.GetAll()
+	shadowStages := r.shadow.GetAll()
+	stageKeys := make(map[string]struct{}, len(liveStages))
+	for stageKey := range liveStages {
+		stageKeys[stageKey] = struct{}{}
+	}
+	for stageKey := range shadowStages {
+		stageKeys[stageKey] = struct{}{}
+	}
+
+	diffs := make([]*StageDiff, 0)
+	for stageKey := range stageKeys {
+		liveConf, shadowConf := liveStages[stageKey], shadowStages[stageKey]
+		if liveConf == nil {
+			liveConf = entity.NewEmptyApisixConfiguration()
+		}
+		if shadowConf == nil {
+			shadowConf = entity.NewEmptyApisixConfiguration()
+		}
+		put, toDelete := r.differ.Diff(liveConf, shadowConf)
+		diff := newStageDiff(stageKey, put, toDelete)
+		if !diff.IsEmpty() {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	globalDiff := newGlobalDiff(r.differ.DiffGlobal(r.live.GetGlobal(), r.shadow.GetGlobal()))
+	if !globalDiff.IsEmpty() {
+		diffs = append(diffs, globalDiff)
+	}
+	return sortStageDiffs(diffs)
+}
+
+// stageResourceIDs 按资源类型汇总资源 id
+func stageResourceIDs(conf *entity.ApisixStageResource) map[string][]string {
+	ids := make(map[string][]string)
+	for ref := range stageResourceNodes(conf) {
+		ids[ref.resourceType] = append(ids[ref.resourceType], ref.id)
+	}
+	for _, list := range ids {
+		sort.Strings(list)
+	}
+	return ids
+}
+
+func globalResourceIDs(conf *entity.ApisixGlobalResource) map[string][]string {
+	ids := make(map[string][]string)
+	if conf == nil {
+		return ids
+	}
+	if len(conf.PluginMetadata) > 0 {
+		ids[constant.ApisixResourceTypePluginMetadata] = slices.Sorted(maps.Keys(conf.PluginMetadata))
+	}
+	if len(conf.GlobalRules) > 0 {
+		ids[constant.ApisixResourceTypeGlobalRules] = slices.Sorted(maps.Keys(conf.GlobalRules))
+	}
+	return ids
+}
+
+func sortStageDiffs(diffs []*StageDiff) []*StageDiff {
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].StageKey < diffs[j].StageKey
+	})
+	return diffs
+}
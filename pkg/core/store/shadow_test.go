@@ -0,0 +1,144 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	"context"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+var _ = Describe("Shadow mode with EmbedEtcd", func() {
+	var (
+		ctx       context.Context
+		etcd      *embed.Etcd
+		client    *clientv3.Client
+		liveStore *ApisixEtcdStore
+		stageKey  string
+	)
+
+	newStageConf := func(uris ...string) *entity.ApisixStageResource {
+		conf := entity.NewEmptyApisixConfiguration()
+		for i, uri := range uris {
+			id := []string{"route-1", "route-2"}[i]
+			conf.Routes[id] = &entity.Route{
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:     id,
+					Labels: &entity.LabelInfo{Gateway: "gw", Stage: "prod"},
+				},
+				URI: uri,
+			}
+		}
+		return conf
+	}
+
+	waitCached := func(s *ApisixEtcdStore, count int) {
+		Eventually(func() int {
+			return len(s.Get(stageKey).Routes)
+		}, 5*time.Second, 50*time.Millisecond).Should(Equal(count))
+	}
+
+	newStore := func(prefix string) *ApisixEtcdStore {
+		s, err := NewApisixEtcdStore(ctx, client, prefix, time.Millisecond, time.Millisecond, 5*time.Second)
+		Expect(err).ShouldNot(HaveOccurred())
+		DeferCleanup(s.Close)
+		return s
+	}
+
+	BeforeEach(func() {
+		var err error
+		if !metricInitialized {
+			metric.InitMetric(prometheus.NewRegistry())
+			metricInitialized = true
+		}
+		ctx = context.Background()
+		etcd, client, err = startTestEtcd()
+		Expect(err).ShouldNot(HaveOccurred())
+		stageKey = config.GenStagePrimaryKey("gw", "prod")
+
+		liveStore = newStore("/apisix")
+		Expect(liveStore.Alter(ctx, stageKey, newStageConf("/foo"))).To(Succeed())
+		waitCached(liveStore, 1)
+	})
+
+	AfterEach(func() {
+		client.Close()
+		etcd.Close()
+		_ = os.RemoveAll(etcd.Config().Dir)
+	})
+
+	It("should only record the diffs in record only mode", func() {
+		recordStore := newStore("/apisix")
+		recordStore.EnableRecordOnly()
+		waitCached(recordStore, 1)
+		reporter := NewShadowReporter(nil, recordStore)
+
+		Expect(recordStore.Alter(ctx, stageKey, newStageConf("/bar", "/baz"))).To(Succeed())
+		resp, err := client.Get(ctx, "/apisix/routes/", clientv3.WithPrefix())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.Kvs).To(HaveLen(1))
+
+		report := reporter.Report()
+		Expect(report).To(HaveLen(1))
+		Expect(report[0].StageKey).To(Equal(stageKey))
+		Expect(report[0].Put).To(Equal(map[string][]string{
+			constant.ApisixResourceTypeRoutes: {"route-1", "route-2"},
+		}))
+		Expect(report[0].Delete).To(BeEmpty())
+
+		// 与线上一致时不再报告该环境
+		Expect(recordStore.Alter(ctx, stageKey, newStageConf("/foo"))).To(Succeed())
+		Expect(reporter.Report()).To(BeEmpty())
+	})
+
+	It("should compare the shadow prefix with the live prefix", func() {
+		shadowStore := newStore("/apisix-shadow")
+		reporter := NewShadowReporter(liveStore, shadowStore)
+
+		Expect(shadowStore.Alter(ctx, stageKey, newStageConf("/foo"))).To(Succeed())
+		waitCached(shadowStore, 1)
+		Expect(reporter.Report()).To(BeEmpty())
+
+		Expect(shadowStore.Alter(ctx, stageKey, entity.NewEmptyApisixConfiguration())).To(Succeed())
+		Eventually(func() map[string][]string {
+			report := reporter.Report()
+			if len(report) == 0 {
+				return nil
+			}
+			return report[0].Delete
+		}, 5*time.Second, 50*time.Millisecond).Should(Equal(map[string][]string{
+			constant.ApisixResourceTypeRoutes: {"route-1"},
+		}))
+
+		resp, err := client.Get(ctx, "/apisix/routes/route-1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.Kvs).To(HaveLen(1))
+	})
+})
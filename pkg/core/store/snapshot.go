@@ -0,0 +1,292 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/spf13/cast"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+)
+
+// ErrSnapshotNotFound 指定环境不存在该发布版本的快照
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot 一个环境某次发布成功写入 apisix 的配置
+type Snapshot struct {
+	Gateway   string                      `json:"gateway"`
+	Stage     string                      `json:"stage"`
+	PublishID string                      `json:"publish_id"`
+	CreatedAt time.Time                   `json:"created_at"`
+	Resources *entity.ApisixStageResource `json:"resources"`
+}
+
+// NewSnapshot 生成环境配置的快照, 写入 apisix etcd 时会修改资源, 所以这里先深拷贝一份
+func NewSnapshot(gatewayName, stageName, publishID string, conf *entity.ApisixStageResource) (*Snapshot, error) {
+	resources, err := CopyStageResource(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		Gateway:   gatewayName,
+		Stage:     stageName,
+		PublishID: publishID,
+		CreatedAt: time.Now(),
+		Resources: resources,
+	}, nil
+}
+
+// CopyStageResource 深拷贝环境配置, Alter 会修改传入的资源, 同一份配置写入多处时需要各自拷贝
+func CopyStageResource(conf *entity.ApisixStageResource) (*entity.ApisixStageResource, error) {
+	bytes, err := json.Marshal(conf)
+	if err != nil {
+		return nil, fmt.Errorf("marshal stage resources failed: %w", err)
+	}
+	resources := entity.NewEmptyApisixConfiguration()
+	if err = json.Unmarshal(bytes, resources); err != nil {
+		return nil, fmt.Errorf("unmarshal stage resources failed: %w", err)
+	}
+	return resources, nil
+}
+
+// CopyGlobalResource 深拷贝全局资源配置, plugin metadata 序列化后会丢失元数据, 所以逐个拷贝
+func CopyGlobalResource(conf *entity.ApisixGlobalResource) *entity.ApisixGlobalResource {
+	resources := entity.NewEmptyApisixGlobalResource()
+	for key, pm := range conf.PluginMetadata {
+		metadata := pm.ResourceMetadata
+		if pm.Labels != nil {
+			labels := *pm.Labels
+			metadata.Labels = &labels
+		}
+		pluginConf := make(entity.PluginMetadataConf, len(pm.PluginMetadataConf))
+		for name, raw := range pm.PluginMetadataConf {
+			pluginConf[name] = raw
+		}
+		resources.PluginMetadata[key] = &entity.PluginMetadata{
+			ResourceMetadata:   metadata,
+			PluginMetadataConf: pluginConf,
+		}
+	}
+	for key, globalRule := range conf.GlobalRules {
+		rule := *globalRule
+		// 插件配置通过序列化深拷贝, 元数据直接复制
+		if bytes, err := json.Marshal(globalRule.Plugins); err == nil {
+			rule.Plugins = nil
+			_ = json.Unmarshal(bytes, &rule.Plugins)
+		}
+		resources.GlobalRules[key] = &rule
+	}
+	return resources
+}
+
+// StagePublishID 从资源的 label 中获取环境配置的发布版本, 取最大的 publish id
+func StagePublishID(conf *entity.ApisixStageResource) string {
+	publishID := ""
+	check := func(metadata entity.ResourceMetadata) {
+		if metadata.Labels == nil || metadata.Labels.PublishId == "" {
+			return
+		}
+		if publishID == "" || cast.ToInt(metadata.Labels.PublishId) > cast.ToInt(publishID) {
+			publishID = metadata.Labels.PublishId
+		}
+	}
+	for _, route := range conf.Routes {
+		check(route.ResourceMetadata)
+	}
+	for _, service := range conf.Services {
+		check(service.ResourceMetadata)
+	}
+	for _, upstream := range conf.Upstreams {
+		check(upstream.ResourceMetadata)
+	}
+	for _, pluginConfig := range conf.PluginConfigs {
+		check(pluginConfig.ResourceMetadata)
+	}
+	for _, consumer := range conf.Consumers {
+		check(consumer.ResourceMetadata)
+	}
+	for _, consumerGroup := range conf.ConsumerGroups {
+		check(consumerGroup.ResourceMetadata)
+	}
+	for _, ssl := range conf.SSLs {
+		check(ssl.ResourceMetadata)
+	}
+	for _, streamRoute := range conf.StreamRoutes {
+		check(streamRoute.ResourceMetadata)
+	}
+	for _, proto := range conf.Protos {
+		check(proto.ResourceMetadata)
+	}
+	return publishID
+}
+
+// SnapshotStore 在 etcd 的独立前缀下按环境保存最近 maxCount 次发布的快照
+//
+//	{prefix}/snapshots/{gateway}/{stage}/{publish_id}: 快照
+//	{prefix}/pins/{gateway}/{stage}: 回滚时被撤下的发布版本, 在新的发布到来之前不再应用
+type SnapshotStore struct {
+	client   *clientv3.Client
+	prefix   string
+	maxCount int
+	timeout  time.Duration
+
+	logger *zap.SugaredLogger
+}
+
+// NewSnapshotStore ...
+func NewSnapshotStore(client *clientv3.Client, prefix string, maxCount int, timeout time.Duration) *SnapshotStore {
+	return &SnapshotStore{
+		client:   client,
+		prefix:   strings.TrimRight(prefix, "/"),
+		maxCount: maxCount,
+		timeout:  timeout,
+		logger:   logging.GetLogger().Named("snapshot-store"),
+	}
+}
+
+func (s *SnapshotStore) snapshotPrefix(gatewayName, stageName string) string {
+	return path.Join(s.prefix, "snapshots", gatewayName, stageName) + "/"
+}
+
+func (s *SnapshotStore) pinPrefix() string {
+	return s.prefix + "/pins/"
+}
+
+// Save 保存快照, 同一发布版本覆盖写入, 并清理超出 maxCount 的旧快照
+func (s *SnapshotStore) Save(ctx context.Context, snapshot *Snapshot) error {
+	bytes, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("marshal snapshot failed: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stagePrefix := s.snapshotPrefix(snapshot.Gateway, snapshot.Stage)
+	if _, err = s.client.Put(ctx, stagePrefix+snapshot.PublishID, string(bytes)); err != nil {
+		return fmt.Errorf("put snapshot failed: %w", err)
+	}
+
+	resp, err := s.client.Get(ctx, stagePrefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return fmt.Errorf("list snapshots failed: %w", err)
+	}
+	if len(resp.Kvs) <= s.maxCount {
+		return nil
+	}
+	kvs := resp.Kvs
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].ModRevision > kvs[j].ModRevision
+	})
+	for _, kv := range kvs[s.maxCount:] {
+		if _, err = s.client.Delete(ctx, string(kv.Key)); err != nil {
+			return fmt.Errorf("delete expired snapshot failed: %w", err)
+		}
+		s.logger.Debugw("delete expired snapshot", "key", string(kv.Key))
+	}
+	return nil
+}
+
+// List 返回环境的全部快照, 最近应用的在前
+func (s *SnapshotStore) List(ctx context.Context, gatewayName, stageName string) ([]*Snapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	resp, err := s.client.Get(ctx, s.snapshotPrefix(gatewayName, stageName), clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots failed: %w", err)
+	}
+	kvs := resp.Kvs
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].ModRevision > kvs[j].ModRevision
+	})
+	snapshots := make([]*Snapshot, 0, len(kvs))
+	for _, kv := range kvs {
+		snapshot := &Snapshot{}
+		if err = json.Unmarshal(kv.Value, snapshot); err != nil {
+			s.logger.Errorw("unmarshal snapshot failed", "key", string(kv.Key), "err", err)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// Get 获取环境指定发布版本的快照
+func (s *SnapshotStore) Get(ctx context.Context, gatewayName, stageName, publishID string) (*Snapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	resp, err := s.client.Get(ctx, s.snapshotPrefix(gatewayName, stageName)+publishID)
+	if err != nil {
+		return nil, fmt.Errorf("get snapshot failed: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, ErrSnapshotNotFound
+	}
+	snapshot := &Snapshot{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, snapshot); err != nil {
+		return nil, fmt.Errorf("unmarshal snapshot failed: %w", err)
+	}
+	return snapshot, nil
+}
+
+// Pin 记录回滚时被撤下的发布版本
+func (s *SnapshotStore) Pin(ctx context.Context, stageKey, publishID string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.client.Put(ctx, s.pinPrefix()+stageKey, publishID)
+	return err
+}
+
+// Unpin 新的发布到来后清理环境的回滚记录
+func (s *SnapshotStore) Unpin(ctx context.Context, stageKey string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.client.Delete(ctx, s.pinPrefix()+stageKey)
+	return err
+}
+
+// Pins 返回全部环境的回滚记录, key 为环境的 stage key, value 为被撤下的发布版本
+func (s *SnapshotStore) Pins(ctx context.Context) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	resp, err := s.client.Get(ctx, s.pinPrefix(), clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("list pins failed: %w", err)
+	}
+	pins := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		pins[strings.TrimPrefix(string(kv.Key), s.pinPrefix())] = string(kv.Value)
+	}
+	return pins, nil
+}
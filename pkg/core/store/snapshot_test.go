@@ -0,0 +1,67 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+)
+
+var _ = Describe("Snapshot", func() {
+	newConf := func() *entity.ApisixStageResource {
+		conf := entity.NewEmptyApisixConfiguration()
+		conf.Routes["route-1"] = &entity.Route{
+			ResourceMetadata: entity.ResourceMetadata{
+				ID:     "route-1",
+				Labels: &entity.LabelInfo{Gateway: "gw", Stage: "prod", PublishId: "9"},
+			},
+			URI: "/foo",
+		}
+		conf.Services["service-1"] = &entity.Service{
+			ResourceMetadata: entity.ResourceMetadata{
+				ID:     "service-1",
+				Labels: &entity.LabelInfo{Gateway: "gw", Stage: "prod", PublishId: "10"},
+			},
+		}
+		conf.SSLs["ssl-1"] = &entity.SSL{
+			ResourceMetadata: entity.ResourceMetadata{ID: "ssl-1"},
+		}
+		return conf
+	}
+
+	It("should use the max publish id of the resources", func() {
+		Expect(StagePublishID(newConf())).To(Equal("10"))
+		Expect(StagePublishID(entity.NewEmptyApisixConfiguration())).To(BeEmpty())
+	})
+
+	It("should not be affected by the changes of the applied resources", func() {
+		conf := newConf()
+		snapshot, err := NewSnapshot("gw", "prod", "10", conf)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		conf.Routes["route-1"].ClearUnusedFields()
+		conf.Routes["route-1"].URI = "/bar"
+		Expect(snapshot.Resources.Routes["route-1"].URI).To(Equal("/foo"))
+		Expect(snapshot.Resources.Routes["route-1"].Labels.PublishId).To(Equal("9"))
+		Expect(snapshot.Resources.Services).To(HaveKey("service-1"))
+		Expect(snapshot.Resources.SSLs).To(HaveKey("ssl-1"))
+	})
+})
@@ -0,0 +1,534 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/differ"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+// standaloneEndMarker apisix standalone 模式只加载以 #END 结尾的完整文件
+const standaloneEndMarker = "#END"
+
+// standaloneConfig apisix.yaml 的内容, 资源按 id 排序, 保证相同的配置渲染出相同的文件
+type standaloneConfig struct {
+	Routes         []map[string]any `yaml:"routes"`
+	Services       []map[string]any `yaml:"services"`
+	Upstreams      []map[string]any `yaml:"upstreams"`
+	PluginConfigs  []map[string]any `yaml:"plugin_configs"`
+	Consumers      []map[string]any `yaml:"consumers"`
+	ConsumerGroups []map[string]any `yaml:"consumer_groups"`
+	SSLs           []map[string]any `yaml:"ssls"`
+	StreamRoutes   []map[string]any `yaml:"stream_routes"`
+	Protos         []map[string]any `yaml:"protos"`
+	PluginMetadata []map[string]any `yaml:"plugin_metadata"`
+	GlobalRules    []map[string]any `yaml:"global_rules"`
+}
+
+// ApisixStandaloneStore 将所有环境、全局 plugin metadata 和虚拟环境渲染为 apisix standalone 模式的 apisix.yaml,
+// 用于没有 etcd 的边缘节点; 每次写入有变更时重新生成文件, 先写临时文件再 rename, apisix 不会读到不完整的文件
+type ApisixStandaloneStore struct {
+	path       string
+	gatewayDir string
+	differ     *differ.ConfigDiffer
+
+	mux    sync.RWMutex
+	stages map[string]*entity.ApisixStageResource
+	global *entity.ApisixGlobalResource
+
+	logger *zap.SugaredLogger
+}
+
+// NewApisixStandaloneStore 创建 standalone store, 已有的 apisix.yaml 作为初始缓存, 避免重启后覆盖未同步的环境
+func NewApisixStandaloneStore(standalone *config.Standalone) (*ApisixStandaloneStore, error) {
+	s := &ApisixStandaloneStore{
+		path:       standalone.Path,
+		gatewayDir: standalone.GatewayDir,
+		differ:     differ.NewConfigDiffer(),
+		stages:     make(map[string]*entity.ApisixStageResource),
+		global:     entity.NewEmptyApisixGlobalResource(),
+		logger:     logging.GetLogger().Named("standalone-config-store"),
+	}
+	if err := s.load(); err != nil {
+		return nil, fmt.Errorf("load %s failed: %w", s.path, err)
+	}
+	s.logger.Infow("Create standalone config store", "path", s.path, "gatewayDir", s.gatewayDir)
+	return s, nil
+}
+
+// load 从已有的 apisix.yaml 加载资源
+func (s *ApisixStandaloneStore) load() error {
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var conf standaloneConfig
+	if err = yaml.Unmarshal(content, &conf); err != nil {
+		return err
+	}
+
+	items := map[string][]map[string]any{
+		constant.ApisixResourceTypeRoutes:         conf.Routes,
+		constant.ApisixResourceTypeServices:       conf.Services,
+		constant.ApisixResourceTypeUpstreams:      conf.Upstreams,
+		constant.ApisixResourceTypePluginConfigs:  conf.PluginConfigs,
+		constant.ApisixResourceTypeConsumers:      conf.Consumers,
+		constant.ApisixResourceTypeConsumerGroups: conf.ConsumerGroups,
+		constant.ApisixResourceTypeSSL:            conf.SSLs,
+		constant.ApisixResourceTypeStreamRoutes:   conf.StreamRoutes,
+		constant.ApisixResourceTypeProtos:         conf.Protos,
+		constant.ApisixResourceTypePluginMetadata: conf.PluginMetadata,
+		constant.ApisixResourceTypeGlobalRules:    conf.GlobalRules,
+	}
+	for resourceType, values := range items {
+		for _, value := range values {
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			resource, err := registry.UnmarshalApisixResource(resourceType, raw)
+			if err != nil {
+				return err
+			}
+			s.add(resourceType, resource)
+		}
+	}
+	return nil
+}
+
+// add 将资源加入缓存, 调用方需要持有写锁或者在初始化时调用
+func (s *ApisixStandaloneStore) add(resourceType string, resource entity.ApisixResource) {
+	switch resourceType {
+	case constant.ApisixResourceTypePluginMetadata:
+		s.global.PluginMetadata[resource.GetID()] = resource.(*entity.PluginMetadata) //nolint:forcetypeassert
+		return
+	case constant.ApisixResourceTypeGlobalRules:
+		s.global.GlobalRules[resource.GetID()] = resource.(*entity.GlobalRule) //nolint:forcetypeassert
+		return
+	}
+	stageKey := resource.GetStageKey()
+	if _, ok := s.stages[stageKey]; !ok {
+		s.stages[stageKey] = entity.NewEmptyApisixConfiguration()
+	}
+	switch r := resource.(type) {
+	case *entity.Route:
+		s.stages[stageKey].Routes[r.GetID()] = r
+	case *entity.Service:
+		s.stages[stageKey].Services[r.GetID()] = r
+	case *entity.Upstream:
+		s.stages[stageKey].Upstreams[r.GetID()] = r
+	case *entity.PluginConfig:
+		s.stages[stageKey].PluginConfigs[r.GetID()] = r
+	case *entity.Consumer:
+		s.stages[stageKey].Consumers[r.GetID()] = r
+	case *entity.ConsumerGroup:
+		s.stages[stageKey].ConsumerGroups[r.GetID()] = r
+	case *entity.SSL:
+		s.stages[stageKey].SSLs[r.GetID()] = r
+	case *entity.StreamRoute:
+		s.stages[stageKey].StreamRoutes[r.GetID()] = r
+	case *entity.Proto:
+		s.stages[stageKey].Protos[r.GetID()] = r
+	}
+}
+
+// Prefix returns the path of the apisix.yaml
+func (s *ApisixStandaloneStore) Prefix() string {
+	return s.path
+}
+
+// Close ...
+func (s *ApisixStandaloneStore) Close() {}
+
+// Get get a staged apisix configuration
+func (s *ApisixStandaloneStore) Get(stageKey string) *entity.ApisixStageResource {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return copyStage(s.stages[stageKey])
+}
+
+// GetAll get staged apisix configuration map, key is the stage key
+func (s *ApisixStandaloneStore) GetAll() map[string]*entity.ApisixStageResource {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	configMap := make(map[string]*entity.ApisixStageResource, len(s.stages))
+	for stageKey, conf := range s.stages {
+		configMap[stageKey] = copyStage(conf)
+	}
+	return configMap
+}
+
+// GetGlobal 获取全局资源配置
+func (s *ApisixStandaloneStore) GetGlobal() *entity.ApisixGlobalResource {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return &entity.ApisixGlobalResource{
+		PluginMetadata: maps.Clone(s.global.PluginMetadata),
+		GlobalRules:    maps.Clone(s.global.GlobalRules),
+	}
+}
+
+// copyStage 复制资源 map, 资源本身不复制
+func copyStage(conf *entity.ApisixStageResource) *entity.ApisixStageResource {
+	ret := entity.NewEmptyApisixConfiguration()
+	if conf == nil {
+		return ret
+	}
+	maps.Copy(ret.Routes, conf.Routes)
+	maps.Copy(ret.Services, conf.Services)
+	maps.Copy(ret.Upstreams, conf.Upstreams)
+	maps.Copy(ret.PluginConfigs, conf.PluginConfigs)
+	maps.Copy(ret.Consumers, conf.Consumers)
+	maps.Copy(ret.ConsumerGroups, conf.ConsumerGroups)
+	maps.Copy(ret.SSLs, conf.SSLs)
+	maps.Copy(ret.StreamRoutes, conf.StreamRoutes)
+	maps.Copy(ret.Protos, conf.Protos)
+	return ret
+}
+
+// Diff 对比环境的期望配置与缓存中的配置, 不写入文件
+func (s *ApisixStandaloneStore) Diff(stageKey string, conf *entity.ApisixStageResource) *StageDiff {
+	put, toDelete := s.differ.Diff(s.Get(stageKey), conf)
+	return newStageDiff(stageKey, put, toDelete)
+}
+
+// DiffGlobal 对比全局资源的期望配置与缓存中的配置, 不写入文件
+func (s *ApisixStandaloneStore) DiffGlobal(conf *entity.ApisixGlobalResource) *StageDiff {
+	return newGlobalDiff(s.differ.DiffGlobal(s.GetGlobal(), conf))
+}
+
+// Alter 更新环境配置并重新生成文件, 没有变更时不写入
+func (s *ApisixStandaloneStore) Alter(ctx context.Context, stageKey string, conf *entity.ApisixStageResource) error {
+	st := time.Now()
+	err := s.alterStage(stageKey, conf)
+	metric.ReportStageConfigAlterMetric(stageKey, conf, st, err)
+	if err != nil {
+		s.logger.Errorw("Alter by stage failed", "err", err, "stage", stageKey)
+		return err
+	}
+	return nil
+}
+
+func (s *ApisixStandaloneStore) alterStage(stageKey string, conf *entity.ApisixStageResource) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if conf == nil {
+		conf = entity.NewEmptyApisixConfiguration()
+	}
+	old := copyStage(s.stages[stageKey])
+	put, toDelete := s.differ.Diff(old, conf)
+	if len(stageResourceNodes(put))+len(stageResourceNodes(toDelete)) == 0 {
+		s.logger.Infof("%s has no change", stageKey)
+		return nil
+	}
+
+	next := &entity.ApisixStageResource{
+		Routes:         applyDiff(old.Routes, put.Routes, toDelete.Routes),
+		Services:       applyDiff(old.Services, put.Services, toDelete.Services),
+		Upstreams:      applyDiff(old.Upstreams, put.Upstreams, toDelete.Upstreams),
+		PluginConfigs:  applyDiff(old.PluginConfigs, put.PluginConfigs, toDelete.PluginConfigs),
+		Consumers:      applyDiff(old.Consumers, put.Consumers, toDelete.Consumers),
+		ConsumerGroups: applyDiff(old.ConsumerGroups, put.ConsumerGroups, toDelete.ConsumerGroups),
+		SSLs:           applyDiff(old.SSLs, put.SSLs, toDelete.SSLs),
+		StreamRoutes:   applyDiff(old.StreamRoutes, put.StreamRoutes, toDelete.StreamRoutes),
+		Protos:         applyDiff(old.Protos, put.Protos, toDelete.Protos),
+	}
+	// 文件写入失败时保留原来的缓存, 下一次同步重新 diff
+	stages := maps.Clone(s.stages)
+	if len(next.Resources()) == 0 {
+		delete(stages, stageKey)
+	} else {
+		stages[stageKey] = next
+	}
+
+	gateways := []string{stageGateway(old), stageGateway(next)}
+	if stageKey == config.VirtualStageKey {
+		// 虚拟环境包含在每个网关的文件中
+		gateways = nil
+	}
+	if err := s.flush(stages, s.global, gateways); err != nil {
+		return err
+	}
+	s.stages = stages
+	return nil
+}
+
+// AlterGlobal 更新全局资源配置并重新生成文件
+func (s *ApisixStandaloneStore) AlterGlobal(ctx context.Context, conf *entity.ApisixGlobalResource) error {
+	st := time.Now()
+	err := s.alterGlobal(conf)
+	metric.ReportStageConfigAlterMetric(config.GenStagePrimaryKey("apigw", "global_resource"), nil, st, err)
+	if err != nil {
+		s.logger.Errorw("Alter global resource failed", "err", err)
+		return err
+	}
+	return nil
+}
+
+func (s *ApisixStandaloneStore) alterGlobal(conf *entity.ApisixGlobalResource) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if conf == nil {
+		conf = entity.NewEmptyApisixGlobalResource()
+	}
+	put, toDelete := s.differ.DiffGlobal(s.global, conf)
+	if len(put.PluginMetadata)+len(toDelete.PluginMetadata)+len(put.GlobalRules)+len(toDelete.GlobalRules) == 0 {
+		s.logger.Infof("global resource has no change")
+		return nil
+	}
+	global := &entity.ApisixGlobalResource{
+		PluginMetadata: applyDiff(s.global.PluginMetadata, put.PluginMetadata, toDelete.PluginMetadata),
+		GlobalRules:    applyDiff(s.global.GlobalRules, put.GlobalRules, toDelete.GlobalRules),
+	}
+	if err := s.flush(s.stages, global, nil); err != nil {
+		return err
+	}
+	s.global = global
+	return nil
+}
+
+// applyDiff 在 current 的基础上写入 put 并删除 toDelete, 返回新的 map
+func applyDiff[T entity.ApisixResource](current, put, toDelete map[string]T) map[string]T {
+	next := make(map[string]T, len(current)+len(put))
+	maps.Copy(next, current)
+	for id, resource := range put {
+		touchResource(resource)
+		next[id] = resource
+	}
+	for id := range toDelete {
+		delete(next, id)
+	}
+	return next
+}
+
+// stageGateway 环境所属的网关, 空环境返回空字符串
+func stageGateway(conf *entity.ApisixStageResource) string {
+	for _, resource := range conf.Resources() {
+		return resource.GetGatewayName()
+	}
+	return ""
+}
+
+// flush 重新生成 apisix.yaml; 开启按网关输出时, gateways 为 nil 表示重新生成所有网关的文件
+func (s *ApisixStandaloneStore) flush(
+	stages map[string]*entity.ApisixStageResource,
+	global *entity.ApisixGlobalResource,
+	gateways []string,
+) error {
+	if err := s.writeConfig(s.path, slices.Collect(maps.Values(stages)), global); err != nil {
+		return err
+	}
+	if s.gatewayDir == "" {
+		return nil
+	}
+
+	gatewayStages := make(map[string][]*entity.ApisixStageResource)
+	for stageKey, conf := range stages {
+		if stageKey == config.VirtualStageKey {
+			continue
+		}
+		gateway := stageGateway(conf)
+		gatewayStages[gateway] = append(gatewayStages[gateway], conf)
+	}
+	if gateways == nil {
+		gateways = slices.Collect(maps.Keys(gatewayStages))
+	}
+	for _, gateway := range gateways {
+		if gateway == "" {
+			continue
+		}
+		path := filepath.Join(s.gatewayDir, gateway+".yaml")
+		confs, ok := gatewayStages[gateway]
+		if !ok {
+			// 网关已经没有环境, 删除对应的文件
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		if virtualStage, ok := stages[config.VirtualStageKey]; ok {
+			confs = append(confs, virtualStage)
+		}
+		if err := s.writeConfig(path, confs, global); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeConfig 渲染并原子地写入文件
+func (s *ApisixStandaloneStore) writeConfig(
+	path string,
+	stages []*entity.ApisixStageResource,
+	global *entity.ApisixGlobalResource,
+) error {
+	content, err := renderStandalone(stages, global)
+	if err != nil {
+		return fmt.Errorf("render %s failed: %w", path, err)
+	}
+	if err = writeFileAtomic(path, content); err != nil {
+		return fmt.Errorf("write %s failed: %w", path, err)
+	}
+	s.logger.Infow("write standalone config", "path", path, "size", len(content))
+	return nil
+}
+
+// renderStandalone 渲染 apisix.yaml, 以 #END 结尾
+func renderStandalone(stages []*entity.ApisixStageResource, global *entity.ApisixGlobalResource) ([]byte, error) {
+	items := make(map[string]map[string]entity.ApisixResource)
+	for _, conf := range stages {
+		for ref, resource := range stageResourceNodes(conf) {
+			if _, ok := items[ref.resourceType]; !ok {
+				items[ref.resourceType] = make(map[string]entity.ApisixResource)
+			}
+			items[ref.resourceType][ref.id] = resource
+		}
+	}
+	pluginMetadata := make(map[string]entity.ApisixResource, len(global.PluginMetadata))
+	for id, pm := range global.PluginMetadata {
+		pluginMetadata[id] = pm
+	}
+	globalRules := make(map[string]entity.ApisixResource, len(global.GlobalRules))
+	for id, globalRule := range global.GlobalRules {
+		globalRules[id] = globalRule
+	}
+
+	var (
+		conf standaloneConfig
+		err  error
+	)
+	if conf.Routes, err = standaloneItems(items[constant.ApisixResourceTypeRoutes]); err != nil {
+		return nil, err
+	}
+	if conf.Services, err = standaloneItems(items[constant.ApisixResourceTypeServices]); err != nil {
+		return nil, err
+	}
+	if conf.Upstreams, err = standaloneItems(items[constant.ApisixResourceTypeUpstreams]); err != nil {
+		return nil, err
+	}
+	if conf.PluginConfigs, err = standaloneItems(items[constant.ApisixResourceTypePluginConfigs]); err != nil {
+		return nil, err
+	}
+	if conf.Consumers, err = standaloneItems(items[constant.ApisixResourceTypeConsumers]); err != nil {
+		return nil, err
+	}
+	if conf.ConsumerGroups, err = standaloneItems(items[constant.ApisixResourceTypeConsumerGroups]); err != nil {
+		return nil, err
+	}
+	if conf.SSLs, err = standaloneItems(items[constant.ApisixResourceTypeSSL]); err != nil {
+		return nil, err
+	}
+	if conf.StreamRoutes, err = standaloneItems(items[constant.ApisixResourceTypeStreamRoutes]); err != nil {
+		return nil, err
+	}
+	if conf.Protos, err = standaloneItems(items[constant.ApisixResourceTypeProtos]); err != nil {
+		return nil, err
+	}
+	if conf.PluginMetadata, err = standaloneItems(pluginMetadata); err != nil {
+		return nil, err
+	}
+	if conf.GlobalRules, err = standaloneItems(globalRules); err != nil {
+		return nil, err
+	}
+	content, err := yaml.Marshal(&conf)
+	if err != nil {
+		return nil, err
+	}
+	return append(content, standaloneEndMarker+"\n"...), nil
+}
+
+// standaloneItems 按 id 排序并转换为 yaml 的列表项, 保证 id 字段存在
+func standaloneItems(resources map[string]entity.ApisixResource) ([]map[string]any, error) {
+	ids := slices.Sorted(maps.Keys(resources))
+	items := make([]map[string]any, 0, len(ids))
+	for _, id := range ids {
+		raw, err := apisixValue(resources[id])
+		if err != nil {
+			return nil, fmt.Errorf("marshal resource %s failed: %w", id, err)
+		}
+		item := make(map[string]any)
+		if err = json.Unmarshal(raw, &item); err != nil {
+			return nil, fmt.Errorf("unmarshal resource %s failed: %w", id, err)
+		}
+		item["id"] = id
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// writeFileAtomic 先写入同目录下的临时文件再 rename, 读取方不会看到写了一半的文件
+func writeFileAtomic(path string, content []byte) (err error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+strings.TrimSuffix(base, filepath.Ext(base))+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,171 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+var _ = Describe("ApisixStandaloneStore", func() {
+	var (
+		dir        string
+		standalone *config.Standalone
+		s          *ApisixStandaloneStore
+		ctx        context.Context
+	)
+
+	newStage := func(gateway, stage, uri string) *entity.ApisixStageResource {
+		conf := entity.NewEmptyApisixConfiguration()
+		id := gateway + "-" + stage + "-route"
+		conf.Routes[id] = &entity.Route{
+			ResourceMetadata: entity.ResourceMetadata{
+				ID:     id,
+				Labels: &entity.LabelInfo{Gateway: gateway, Stage: stage},
+			},
+			URI: uri,
+		}
+		return conf
+	}
+
+	readConfig := func(path string) *standaloneConfig {
+		content, err := os.ReadFile(path)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(strings.HasSuffix(string(content), "\n#END\n")).To(BeTrue())
+		var conf standaloneConfig
+		Expect(yaml.Unmarshal(content, &conf)).To(Succeed())
+		return &conf
+	}
+
+	BeforeEach(func() {
+		if !metricInitialized {
+			metric.InitMetric(prometheus.NewRegistry())
+			metricInitialized = true
+		}
+		ctx = context.Background()
+		dir = GinkgoT().TempDir()
+		standalone = &config.Standalone{Path: filepath.Join(dir, "conf", "apisix.yaml")}
+
+		var err error
+		s, err = NewApisixStandaloneStore(standalone)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("should render all the stages and the global plugin metadata", func() {
+		Expect(s.Alter(ctx, config.GenStagePrimaryKey("gw", "prod"), newStage("gw", "prod", "/prod"))).To(Succeed())
+		Expect(s.Alter(ctx, config.GenStagePrimaryKey("gw", "test"), newStage("gw", "test", "/test"))).To(Succeed())
+
+		global := entity.NewEmptyApisixGlobalResource()
+		global.PluginMetadata["file-logger"] = &entity.PluginMetadata{
+			ResourceMetadata: entity.ResourceMetadata{ID: "file-logger"},
+			PluginMetadataConf: entity.PluginMetadataConf{
+				"file-logger": []byte(`{"id":"file-logger","log_format":{"host":"$host"}}`),
+			},
+		}
+		Expect(s.AlterGlobal(ctx, global)).To(Succeed())
+
+		conf := readConfig(standalone.Path)
+		Expect(conf.Routes).To(HaveLen(2))
+		Expect(conf.Routes[0]["id"]).To(Equal("gw-prod-route"))
+		Expect(conf.Routes[0]["uri"]).To(Equal("/prod"))
+		Expect(conf.PluginMetadata).To(HaveLen(1))
+		Expect(conf.PluginMetadata[0]["log_format"]).To(HaveKeyWithValue("host", "$host"))
+
+		// 只留下最终的文件, 没有临时文件
+		entries, err := os.ReadDir(filepath.Dir(standalone.Path))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+
+		// 删除环境后重新生成
+		empty := entity.NewEmptyApisixConfiguration()
+		Expect(s.Alter(ctx, config.GenStagePrimaryKey("gw", "test"), empty)).To(Succeed())
+		Expect(readConfig(standalone.Path).Routes).To(HaveLen(1))
+		Expect(s.GetAll()).To(HaveLen(1))
+	})
+
+	It("should load the existing file after restart", func() {
+		stageKey := config.GenStagePrimaryKey("gw", "prod")
+		Expect(s.Alter(ctx, stageKey, newStage("gw", "prod", "/prod"))).To(Succeed())
+
+		restarted, err := NewApisixStandaloneStore(standalone)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(restarted.Get(stageKey).Routes).To(HaveKey("gw-prod-route"))
+		Expect(restarted.Diff(stageKey, newStage("gw", "prod", "/prod")).IsEmpty()).To(BeTrue())
+	})
+
+	It("should write stream routes without labels and restore the stage from the id", func() {
+		stageKey := config.GenStagePrimaryKey("gw", "prod")
+		conf := entity.NewEmptyApisixConfiguration()
+		conf.StreamRoutes["bk-apigw.gw.prod.tcp"] = &entity.StreamRoute{
+			ResourceMetadata: entity.ResourceMetadata{
+				ID:     "bk-apigw.gw.prod.tcp",
+				Labels: &entity.LabelInfo{Gateway: "gw", Stage: "prod"},
+			},
+			ServerPort: 9100,
+			UpstreamID: "1",
+		}
+		Expect(s.Alter(ctx, stageKey, conf)).To(Succeed())
+
+		streamRoutes := readConfig(standalone.Path).StreamRoutes
+		Expect(streamRoutes).To(HaveLen(1))
+		Expect(streamRoutes[0]).NotTo(HaveKey("labels"))
+		Expect(streamRoutes[0]["server_port"]).To(Equal(9100))
+
+		restarted, err := NewApisixStandaloneStore(standalone)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(restarted.Get(stageKey).StreamRoutes).To(HaveKey("bk-apigw.gw.prod.tcp"))
+	})
+
+	It("should write one file per gateway with the virtual stage", func() {
+		standalone.GatewayDir = filepath.Join(dir, "gateways")
+		var err error
+		s, err = NewApisixStandaloneStore(standalone)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		virtualStageKey := config.VirtualStageKey
+		DeferCleanup(func() { config.VirtualStageKey = virtualStageKey })
+		config.VirtualStageKey = config.GenStagePrimaryKey("-", "-")
+		Expect(s.Alter(ctx, config.VirtualStageKey, newStage("-", "-", "/healthz"))).To(Succeed())
+		Expect(s.Alter(ctx, config.GenStagePrimaryKey("gw-a", "prod"), newStage("gw-a", "prod", "/a"))).To(Succeed())
+		Expect(s.Alter(ctx, config.GenStagePrimaryKey("gw-b", "prod"), newStage("gw-b", "prod", "/b"))).To(Succeed())
+
+		Expect(readConfig(standalone.Path).Routes).To(HaveLen(3))
+		gatewayA := readConfig(filepath.Join(standalone.GatewayDir, "gw-a.yaml"))
+		Expect(gatewayA.Routes).To(HaveLen(2))
+		Expect(gatewayA.Routes[0]["id"]).To(Equal("----route"))
+		Expect(gatewayA.Routes[1]["id"]).To(Equal("gw-a-prod-route"))
+		Expect(filepath.Join(standalone.GatewayDir, "-.yaml")).NotTo(BeAnExistingFile())
+
+		// 网关没有环境后删除对应的文件
+		empty := entity.NewEmptyApisixConfiguration()
+		Expect(s.Alter(ctx, config.GenStagePrimaryKey("gw-b", "prod"), empty)).To(Succeed())
+		Expect(filepath.Join(standalone.GatewayDir, "gw-b.yaml")).NotTo(BeAnExistingFile())
+	})
+})
@@ -21,13 +21,16 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	json "github.com/json-iterator/go"
+	"github.com/tidwall/sjson"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
 
@@ -44,10 +47,56 @@ import (
 var apisixResourceTypes = []string{
 	constant.ApisixResourceTypeRoutes,
 	constant.ApisixResourceTypeServices,
+	constant.ApisixResourceTypeUpstreams,
+	constant.ApisixResourceTypePluginConfigs,
+	constant.ApisixResourceTypeConsumers,
+	constant.ApisixResourceTypeConsumerGroups,
 	constant.ApisixResourceTypeSSL,
+	constant.ApisixResourceTypeStreamRoutes,
+	constant.ApisixResourceTypeProtos,
 	constant.ApisixResourceTypePluginMetadata,
+	constant.ApisixResourceTypeGlobalRules,
 }
 
+// globalResourceTypes 全局资源类型, 不属于任何环境
+var globalResourceTypes = []string{
+	constant.ApisixResourceTypePluginMetadata,
+	constant.ApisixResourceTypeGlobalRules,
+}
+
+// stageResourceTypes 环境维度的资源类型
+var stageResourceTypes = []string{
+	constant.ApisixResourceTypeRoutes,
+	constant.ApisixResourceTypeServices,
+	constant.ApisixResourceTypeUpstreams,
+	constant.ApisixResourceTypePluginConfigs,
+	constant.ApisixResourceTypeConsumers,
+	constant.ApisixResourceTypeConsumerGroups,
+	constant.ApisixResourceTypeSSL,
+	constant.ApisixResourceTypeStreamRoutes,
+	constant.ApisixResourceTypeProtos,
+}
+
+// ApisixStore apisix 配置的存储后端, 本地缓存 apisix 中的资源, 写入时与缓存 diff 后只写入变更的资源
+type ApisixStore interface {
+	// Prefix 后端的标识, etcd 为 key 前缀, admin api 为地址
+	Prefix() string
+	Get(stageKey string) *entity.ApisixStageResource
+	GetAll() map[string]*entity.ApisixStageResource
+	GetGlobal() *entity.ApisixGlobalResource
+	Alter(ctx context.Context, stageKey string, conf *entity.ApisixStageResource) error
+	AlterGlobal(ctx context.Context, conf *entity.ApisixGlobalResource) error
+	Diff(stageKey string, conf *entity.ApisixStageResource) *StageDiff
+	DiffGlobal(conf *entity.ApisixGlobalResource) *StageDiff
+	Close()
+}
+
+var (
+	_ ApisixStore = (*ApisixEtcdStore)(nil)
+	_ ApisixStore = (*ApisixAdminStore)(nil)
+	_ ApisixStore = (*ApisixStandaloneStore)(nil)
+)
+
 // ApisixEtcdStore ...
 type ApisixEtcdStore struct {
 	client *clientv3.Client
@@ -66,6 +115,9 @@ type ApisixEtcdStore struct {
 
 	lock *sync.RWMutex
 
+	// recorder 不为 nil 时只记录变更, 不写入 apisix etcd
+	recorder *shadowRecorder
+
 	// ctx for controlling the lifecycle of registry goroutines
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -104,6 +156,16 @@ func NewApisixEtcdStore(ctx context.Context, client *clientv3.Client, prefix str
 	return s, nil
 }
 
+// Prefix returns the apisix etcd key prefix of the store
+func (s *ApisixEtcdStore) Prefix() string {
+	return s.prefix
+}
+
+// Fork 使用同一个 etcd client 和写入参数创建另一个前缀的 store, 调用方负责 Close
+func (s *ApisixEtcdStore) Fork(ctx context.Context, prefix string) (*ApisixEtcdStore, error) {
+	return NewApisixEtcdStore(ctx, s.client, prefix, s.putInterval, s.delInterval, s.syncTimeout)
+}
+
 // Close stops all registry goroutines and releases resources
 func (s *ApisixEtcdStore) Close() {
 	if s.cancel != nil {
@@ -152,41 +214,119 @@ func (s *ApisixEtcdStore) Get(stageKey string) *entity.ApisixStageResource {
 	for key, val := range services {
 		ret.Services[key] = val.(*entity.Service) //nolint:forcetypeassert
 	}
+	upstreams := s.registry[constant.ApisixResourceTypeUpstreams].GetStageResources(stageKey)
+	for key, val := range upstreams {
+		ret.Upstreams[key] = val.(*entity.Upstream) //nolint:forcetypeassert
+	}
+	pluginConfigs := s.registry[constant.ApisixResourceTypePluginConfigs].GetStageResources(stageKey)
+	for key, val := range pluginConfigs {
+		ret.PluginConfigs[key] = val.(*entity.PluginConfig) //nolint:forcetypeassert
+	}
+	consumers := s.registry[constant.ApisixResourceTypeConsumers].GetStageResources(stageKey)
+	for key, val := range consumers {
+		ret.Consumers[key] = val.(*entity.Consumer) //nolint:forcetypeassert
+	}
+	consumerGroups := s.registry[constant.ApisixResourceTypeConsumerGroups].GetStageResources(stageKey)
+	for key, val := range consumerGroups {
+		ret.ConsumerGroups[key] = val.(*entity.ConsumerGroup) //nolint:forcetypeassert
+	}
 	ssls := s.registry[constant.ApisixResourceTypeSSL].GetStageResources(stageKey)
 	for key, val := range ssls {
 		ret.SSLs[key] = val.(*entity.SSL) //nolint:forcetypeassert
 	}
+	streamRoutes := s.registry[constant.ApisixResourceTypeStreamRoutes].GetStageResources(stageKey)
+	for key, val := range streamRoutes {
+		ret.StreamRoutes[key] = val.(*entity.StreamRoute) //nolint:forcetypeassert
+	}
+	protos := s.registry[constant.ApisixResourceTypeProtos].GetStageResources(stageKey)
+	for key, val := range protos {
+		ret.Protos[key] = val.(*entity.Proto) //nolint:forcetypeassert
+	}
 	return ret
 }
 
-// GetAll get staged apisix configuration map
+// GetAll get staged apisix configuration map, key is the stage key
 func (s *ApisixEtcdStore) GetAll() map[string]*entity.ApisixStageResource {
 	configMap := make(map[string]*entity.ApisixStageResource)
 	routeMap := s.registry[constant.ApisixResourceTypeRoutes].GetAllResources()
 	for key, route := range routeMap {
-		stageName := route.GetStageName()
-		if _, ok := configMap[stageName]; !ok {
-			configMap[stageName] = entity.NewEmptyApisixConfiguration()
+		stageKey := route.GetStageKey()
+		if _, ok := configMap[stageKey]; !ok {
+			configMap[stageKey] = entity.NewEmptyApisixConfiguration()
 		}
-		configMap[stageName].Routes[key] = route.(*entity.Route) //nolint:forcetypeassert
+		configMap[stageKey].Routes[key] = route.(*entity.Route) //nolint:forcetypeassert
 	}
 
 	serviceMap := s.registry[constant.ApisixResourceTypeServices].GetAllResources()
 	for key, service := range serviceMap {
-		stageName := service.GetStageName()
-		if _, ok := configMap[stageName]; !ok {
-			configMap[stageName] = entity.NewEmptyApisixConfiguration()
+		stageKey := service.GetStageKey()
+		if _, ok := configMap[stageKey]; !ok {
+			configMap[stageKey] = entity.NewEmptyApisixConfiguration()
+		}
+		configMap[stageKey].Services[key] = service.(*entity.Service) //nolint:forcetypeassert
+	}
+
+	upstreamMap := s.registry[constant.ApisixResourceTypeUpstreams].GetAllResources()
+	for key, upstream := range upstreamMap {
+		stageKey := upstream.GetStageKey()
+		if _, ok := configMap[stageKey]; !ok {
+			configMap[stageKey] = entity.NewEmptyApisixConfiguration()
+		}
+		configMap[stageKey].Upstreams[key] = upstream.(*entity.Upstream) //nolint:forcetypeassert
+	}
+
+	pluginConfigMap := s.registry[constant.ApisixResourceTypePluginConfigs].GetAllResources()
+	for key, pluginConfig := range pluginConfigMap {
+		stageKey := pluginConfig.GetStageKey()
+		if _, ok := configMap[stageKey]; !ok {
+			configMap[stageKey] = entity.NewEmptyApisixConfiguration()
 		}
-		configMap[stageName].Services[key] = service.(*entity.Service) //nolint:forcetypeassert
+		configMap[stageKey].PluginConfigs[key] = pluginConfig.(*entity.PluginConfig) //nolint:forcetypeassert
+	}
+
+	consumerMap := s.registry[constant.ApisixResourceTypeConsumers].GetAllResources()
+	for key, consumer := range consumerMap {
+		stageKey := consumer.GetStageKey()
+		if _, ok := configMap[stageKey]; !ok {
+			configMap[stageKey] = entity.NewEmptyApisixConfiguration()
+		}
+		configMap[stageKey].Consumers[key] = consumer.(*entity.Consumer) //nolint:forcetypeassert
+	}
+
+	consumerGroupMap := s.registry[constant.ApisixResourceTypeConsumerGroups].GetAllResources()
+	for key, consumerGroup := range consumerGroupMap {
+		stageKey := consumerGroup.GetStageKey()
+		if _, ok := configMap[stageKey]; !ok {
+			configMap[stageKey] = entity.NewEmptyApisixConfiguration()
+		}
+		configMap[stageKey].ConsumerGroups[key] = consumerGroup.(*entity.ConsumerGroup) //nolint:forcetypeassert
 	}
 
 	sslMap := s.registry[constant.ApisixResourceTypeSSL].GetAllResources()
 	for key, ssl := range sslMap {
-		stageName := ssl.GetStageName()
-		if _, ok := configMap[stageName]; !ok {
-			configMap[stageName] = entity.NewEmptyApisixConfiguration()
+		stageKey := ssl.GetStageKey()
+		if _, ok := configMap[stageKey]; !ok {
+			configMap[stageKey] = entity.NewEmptyApisixConfiguration()
+		}
+		configMap[stageKey].SSLs[key] = ssl.(*entity.SSL) //nolint:forcetypeassert
+	}
+
+	streamRouteMap := s.registry[constant.ApisixResourceTypeStreamRoutes].GetAllResources()
+	for key, streamRoute := range streamRouteMap {
+		stageKey := streamRoute.GetStageKey()
+		if _, ok := configMap[stageKey]; !ok {
+			configMap[stageKey] = entity.NewEmptyApisixConfiguration()
 		}
-		configMap[stageName].SSLs[key] = ssl.(*entity.SSL) //nolint:forcetypeassert
+		configMap[stageKey].StreamRoutes[key] = streamRoute.(*entity.StreamRoute) //nolint:forcetypeassert
+	}
+
+	protoMap := s.registry[constant.ApisixResourceTypeProtos].GetAllResources()
+	for key, proto := range protoMap {
+		stageKey := proto.GetStageKey()
+		if _, ok := configMap[stageKey]; !ok {
+			configMap[stageKey] = entity.NewEmptyApisixConfiguration()
+		}
+		configMap[stageKey].Protos[key] = proto.(*entity.Proto) //nolint:forcetypeassert
 	}
 	return configMap
 }
@@ -213,6 +353,22 @@ func (s *ApisixEtcdStore) Alter(
 
 func (s *ApisixEtcdStore) alterByStage(
 	ctx context.Context, stageKey string, conf *entity.ApisixStageResource,
+) (err error) {
+	for retry := 0; ; retry++ {
+		err = s.applyStage(ctx, stageKey, conf)
+		if !errors.Is(err, ErrTxnConflict) || retry >= maxConflictRetry {
+			return err
+		}
+		// 资源已被其他写入方修改, 刷新缓存后重新 diff, 而不是直接覆盖
+		s.logger.Warnw("Apisix etcd conflict, refresh cache and diff again", "stage", stageKey, "retry", retry)
+		if err = s.refresh(ctx, stageResourceTypes); err != nil {
+			return fmt.Errorf("refresh cache failed: %w", err)
+		}
+	}
+}
+
+func (s *ApisixEtcdStore) applyStage(
+	ctx context.Context, stageKey string, conf *entity.ApisixStageResource,
 ) (err error) {
 	// get cached config
 	oldConf := s.Get(stageKey)
@@ -220,75 +376,150 @@ func (s *ApisixEtcdStore) alterByStage(
 	// diff config
 	putConf, deleteConf := s.differ.Diff(oldConf, conf)
 
-	var putFlag, delFlag bool
-	// put resources
-	if putConf != nil {
-		if err = s.batchPutResource(ctx, constant.ApisixResourceTypeSSL, putConf.SSLs); err != nil {
-			return fmt.Errorf("batch put ssl failed: %w", err)
+	if s.recorder != nil {
+		s.recorder.record(newStageDiff(stageKey, putConf, deleteConf))
+		return nil
+	}
+
+	// 任意一步失败时回滚已经提交的变更, 避免环境处于部分生效的状态
+	txn := newStageTxn(s.client, s.syncTimeout, s.logger)
+	defer func() {
+		if err != nil {
+			txn.rollback(ctx)
+		}
+	}()
+
+	// 写入时被依赖的资源在前, 删除时引用方在前, 避免 apisix 看到引用了不存在资源的配置
+	putNodes := stageResourceNodes(putConf)
+	deleteNodes := stageResourceNodes(deleteConf)
+	steps := make([]applyStep, 0)
+	for _, level := range dependencyLevels(putNodes) {
+		steps = append(steps, applyStep{refs: level, nodes: putNodes, waitTimeout: s.putInterval})
+	}
+	deleteLevels := dependencyLevels(deleteNodes)
+	slices.Reverse(deleteLevels)
+	for _, level := range deleteLevels {
+		steps = append(steps, applyStep{refs: level, nodes: deleteNodes, delete: true, waitTimeout: s.delInterval})
+	}
+
+	for i, step := range steps {
+		if err = s.applyStep(ctx, txn, step); err != nil {
+			return err
 		}
-		if err = s.batchPutResource(ctx, constant.ApisixResourceTypeServices, putConf.Services); err != nil {
-			return fmt.Errorf("batch put services failed: %w", err)
+		// 后面的步骤依赖本步骤的变更, 等待 watch 看到本步骤的写入后再继续
+		if i < len(steps)-1 {
+			s.waitForApplied(ctx, txn.revision(), step)
 		}
+	}
 
-		// sleep putInterVal to avoid resource data inconsistency
-		time.Sleep(s.putInterval)
+	if len(putNodes) > 0 {
+		s.logger.Infof(
+			"put gateway[key=%s] conf count:"+
+				"[route:%d,serivce:%d,upstream:%d,plugin_config:%d,consumer:%d,consumer_group:%d,ssl:%d,"+
+				"stream_route:%d,proto:%d]",
+			stageKey,
+			len(putConf.Routes),
+			len(putConf.Services),
+			len(putConf.Upstreams),
+			len(putConf.PluginConfigs),
+			len(putConf.Consumers),
+			len(putConf.ConsumerGroups),
+			len(putConf.SSLs),
+			len(putConf.StreamRoutes),
+			len(putConf.Protos),
+		)
+	}
+	if len(deleteNodes) > 0 {
+		s.logger.Infof(
+			"delete gateway[key=%s] conf count:"+
+				"[route:%d,service:%d,upstream:%d,plugin_config:%d,consumer:%d,consumer_group:%d,ssl:%d,"+
+				"stream_route:%d,proto:%d]",
+			stageKey,
+			len(deleteConf.Routes),
+			len(deleteConf.Services),
+			len(deleteConf.Upstreams),
+			len(deleteConf.PluginConfigs),
+			len(deleteConf.Consumers),
+			len(deleteConf.ConsumerGroups),
+			len(deleteConf.SSLs),
+			len(deleteConf.StreamRoutes),
+			len(deleteConf.Protos),
+		)
+	}
+	if len(steps) == 0 {
+		s.logger.Infof("%s has no change", stageKey)
+	}
 
-		if err = s.batchPutResource(ctx, constant.ApisixResourceTypeRoutes, putConf.Routes); err != nil {
-			return fmt.Errorf("batch put routes failed: %w", err)
-		}
+	return nil
+}
 
-		if len(putConf.Routes)+len(putConf.Services)+len(putConf.SSLs) > 0 {
-			s.logger.Infof(
-				"put gateway[key=%s] conf count:[route:%d,serivce:%d,ssl:%d]",
-				stageKey,
-				len(putConf.Routes),
-				len(putConf.Services),
-				len(putConf.SSLs),
-			)
-			putFlag = true
+// applyStep 同一依赖层级的资源, 层内的资源互不依赖, 可以一起提交
+type applyStep struct {
+	refs   []resourceRef
+	nodes  map[resourceRef]entity.ApisixResource
+	delete bool
+	// waitTimeout 等待 watch 看到本步骤写入的最长时间
+	waitTimeout time.Duration
+}
+
+// resourceTypes 本步骤涉及的资源类型
+func (step applyStep) resourceTypes() []string {
+	types := make([]string, 0, len(step.refs))
+	for _, ref := range step.refs {
+		if !slices.Contains(types, ref.resourceType) {
+			types = append(types, ref.resourceType)
 		}
 	}
+	return types
+}
 
-	// delete resources
-	if deleteConf != nil {
-		if err = s.batchDeleteResource(ctx, constant.ApisixResourceTypeRoutes, deleteConf.Routes); err != nil {
-			return fmt.Errorf("batch delete routes failed: %w", err)
+func (s *ApisixEtcdStore) applyStep(ctx context.Context, txn *stageTxn, step applyStep) error {
+	ops := make([]txnOp, 0, len(step.refs))
+	for _, ref := range step.refs {
+		if step.delete {
+			ops = append(ops, s.deleteOp(ref.resourceType, ref.id, step.nodes[ref]))
+			continue
 		}
-		if err = s.batchDeleteResource(ctx, constant.ApisixResourceTypeSSL, deleteConf.SSLs); err != nil {
-			return fmt.Errorf("batch delete ssl failed: %w", err)
+		op, err := s.putOp(ref.resourceType, ref.id, step.nodes[ref])
+		if err != nil {
+			return fmt.Errorf("build put %s op failed: %w", ref.resourceType, err)
 		}
+		ops = append(ops, op)
+	}
 
-		if len(deleteConf.Services) > 0 {
-			// sleep delInterval to avoid resource data inconsistency
-			time.Sleep(s.delInterval)
-			if err = s.batchDeleteResource(
-				ctx,
-				constant.ApisixResourceTypeServices,
-				deleteConf.Services,
-			); err != nil {
-				return fmt.Errorf("batch delete service failed: %w", err)
-			}
-		}
-		if len(deleteConf.Routes)+len(deleteConf.Services)+len(deleteConf.SSLs) > 0 {
-			s.logger.Infof(
-				"delete gateway[key=%s] conf count:[route:%d,service:%d,ssl:%d]",
-				stageKey,
-				len(deleteConf.Routes),
-				len(deleteConf.Services),
-				len(deleteConf.SSLs),
-			)
-			delFlag = true
-		}
+	action := "put"
+	if step.delete {
+		action = "delete"
+	}
+	if err := txn.apply(ctx, ops); err != nil {
+		s.logger.Errorw("Apply resources failed", "err", err, "action", action, "resourceTypes", step.resourceTypes())
+		return fmt.Errorf("batch %s %v failed: %w", action, step.resourceTypes(), err)
 	}
+	return nil
+}
 
-	if !putFlag && !delFlag {
-		s.logger.Infof("%s has no change", stageKey)
+// waitForApplied 等待本地缓存的 watch 同步到 revision, 以此确认写入已经可以被 apisix watch 到;
+// 超时后不再等待, 继续执行后面的步骤
+func (s *ApisixEtcdStore) waitForApplied(ctx context.Context, revision int64, step applyStep) {
+	for _, resourceType := range step.resourceTypes() {
+		if !s.registry[resourceType].WaitForRevision(ctx, revision, step.waitTimeout) {
+			s.logger.Warnw("Wait for watch to see the revision timeout, continue",
+				"resourceType", resourceType, "revision", revision, "timeout", step.waitTimeout)
+		}
 	}
+}
 
+// refresh 重新全量同步指定资源类型的缓存
+func (s *ApisixEtcdStore) refresh(ctx context.Context, resourceTypes []string) error {
+	for _, resourceType := range resourceTypes {
+		if err := s.registry[resourceType].Refresh(ctx); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// GetGlobal 获取全局资源配置（从 apisix etcd 中获取所有没有 stage 标签的 plugin metadata）
+// GetGlobal 获取全局资源配置（从 apisix etcd 中获取所有没有 stage 标签的 plugin metadata 和 operator 管理的 global rule）
 func (s *ApisixEtcdStore) GetGlobal() *entity.ApisixGlobalResource {
 	ret := entity.NewEmptyApisixGlobalResource()
 	// 获取所有 plugin metadata，过滤出没有 stage 标签的（即 global 资源）
@@ -299,6 +530,13 @@ func (s *ApisixEtcdStore) GetGlobal() *entity.ApisixGlobalResource {
 			ret.PluginMetadata[key] = pm.(*entity.PluginMetadata) //nolint:forcetypeassert
 		}
 	}
+	globalRuleMap := s.registry[constant.ApisixResourceTypeGlobalRules].GetAllResources()
+	for key, globalRule := range globalRuleMap {
+		// 只对比 operator 管理的 global rule, 其他方式创建的不会被删除
+		if globalRule.(*entity.GlobalRule).IsManaged() { //nolint:forcetypeassert
+			ret.GlobalRules[key] = globalRule.(*entity.GlobalRule) //nolint:forcetypeassert
+		}
+	}
 	return ret
 }
 
@@ -323,6 +561,21 @@ func (s *ApisixEtcdStore) AlterGlobal(
 
 func (s *ApisixEtcdStore) alterGlobal(
 	ctx context.Context, conf *entity.ApisixGlobalResource,
+) (err error) {
+	for retry := 0; ; retry++ {
+		err = s.applyGlobal(ctx, conf)
+		if !errors.Is(err, ErrTxnConflict) || retry >= maxConflictRetry {
+			return err
+		}
+		s.logger.Warnw("Apisix etcd conflict, refresh global cache and diff again", "retry", retry)
+		if err = s.refresh(ctx, globalResourceTypes); err != nil {
+			return fmt.Errorf("refresh cache failed: %w", err)
+		}
+	}
+}
+
+func (s *ApisixEtcdStore) applyGlobal(
+	ctx context.Context, conf *entity.ApisixGlobalResource,
 ) (err error) {
 	// get cached global config
 	oldConf := s.GetGlobal()
@@ -330,11 +583,24 @@ func (s *ApisixEtcdStore) alterGlobal(
 	// diff config
 	putConf, deleteConf := s.differ.DiffGlobal(oldConf, conf)
 
+	if s.recorder != nil {
+		s.recorder.record(newGlobalDiff(putConf, deleteConf))
+		return nil
+	}
+
+	txn := newStageTxn(s.client, s.syncTimeout, s.logger)
+	defer func() {
+		if err != nil {
+			txn.rollback(ctx)
+		}
+	}()
+
 	var putFlag, delFlag bool
 	// put resources
 	if putConf != nil && len(putConf.PluginMetadata) > 0 {
 		if err = s.batchPutResource(
 			ctx,
+			txn,
 			constant.ApisixResourceTypePluginMetadata,
 			putConf.PluginMetadata,
 		); err != nil {
@@ -346,11 +612,26 @@ func (s *ApisixEtcdStore) alterGlobal(
 			len(putConf.PluginMetadata),
 		)
 	}
+	if putConf != nil && len(putConf.GlobalRules) > 0 {
+		if err = s.batchPutResource(
+			ctx,
+			txn,
+			constant.ApisixResourceTypeGlobalRules,
+			putConf.GlobalRules,
+		); err != nil {
+			return fmt.Errorf("batch put global rules failed: %w", err)
+		}
+		putFlag = true
+		s.logger.Infof(
+			"put global global_rule count:%d",
+			len(putConf.GlobalRules),
+		)
+	}
 
 	// delete resources
 	if deleteConf != nil && len(deleteConf.PluginMetadata) > 0 {
 		if err = s.batchDeleteResource(
-			ctx, constant.ApisixResourceTypePluginMetadata, deleteConf.PluginMetadata,
+			ctx, txn, constant.ApisixResourceTypePluginMetadata, deleteConf.PluginMetadata,
 		); err != nil {
 			return fmt.Errorf("batch delete global plugin metadata failed: %w", err)
 		}
@@ -360,6 +641,18 @@ func (s *ApisixEtcdStore) alterGlobal(
 			len(deleteConf.PluginMetadata),
 		)
 	}
+	if deleteConf != nil && len(deleteConf.GlobalRules) > 0 {
+		if err = s.batchDeleteResource(
+			ctx, txn, constant.ApisixResourceTypeGlobalRules, deleteConf.GlobalRules,
+		); err != nil {
+			return fmt.Errorf("batch delete global rules failed: %w", err)
+		}
+		delFlag = true
+		s.logger.Infof(
+			"delete global global_rule count:%d",
+			len(deleteConf.GlobalRules),
+		)
+	}
 
 	if !putFlag && !delFlag {
 		s.logger.Infof("global resource has no change")
@@ -369,94 +662,119 @@ func (s *ApisixEtcdStore) alterGlobal(
 }
 
 func (s *ApisixEtcdStore) batchPutResource(
-	ctx context.Context, resourceType string, resources any,
+	ctx context.Context, txn *stageTxn, resourceType string, resources any,
 ) error {
-	resourceStore := s.registry[resourceType]
-
-	resourceIter := reflect.ValueOf(resources).MapRange()
+	resourceValue := reflect.ValueOf(resources)
+	resourceIter := resourceValue.MapRange()
+	ops := make([]txnOp, 0, resourceValue.Len())
 	for resourceIter.Next() {
-		// set create time from cache resource
-		st := time.Now()
 		key := resourceIter.Key().Interface().(string)                       //nolint:forcetypeassert
 		resource := resourceIter.Value().Interface().(entity.ApisixResource) //nolint:forcetypeassert
-		if resource.GetCreateTime() == 0 {
-			resource.SetCreateTime(st.Unix())
-		}
-		resource.SetUpdateTime(st.Unix())
-		// remove unused fields
-		resource.ClearUnusedFields()
-		bytes, err := json.Marshal(resource)
+		op, err := s.putOp(resourceType, key, resource)
 		if err != nil {
-			s.logger.Error(
-				"Marshal resource failed",
-				"err",
-				err,
-				"resourceType",
-				resourceType,
-				"resourceID",
-				resource.GetID(),
-			)
-			return fmt.Errorf("marshal resource failed: %w", err)
+			return err
 		}
+		ops = append(ops, op)
+	}
 
-		s.logger.Debugw("Put resource to etcd", "resourceType", resourceType, "resourceID", resource.GetID())
-
-		_, err = s.client.Put(ctx, resourceStore.Prefix+key, string(bytes))
-
-		metric.ReportApisixEtcdMetric(resourceType, metric.ActionPut, st, err)
-
-		if err != nil {
-			s.logger.Errorw(
-				"Put resource failed",
-				"err",
-				err,
-				"resourceType",
-				resourceType,
-				"resourceID",
-				resource.GetID(),
-			)
-			return fmt.Errorf("put resource failed: %w", err)
-		}
+	if err := txn.apply(ctx, ops); err != nil {
+		s.logger.Errorw("Put resource failed", "err", err, "resourceType", resourceType)
+		return fmt.Errorf("put resource failed: %w", err)
 	}
 	return nil
 }
 
 func (s *ApisixEtcdStore) batchDeleteResource(
-	ctx context.Context, resourceType string, resources any,
+	ctx context.Context, txn *stageTxn, resourceType string, resources any,
 ) error {
-	resourceStore := s.registry[resourceType]
-	resourceMap := reflect.ValueOf(resources).MapRange()
+	resourceValue := reflect.ValueOf(resources)
+	resourceMap := resourceValue.MapRange()
+	ops := make([]txnOp, 0, resourceValue.Len())
 	for resourceMap.Next() {
-		st := time.Now()
-
 		key := resourceMap.Key().Interface().(string)                       //nolint:forcetypeassert
 		resource := resourceMap.Value().Interface().(entity.ApisixResource) //nolint:forcetypeassert
+		ops = append(ops, s.deleteOp(resourceType, key, resource))
+	}
+
+	if err := txn.apply(ctx, ops); err != nil {
+		s.logger.Errorw("Delete resource failed", "err", err, "resourceType", resourceType)
+		return fmt.Errorf("delete resource failed: %w", err)
+	}
+	return nil
+}
+
+func (s *ApisixEtcdStore) putOp(resourceType, key string, resource entity.ApisixResource) (txnOp, error) {
+	resourceStore := s.registry[resourceType]
 
-		s.logger.Debugw(
-			"Delete resource from etcd",
+	bytes, err := marshalResource(resource)
+	if err != nil {
+		s.logger.Error(
+			"Marshal resource failed",
+			"err",
+			err,
 			"resourceType",
 			resourceType,
 			"resourceID",
 			resource.GetID(),
 		)
+		return txnOp{}, fmt.Errorf("marshal resource failed: %w", err)
+	}
 
-		_, err := s.client.Delete(ctx, resourceStore.Prefix+key)
+	s.logger.Debugw("Put resource to etcd", "resourceType", resourceType, "resourceID", resource.GetID())
 
-		metric.ReportApisixEtcdMetric(resourceType, metric.ActionDelete, st, err)
+	return txnOp{
+		resourceType: resourceType,
+		key:          resourceStore.Prefix + key,
+		value:        bytes,
+		modRevision:  resourceStore.GetModRevision(key),
+	}, nil
+}
 
-		if err != nil {
-			s.logger.Errorw(
-				"Delete resource failed",
-				"err",
-				err,
-				"resourceType",
-				resourceType,
-				"resourceID",
-				resource.GetID(),
-			)
-			return fmt.Errorf("delete resource failed: %w", err)
-		}
+// marshalResource 设置资源的创建和更新时间, 清理不需要写入 apisix 的字段后序列化
+func marshalResource(resource entity.ApisixResource) ([]byte, error) {
+	touchResource(resource)
+	return apisixValue(resource)
+}
+
+// apisixValue 序列化为写入 apisix 的配置; stream_route 和 proto 的 schema 中没有 labels 字段, 序列化后移除,
+// 缓存中的资源保留 labels, 从 apisix 读取时根据 id 还原
+func apisixValue(resource entity.ApisixResource) ([]byte, error) {
+	value, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	switch resource.(type) {
+	case *entity.StreamRoute, *entity.Proto:
+		return sjson.DeleteBytes(value, "labels")
 	}
+	return value, nil
+}
 
-	return nil
+// touchResource 设置资源的创建和更新时间, 清理不需要写入 apisix 的字段
+func touchResource(resource entity.ApisixResource) {
+	st := time.Now()
+	if resource.GetCreateTime() == 0 {
+		resource.SetCreateTime(st.Unix())
+	}
+	resource.SetUpdateTime(st.Unix())
+	// remove unused fields
+	resource.ClearUnusedFields()
+}
+
+func (s *ApisixEtcdStore) deleteOp(resourceType, key string, resource entity.ApisixResource) txnOp {
+	resourceStore := s.registry[resourceType]
+
+	s.logger.Debugw(
+		"Delete resource from etcd",
+		"resourceType",
+		resourceType,
+		"resourceID",
+		resource.GetID(),
+	)
+
+	return txnOp{
+		resourceType: resourceType,
+		key:          resourceStore.Prefix + key,
+		modRevision:  resourceStore.GetModRevision(key),
+	}
 }
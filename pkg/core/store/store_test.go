@@ -272,9 +272,14 @@ var _ = Describe("ApisixEtcdStore", func() {
 		It("should contain all required resource types", func() {
 			Expect(apisixResourceTypes).To(ContainElement(constant.ApisixResourceTypeRoutes))
 			Expect(apisixResourceTypes).To(ContainElement(constant.ApisixResourceTypeServices))
+			Expect(apisixResourceTypes).To(ContainElement(constant.ApisixResourceTypeUpstreams))
+			Expect(apisixResourceTypes).To(ContainElement(constant.ApisixResourceTypePluginConfigs))
+			Expect(apisixResourceTypes).To(ContainElement(constant.ApisixResourceTypeConsumers))
+			Expect(apisixResourceTypes).To(ContainElement(constant.ApisixResourceTypeConsumerGroups))
 			Expect(apisixResourceTypes).To(ContainElement(constant.ApisixResourceTypeSSL))
 			Expect(apisixResourceTypes).To(ContainElement(constant.ApisixResourceTypePluginMetadata))
-			Expect(apisixResourceTypes).To(HaveLen(4))
+			Expect(apisixResourceTypes).To(ContainElement(constant.ApisixResourceTypeGlobalRules))
+			Expect(apisixResourceTypes).To(HaveLen(11))
 		})
 	})
 })
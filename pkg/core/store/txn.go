@@ -0,0 +1,183 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package store ...
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+const (
+	// maxTxnOps etcd 单个事务允许的最大操作数, 与 etcd 的默认配置 --max-txn-ops 保持一致
+	maxTxnOps = 128
+	// maxConflictRetry 写入冲突时刷新缓存并重新 diff 的最大次数
+	maxConflictRetry = 3
+)
+
+// ErrTxnConflict apisix etcd 中的资源已经被其他写入方修改, 与缓存中的 mod_revision 不一致
+var ErrTxnConflict = errors.New("apisix etcd resource modified by other writer")
+
+// txnOp 单个资源的写入操作
+type txnOp struct {
+	resourceType string
+	key          string
+	// value 为 nil 表示删除
+	value []byte
+	// modRevision 缓存中最后一次看到的 mod_revision, 0 表示资源不存在
+	modRevision int64
+}
+
+func (op txnOp) isDelete() bool {
+	return op.value == nil
+}
+
+// committedBatch 已经提交成功的一批操作, 用于失败时回滚
+type committedBatch struct {
+	revision int64
+	ops      []txnOp
+	prevKvs  []*mvccpb.KeyValue
+}
+
+// stageTxn 将一次变更分批以 etcd 事务写入, 任意一批失败时回滚之前已经提交的批次
+type stageTxn struct {
+	client      *clientv3.Client
+	syncTimeout time.Duration
+	committed   []committedBatch
+
+	logger *zap.SugaredLogger
+}
+
+func newStageTxn(client *clientv3.Client, syncTimeout time.Duration, logger *zap.SugaredLogger) *stageTxn {
+	return &stageTxn{
+		client:      client,
+		syncTimeout: syncTimeout,
+		logger:      logger,
+	}
+}
+
+// apply 按 maxTxnOps 分批提交, 每个操作都要求 etcd 中的 mod_revision 与缓存一致
+func (t *stageTxn) apply(ctx context.Context, ops []txnOp) error {
+	for start := 0; start < len(ops); start += maxTxnOps {
+		end := min(start+maxTxnOps, len(ops))
+		if err := t.commit(ctx, ops[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *stageTxn) commit(ctx context.Context, ops []txnOp) error {
+	st := time.Now()
+	cmps := make([]clientv3.Cmp, 0, len(ops))
+	thenOps := make([]clientv3.Op, 0, len(ops))
+	for _, op := range ops {
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(op.key), "=", op.modRevision))
+		if op.isDelete() {
+			thenOps = append(thenOps, clientv3.OpDelete(op.key, clientv3.WithPrevKV()))
+		} else {
+			thenOps = append(thenOps, clientv3.OpPut(op.key, string(op.value), clientv3.WithPrevKV()))
+		}
+	}
+
+	resp, err := t.client.Txn(ctx).If(cmps...).Then(thenOps...).Commit()
+	if err == nil && !resp.Succeeded {
+		err = ErrTxnConflict
+	}
+	for _, op := range ops {
+		action := metric.ActionPut
+		if op.isDelete() {
+			action = metric.ActionDelete
+		}
+		metric.ReportApisixEtcdMetric(op.resourceType, action, st, err)
+	}
+	if err != nil {
+		t.logger.Errorw("Commit txn failed", "err", err, "opCount", len(ops))
+		return fmt.Errorf("commit txn failed: %w", err)
+	}
+
+	batch := committedBatch{
+		revision: resp.Header.Revision,
+		ops:      ops,
+		prevKvs:  make([]*mvccpb.KeyValue, len(ops)),
+	}
+	for i, r := range resp.Responses {
+		if putResp := r.GetResponsePut(); putResp != nil {
+			batch.prevKvs[i] = putResp.PrevKv
+		}
+		if delResp := r.GetResponseDeleteRange(); delResp != nil && len(delResp.PrevKvs) > 0 {
+			batch.prevKvs[i] = delResp.PrevKvs[0]
+		}
+	}
+	t.committed = append(t.committed, batch)
+	return nil
+}
+
+// revision 最后一次提交成功的 etcd revision, 没有提交过时返回 0
+func (t *stageTxn) revision() int64 {
+	if len(t.committed) == 0 {
+		return 0
+	}
+	return t.committed[len(t.committed)-1].revision
+}
+
+// rollback 逆序恢复已提交批次的原始数据, 只恢复没有再被其他写入方修改过的资源
+func (t *stageTxn) rollback(ctx context.Context) {
+	// 原 ctx 可能已经超时或取消, 回滚需要独立的超时时间
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), t.syncTimeout)
+	defer cancel()
+
+	for i := len(t.committed) - 1; i >= 0; i-- {
+		batch := t.committed[i]
+		cmps := make([]clientv3.Cmp, 0, len(batch.ops))
+		thenOps := make([]clientv3.Op, 0, len(batch.ops))
+		for j, op := range batch.ops {
+			expectRevision := batch.revision
+			if op.isDelete() {
+				expectRevision = 0
+			}
+			cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(op.key), "=", expectRevision))
+			if prev := batch.prevKvs[j]; prev != nil {
+				thenOps = append(thenOps, clientv3.OpPut(op.key, string(prev.Value)))
+			} else {
+				thenOps = append(thenOps, clientv3.OpDelete(op.key))
+			}
+		}
+		resp, err := t.client.Txn(ctx).If(cmps...).Then(thenOps...).Commit()
+		if err != nil {
+			t.logger.Errorw("Rollback txn failed", "err", err, "revision", batch.revision)
+			continue
+		}
+		if !resp.Succeeded {
+			t.logger.Errorw("Rollback txn conflict, resources modified by other writer",
+				"revision", batch.revision)
+			continue
+		}
+		t.logger.Infow("Rollback txn success", "revision", batch.revision, "opCount", len(batch.ops))
+	}
+	t.committed = nil
+}
@@ -0,0 +1,258 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+var _ = Describe("stageTxn with EmbedEtcd", func() {
+	var (
+		ctx    context.Context
+		etcd   *embed.Etcd
+		client *clientv3.Client
+		txn    *stageTxn
+	)
+
+	BeforeEach(func() {
+		var err error
+		if !metricInitialized {
+			metric.InitMetric(prometheus.NewRegistry())
+			metricInitialized = true
+		}
+		ctx = context.Background()
+		etcd, client, err = startTestEtcd()
+		Expect(err).ShouldNot(HaveOccurred())
+		txn = newStageTxn(client, 5*time.Second, logging.GetLogger().Named("test-txn"))
+	})
+
+	AfterEach(func() {
+		client.Close()
+		etcd.Close()
+		_ = os.RemoveAll(etcd.Config().Dir)
+	})
+
+	It("should apply ops in batches", func() {
+		ops := make([]txnOp, 0, maxTxnOps+2)
+		for i := 0; i < maxTxnOps+2; i++ {
+			ops = append(ops, txnOp{
+				resourceType: constant.ApisixResourceTypeRoutes,
+				key:          fmt.Sprintf("/apisix/routes/route-%d", i),
+				value:        []byte(`{}`),
+			})
+		}
+		Expect(txn.apply(ctx, ops)).To(Succeed())
+		Expect(txn.committed).To(HaveLen(2))
+
+		resp, err := client.Get(ctx, "/apisix/routes/", clientv3.WithPrefix(), clientv3.WithCountOnly())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.Count).To(Equal(int64(maxTxnOps + 2)))
+	})
+
+	It("should fail on conflict and rollback the committed batches", func() {
+		putResp, err := client.Put(ctx, "/apisix/routes/existing", `{"id": "existing"}`)
+		Expect(err).ShouldNot(HaveOccurred())
+		// 其他写入方修改了资源, 缓存中的 mod_revision 已经过期
+		_, err = client.Put(ctx, "/apisix/routes/conflict", `{"id": "other"}`)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		ops := make([]txnOp, 0, maxTxnOps+1)
+		ops = append(ops, txnOp{
+			resourceType: constant.ApisixResourceTypeRoutes,
+			key:          "/apisix/routes/existing",
+			modRevision:  putResp.Header.Revision,
+		})
+		for i := 1; i < maxTxnOps; i++ {
+			ops = append(ops, txnOp{
+				resourceType: constant.ApisixResourceTypeRoutes,
+				key:          fmt.Sprintf("/apisix/routes/route-%d", i),
+				value:        []byte(`{}`),
+			})
+		}
+		ops = append(ops, txnOp{
+			resourceType: constant.ApisixResourceTypeRoutes,
+			key:          "/apisix/routes/conflict",
+			value:        []byte(`{"id": "conflict"}`),
+		})
+
+		err = txn.apply(ctx, ops)
+		Expect(errors.Is(err, ErrTxnConflict)).To(BeTrue())
+
+		txn.rollback(ctx)
+		resp, err := client.Get(ctx, "/apisix/routes/", clientv3.WithPrefix())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.Kvs).To(HaveLen(2))
+		values := map[string]string{}
+		for _, kv := range resp.Kvs {
+			values[string(kv.Key)] = string(kv.Value)
+		}
+		Expect(values).To(Equal(map[string]string{
+			"/apisix/routes/existing": `{"id": "existing"}`,
+			"/apisix/routes/conflict": `{"id": "other"}`,
+		}))
+	})
+})
+
+var _ = Describe("ApisixEtcdStore with EmbedEtcd", func() {
+	var (
+		ctx    context.Context
+		etcd   *embed.Etcd
+		client *clientv3.Client
+		store  *ApisixEtcdStore
+	)
+
+	newRoute := func(id, uri string) *entity.Route {
+		return &entity.Route{
+			ResourceMetadata: entity.ResourceMetadata{
+				ID:     id,
+				Labels: &entity.LabelInfo{Gateway: "gw", Stage: "prod"},
+			},
+			URI: uri,
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		if !metricInitialized {
+			metric.InitMetric(prometheus.NewRegistry())
+			metricInitialized = true
+		}
+		ctx = context.Background()
+		etcd, client, err = startTestEtcd()
+		Expect(err).ShouldNot(HaveOccurred())
+		store, err = NewApisixEtcdStore(ctx, client, "/apisix", time.Millisecond, time.Millisecond, 5*time.Second)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		store.Close()
+		client.Close()
+		etcd.Close()
+		_ = os.RemoveAll(etcd.Config().Dir)
+	})
+
+	It("should refresh cache and diff again when resource modified by other writer", func() {
+		stageKey := config.GenStagePrimaryKey("gw", "prod")
+		conf := &entity.ApisixStageResource{
+			Routes: map[string]*entity.Route{"route-1": newRoute("route-1", "/foo")},
+		}
+		Expect(store.Alter(ctx, stageKey, conf)).To(Succeed())
+		Eventually(func() int64 {
+			return store.registry[constant.ApisixResourceTypeRoutes].GetModRevision("route-1")
+		}, 5*time.Second, 10*time.Millisecond).ShouldNot(BeZero())
+
+		// 停止缓存的增量同步, 模拟缓存还没有看到其他写入方的修改
+		store.registry[constant.ApisixResourceTypeRoutes].Close()
+		_, err := client.Put(ctx, "/apisix/routes/route-1",
+			`{"id": "route-1", "uri": "/other", "labels": {"gateway.bk.tencent.com/gateway": "gw", `+
+				`"gateway.bk.tencent.com/stage": "prod"}}`)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		conf = &entity.ApisixStageResource{
+			Routes: map[string]*entity.Route{"route-1": newRoute("route-1", "/bar")},
+		}
+		Expect(store.Alter(ctx, stageKey, conf)).To(Succeed())
+
+		resp, err := client.Get(ctx, "/apisix/routes/route-1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(string(resp.Kvs[0].Value)).To(ContainSubstring(`"uri":"/bar"`))
+	})
+
+	It("should apply in dependency order without waiting for the full interval", func() {
+		// 等待时间足够长, 如果没有等到 watch 的信号, 测试会超时
+		waitStore, err := NewApisixEtcdStore(ctx, client, "/apisix", time.Minute, time.Minute, 5*time.Second)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer waitStore.Close()
+
+		stageKey := config.GenStagePrimaryKey("gw", "prod")
+		route := newRoute("route-1", "/foo")
+		route.ServiceID = "service-1"
+		conf := &entity.ApisixStageResource{
+			Routes: map[string]*entity.Route{"route-1": route},
+			Services: map[string]*entity.Service{"service-1": {
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:     "service-1",
+					Labels: &entity.LabelInfo{Gateway: "gw", Stage: "prod"},
+				},
+			}},
+		}
+
+		st := time.Now()
+		Expect(waitStore.Alter(ctx, stageKey, conf)).To(Succeed())
+		routeResp, err := client.Get(ctx, "/apisix/routes/route-1")
+		Expect(err).ShouldNot(HaveOccurred())
+		serviceResp, err := client.Get(ctx, "/apisix/services/service-1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(serviceResp.Kvs[0].ModRevision).To(BeNumerically("<", routeResp.Kvs[0].ModRevision))
+
+		Expect(waitStore.Alter(ctx, stageKey, entity.NewEmptyApisixConfiguration())).To(Succeed())
+		routeResp, err = client.Get(ctx, "/apisix/routes/route-1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(routeResp.Kvs).To(BeEmpty())
+		serviceResp, err = client.Get(ctx, "/apisix/services/service-1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(serviceResp.Kvs).To(BeEmpty())
+		Expect(time.Since(st)).To(BeNumerically("<", 30*time.Second))
+	})
+})
+
+// startTestEtcd starts an embedded etcd for testing
+func startTestEtcd() (*embed.Etcd, *clientv3.Client, error) {
+	cfg := embed.NewConfig()
+	cfg.Dir, _ = os.MkdirTemp("", "etcd-store-test")
+	cfg.LogLevel = "error"
+
+	// Use random ports to avoid conflicts
+	cfg.ListenClientUrls = []url.URL{{Scheme: "http", Host: "localhost:0"}}
+	cfg.ListenPeerUrls = []url.URL{{Scheme: "http", Host: "localhost:0"}}
+
+	etcd, err := embed.StartEtcd(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	select {
+	case <-etcd.Server.ReadyNotify():
+		client, err := clientv3.New(clientv3.Config{
+			Endpoints:   []string{etcd.Clients[0].Addr().String()},
+			DialTimeout: time.Second,
+		})
+		return etcd, client, err
+	case <-time.After(30 * time.Second):
+		etcd.Close()
+		return nil, nil, fmt.Errorf("etcd server took too long to start")
+	}
+}
@@ -19,7 +19,12 @@
 // Package synchronizer ...
 package synchronizer
 
-import "github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+import (
+	"time"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils"
+)
 
 var (
 	virtualGatewayName string = "-"
@@ -27,6 +32,17 @@ var (
 
 	fileLoggerLogPath        string = "/usr/local/apisix/logs/access.log"
 	extraApisixResourcesPath string
+
+	// concurrencyLimit 不同环境并行写入 apisix etcd 的最大数量
+	concurrencyLimit = 1
+
+	// primaryTargetName config.Apisix.Etcd 对应的 apisix 集群名称
+	primaryTargetName = "default"
+	// 其他 apisix 集群同步失败后在后台重试, 超过最大次数后等待下一次发布
+	targetRetryMaxAttempts int64 = 3
+	targetRetryBackoff           = utils.Backoff{BaseDelay: 2 * time.Second, MaxDelay: 5 * time.Minute}
+	// 发布最多等待其他集群 targetWaitTimeout, 超时的写入在后台继续, 结果记录为 pending
+	targetWaitTimeout = 5 * time.Second
 )
 
 // Init ...
@@ -36,4 +52,24 @@ func Init(cfg *config.Config) {
 
 	fileLoggerLogPath = cfg.Apisix.VirtualStage.FileLoggerLogPath
 	extraApisixResourcesPath = cfg.Apisix.VirtualStage.ExtraApisixResources
+
+	if cfg.Operator.AgentConcurrencyLimit > 0 {
+		concurrencyLimit = cfg.Operator.AgentConcurrencyLimit
+	}
+
+	if cfg.Apisix.Name != "" {
+		primaryTargetName = cfg.Apisix.Name
+	}
+	if cfg.Operator.CommitRetry.MaxAttempts > 0 {
+		targetRetryMaxAttempts = int64(cfg.Operator.CommitRetry.MaxAttempts)
+	}
+	if cfg.Operator.CommitRetry.BaseDelay > 0 {
+		targetRetryBackoff.BaseDelay = cfg.Operator.CommitRetry.BaseDelay
+	}
+	if cfg.Operator.CommitRetry.MaxDelay > 0 {
+		targetRetryBackoff.MaxDelay = cfg.Operator.CommitRetry.MaxDelay
+	}
+	if cfg.Operator.CommitRetry.TargetWaitTimeout > 0 {
+		targetWaitTimeout = cfg.Operator.CommitRetry.TargetWaitTimeout
+	}
 }
@@ -0,0 +1,176 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package synchronizer
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	cfg "github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+)
+
+// placementRule 环境到 apisix 集群的放置规则
+type placementRule struct {
+	gateway string
+	stage   string
+	labels  map[string]string
+	targets map[string]struct{}
+}
+
+func (r *placementRule) match(gatewayName, stageName string, labels *entity.LabelInfo) bool {
+	if !matchPattern(r.gateway, gatewayName) || !matchPattern(r.stage, stageName) {
+		return false
+	}
+	for key, value := range r.labels {
+		if labels.Get(key) != value {
+			return false
+		}
+	}
+	return true
+}
+
+func matchPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
+
+// SetPlacement 设置放置规则, 按顺序匹配第一条规则, 没有匹配的规则时同步到所有集群; 需要在 AddTarget 之后调用
+func (as *ApisixConfigSynchronizer) SetPlacement(rules []cfg.PlacementRule) error {
+	names := make(map[string]struct{}, len(as.targets))
+	for _, t := range as.targets {
+		names[t.name] = struct{}{}
+	}
+
+	placement := make([]*placementRule, 0, len(rules))
+	for i, rule := range rules {
+		r := &placementRule{
+			gateway: rule.Gateway,
+			stage:   rule.Stage,
+			labels:  make(map[string]string, len(rule.Labels)),
+			targets: make(map[string]struct{}, len(rule.Targets)),
+		}
+		for _, label := range rule.Labels {
+			key, value, _ := strings.Cut(label, "=")
+			r.labels[key] = value
+		}
+		for _, name := range rule.Targets {
+			if _, ok := names[name]; !ok {
+				return fmt.Errorf("placement rule %d refers to unknown target %s", i, name)
+			}
+			r.targets[name] = struct{}{}
+		}
+		placement = append(placement, r)
+	}
+	as.placement = placement
+	return nil
+}
+
+// placedTargets 返回环境放置的集群, nil 表示所有集群
+func (as *ApisixConfigSynchronizer) placedTargets(
+	gatewayName, stageName string,
+	labels *entity.LabelInfo,
+) map[string]struct{} {
+	for _, rule := range as.placement {
+		if rule.match(gatewayName, stageName, labels) {
+			return rule.targets
+		}
+	}
+	return nil
+}
+
+// IsPlacedOnPrimary 环境是否放置在主集群上, 没有放置在主集群上的环境不应该在主集群上有资源
+func (as *ApisixConfigSynchronizer) IsPlacedOnPrimary(gatewayName, stageName string, labels *entity.LabelInfo) bool {
+	return isPlaced(as.placedTargets(gatewayName, stageName, labels), as.targets[0])
+}
+
+func isPlaced(placed map[string]struct{}, t *Target) bool {
+	if placed == nil {
+		return true
+	}
+	_, ok := placed[t.name]
+	return ok
+}
+
+// hasStage 集群上是否有环境的资源
+func hasStage(t *Target, key string) bool {
+	conf := t.store.Get(key)
+	return len(conf.Resources()) > 0
+}
+
+// stageLabels 从资源的 label 中获取发布的 label, 删除环境时配置为空, 返回 nil
+func stageLabels(conf *entity.ApisixStageResource) *entity.LabelInfo {
+	for _, route := range conf.Routes {
+		return route.Labels
+	}
+	for _, service := range conf.Services {
+		return service.Labels
+	}
+	for _, upstream := range conf.Upstreams {
+		return upstream.Labels
+	}
+	for _, pluginConfig := range conf.PluginConfigs {
+		return pluginConfig.Labels
+	}
+	for _, consumer := range conf.Consumers {
+		return consumer.Labels
+	}
+	for _, consumerGroup := range conf.ConsumerGroups {
+		return consumerGroup.Labels
+	}
+	for _, ssl := range conf.SSLs {
+		return ssl.Labels
+	}
+	for _, streamRoute := range conf.StreamRoutes {
+		return streamRoute.Labels
+	}
+	for _, proto := range conf.Protos {
+		return proto.Labels
+	}
+	return nil
+}
+
+// stageApplies 根据放置规则生成写入各个集群的函数: 放置的集群写入环境配置, 环境迁移走的集群清理资源, 其他集群跳过
+func stageApplies(
+	key string,
+	config *entity.ApisixStageResource,
+	placed map[string]struct{},
+) (func(t *Target) targetApply, error) {
+	apply, err := stageApply(key, config)
+	if err != nil {
+		return nil, err
+	}
+	cleanup, err := stageApply(key, entity.NewEmptyApisixConfiguration())
+	if err != nil {
+		return nil, err
+	}
+	return func(t *Target) targetApply {
+		if isPlaced(placed, t) {
+			return apply
+		}
+		if hasStage(t, key) {
+			return cleanup
+		}
+		return nil
+	}, nil
+}
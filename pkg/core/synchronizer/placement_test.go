@@ -0,0 +1,161 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package synchronizer_test
+
+import (
+	"context"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/tests/util"
+)
+
+var _ = Describe("ApisixConfigSynchronizer placement", func() {
+	var (
+		etcd        *embed.Etcd
+		client      *clientv3.Client
+		primary     *store.ApisixEtcdStore
+		targetStore *store.ApisixEtcdStore
+		syncer      *synchronizer.ApisixConfigSynchronizer
+		ctx         context.Context
+		stageKey    string
+	)
+
+	newStageConf := func(publishID, apisixVersion string) *entity.ApisixStageResource {
+		conf := entity.NewEmptyApisixConfiguration()
+		conf.Routes["route-1"] = &entity.Route{
+			ResourceMetadata: entity.ResourceMetadata{
+				ID: "route-1",
+				Labels: &entity.LabelInfo{
+					Gateway:       "dedicated-gateway",
+					Stage:         "prod",
+					PublishId:     publishID,
+					ApisixVersion: apisixVersion,
+				},
+			},
+			URI: "/v" + publishID,
+		}
+		return conf
+	}
+
+	routeURI := func(s *store.ApisixEtcdStore) func() string {
+		return func() string {
+			route := s.Get(stageKey).Routes["route-1"]
+			if route == nil {
+				return ""
+			}
+			return route.URI
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		metric.InitMetric(prometheus.NewRegistry())
+		synchronizer.Init(&config.Config{})
+		stageKey = config.GenStagePrimaryKey("dedicated-gateway", "prod")
+
+		client, etcd, err = util.StartEmbedEtcdClient(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		primary, err = store.NewApisixEtcdStore(
+			ctx, client, "/apisix", 10*time.Millisecond, 10*time.Millisecond, time.Second)
+		Expect(err).ShouldNot(HaveOccurred())
+		targetStore, err = store.NewApisixEtcdStore(
+			ctx, client, "/apisix-b", 10*time.Millisecond, 10*time.Millisecond, time.Second)
+		Expect(err).ShouldNot(HaveOccurred())
+		syncer = synchronizer.NewSynchronizer(primary, "/healthz")
+		syncer.AddTarget("zone-b", targetStore)
+	})
+
+	AfterEach(func() {
+		primary.Close()
+		targetStore.Close()
+		client.Close()
+		etcd.Close()
+		_ = os.RemoveAll(etcd.Config().Dir)
+	})
+
+	It("should reject the rules referring to unknown targets", func() {
+		err := syncer.SetPlacement([]config.PlacementRule{{Gateway: "dedicated-*", Targets: []string{"zone-c"}}})
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should match the first rule by gateway, stage and labels", func() {
+		Expect(syncer.SetPlacement([]config.PlacementRule{
+			{Labels: []string{"apisix-version=3.13.X"}, Targets: []string{"default"}},
+			{Gateway: "dedicated-*", Stage: "prod", Targets: []string{"zone-b"}},
+		})).To(Succeed())
+
+		Expect(syncer.IsPlacedOnPrimary("dedicated-gateway", "prod", nil)).To(BeFalse())
+		Expect(syncer.IsPlacedOnPrimary("dedicated-gateway", "test", nil)).To(BeTrue())
+		Expect(syncer.IsPlacedOnPrimary("shared-gateway", "prod", nil)).To(BeTrue())
+		Expect(syncer.IsPlacedOnPrimary("dedicated-gateway", "prod", &entity.LabelInfo{
+			ApisixVersion: "3.13.X",
+		})).To(BeTrue())
+	})
+
+	It("should sync the stage only to the placed targets and clean up when the placement moves", func() {
+		Expect(syncer.SetPlacement([]config.PlacementRule{
+			{Gateway: "dedicated-*", Targets: []string{"zone-b"}},
+		})).To(Succeed())
+
+		Expect(syncer.Sync(ctx, "dedicated-gateway", "prod", newStageConf("1", ""))).To(Succeed())
+		Eventually(routeURI(targetStore), 5*time.Second, 20*time.Millisecond).Should(Equal("/v1"))
+		Consistently(routeURI(primary), 200*time.Millisecond, 20*time.Millisecond).Should(BeEmpty())
+		Expect(syncer.TargetResults(stageKey)).To(Equal(map[string]string{
+			"zone-b": synchronizer.TargetSyncSuccess,
+		}))
+
+		// 环境迁移到主集群, 清理原集群上的资源
+		Expect(syncer.SetPlacement([]config.PlacementRule{
+			{Gateway: "dedicated-*", Targets: []string{"default"}},
+		})).To(Succeed())
+		Expect(syncer.Sync(ctx, "dedicated-gateway", "prod", newStageConf("2", ""))).To(Succeed())
+		Eventually(routeURI(primary), 5*time.Second, 20*time.Millisecond).Should(Equal("/v2"))
+		Eventually(routeURI(targetStore), 5*time.Second, 20*time.Millisecond).Should(BeEmpty())
+
+		// 原集群已经清理完成, 之后的发布不再写入
+		Expect(syncer.Sync(ctx, "dedicated-gateway", "prod", newStageConf("3", ""))).To(Succeed())
+		Eventually(routeURI(primary), 5*time.Second, 20*time.Millisecond).Should(Equal("/v3"))
+		Expect(syncer.TargetResults(stageKey)).To(Equal(map[string]string{
+			"default": synchronizer.TargetSyncSuccess,
+		}))
+	})
+
+	It("should sync the stage matching no rule to all targets", func() {
+		Expect(syncer.SetPlacement([]config.PlacementRule{
+			{Gateway: "shared-*", Targets: []string{"default"}},
+		})).To(Succeed())
+
+		Expect(syncer.Sync(ctx, "dedicated-gateway", "prod", newStageConf("1", ""))).To(Succeed())
+		Eventually(routeURI(primary), 5*time.Second, 20*time.Millisecond).Should(Equal("/v1"))
+		Eventually(routeURI(targetStore), 5*time.Second, 20*time.Millisecond).Should(Equal("/v1"))
+	})
+})
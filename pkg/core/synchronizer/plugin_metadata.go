@@ -0,0 +1,148 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package synchronizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+)
+
+// syncStagePluginMetadata 记录环境级别的插件元数据, 有变化时与全局资源合并后重新写入
+func (as *ApisixConfigSynchronizer) syncStagePluginMetadata(
+	ctx context.Context,
+	key string,
+	pluginMetadata map[string]*entity.PluginMetadata,
+) error {
+	as.globalMux.Lock()
+	defer as.globalMux.Unlock()
+
+	old, ok := as.stagePluginMetadata[key]
+	if samePluginMetadata(old, pluginMetadata) {
+		return nil
+	}
+	if len(pluginMetadata) == 0 {
+		delete(as.stagePluginMetadata, key)
+	} else {
+		as.stagePluginMetadata[key] = pluginMetadata
+	}
+	if as.globalConfig == nil {
+		// 全局资源还没有同步过, 直接写入会删除 apisix 中已有的全局插件元数据, 等全局资源同步时一起写入
+		as.logger.Infow("global resource has not been synced, defer the stage plugin metadata", "key", key)
+		return nil
+	}
+
+	as.logger.Infow("stage plugin metadata changed, sync global resource", "key", key)
+	err := as.applyGlobal(ctx, as.withStagePluginMetadata(as.globalConfig))
+	if err != nil {
+		// 恢复记录, 重试时重新写入
+		if ok {
+			as.stagePluginMetadata[key] = old
+		} else {
+			delete(as.stagePluginMetadata, key)
+		}
+		return err
+	}
+	as.claimPluginMetadata(pluginMetadata)
+	return nil
+}
+
+// claimPluginMetadata 记录全局资源或环境声明过的插件元数据; 调用方需要持有 globalMux 的写锁
+func (as *ApisixConfigSynchronizer) claimPluginMetadata(pluginMetadata map[string]*entity.PluginMetadata) {
+	for name := range pluginMetadata {
+		as.claimedPluginMetadata[name] = struct{}{}
+	}
+}
+
+// withStagePluginMetadata 将环境级别的插件元数据合并到全局资源中:
+// 环境的配置优先于全局的同名配置, 多个环境配置了同一个插件时按 stage key 排序取第一个;
+// 启动后还没有被声明过的插件元数据可能属于尚未重新同步的环境, 保留 apisix 中已有的配置
+func (as *ApisixConfigSynchronizer) withStagePluginMetadata(
+	config *entity.ApisixGlobalResource,
+) *entity.ApisixGlobalResource {
+	ret := entity.NewEmptyApisixGlobalResource()
+	maps.Copy(ret.PluginMetadata, config.PluginMetadata)
+	maps.Copy(ret.GlobalRules, config.GlobalRules)
+
+	for name, pm := range as.store.GetGlobal().PluginMetadata {
+		if _, ok := as.claimedPluginMetadata[name]; !ok {
+			as.logger.Debugw("plugin metadata has not been claimed since startup, keep it", "name", name)
+			ret.PluginMetadata[name] = pm
+		}
+	}
+
+	owners := make(map[string]string)
+	for _, key := range slices.Sorted(maps.Keys(as.stagePluginMetadata)) {
+		for name, pm := range as.stagePluginMetadata[key] {
+			if owner, ok := owners[name]; ok {
+				as.logger.Warnw("plugin metadata is defined by multiple stages, ignore it",
+					"name", name, "stage", key, "owner", owner)
+				continue
+			}
+			owners[name] = key
+			// 写入 apisix 时不带环境信息, 与全局的插件元数据保持一致
+			ret.PluginMetadata[name] = &entity.PluginMetadata{
+				ResourceMetadata: entity.ResourceMetadata{
+					ID:         pm.ID,
+					Kind:       pm.Kind,
+					APIVersion: pm.APIVersion,
+					Ctx:        pm.Ctx,
+				},
+				PluginMetadataConf: pm.PluginMetadataConf,
+			}
+		}
+	}
+	return ret
+}
+
+// ResyncGlobal 重新写入最近一次同步的全局资源与环境的插件元数据, 用于修复 apisix 中被改动的插件元数据
+func (as *ApisixConfigSynchronizer) ResyncGlobal(ctx context.Context) error {
+	as.globalMux.Lock()
+	defer as.globalMux.Unlock()
+	if as.globalConfig == nil {
+		return nil
+	}
+	return as.applyGlobal(ctx, as.withStagePluginMetadata(as.globalConfig))
+}
+
+// PluginMetadataOwner 返回写入 apisix 的插件元数据来自哪个环境, 多个环境配置了同一个插件时按 stage key 排序取第一个;
+// 没有环境配置该插件, 或者启动后配置该插件的环境还没有同步时返回空
+func (as *ApisixConfigSynchronizer) PluginMetadataOwner(name string) string {
+	as.globalMux.RLock()
+	defer as.globalMux.RUnlock()
+	for _, key := range slices.Sorted(maps.Keys(as.stagePluginMetadata)) {
+		if _, ok := as.stagePluginMetadata[key][name]; ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// samePluginMetadata 比较两组插件元数据的配置是否相同
+func samePluginMetadata(a, b map[string]*entity.PluginMetadata) bool {
+	return maps.EqualFunc(a, b, func(x, y *entity.PluginMetadata) bool {
+		return maps.EqualFunc(x.PluginMetadataConf, y.PluginMetadataConf, func(m, n json.RawMessage) bool {
+			return bytes.Equal(m, n)
+		})
+	})
+}
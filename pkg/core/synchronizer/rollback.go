@@ -0,0 +1,213 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package synchronizer
+
+import (
+	"context"
+	"errors"
+
+	cfg "github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+// ErrSnapshotDisabled 未开启发布快照, 无法回滚
+var ErrSnapshotDisabled = errors.New("release snapshot is not enabled")
+
+// EnableSnapshot 开启发布快照, 并加载已有的回滚记录
+func (as *ApisixConfigSynchronizer) EnableSnapshot(ctx context.Context, snapshots *store.SnapshotStore) error {
+	pins, err := snapshots.Pins(ctx)
+	if err != nil {
+		return err
+	}
+	as.pinsMux.Lock()
+	defer as.pinsMux.Unlock()
+	as.snapshots = snapshots
+	as.pins = pins
+	return nil
+}
+
+// ListSnapshots 返回环境的快照, 最近应用的在前
+func (as *ApisixConfigSynchronizer) ListSnapshots(
+	ctx context.Context,
+	gatewayName, stageName string,
+) ([]*store.Snapshot, error) {
+	if as.snapshots == nil {
+		return nil, ErrSnapshotDisabled
+	}
+	return as.snapshots.List(ctx, gatewayName, stageName)
+}
+
+// Rollback 将环境回滚到指定发布版本的快照
+func (as *ApisixConfigSynchronizer) Rollback(
+	ctx context.Context,
+	gatewayName, stageName, publishID string,
+) (*store.Snapshot, error) {
+	if as.snapshots == nil {
+		return nil, ErrSnapshotDisabled
+	}
+	snapshot, err := as.snapshots.Get(ctx, gatewayName, stageName, publishID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := cfg.GenStagePrimaryKey(gatewayName, stageName)
+	release, unlock, err := as.acquireStage(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	err = as.rollback(ctx, key, snapshot)
+	release()
+	metric.ReportStageRollbackMetric(gatewayName, stageName, err)
+	if err != nil {
+		return snapshot, err
+	}
+	return snapshot, as.rollbackPluginMetadata(ctx, key, snapshot)
+}
+
+// RollbackToPrevious 将环境回滚到 failedPublishID 之前最近应用的快照, 用于发布后版本探测失败时自动回滚
+func (as *ApisixConfigSynchronizer) RollbackToPrevious(
+	ctx context.Context,
+	gatewayName, stageName, failedPublishID string,
+) (snapshot *store.Snapshot, err error) {
+	if as.snapshots == nil {
+		return nil, ErrSnapshotDisabled
+	}
+	key := cfg.GenStagePrimaryKey(gatewayName, stageName)
+	release, unlock, err := as.acquireStage(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	snapshot, err = as.rollbackToPrevious(ctx, key, gatewayName, stageName, failedPublishID)
+	release()
+	if err != nil || snapshot == nil {
+		return snapshot, err
+	}
+	return snapshot, as.rollbackPluginMetadata(ctx, key, snapshot)
+}
+
+// rollbackToPrevious 调用方需要持有环境锁
+func (as *ApisixConfigSynchronizer) rollbackToPrevious(
+	ctx context.Context,
+	key, gatewayName, stageName, failedPublishID string,
+) (snapshot *store.Snapshot, err error) {
+	// 已经回滚过, 避免重复回滚到更早的版本
+	if as.pinnedPublishID(key) == failedPublishID {
+		return nil, nil
+	}
+
+	snapshots, err := as.snapshots.List(ctx, gatewayName, stageName)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range snapshots {
+		if s.PublishID != failedPublishID {
+			snapshot = s
+			break
+		}
+	}
+	if snapshot == nil {
+		return nil, store.ErrSnapshotNotFound
+	}
+
+	err = as.rollback(ctx, key, snapshot)
+	metric.ReportStageRollbackMetric(gatewayName, stageName, err)
+	return snapshot, err
+}
+
+// rollback 应用快照, 并记录被撤下的发布版本, 避免 committer 或漂移对账再次应用它; 调用方需要持有环境锁
+func (as *ApisixConfigSynchronizer) rollback(ctx context.Context, key string, snapshot *store.Snapshot) error {
+	// 连续回滚时保留最初被撤下的版本, 它才是控制面上的最新发布
+	current := as.pinnedPublishID(key)
+	if current == "" {
+		snapshots, err := as.snapshots.List(ctx, snapshot.Gateway, snapshot.Stage)
+		if err != nil {
+			return err
+		}
+		if len(snapshots) > 0 {
+			current = snapshots[0].PublishID
+		}
+	}
+
+	err := as.apply(ctx, snapshot.Gateway, snapshot.Stage, snapshot.PublishID, snapshot.Resources)
+	if err != nil {
+		return err
+	}
+
+	if current == "" || current == snapshot.PublishID {
+		as.unpin(ctx, key)
+	} else {
+		as.pin(ctx, key, current)
+	}
+	as.logger.Infow("rollback stage success", "key", key, "publishID", snapshot.PublishID, "rolledBack", current)
+	return nil
+}
+
+// rollbackPluginMetadata 回滚环境级别的插件元数据, 需要写入全局资源; 调用方需要持有环境锁, 不能持有全局资源读锁
+func (as *ApisixConfigSynchronizer) rollbackPluginMetadata(
+	ctx context.Context,
+	key string,
+	snapshot *store.Snapshot,
+) error {
+	pluginMetadata := make(map[string]*entity.PluginMetadata, len(snapshot.Resources.PluginMetadata))
+	for name, pm := range snapshot.Resources.PluginMetadata {
+		// 快照中的插件元数据只保存了插件的配置, 元数据由插件名称还原
+		pm.ID, pm.Name, pm.Kind = name, name, constant.PluginMetadata
+		pluginMetadata[name] = pm
+	}
+	return as.syncStagePluginMetadata(ctx, key, pluginMetadata)
+}
+
+func (as *ApisixConfigSynchronizer) pinnedPublishID(key string) string {
+	as.pinsMux.Lock()
+	defer as.pinsMux.Unlock()
+	return as.pins[key]
+}
+
+// isRolledBack 发布版本是否已经被回滚撤下
+func (as *ApisixConfigSynchronizer) isRolledBack(key, publishID string) bool {
+	return publishID != "" && as.pinnedPublishID(key) == publishID
+}
+
+func (as *ApisixConfigSynchronizer) pin(ctx context.Context, key, publishID string) {
+	as.pinsMux.Lock()
+	as.pins[key] = publishID
+	as.pinsMux.Unlock()
+	// 持久化失败时仅在内存中生效, 重启后可能会重新应用被撤下的版本
+	if err := as.snapshots.Pin(ctx, key, publishID); err != nil {
+		as.logger.Errorw("Failed to save rollback pin", "err", err, "key", key, "publishID", publishID)
+	}
+}
+
+func (as *ApisixConfigSynchronizer) unpin(ctx context.Context, key string) {
+	as.pinsMux.Lock()
+	_, ok := as.pins[key]
+	delete(as.pins, key)
+	as.pinsMux.Unlock()
+	if !ok || as.snapshots == nil {
+		return
+	}
+	if err := as.snapshots.Unpin(ctx, key); err != nil {
+		as.logger.Errorw("Failed to delete rollback pin", "err", err, "key", key)
+	}
+}
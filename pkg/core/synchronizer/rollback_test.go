@@ -0,0 +1,207 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package synchronizer_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/tests/util"
+)
+
+var _ = Describe("ApisixConfigSynchronizer rollback", func() {
+	var (
+		etcd      *embed.Etcd
+		client    *clientv3.Client
+		etcdStore *store.ApisixEtcdStore
+		snapshots *store.SnapshotStore
+		syncer    *synchronizer.ApisixConfigSynchronizer
+		ctx       context.Context
+		stageKey  string
+	)
+
+	newStageConf := func(publishID string) *entity.ApisixStageResource {
+		conf := entity.NewEmptyApisixConfiguration()
+		conf.Routes["route-1"] = &entity.Route{
+			ResourceMetadata: entity.ResourceMetadata{
+				ID: "route-1",
+				Labels: &entity.LabelInfo{
+					Gateway:   "test-gateway",
+					Stage:     "test-stage",
+					PublishId: publishID,
+				},
+			},
+			URI: "/v" + publishID,
+		}
+		return conf
+	}
+
+	currentURI := func() string {
+		route := etcdStore.Get(stageKey).Routes["route-1"]
+		if route == nil {
+			return ""
+		}
+		return route.URI
+	}
+
+	// 等待 store 的缓存看到写入, 下一次同步才能 diff 出正确的变更
+	syncAndWait := func(publishID string) {
+		Expect(syncer.Sync(ctx, "test-gateway", "test-stage", newStageConf(publishID))).To(Succeed())
+		Eventually(currentURI, 5*time.Second, 20*time.Millisecond).Should(Equal("/v" + publishID))
+	}
+
+	snapshotIDs := func() []string {
+		list, err := syncer.ListSnapshots(ctx, "test-gateway", "test-stage")
+		Expect(err).ShouldNot(HaveOccurred())
+		ids := make([]string, 0, len(list))
+		for _, snapshot := range list {
+			ids = append(ids, snapshot.PublishID)
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		metric.InitMetric(prometheus.NewRegistry())
+		synchronizer.Init(&config.Config{})
+		stageKey = config.GenStagePrimaryKey("test-gateway", "test-stage")
+
+		client, etcd, err = util.StartEmbedEtcdClient(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		etcdStore, err = store.NewApisixEtcdStore(ctx, client, "/apisix",
+			10*time.Millisecond, 10*time.Millisecond, 5*time.Second)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		syncer = synchronizer.NewSynchronizer(etcdStore, "/healthz")
+		snapshots = store.NewSnapshotStore(client, "/snapshots", 2, 5*time.Second)
+		Expect(syncer.EnableSnapshot(ctx, snapshots)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		etcdStore.Close()
+		client.Close()
+		etcd.Close()
+		_ = os.RemoveAll(etcd.Config().Dir)
+	})
+
+	It("should return ErrSnapshotDisabled when snapshot is not enabled", func() {
+		plain := synchronizer.NewSynchronizer(etcdStore, "/healthz")
+		_, err := plain.Rollback(ctx, "test-gateway", "test-stage", "1")
+		Expect(err).To(MatchError(synchronizer.ErrSnapshotDisabled))
+		_, err = plain.ListSnapshots(ctx, "test-gateway", "test-stage")
+		Expect(err).To(MatchError(synchronizer.ErrSnapshotDisabled))
+	})
+
+	It("should keep the latest snapshots and rollback to one of them", func() {
+		syncAndWait("1")
+		syncAndWait("2")
+		Expect(snapshotIDs()).To(Equal([]string{"2", "1"}))
+
+		_, err := syncer.Rollback(ctx, "test-gateway", "test-stage", "9")
+		Expect(err).To(MatchError(store.ErrSnapshotNotFound))
+
+		snapshot, err := syncer.Rollback(ctx, "test-gateway", "test-stage", "1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapshot.PublishID).To(Equal("1"))
+		Eventually(currentURI, 5*time.Second, 20*time.Millisecond).Should(Equal("/v1"))
+		Expect(snapshotIDs()).To(Equal([]string{"1", "2"}))
+
+		// 被撤下的发布再次同步时跳过, 并且重启后仍然生效
+		Expect(syncer.Sync(ctx, "test-gateway", "test-stage", newStageConf("2"))).To(Succeed())
+		Consistently(currentURI, 200*time.Millisecond, 20*time.Millisecond).Should(Equal("/v1"))
+		Expect(snapshots.Pins(ctx)).To(Equal(map[string]string{stageKey: "2"}))
+
+		// 新的发布正常生效, 并清理回滚记录
+		syncAndWait("3")
+		Expect(snapshots.Pins(ctx)).To(BeEmpty())
+		Expect(snapshotIDs()).To(Equal([]string{"3", "1"}))
+	})
+
+	It("should rollback to the previous snapshot only once", func() {
+		syncAndWait("1")
+		syncAndWait("2")
+
+		snapshot, err := syncer.RollbackToPrevious(ctx, "test-gateway", "test-stage", "2")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapshot.PublishID).To(Equal("1"))
+		Eventually(currentURI, 5*time.Second, 20*time.Millisecond).Should(Equal("/v1"))
+
+		snapshot, err = syncer.RollbackToPrevious(ctx, "test-gateway", "test-stage", "2")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapshot).To(BeNil())
+
+		// 新实例加载已有的回滚记录
+		restarted := synchronizer.NewSynchronizer(etcdStore, "/healthz")
+		Expect(restarted.EnableSnapshot(ctx, snapshots)).To(Succeed())
+		Expect(restarted.Sync(ctx, "test-gateway", "test-stage", newStageConf("2"))).To(Succeed())
+		Consistently(currentURI, 200*time.Millisecond, 20*time.Millisecond).Should(Equal("/v1"))
+	})
+
+	It("should fail to rollback to previous without an earlier snapshot", func() {
+		syncAndWait("1")
+		_, err := syncer.RollbackToPrevious(ctx, "test-gateway", "test-stage", "1")
+		Expect(err).To(MatchError(store.ErrSnapshotNotFound))
+	})
+
+	It("should rollback the stage plugin metadata with the stage", func() {
+		withPluginMetadata := func(publishID string) *entity.ApisixStageResource {
+			conf := newStageConf(publishID)
+			pm := createPluginMetadata("file-logger", map[string]any{"path": "/logs/v" + publishID + ".log"})
+			pm.Labels = &entity.LabelInfo{Gateway: "test-gateway", Stage: "test-stage", PublishId: publishID}
+			conf.PluginMetadata["file-logger"] = pm
+			return conf
+		}
+		pluginMetadataPath := func() string {
+			resp, err := client.Get(ctx, "/apisix/plugin_metadata/file-logger")
+			Expect(err).ShouldNot(HaveOccurred())
+			if len(resp.Kvs) == 0 {
+				return ""
+			}
+			var value map[string]any
+			Expect(json.Unmarshal(resp.Kvs[0].Value, &value)).To(Succeed())
+			return value["path"].(string)
+		}
+
+		Expect(syncer.SyncGlobal(ctx, entity.NewEmptyApisixGlobalResource())).To(Succeed())
+		Expect(syncer.Sync(ctx, "test-gateway", "test-stage", withPluginMetadata("1"))).To(Succeed())
+		Eventually(currentURI, 5*time.Second, 20*time.Millisecond).Should(Equal("/v1"))
+		Expect(syncer.Sync(ctx, "test-gateway", "test-stage", withPluginMetadata("2"))).To(Succeed())
+		Eventually(currentURI, 5*time.Second, 20*time.Millisecond).Should(Equal("/v2"))
+		Expect(pluginMetadataPath()).To(Equal("/logs/v2.log"))
+
+		_, err := syncer.Rollback(ctx, "test-gateway", "test-stage", "1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Eventually(currentURI, 5*time.Second, 20*time.Millisecond).Should(Equal("/v1"))
+		Expect(pluginMetadataPath()).To(Equal("/logs/v1.log"))
+	})
+})
@@ -28,6 +28,7 @@ import (
 	"go.uber.org/zap"
 
 	cfg "github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
@@ -35,9 +36,34 @@ import (
 )
 
 // ApisixConfigSynchronizer synchronizes the API Gateway configuration.
+// 不同环境的同步最多并行 concurrencyLimit 个, 同一环境的同步串行执行, 全局资源的同步独占执行
 type ApisixConfigSynchronizer struct {
-	store    *store.ApisixEtcdStore
-	flushMux sync.Mutex
+	store store.ApisixStore
+	// targets 同步的 apisix 集群, 第一个为 store 对应的主集群
+	targets []*Target
+	// placement 环境到集群的放置规则, 为空时同步到所有集群
+	placement []*placementRule
+
+	// globalMux 环境同步持有读锁, 全局资源同步持有写锁
+	globalMux sync.RWMutex
+	// globalConfig 最近一次同步的全局资源, stagePluginMetadata 各环境的插件元数据, 两者合并后写入 apisix;
+	// 由 globalMux 的写锁保护
+	globalConfig        *entity.ApisixGlobalResource
+	stagePluginMetadata map[string]map[string]*entity.PluginMetadata
+	// claimedPluginMetadata 启动后全局资源或环境声明过的插件元数据名称, 由 globalMux 的写锁保护;
+	// 重启后环境的插件元数据需要等环境重新同步才能恢复, 未声明过的插件元数据写入全局资源时保留
+	claimedPluginMetadata map[string]struct{}
+	// slots 环境同步的并发槽位
+	slots chan struct{}
+
+	stageLocksMux sync.Mutex
+	stageLocks    map[string]*stageLock
+
+	// snapshots 不为 nil 时每次同步成功后保存环境快照, 用于回滚
+	snapshots *store.SnapshotStore
+	// pins 回滚时被撤下的发布版本, key 为 stage key
+	pinsMux sync.Mutex
+	pins    map[string]string
 
 	apisixHealthzURI string
 
@@ -45,15 +71,81 @@ type ApisixConfigSynchronizer struct {
 }
 
 // NewSynchronizer create new Synchronizer
-func NewSynchronizer(store *store.ApisixEtcdStore, apisixHealthzURI string) *ApisixConfigSynchronizer {
+func NewSynchronizer(store store.ApisixStore, apisixHealthzURI string) *ApisixConfigSynchronizer {
 	syncer := &ApisixConfigSynchronizer{
-		store:            store,
-		apisixHealthzURI: apisixHealthzURI,
-		logger:           logging.GetLogger().Named("apisix-config-synchronizer"),
+		store:                 store,
+		targets:               []*Target{newTarget(primaryTargetName, store, true)},
+		stagePluginMetadata:   make(map[string]map[string]*entity.PluginMetadata),
+		claimedPluginMetadata: make(map[string]struct{}),
+		slots:                 make(chan struct{}, concurrencyLimit),
+		stageLocks:            make(map[string]*stageLock),
+		pins:                  make(map[string]string),
+		apisixHealthzURI:      apisixHealthzURI,
+		logger:                logging.GetLogger().Named("apisix-config-synchronizer"),
 	}
 	return syncer
 }
 
+// stageLock 同一环境的同步锁, refs 为持有或等待该锁的同步数量, 归零后从 map 中移除
+type stageLock struct {
+	mux  sync.Mutex
+	refs int
+}
+
+// lockStage 加环境锁, 返回解锁函数
+func (as *ApisixConfigSynchronizer) lockStage(key string) func() {
+	as.stageLocksMux.Lock()
+	lock, ok := as.stageLocks[key]
+	if !ok {
+		lock = &stageLock{}
+		as.stageLocks[key] = lock
+	}
+	lock.refs++
+	as.stageLocksMux.Unlock()
+
+	lock.mux.Lock()
+	return func() {
+		lock.mux.Unlock()
+		as.stageLocksMux.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(as.stageLocks, key)
+		}
+		as.stageLocksMux.Unlock()
+	}
+}
+
+// acquireStage 依次获取环境锁、并发槽位和全局资源读锁; release 释放并发槽位和全局资源读锁, unlock 释放环境锁,
+// 调用方可以先 release 再继续持有环境锁写入环境的插件元数据, 保持先环境锁后全局资源锁的顺序
+func (as *ApisixConfigSynchronizer) acquireStage(ctx context.Context, key string) (release, unlock func(), err error) {
+	metric.ReportSyncQueuedMetric(metric.SyncTypeStage, 1)
+	unlockStage := as.lockStage(key)
+	select {
+	case as.slots <- struct{}{}:
+	case <-ctx.Done():
+		unlockStage()
+		metric.ReportSyncQueuedMetric(metric.SyncTypeStage, -1)
+		return nil, nil, ctx.Err()
+	}
+	as.globalMux.RLock()
+	metric.ReportSyncQueuedMetric(metric.SyncTypeStage, -1)
+
+	metric.ReportSyncInFlightMetric(metric.SyncTypeStage, 1)
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			metric.ReportSyncInFlightMetric(metric.SyncTypeStage, -1)
+			as.globalMux.RUnlock()
+			<-as.slots
+		})
+	}
+	unlock = func() {
+		release()
+		unlockStage()
+	}
+	return release, unlock, nil
+}
+
 // Sync will sync new staged apisix configuration
 func (as *ApisixConfigSynchronizer) Sync(
 	ctx context.Context,
@@ -62,11 +154,95 @@ func (as *ApisixConfigSynchronizer) Sync(
 ) error {
 	key := cfg.GenStagePrimaryKey(gatewayName, stageName)
 
-	as.flushMux.Lock()
-	defer as.flushMux.Unlock()
+	release, unlock, err := as.acquireStage(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	applied, err := as.syncStage(ctx, key, gatewayName, stageName, config)
+	release()
+	if err != nil || !applied {
+		return err
+	}
+	// 插件元数据不属于环境资源, 需要合并到全局资源中写入; 释放全局资源读锁后仍持有环境锁,
+	// 避免同一环境的其他同步在这之后写入旧的插件元数据
+	var pluginMetadata map[string]*entity.PluginMetadata
+	if config != nil {
+		pluginMetadata = config.PluginMetadata
+	}
+	return as.syncStagePluginMetadata(ctx, key, pluginMetadata)
+}
+
+// syncStage 写入环境配置, 返回发布是否生效; 调用方需要持有环境锁和全局资源读锁
+func (as *ApisixConfigSynchronizer) syncStage(
+	ctx context.Context,
+	key, gatewayName, stageName string,
+	config *entity.ApisixStageResource,
+) (bool, error) {
+	publishID := store.StagePublishID(config)
+	if as.isRolledBack(key, publishID) {
+		as.logger.Infow("skip the release which has been rolled back", "key", key, "publishID", publishID)
+		return false, nil
+	}
+
+	err := as.apply(ctx, gatewayName, stageName, publishID, config)
+	if err != nil {
+		return false, err
+	}
+	// 新的发布已经生效, 清理回滚记录
+	as.unpin(ctx, key)
+	return true, nil
+}
+
+// apply 写入环境配置, 成功后保存快照; 调用方需要持有环境锁
+func (as *ApisixConfigSynchronizer) apply(
+	ctx context.Context,
+	gatewayName, stageName, publishID string,
+	config *entity.ApisixStageResource,
+) error {
+	key := cfg.GenStagePrimaryKey(gatewayName, stageName)
+
+	// 写入时会修改资源, 需要在写入之前生成快照
+	var snapshot *store.Snapshot
+	if as.snapshots != nil && publishID != "" {
+		var err error
+		snapshot, err = store.NewSnapshot(gatewayName, stageName, publishID, config)
+		if err != nil {
+			as.logger.Errorw("Failed to make stage snapshot", "err", err, "key", key, "publishID", publishID)
+		}
+	}
+	// 快照中包含插件元数据, 写入的环境资源中不包含, 由调用方写入
+	config = withoutPluginMetadata(config)
+
+	placed := as.placedTargets(gatewayName, stageName, stageLabels(config))
 
-	as.logger.Debugw("flush changes", "key", key, "config", config)
-	err := as.store.Alter(ctx, key, config)
+	// 写入主集群时会修改资源, 需要在写入之前为其他集群生成配置
+	wait := func() {}
+	if len(as.targets) > 1 {
+		applyTo, err := stageApplies(key, config, placed)
+		if err != nil {
+			return err
+		}
+		wait = as.syncTargets(ctx, key, publishID, applyTo)
+	}
+
+	var err error
+	primary := as.targets[0]
+	switch {
+	case isPlaced(placed, primary):
+		as.logger.Debugw("flush changes", "key", key, "config", config)
+		err = primary.alterStage(ctx, key, config)
+		as.recordPrimary(key, publishID, err)
+	case hasStage(primary, key):
+		// 环境已经迁移到其他集群, 清理主集群上的资源
+		as.logger.Infow("clean up stage which is not placed on primary target", "key", key)
+		err = primary.alterStage(ctx, key, entity.NewEmptyApisixConfiguration())
+		as.recordPrimary(key, publishID, err)
+	default:
+		primary.forget(key)
+	}
+	wait()
 	if err != nil {
 		as.logger.Errorw("Failed to sync stage", "err", err, "key", key, "content", config)
 		return err
@@ -74,16 +250,59 @@ func (as *ApisixConfigSynchronizer) Sync(
 
 	metric.ReportStageConfigSyncMetric(gatewayName, stageName)
 
+	if snapshot != nil {
+		// 快照保存失败不影响发布结果
+		if err = as.snapshots.Save(ctx, snapshot); err != nil {
+			as.logger.Errorw("Failed to save stage snapshot", "err", err, "key", key, "publishID", publishID)
+		}
+	}
 	return nil
 }
 
+// withoutPluginMetadata 返回不包含插件元数据的环境配置, 不修改传入的配置
+func withoutPluginMetadata(config *entity.ApisixStageResource) *entity.ApisixStageResource {
+	if config == nil || config.PluginMetadata == nil {
+		return config
+	}
+	stageConfig := *config
+	stageConfig.PluginMetadata = nil
+	return &stageConfig
+}
+
 // SyncGlobal 同步全局资源配置到 apisix etcd
 func (as *ApisixConfigSynchronizer) SyncGlobal(
 	ctx context.Context,
 	config *entity.ApisixGlobalResource,
 ) error {
-	as.flushMux.Lock()
-	defer as.flushMux.Unlock()
+	metric.ReportSyncQueuedMetric(metric.SyncTypeGlobal, 1)
+	as.globalMux.Lock()
+	defer as.globalMux.Unlock()
+	metric.ReportSyncQueuedMetric(metric.SyncTypeGlobal, -1)
+
+	metric.ReportSyncInFlightMetric(metric.SyncTypeGlobal, 1)
+	defer metric.ReportSyncInFlightMetric(metric.SyncTypeGlobal, -1)
+
+	// 写入时会修改资源, 保留一份用于和环境级别的插件元数据重新合并
+	as.globalConfig = store.CopyGlobalResource(config)
+	as.claimPluginMetadata(config.PluginMetadata)
+	return as.applyGlobal(ctx, as.withStagePluginMetadata(config))
+}
+
+// applyGlobal 写入全局资源配置; 调用方需要持有 globalMux 的写锁
+func (as *ApisixConfigSynchronizer) applyGlobal(ctx context.Context, config *entity.ApisixGlobalResource) error {
+	wait := func() {}
+	if len(as.targets) > 1 {
+		// 全局资源同步到所有集群
+		apply := as.globalApply(config)
+		wait = as.syncTargets(ctx, constant.GlobalResourceKey, "", func(*Target) targetApply { return apply })
+	}
+	err := as.syncGlobal(ctx, config)
+	as.recordPrimary(constant.GlobalResourceKey, "", err)
+	wait()
+	return err
+}
+
+func (as *ApisixConfigSynchronizer) syncGlobal(ctx context.Context, config *entity.ApisixGlobalResource) error {
 	as.logger.Debugw("flush global changes", "config", config)
 	err := as.store.AlterGlobal(ctx, config)
 	if err != nil {
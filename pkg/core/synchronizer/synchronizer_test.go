@@ -21,12 +21,16 @@ package synchronizer_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/server/v3/embed"
 
@@ -548,6 +552,111 @@ var _ = Describe("ApisixConfigSynchronizer with EmbedEtcd", func() {
 		})
 	})
 
+	Describe("Sync concurrently", func() {
+		It("should sync stages in parallel and serialize the same stage", func() {
+			synchronizer.Init(&config.Config{Operator: config.Operator{AgentConcurrencyLimit: 4}})
+			parallelSyncer := synchronizer.NewSynchronizer(etcdStore, apisixHealthzURI)
+
+			newConfig := func(gateway, uri string) *entity.ApisixStageResource {
+				id := gateway + "-route"
+				return &entity.ApisixStageResource{
+					Routes: map[string]*entity.Route{
+						id: {
+							ResourceMetadata: entity.ResourceMetadata{
+								ID:     id,
+								Labels: &entity.LabelInfo{Gateway: gateway, Stage: "prod"},
+							},
+							URI:    uri,
+							Status: 1,
+						},
+					},
+				}
+			}
+
+			wg := sync.WaitGroup{}
+			errs := make(chan error, 17)
+			for i := 0; i < 8; i++ {
+				gateway := fmt.Sprintf("gateway-%d", i)
+				for _, uri := range []string{"/v1/*", "/v2/*"} {
+					wg.Add(1)
+					go func() {
+						defer GinkgoRecover()
+						defer wg.Done()
+						errs <- parallelSyncer.Sync(ctx, gateway, "prod", newConfig(gateway, uri))
+					}()
+				}
+			}
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				errs <- parallelSyncer.SyncGlobal(ctx, entity.NewEmptyApisixGlobalResource())
+			}()
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+
+			for i := 0; i < 8; i++ {
+				resp, err := client.Get(ctx, fmt.Sprintf("/apisix/routes/gateway-%d-route", i))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(resp.Kvs).To(HaveLen(1))
+			}
+			for _, syncType := range []string{metric.SyncTypeStage, metric.SyncTypeGlobal} {
+				Expect(testutil.ToFloat64(metric.SynchronizerQueuedGauge.WithLabelValues(syncType))).To(BeZero())
+				Expect(testutil.ToFloat64(metric.SynchronizerInFlightGauge.WithLabelValues(syncType))).To(BeZero())
+			}
+		})
+
+		It("should only let one of the stages listening on the same port through", func() {
+			synchronizer.Init(&config.Config{Operator: config.Operator{AgentConcurrencyLimit: 4}})
+			parallelSyncer := synchronizer.NewSynchronizer(etcdStore, apisixHealthzURI)
+
+			newConfig := func(gateway string) *entity.ApisixStageResource {
+				id := "bk-apigw." + gateway + ".prod.tcp"
+				conf := entity.NewEmptyApisixConfiguration()
+				conf.StreamRoutes[id] = &entity.StreamRoute{
+					ResourceMetadata: entity.ResourceMetadata{
+						ID:     id,
+						Labels: &entity.LabelInfo{Gateway: gateway, Stage: "prod", PublishId: "1"},
+					},
+					ServerPort: 9100,
+					UpstreamID: "1",
+				}
+				return conf
+			}
+
+			wg := sync.WaitGroup{}
+			errs := make(chan error, 8)
+			for i := 0; i < 8; i++ {
+				gateway := fmt.Sprintf("gateway%d", i)
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					errs <- parallelSyncer.Sync(ctx, gateway, "prod", newConfig(gateway))
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			succeeded := 0
+			for err := range errs {
+				if err == nil {
+					succeeded++
+					continue
+				}
+				Expect(errors.Is(err, store.ErrStreamRouteConflict)).To(BeTrue())
+			}
+			Expect(succeeded).To(Equal(1))
+
+			resp, err := client.Get(ctx, "/apisix/stream_routes/", clientv3.WithPrefix())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resp.Kvs).To(HaveLen(1))
+		})
+	})
+
 	Describe("RemoveNotExistStage", func() {
 		It("should remove stages that no longer exist", func() {
 			// Create configs for multiple stages
@@ -774,6 +883,33 @@ var _ = Describe("ApisixConfigSynchronizer with EmbedEtcd", func() {
 			Expect(resp.Kvs).To(HaveLen(0))
 		})
 
+		It("should keep the global rules not managed by the operator", func() {
+			_, err := client.Put(ctx, "/apisix/global_rules/manual", `{"id":"manual","plugins":{"prometheus":{}}}`)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			globalConfig := entity.NewEmptyApisixGlobalResource()
+			globalConfig.GlobalRules["bk-apigw.1"] = &entity.GlobalRule{
+				ResourceMetadata: entity.ResourceMetadata{ID: "bk-apigw.1"},
+				Plugins:          map[string]any{"cors": map[string]any{}},
+			}
+			err = syncer.SyncGlobal(ctx, globalConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			time.Sleep(200 * time.Millisecond)
+
+			// 发布的全局规则下线后，手工维护的全局规则仍然保留
+			err = syncer.SyncGlobal(ctx, entity.NewEmptyApisixGlobalResource())
+			Expect(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Get(ctx, "/apisix/global_rules/bk-apigw.1")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resp.Kvs).To(HaveLen(0))
+
+			resp, err = client.Get(ctx, "/apisix/global_rules/manual")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resp.Kvs).To(HaveLen(1))
+		})
+
 		It("should sync virtual stage along with global config", func() {
 			globalConfig := &entity.ApisixGlobalResource{
 				PluginMetadata: map[string]*entity.PluginMetadata{
@@ -849,5 +985,98 @@ var _ = Describe("ApisixConfigSynchronizer with EmbedEtcd", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(resp.Kvs).To(HaveLen(0))
 		})
+
+		It("should override the global plugin metadata with the stage one", func() {
+			stagePluginMetadata := func(path string) *entity.ApisixStageResource {
+				conf := entity.NewEmptyApisixConfiguration()
+				pm := createPluginMetadata("file-logger", map[string]any{"path": path})
+				pm.Labels = &entity.LabelInfo{Gateway: "test-gateway", Stage: "test-stage"}
+				conf.PluginMetadata["file-logger"] = pm
+				return conf
+			}
+			pluginMetadataPath := func() string {
+				resp, err := client.Get(ctx, "/apisix/plugin_metadata/file-logger")
+				Expect(err).ShouldNot(HaveOccurred())
+				if len(resp.Kvs) == 0 {
+					return ""
+				}
+				var value map[string]any
+				Expect(json.Unmarshal(resp.Kvs[0].Value, &value)).To(Succeed())
+				Expect(value).NotTo(HaveKey("labels"))
+				return value["path"].(string)
+			}
+
+			// 全局资源同步之前只记录环境的插件元数据, 不修改传入的配置
+			stageConfig := stagePluginMetadata("/logs/stage.log")
+			err := syncer.Sync(ctx, "test-gateway", "test-stage", stageConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(pluginMetadataPath()).To(BeEmpty())
+			Expect(stageConfig.PluginMetadata).To(HaveKey("file-logger"))
+
+			globalConfig := &entity.ApisixGlobalResource{
+				PluginMetadata: map[string]*entity.PluginMetadata{
+					"file-logger": createPluginMetadata("file-logger", map[string]any{
+						"path": "/logs/global.log",
+					}),
+				},
+			}
+			err = syncer.SyncGlobal(ctx, globalConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(pluginMetadataPath()).To(Equal("/logs/stage.log"))
+
+			time.Sleep(200 * time.Millisecond)
+
+			// 环境的插件元数据变化后重新写入
+			err = syncer.Sync(ctx, "test-gateway", "test-stage", stagePluginMetadata("/logs/stage-2.log"))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(pluginMetadataPath()).To(Equal("/logs/stage-2.log"))
+
+			time.Sleep(200 * time.Millisecond)
+
+			// 环境删除插件元数据后恢复全局的配置
+			err = syncer.Sync(ctx, "test-gateway", "test-stage", entity.NewEmptyApisixConfiguration())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(pluginMetadataPath()).To(Equal("/logs/global.log"))
+		})
+
+		It("should keep the stage plugin metadata which has not been synced since restart", func() {
+			stageConfig := entity.NewEmptyApisixConfiguration()
+			pm := createPluginMetadata("file-logger", map[string]any{"path": "/logs/stage.log"})
+			pm.Labels = &entity.LabelInfo{Gateway: "test-gateway", Stage: "test-stage"}
+			stageConfig.PluginMetadata["file-logger"] = pm
+			Expect(syncer.Sync(ctx, "test-gateway", "test-stage", stageConfig)).To(Succeed())
+			Expect(syncer.SyncGlobal(ctx, entity.NewEmptyApisixGlobalResource())).To(Succeed())
+			pluginMetadataExists := func(name string) bool {
+				resp, err := client.Get(ctx, "/apisix/plugin_metadata/"+name)
+				Expect(err).ShouldNot(HaveOccurred())
+				return len(resp.Kvs) > 0
+			}
+			Expect(pluginMetadataExists("file-logger")).To(BeTrue())
+
+			// 重启后先同步全局资源, 环境还没有重新同步
+			restartedStore, err := store.NewApisixEtcdStore(
+				ctx, client, "/apisix", 10*time.Millisecond, 10*time.Millisecond, 5*time.Second,
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer restartedStore.Close()
+			restarted := synchronizer.NewSynchronizer(restartedStore, apisixHealthzURI)
+			globalConfig := &entity.ApisixGlobalResource{
+				PluginMetadata: map[string]*entity.PluginMetadata{
+					"prometheus": createPluginMetadata("prometheus", map[string]any{}),
+				},
+			}
+			Expect(restarted.SyncGlobal(ctx, globalConfig)).To(Succeed())
+			Expect(pluginMetadataExists("prometheus")).To(BeTrue())
+			Expect(pluginMetadataExists("file-logger")).To(BeTrue())
+
+			time.Sleep(200 * time.Millisecond)
+
+			// 环境重新同步后, 删除插件元数据时从 apisix 中删除
+			Expect(restarted.Sync(ctx, "test-gateway", "test-stage", stageConfig)).To(Succeed())
+			Expect(restarted.Sync(ctx, "test-gateway", "test-stage", entity.NewEmptyApisixConfiguration())).
+				To(Succeed())
+			Expect(pluginMetadataExists("file-logger")).To(BeFalse())
+			Expect(pluginMetadataExists("prometheus")).To(BeTrue())
+		})
 	})
 })
@@ -0,0 +1,406 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package synchronizer
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	cfg "github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+)
+
+// 环境在集群上最近一次同步的状态
+const (
+	TargetSyncSuccess = "success"
+	TargetSyncFailure = "failure"
+	// TargetSyncPending 集群不可用, 等待后台重试
+	TargetSyncPending = "pending"
+)
+
+// TargetSyncStatus 环境在一个 apisix 集群上最近一次同步的状态
+type TargetSyncStatus struct {
+	// Key 环境资源为 stage key, 全局资源为 global_resource
+	Key       string    `json:"key"`
+	PublishID string    `json:"publish_id,omitempty"`
+	State     string    `json:"state"`
+	Attempts  int64     `json:"attempts,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TargetStatus apisix 集群的同步状态, Stages 只包含同步失败或等待重试的环境
+type TargetStatus struct {
+	Name    string              `json:"name"`
+	Prefix  string              `json:"prefix"`
+	Primary bool                `json:"primary"`
+	Healthy bool                `json:"healthy"`
+	Synced  int                 `json:"synced"`
+	Stages  []*TargetSyncStatus `json:"stages"`
+}
+
+// targetApply 写入一个集群, 每次调用都使用独立的配置拷贝
+type targetApply func(ctx context.Context, t *Target) error
+
+// pendingSync 等待写入的同步, 同一环境只保留最新的配置; timer 为 nil 时等待正在进行的写入结束
+type pendingSync struct {
+	publishID string
+	apply     targetApply
+	attempts  int64
+	timer     *time.Timer
+}
+
+// Target 同步的 apisix 集群
+// 主集群 (config.Apisix.Etcd) 同步失败时由 committer 重试, 其他集群同步失败时在后台重试, 不阻塞主集群和其他集群
+type Target struct {
+	name    string
+	store   store.ApisixStore
+	primary bool
+	// streamRoutes 串行化集群上 stream route 的冲突检查和写入
+	streamRoutes *store.StreamRouteGuard
+
+	mux sync.Mutex
+	// healthy 最近一次写入失败后置为 false, 不可用期间的同步直接进入重试队列, 避免每次都等待超时
+	healthy bool
+	// running 正在写入的环境, 写入期间的新配置保存在 pending 中, 写入结束后再写入
+	running map[string]chan struct{}
+	pending map[string]*pendingSync
+	status  map[string]*TargetSyncStatus
+}
+
+func newTarget(name string, s store.ApisixStore, primary bool) *Target {
+	return &Target{
+		name:         name,
+		store:        s,
+		primary:      primary,
+		streamRoutes: store.NewStreamRouteGuard(),
+		healthy:      true,
+		running:      make(map[string]chan struct{}),
+		pending:      make(map[string]*pendingSync),
+		status:       make(map[string]*TargetSyncStatus),
+	}
+}
+
+// record 记录同步结果; 调用方需要持有 t.mux
+func (t *Target) record(key, publishID, state string, attempts int64, err error) {
+	status := &TargetSyncStatus{
+		Key:       key,
+		PublishID: publishID,
+		State:     state,
+		Attempts:  attempts,
+		UpdatedAt: time.Now(),
+	}
+	if err != nil {
+		status.LastError = err.Error()
+	}
+	t.status[key] = status
+
+	switch state {
+	case TargetSyncSuccess:
+		metric.ReportTargetSyncMetric(t.name, metric.ResultSuccess)
+	case TargetSyncFailure:
+		metric.ReportTargetSyncMetric(t.name, metric.ResultFail)
+	default:
+		metric.ReportTargetSyncMetric(t.name, metric.ResultPending)
+	}
+	metric.ReportTargetPendingMetric(t.name, len(t.pending))
+}
+
+// forget 环境不再同步到该集群, 取消等待中的重试并清理同步状态
+func (t *Target) forget(key string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if _, ok := t.pending[key]; ok {
+		t.cancelPending(key)
+		metric.ReportTargetPendingMetric(t.name, len(t.pending))
+	}
+	delete(t.status, key)
+}
+
+// Status 返回集群的同步状态
+func (t *Target) Status() *TargetStatus {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	status := &TargetStatus{
+		Name:    t.name,
+		Prefix:  t.store.Prefix(),
+		Primary: t.primary,
+		Healthy: t.healthy,
+		Stages:  make([]*TargetSyncStatus, 0),
+	}
+	for _, s := range t.status {
+		if s.State == TargetSyncSuccess {
+			status.Synced++
+			continue
+		}
+		status.Stages = append(status.Stages, s)
+	}
+	sort.Slice(status.Stages, func(i, j int) bool {
+		return status.Stages[i].Key < status.Stages[j].Key
+	})
+	return status
+}
+
+// AddTarget 添加一个同步的 apisix 集群, 需要在开始同步之前调用
+func (as *ApisixConfigSynchronizer) AddTarget(name string, s store.ApisixStore) {
+	as.targets = append(as.targets, newTarget(name, s, false))
+}
+
+// TargetStatus 返回所有集群的同步状态, 主集群在前
+func (as *ApisixConfigSynchronizer) TargetStatus() []*TargetStatus {
+	statuses := make([]*TargetStatus, 0, len(as.targets))
+	for _, t := range as.targets {
+		statuses = append(statuses, t.Status())
+	}
+	return statuses
+}
+
+// TargetResults 返回环境在各个集群上最近一次同步的结果, 只有一个集群时返回 nil, 用于发布事件上报
+func (as *ApisixConfigSynchronizer) TargetResults(key string) map[string]string {
+	if len(as.targets) <= 1 {
+		return nil
+	}
+	results := make(map[string]string, len(as.targets))
+	for _, t := range as.targets {
+		t.mux.Lock()
+		if status, ok := t.status[key]; ok {
+			results[t.name] = status.State
+			if status.LastError != "" {
+				results[t.name] += ": " + status.LastError
+			}
+		}
+		t.mux.Unlock()
+	}
+	return results
+}
+
+// syncTargets 并行写入除主集群以外的集群, 返回等待函数; applyTo 返回 nil 时跳过该集群
+// 同一环境在一个集群上同时只有一个写入, 等待函数最多等待 targetWaitTimeout, 不阻塞主集群和后续的发布
+func (as *ApisixConfigSynchronizer) syncTargets(
+	ctx context.Context,
+	key, publishID string,
+	applyTo func(t *Target) targetApply,
+) func() {
+	// 超时后写入在后台继续, 不随发布的 ctx 取消
+	ctx = context.WithoutCancel(ctx)
+	running := make(map[*Target]chan struct{})
+	for _, t := range as.targets[1:] {
+		apply := applyTo(t)
+		if apply == nil {
+			t.forget(key)
+			continue
+		}
+
+		t.mux.Lock()
+		switch {
+		case !t.healthy:
+			as.enqueueTarget(t, key, publishID, apply, nil)
+		case t.running[key] != nil:
+			// 上一次写入还没有结束, 结束后写入最新的配置
+			p := t.queue(key, publishID, apply)
+			t.record(key, publishID, TargetSyncPending, p.attempts, nil)
+		default:
+			// 新的配置覆盖等待重试的配置
+			t.cancelPending(key)
+			running[t] = as.runTarget(ctx, t, key, publishID, apply, 0)
+		}
+		t.mux.Unlock()
+	}
+
+	return func() {
+		waitCtx, cancel := context.WithTimeout(context.Background(), targetWaitTimeout)
+		defer cancel()
+		for t, done := range running {
+			select {
+			case <-done:
+				continue
+			case <-waitCtx.Done():
+			}
+			as.logger.Warnw("Timed out waiting for target, keep syncing in background", "target", t.name, "key", key)
+			t.mux.Lock()
+			// 没有更新的配置在等待时, 记录本次发布在该集群上等待写入
+			if _, queued := t.pending[key]; t.running[key] == done && !queued {
+				t.record(key, publishID, TargetSyncPending, 0, nil)
+			}
+			t.mux.Unlock()
+		}
+	}
+}
+
+// recordPrimary 记录主集群的同步结果, 主集群失败时由 committer 重试
+func (as *ApisixConfigSynchronizer) recordPrimary(key, publishID string, err error) {
+	t := as.targets[0]
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.healthy = err == nil
+	if err != nil {
+		t.record(key, publishID, TargetSyncFailure, 0, err)
+		return
+	}
+	t.record(key, publishID, TargetSyncSuccess, 0, nil)
+}
+
+// runTarget 在后台写入集群, 返回的 channel 在记录写入结果后关闭; 调用方需要持有 t.mux
+func (as *ApisixConfigSynchronizer) runTarget(
+	ctx context.Context,
+	t *Target,
+	key, publishID string,
+	apply targetApply,
+	attempts int64,
+) chan struct{} {
+	done := make(chan struct{})
+	t.running[key] = done
+	go func() {
+		err := apply(ctx, t)
+		as.finishTarget(ctx, t, key, publishID, apply, attempts, err)
+	}()
+	return done
+}
+
+// finishTarget 处理集群的写入结果, 有更新的配置在等待时继续写入, 失败时加入重试队列
+func (as *ApisixConfigSynchronizer) finishTarget(
+	ctx context.Context,
+	t *Target,
+	key, publishID string,
+	apply targetApply,
+	attempts int64,
+	err error,
+) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	close(t.running[key])
+	delete(t.running, key)
+	next, hasNext := t.pending[key]
+
+	if err == nil {
+		t.healthy = true
+		t.record(key, publishID, TargetSyncSuccess, attempts, nil)
+		if hasNext {
+			t.cancelPending(key)
+			as.runTarget(ctx, t, key, next.publishID, next.apply, 0)
+		}
+		return
+	}
+
+	as.logger.Errorw("Failed to sync target", "target", t.name, "key", key, "attempts", attempts, "err", err)
+	t.healthy = false
+	if hasNext {
+		// 已有更新的配置, 放弃当前的配置, 重试更新的配置
+		as.enqueueTarget(t, key, next.publishID, next.apply, err)
+		return
+	}
+	if attempts >= targetRetryMaxAttempts {
+		// 放弃重试, 等待下一次发布
+		t.record(key, publishID, TargetSyncFailure, attempts, err)
+		return
+	}
+	t.pending[key] = &pendingSync{attempts: attempts}
+	as.enqueueTarget(t, key, publishID, apply, err)
+}
+
+// queue 保存等待写入的配置, 同一环境只保留最新的配置; 调用方需要持有 t.mux
+func (t *Target) queue(key, publishID string, apply targetApply) *pendingSync {
+	p, ok := t.pending[key]
+	if !ok {
+		p = &pendingSync{}
+		t.pending[key] = p
+	}
+	p.publishID = publishID
+	p.apply = apply
+	return p
+}
+
+// cancelPending 取消等待中的写入; 调用方需要持有 t.mux
+func (t *Target) cancelPending(key string) {
+	if p, ok := t.pending[key]; ok {
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+		delete(t.pending, key)
+	}
+}
+
+// enqueueTarget 将同步加入重试队列, 已有等待中的重试时只替换配置; 调用方需要持有 t.mux
+func (as *ApisixConfigSynchronizer) enqueueTarget(
+	t *Target,
+	key, publishID string,
+	apply targetApply,
+	err error,
+) {
+	p := t.queue(key, publishID, apply)
+	if p.timer == nil {
+		p.attempts++
+		p.timer = time.AfterFunc(targetRetryBackoff.Delay(p.attempts), func() {
+			as.retryTarget(t, key)
+		})
+	}
+	t.record(key, publishID, TargetSyncPending, p.attempts-1, err)
+}
+
+// retryTarget 重试等待中的同步; 上一次写入还没有结束时等待其结束后再写入, 避免旧的配置覆盖新的配置
+func (as *ApisixConfigSynchronizer) retryTarget(t *Target, key string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	p, ok := t.pending[key]
+	if !ok {
+		return
+	}
+	p.timer = nil
+	if t.running[key] != nil {
+		return
+	}
+	delete(t.pending, key)
+	as.runTarget(context.Background(), t, key, p.publishID, p.apply, p.attempts)
+}
+
+// stageApply 生成写入环境配置的函数, 先拷贝一份不会被修改的配置, 每次写入时再各自拷贝
+func stageApply(key string, config *entity.ApisixStageResource) (targetApply, error) {
+	origin, err := store.CopyStageResource(config)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, t *Target) error {
+		conf, err := store.CopyStageResource(origin)
+		if err != nil {
+			return err
+		}
+		return t.alterStage(ctx, key, conf)
+	}, nil
+}
+
+// alterStage 检查环境的 stream route 与集群上其他环境没有冲突后写入
+func (t *Target) alterStage(ctx context.Context, key string, conf *entity.ApisixStageResource) error {
+	return t.streamRoutes.Alter(ctx, t.store, key, conf)
+}
+
+// globalApply 生成写入全局资源和虚拟环境的函数
+func (as *ApisixConfigSynchronizer) globalApply(config *entity.ApisixGlobalResource) targetApply {
+	origin := store.CopyGlobalResource(config)
+	return func(ctx context.Context, t *Target) error {
+		if err := t.store.AlterGlobal(ctx, store.CopyGlobalResource(origin)); err != nil {
+			return err
+		}
+		virtualStage := NewVirtualStage(as.apisixHealthzURI)
+		return t.store.Alter(ctx, cfg.VirtualStageKey, virtualStage.MakeConfiguration())
+	}
+}
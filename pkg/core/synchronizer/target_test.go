@@ -0,0 +1,223 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package synchronizer_test
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/metric"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/tests/util"
+)
+
+// slowStore 模拟写入很慢的集群, release 关闭之前写入一直阻塞
+type slowStore struct {
+	store.ApisixStore
+	release chan struct{}
+	alters  atomic.Int64
+}
+
+func (s *slowStore) Alter(ctx context.Context, stageKey string, conf *entity.ApisixStageResource) error {
+	s.alters.Add(1)
+	<-s.release
+	return s.ApisixStore.Alter(ctx, stageKey, conf)
+}
+
+var _ = Describe("ApisixConfigSynchronizer targets", func() {
+	var (
+		etcd        *embed.Etcd
+		client      *clientv3.Client
+		primary     *store.ApisixEtcdStore
+		targetStore *store.ApisixEtcdStore
+		syncer      *synchronizer.ApisixConfigSynchronizer
+		ctx         context.Context
+		stageKey    string
+	)
+
+	newStageConf := func(publishID string) *entity.ApisixStageResource {
+		conf := entity.NewEmptyApisixConfiguration()
+		conf.Routes["route-1"] = &entity.Route{
+			ResourceMetadata: entity.ResourceMetadata{
+				ID: "route-1",
+				Labels: &entity.LabelInfo{
+					Gateway:   "test-gateway",
+					Stage:     "test-stage",
+					PublishId: publishID,
+				},
+			},
+			URI: "/v" + publishID,
+		}
+		return conf
+	}
+
+	routeURI := func(s *store.ApisixEtcdStore) func() string {
+		return func() string {
+			route := s.Get(stageKey).Routes["route-1"]
+			if route == nil {
+				return ""
+			}
+			return route.URI
+		}
+	}
+
+	newStore := func(c *clientv3.Client, prefix string) *store.ApisixEtcdStore {
+		s, err := store.NewApisixEtcdStore(ctx, c, prefix, 10*time.Millisecond, 10*time.Millisecond, time.Second)
+		Expect(err).ShouldNot(HaveOccurred())
+		return s
+	}
+
+	initSynchronizer := func(targetWaitTimeout time.Duration) {
+		synchronizer.Init(&config.Config{
+			Operator: config.Operator{
+				CommitRetry: config.CommitRetry{
+					MaxAttempts:       2,
+					BaseDelay:         20 * time.Millisecond,
+					MaxDelay:          40 * time.Millisecond,
+					TargetWaitTimeout: targetWaitTimeout,
+				},
+			},
+		})
+	}
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		metric.InitMetric(prometheus.NewRegistry())
+		initSynchronizer(5 * time.Second)
+		stageKey = config.GenStagePrimaryKey("test-gateway", "test-stage")
+
+		client, etcd, err = util.StartEmbedEtcdClient(ctx)
+		Expect(err).ShouldNot(HaveOccurred())
+		primary = newStore(client, "/apisix")
+		syncer = synchronizer.NewSynchronizer(primary, "/healthz")
+	})
+
+	AfterEach(func() {
+		primary.Close()
+		if targetStore != nil {
+			targetStore.Close()
+		}
+		client.Close()
+		etcd.Close()
+		_ = os.RemoveAll(etcd.Config().Dir)
+	})
+
+	It("should not report the target results with only one target", func() {
+		Expect(syncer.Sync(ctx, "test-gateway", "test-stage", newStageConf("1"))).To(Succeed())
+		Expect(syncer.TargetResults(stageKey)).To(BeNil())
+
+		statuses := syncer.TargetStatus()
+		Expect(statuses).To(HaveLen(1))
+		Expect(statuses[0].Name).To(Equal("default"))
+		Expect(statuses[0].Primary).To(BeTrue())
+		Expect(statuses[0].Synced).To(Equal(1))
+	})
+
+	It("should sync the stage to every target", func() {
+		targetStore = newStore(client, "/apisix-b")
+		syncer.AddTarget("zone-b", targetStore)
+
+		Expect(syncer.Sync(ctx, "test-gateway", "test-stage", newStageConf("1"))).To(Succeed())
+		Eventually(routeURI(primary), 5*time.Second, 20*time.Millisecond).Should(Equal("/v1"))
+		Eventually(routeURI(targetStore), 5*time.Second, 20*time.Millisecond).Should(Equal("/v1"))
+		Expect(syncer.TargetResults(stageKey)).To(Equal(map[string]string{
+			"default": synchronizer.TargetSyncSuccess,
+			"zone-b":  synchronizer.TargetSyncSuccess,
+		}))
+
+		// 写入主集群时清理的 publish id 不影响其他集群
+		resp, err := client.Get(ctx, "/apisix-b/routes/route-1")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resp.Kvs).To(HaveLen(1))
+
+		Expect(syncer.SyncGlobal(ctx, entity.NewEmptyApisixGlobalResource())).To(Succeed())
+		Expect(syncer.TargetResults("global_resource")).To(HaveLen(2))
+	})
+
+	It("should not block the healthy targets when a target fails", func() {
+		brokenClient, err := clientv3.New(clientv3.Config{
+			Endpoints:   []string{etcd.Clients[0].Addr().String()},
+			DialTimeout: time.Second,
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		targetStore = newStore(brokenClient, "/apisix-b")
+		syncer.AddTarget("zone-b", targetStore)
+		Expect(brokenClient.Close()).To(Succeed())
+
+		Expect(syncer.Sync(ctx, "test-gateway", "test-stage", newStageConf("1"))).To(Succeed())
+		Eventually(routeURI(primary), 5*time.Second, 20*time.Millisecond).Should(Equal("/v1"))
+		results := syncer.TargetResults(stageKey)
+		Expect(results["default"]).To(Equal(synchronizer.TargetSyncSuccess))
+		Expect(results["zone-b"]).To(HavePrefix(synchronizer.TargetSyncPending))
+
+		// 重试超过最大次数后放弃, 等待下一次发布; 每次写入失败需要等待 etcd client 的重试超时
+		Eventually(func() string {
+			return syncer.TargetResults(stageKey)["zone-b"]
+		}, 20*time.Second, 50*time.Millisecond).Should(HavePrefix(synchronizer.TargetSyncFailure))
+
+		statuses := syncer.TargetStatus()
+		Expect(statuses).To(HaveLen(2))
+		Expect(statuses[1].Healthy).To(BeFalse())
+		Expect(statuses[1].Stages).To(HaveLen(1))
+		Expect(statuses[1].Stages[0].Attempts).To(Equal(int64(2)))
+		Expect(statuses[1].Stages[0].PublishID).To(Equal("1"))
+
+		// 不可用的集群直接进入重试队列, 主集群照常同步
+		Expect(syncer.Sync(ctx, "test-gateway", "test-stage", newStageConf("2"))).To(Succeed())
+		Eventually(routeURI(primary), 5*time.Second, 20*time.Millisecond).Should(Equal("/v2"))
+		Expect(syncer.TargetResults(stageKey)["zone-b"]).To(HavePrefix(synchronizer.TargetSyncPending))
+	})
+
+	It("should not wait for a slow target longer than the target wait timeout", func() {
+		initSynchronizer(100 * time.Millisecond)
+		targetStore = newStore(client, "/apisix-b")
+		slow := &slowStore{ApisixStore: targetStore, release: make(chan struct{})}
+		syncer.AddTarget("zone-b", slow)
+
+		start := time.Now()
+		Expect(syncer.Sync(ctx, "test-gateway", "test-stage", newStageConf("1"))).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically("<", 2*time.Second))
+		Eventually(routeURI(primary), 5*time.Second, 20*time.Millisecond).Should(Equal("/v1"))
+		Expect(syncer.TargetResults(stageKey)["zone-b"]).To(Equal(synchronizer.TargetSyncPending))
+
+		// 上一次写入没有结束时, 新的配置等待其结束后写入, 不阻塞主集群
+		Expect(syncer.Sync(ctx, "test-gateway", "test-stage", newStageConf("2"))).To(Succeed())
+		Eventually(routeURI(primary), 5*time.Second, 20*time.Millisecond).Should(Equal("/v2"))
+		Expect(slow.alters.Load()).To(Equal(int64(1)))
+
+		close(slow.release)
+		Eventually(routeURI(targetStore), 5*time.Second, 20*time.Millisecond).Should(Equal("/v2"))
+		Eventually(func() string {
+			return syncer.TargetResults(stageKey)["zone-b"]
+		}, 5*time.Second, 20*time.Millisecond).Should(Equal(synchronizer.TargetSyncSuccess))
+		Expect(slow.alters.Load()).To(Equal(int64(2)))
+	})
+})
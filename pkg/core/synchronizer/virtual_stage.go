@@ -165,6 +165,34 @@ func (s *VirtualStage) MakeConfiguration() *entity.ApisixStageResource {
 		}
 	}
 
+	for _, upstream := range extraConfiguration.Upstreams {
+		if upstream != nil && upstream.ID != "" {
+			upstream.Labels = s.Labels
+			ret.Upstreams[upstream.ID] = upstream
+		}
+	}
+
+	for _, pluginConfig := range extraConfiguration.PluginConfigs {
+		if pluginConfig != nil && pluginConfig.ID != "" {
+			pluginConfig.Labels = s.Labels
+			ret.PluginConfigs[pluginConfig.ID] = pluginConfig
+		}
+	}
+
+	for _, consumer := range extraConfiguration.Consumers {
+		if consumer != nil && consumer.Username != "" {
+			consumer.Labels = s.Labels
+			ret.Consumers[consumer.Username] = consumer
+		}
+	}
+
+	for _, consumerGroup := range extraConfiguration.ConsumerGroups {
+		if consumerGroup != nil && consumerGroup.ID != "" {
+			consumerGroup.Labels = s.Labels
+			ret.ConsumerGroups[consumerGroup.ID] = consumerGroup
+		}
+	}
+
 	for _, ssl := range extraConfiguration.SSLs {
 		if ssl != nil && ssl.ID != "" {
 			ssl.Labels = s.Labels
@@ -172,6 +200,20 @@ func (s *VirtualStage) MakeConfiguration() *entity.ApisixStageResource {
 		}
 	}
 
+	for _, streamRoute := range extraConfiguration.StreamRoutes {
+		if streamRoute != nil && streamRoute.ID != "" {
+			streamRoute.Labels = s.Labels
+			ret.StreamRoutes[streamRoute.ID] = streamRoute
+		}
+	}
+
+	for _, proto := range extraConfiguration.Protos {
+		if proto != nil && proto.ID != "" {
+			proto.Labels = s.Labels
+			ret.Protos[proto.ID] = proto
+		}
+	}
+
 	for _, route := range extraConfiguration.Routes {
 		if route != nil && route.ID != "" {
 			route.Labels = s.Labels
@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cast"
 	"github.com/tidwall/gjson"
@@ -36,7 +37,9 @@ import (
 type ApisixResource interface {
 	GetID() string
 	GetReleaseInfo() *ReleaseInfo
+	GetGatewayName() string
 	GetStageName() string
+	GetStageKey() string
 	GetCreateTime() int64
 	GetUpdateTime() int64
 	SetCreateTime(int64)
@@ -46,36 +49,94 @@ type ApisixResource interface {
 
 // ApisixStageResource 网关环境资源配置
 type ApisixStageResource struct {
-	Routes   map[string]*Route   `json:"routes,omitempty"  yaml:"routes"`
-	Services map[string]*Service `json:"services,omitempty" yaml:"services"`
-	SSLs     map[string]*SSL     `json:"ssls,omitempty" yaml:"ssls"`
+	Routes         map[string]*Route         `json:"routes,omitempty"  yaml:"routes"`
+	Services       map[string]*Service       `json:"services,omitempty" yaml:"services"`
+	Upstreams      map[string]*Upstream      `json:"upstreams,omitempty" yaml:"upstreams"`
+	PluginConfigs  map[string]*PluginConfig  `json:"plugin_configs,omitempty" yaml:"plugin_configs"`
+	Consumers      map[string]*Consumer      `json:"consumers,omitempty" yaml:"consumers"`
+	ConsumerGroups map[string]*ConsumerGroup `json:"consumer_groups,omitempty" yaml:"consumer_groups"`
+	SSLs           map[string]*SSL           `json:"ssls,omitempty" yaml:"ssls"`
+	StreamRoutes   map[string]*StreamRoute   `json:"stream_routes,omitempty" yaml:"stream_routes"`
+	Protos         map[string]*Proto         `json:"protos,omitempty" yaml:"protos"`
+	// PluginMetadata 环境级别的插件元数据, apisix 中插件元数据是全局的, 由同步器合并到全局资源中写入
+	PluginMetadata map[string]*PluginMetadata `json:"plugin_metadata,omitempty" yaml:"-"`
 }
 
 type ExtraApisixStageResource struct {
-	Routes   []*Route   `json:"routes,omitempty" yaml:"routes"`
-	Services []*Service `json:"services,omitempty" yaml:"services"`
-	SSLs     []*SSL     `json:"ssls,omitempty" yaml:"ssls"`
+	Routes         []*Route         `json:"routes,omitempty" yaml:"routes"`
+	Services       []*Service       `json:"services,omitempty" yaml:"services"`
+	Upstreams      []*Upstream      `json:"upstreams,omitempty" yaml:"upstreams"`
+	PluginConfigs  []*PluginConfig  `json:"plugin_configs,omitempty" yaml:"plugin_configs"`
+	Consumers      []*Consumer      `json:"consumers,omitempty" yaml:"consumers"`
+	ConsumerGroups []*ConsumerGroup `json:"consumer_groups,omitempty" yaml:"consumer_groups"`
+	SSLs           []*SSL           `json:"ssls,omitempty" yaml:"ssls"`
+	StreamRoutes   []*StreamRoute   `json:"stream_routes,omitempty" yaml:"stream_routes"`
+	Protos         []*Proto         `json:"protos,omitempty" yaml:"protos"`
 }
 
 // NewEmptyApisixConfiguration will build a new apisix configuration object
 func NewEmptyApisixConfiguration() *ApisixStageResource {
 	return &ApisixStageResource{
-		Routes:   make(map[string]*Route),
-		Services: make(map[string]*Service),
-		SSLs:     make(map[string]*SSL),
+		Routes:         make(map[string]*Route),
+		Services:       make(map[string]*Service),
+		Upstreams:      make(map[string]*Upstream),
+		PluginConfigs:  make(map[string]*PluginConfig),
+		Consumers:      make(map[string]*Consumer),
+		ConsumerGroups: make(map[string]*ConsumerGroup),
+		SSLs:           make(map[string]*SSL),
+		StreamRoutes:   make(map[string]*StreamRoute),
+		Protos:         make(map[string]*Proto),
+		PluginMetadata: make(map[string]*PluginMetadata),
+	}
+}
+
+// Resources 返回环境下的所有资源, 不区分资源类型
+func (c *ApisixStageResource) Resources() []ApisixResource {
+	resources := make([]ApisixResource, 0,
+		len(c.Routes)+len(c.Services)+len(c.Upstreams)+len(c.PluginConfigs)+
+			len(c.Consumers)+len(c.ConsumerGroups)+len(c.SSLs)+len(c.StreamRoutes)+len(c.Protos))
+	for _, route := range c.Routes {
+		resources = append(resources, route)
+	}
+	for _, service := range c.Services {
+		resources = append(resources, service)
+	}
+	for _, upstream := range c.Upstreams {
+		resources = append(resources, upstream)
+	}
+	for _, pluginConfig := range c.PluginConfigs {
+		resources = append(resources, pluginConfig)
+	}
+	for _, consumer := range c.Consumers {
+		resources = append(resources, consumer)
+	}
+	for _, consumerGroup := range c.ConsumerGroups {
+		resources = append(resources, consumerGroup)
 	}
+	for _, ssl := range c.SSLs {
+		resources = append(resources, ssl)
+	}
+	for _, streamRoute := range c.StreamRoutes {
+		resources = append(resources, streamRoute)
+	}
+	for _, proto := range c.Protos {
+		resources = append(resources, proto)
+	}
+	return resources
 }
 
 // NewEmptyApisixGlobalResource ...
 func NewEmptyApisixGlobalResource() *ApisixGlobalResource {
 	return &ApisixGlobalResource{
 		PluginMetadata: make(map[string]*PluginMetadata),
+		GlobalRules:    make(map[string]*GlobalRule),
 	}
 }
 
 // ApisixGlobalResource 全局资源配置
 type ApisixGlobalResource struct {
 	PluginMetadata map[string]*PluginMetadata `json:"plugin_metadata,omitempty"`
+	GlobalRules    map[string]*GlobalRule     `json:"global_rules,omitempty"`
 }
 
 // Status ...
@@ -233,20 +294,25 @@ type Upstream struct {
 
 // Consumer ...
 type Consumer struct {
-	Username string            `json:"username"`
-	Desc     string            `json:"desc,omitempty"`
-	Plugins  map[string]any    `json:"plugins,omitempty"`
-	Labels   map[string]string `json:"labels,omitempty"`
-	GroupID  string            `json:"group_id,omitempty"`
+	ResourceMetadata `yaml:",inline"`
+	Username         string         `json:"username" yaml:"username"`
+	Desc             string         `json:"desc,omitempty" yaml:"desc"`
+	Plugins          map[string]any `json:"plugins,omitempty" yaml:"plugins"`
+	GroupID          string         `json:"group_id,omitempty" yaml:"group_id"`
+}
+
+// GetID consumer 没有 id 字段, apisix 中以 username 作为唯一标识
+func (c *Consumer) GetID() string {
+	return c.Username
 }
 
 // ConsumerGroup ...
 type ConsumerGroup struct {
-	Desc       string            `json:"desc,omitempty"`
-	Plugins    map[string]any    `json:"plugins,omitempty"`
-	Labels     map[string]string `json:"labels,omitempty"`
-	CreateTime int64             `json:"create_time,omitempty"`
-	UpdateTime int64             `json:"update_time,omitempty"`
+	ResourceMetadata `yaml:",inline"`
+	Desc             string         `json:"desc,omitempty" yaml:"desc"`
+	Plugins          map[string]any `json:"plugins" yaml:"plugins"`
+	CreateTime       int64          `json:"create_time,omitempty" yaml:"create_time,omitempty"`
+	UpdateTime       int64          `json:"update_time,omitempty" yaml:"update_time,omitempty"`
 }
 
 // Service ...
@@ -290,6 +356,11 @@ type GlobalRule struct {
 	Plugins          map[string]any `json:"plugins" yaml:"plugins"`
 }
 
+// IsManaged 是否由 operator 管理, 即 id 带有 constant.ManagedIDPrefix 前缀
+func (g *GlobalRule) IsManaged() bool {
+	return strings.HasPrefix(g.ID, constant.ManagedIDPrefix)
+}
+
 // PluginMetadataConf ...
 type PluginMetadataConf map[string]json.RawMessage
 
@@ -332,9 +403,9 @@ type ServerInfo struct {
 
 // PluginConfig ...
 type PluginConfig struct {
-	ResourceMetadata
-	Desc    string         `json:"desc,omitempty"`
-	Plugins map[string]any `json:"plugins"`
+	ResourceMetadata `yaml:",inline"`
+	Desc             string         `json:"desc,omitempty" yaml:"desc"`
+	Plugins          map[string]any `json:"plugins" yaml:"plugins"`
 }
 
 // SSLClient ...
@@ -363,30 +434,101 @@ type SSL struct {
 }
 
 // Proto ...
+// 与 StreamRoute 一样, apisix 的 proto schema 中没有 labels 字段, 根据 id 还原所属的环境
 type Proto struct {
-	ResourceMetadata
-	Desc    string `json:"desc,omitempty"`
-	Content string `json:"content"`
+	ResourceMetadata `yaml:",inline"`
+	Desc             string `json:"desc,omitempty" yaml:"desc"`
+	Content          string `json:"content" yaml:"content"`
+	CreateTime       int64  `json:"create_time,omitempty" yaml:"create_time,omitempty"`
+	UpdateTime       int64  `json:"update_time,omitempty" yaml:"update_time,omitempty"`
+}
+
+// GetCreateTime ...
+func (p *Proto) GetCreateTime() int64 {
+	return p.CreateTime
+}
+
+// GetUpdateTime ...
+func (p *Proto) GetUpdateTime() int64 {
+	return p.UpdateTime
+}
+
+// SetCreateTime ...
+func (p *Proto) SetCreateTime(i int64) {
+	p.CreateTime = i
+}
+
+// SetUpdateTime ...
+func (p *Proto) SetUpdateTime(i int64) {
+	p.UpdateTime = i
 }
 
 // StreamRouteProtocol ...
 type StreamRouteProtocol struct {
-	Name string         `json:"name,omitempty"`
-	Conf map[string]any `json:"conf,omitempty"`
+	Name       string           `json:"name,omitempty" yaml:"name"`
+	SuperiorID any              `json:"superior_id,omitempty" yaml:"superior_id"`
+	Conf       map[string]any   `json:"conf,omitempty" yaml:"conf"`
+	Logger     []map[string]any `json:"logger,omitempty" yaml:"logger"`
 }
 
 // StreamRoute ...
+// apisix 的 stream_route schema 中没有 labels 字段, 写入 apisix 时不带 labels,
+// 从 apisix 读取时根据 bk-apigw.{gateway}.{stage}.{name} 格式的 id 还原所属的环境, 见 StageLabelsFromID
 type StreamRoute struct {
-	ResourceMetadata
-	RemoteAddr string               `json:"remote_addr,omitempty" yaml:"remote_addr"`
-	ServerAddr string               `json:"server_addr,omitempty" yaml:"server_addr"`
-	ServerPort int                  `json:"server_port,omitempty" yaml:"server_port"`
-	SNI        string               `json:"sni,omitempty" yaml:"sni"`
-	UpstreamID any                  `json:"upstream_id,omitempty" yaml:"upstream_id"`
-	Upstream   *UpstreamDef         `json:"upstream,omitempty" yaml:"upstream"`
-	ServiceID  any                  `json:"service_id,omitempty" yaml:"service_id"`
-	Plugins    map[string]any       `json:"plugins,omitempty" yaml:"plugins"`
-	Protocol   *StreamRouteProtocol `json:"protocol,omitempty" yaml:"protocol"`
+	ResourceMetadata `yaml:",inline"`
+	Desc             string               `json:"desc,omitempty" yaml:"desc"`
+	RemoteAddr       string               `json:"remote_addr,omitempty" yaml:"remote_addr"`
+	ServerAddr       string               `json:"server_addr,omitempty" yaml:"server_addr"`
+	ServerPort       int                  `json:"server_port,omitempty" yaml:"server_port"`
+	SNI              string               `json:"sni,omitempty" yaml:"sni"`
+	UpstreamID       any                  `json:"upstream_id,omitempty" yaml:"upstream_id"`
+	Upstream         *UpstreamDef         `json:"upstream,omitempty" yaml:"upstream"`
+	ServiceID        any                  `json:"service_id,omitempty" yaml:"service_id"`
+	Plugins          map[string]any       `json:"plugins,omitempty" yaml:"plugins"`
+	Protocol         *StreamRouteProtocol `json:"protocol,omitempty" yaml:"protocol"`
+	CreateTime       int64                `json:"create_time,omitempty" yaml:"create_time,omitempty"`
+	UpdateTime       int64                `json:"update_time,omitempty" yaml:"update_time,omitempty"`
+}
+
+// GetCreateTime ...
+func (s *StreamRoute) GetCreateTime() int64 {
+	return s.CreateTime
+}
+
+// GetUpdateTime ...
+func (s *StreamRoute) GetUpdateTime() int64 {
+	return s.UpdateTime
+}
+
+// SetCreateTime ...
+func (s *StreamRoute) SetCreateTime(i int64) {
+	s.CreateTime = i
+}
+
+// SetUpdateTime ...
+func (s *StreamRoute) SetUpdateTime(i int64) {
+	s.UpdateTime = i
+}
+
+// RestoreStageLabels 没有 labels 时根据 id 还原环境的 label, 用于 apisix schema 中没有 labels 字段的资源
+func (rm *ResourceMetadata) RestoreStageLabels() {
+	if rm.Labels == nil {
+		rm.Labels = StageLabelsFromID(rm.ID)
+	}
+}
+
+// StageLabelsFromID 根据 bk-apigw.{gateway}.{stage}.{name} 格式的资源 id 还原环境的 label, 格式不符时返回 nil;
+// 没有 constant.ManagedIDPrefix 前缀的资源不是 operator 管理的; 网关和环境名称中不包含 "."
+func StageLabelsFromID(id string) *LabelInfo {
+	name, ok := strings.CutPrefix(id, constant.ManagedIDPrefix)
+	if !ok {
+		return nil
+	}
+	parts := strings.SplitN(name, ".", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil
+	}
+	return &LabelInfo{Gateway: parts[0], Stage: parts[1]}
 }
 
 // ResourceMetadata describes the metadata of a resource object, which includes the
@@ -401,6 +543,8 @@ type ResourceMetadata struct {
 	RetryCount    int64                   `json:"-" yaml:"-"`
 	Ctx           context.Context         `json:"-" yaml:"-"`
 	ApisixVersion string                  `json:"apisix_version,omitempty" yaml:"apisix_version"`
+	// Origin 资源来源的控制面名称, 同时 watch 多个控制面时用于区分
+	Origin string `json:"-" yaml:"-"`
 }
 
 // GetID returns the resource ID
@@ -434,10 +578,8 @@ func (rm *ResourceMetadata) IsEmpty() bool {
 	if rm == nil {
 		return true
 	}
-	// FIXME: there would be more global resources in the future, we need to add more, refactor this to a
-	// IsGlobalKind function?
-	// PluginMetadata 是全局资源，不依赖于 gateway 和 stage
-	if rm.Kind == constant.PluginMetadata {
+	// 全局资源不依赖于 gateway 和 stage
+	if rm.Kind.IsGlobal() {
 		return false
 	}
 	return rm.Labels.Gateway == "" && rm.Labels.Stage == ""
@@ -445,15 +587,13 @@ func (rm *ResourceMetadata) IsEmpty() bool {
 
 // IsGlobalResource check if the metadata object is global
 func (rm *ResourceMetadata) IsGlobalResource() bool {
-	// FIXME: there would be more global resources in the future, we need to add more, refactor this to a
-	// IsGlobalKind function?
-	return rm.Kind == constant.PluginMetadata && rm.GetStageName() == ""
+	return rm.Kind.IsGlobal() && rm.GetStageName() == ""
 }
 
 // GetReleaseID returns the release ID for the resource
 func (rm *ResourceMetadata) GetReleaseID() string {
-	// stage 相关资源都是按照 stage 维度来管理的
-	if rm.Kind != constant.PluginMetadata {
+	// stage 相关资源都是按照 stage 维度来管理的, 包括环境级别的插件元数据
+	if !rm.IsGlobalResource() {
 		return config.GenStagePrimaryKey(rm.Labels.Gateway, rm.Labels.Stage)
 	}
 	return rm.ID
@@ -530,3 +670,25 @@ type LabelInfo struct {
 	PublishId     string `json:"gateway.bk.tencent.com/publish-id,omitempty"`
 	ApisixVersion string `json:"gateway.bk.tencent.com/apisix-version,omitempty"`
 }
+
+// labelKeyPrefix 资源 label key 的前缀
+const labelKeyPrefix = "gateway.bk.tencent.com/"
+
+// Get 根据 label key 获取值, key 可以省略 gateway.bk.tencent.com/ 前缀
+func (l *LabelInfo) Get(key string) string {
+	if l == nil {
+		return ""
+	}
+	switch strings.TrimPrefix(key, labelKeyPrefix) {
+	case "gateway":
+		return l.Gateway
+	case "stage":
+		return l.Stage
+	case "publish-id":
+		return l.PublishId
+	case "apisix-version":
+		return l.ApisixVersion
+	default:
+		return ""
+	}
+}
@@ -49,6 +49,7 @@ var _ = Describe("Entity", func() {
 			resource := NewEmptyApisixGlobalResource()
 			Expect(resource).NotTo(BeNil())
 			Expect(resource.PluginMetadata).NotTo(BeNil())
+			Expect(resource.GlobalRules).NotTo(BeNil())
 
 			Expect(len(resource.PluginMetadata)).To(Equal(0))
 		})
@@ -288,6 +289,13 @@ var _ = Describe("Entity", func() {
 				Expect(rm.IsGlobalResource()).To(BeFalse())
 			})
 
+			It("should return true for GlobalRule with no stage", func() {
+				rm.Kind = constant.GlobalRule
+				rm.Labels.Stage = ""
+				Expect(rm.IsGlobalResource()).To(BeTrue())
+				Expect(rm.IsEmpty()).To(BeFalse())
+			})
+
 			It("should return false for non-PluginMetadata resources", func() {
 				rm.Kind = constant.Route
 				Expect(rm.IsGlobalResource()).To(BeFalse())
@@ -301,10 +309,17 @@ var _ = Describe("Entity", func() {
 				Expect(rm.GetReleaseID()).To(Equal(expected))
 			})
 
-			It("should return ID for PluginMetadata resources", func() {
+			It("should return ID for global PluginMetadata resources", func() {
 				rm.Kind = constant.PluginMetadata
+				rm.Labels = &LabelInfo{}
 				Expect(rm.GetReleaseID()).To(Equal("test-id"))
 			})
+
+			It("should return stage key for stage PluginMetadata resources", func() {
+				rm.Kind = constant.PluginMetadata
+				expected := "bk.release.test-gateway.test-stage"
+				Expect(rm.GetReleaseID()).To(Equal(expected))
+			})
 		})
 
 		Context("GetReleaseInfo", func() {
@@ -454,6 +469,16 @@ var _ = Describe("Entity", func() {
 			Expect(result).To(HaveKeyWithValue("gateway.bk.tencent.com/publish-id", "100"))
 			Expect(result).To(HaveKeyWithValue("gateway.bk.tencent.com/apisix-version", "3.13.X"))
 		})
+
+		It("should get the label by key with or without prefix", func() {
+			labels := &LabelInfo{Gateway: "test-gateway", ApisixVersion: "3.13.X"}
+			Expect(labels.Get("gateway.bk.tencent.com/gateway")).To(Equal("test-gateway"))
+			Expect(labels.Get("apisix-version")).To(Equal("3.13.X"))
+			Expect(labels.Get("unknown")).To(BeEmpty())
+
+			var nilLabels *LabelInfo
+			Expect(nilLabels.Get("gateway")).To(BeEmpty())
+		})
 	})
 
 	Describe("Edge Cases", func() {
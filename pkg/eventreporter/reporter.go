@@ -21,6 +21,7 @@ package eventreporter
 
 import (
 	"context"
+	"errors"
 	"log"
 	"strings"
 	"sync"
@@ -39,6 +40,8 @@ import (
 var (
 	reporter     *Reporter
 	reporterOnce sync.Once
+
+	errProbeTimeout = errors.New("version publish probe timeout")
 )
 
 type reportEvent struct {
@@ -63,8 +66,15 @@ type Reporter struct {
 	reportChain  chan struct{} // control reporter concurrency
 	close        chan struct{}
 	versionProbe versionProbe
+	// disabled 影子模式下不上报发布事件, 避免干扰线上实例的上报
+	disabled bool
+	// onLoadFailure 版本探测失败或超时后的回调
+	onLoadFailure LoadFailureHandler
 }
 
+// LoadFailureHandler 发布后 apisix 加载配置失败 (版本探测失败或超时) 的处理函数
+type LoadFailureHandler func(ctx context.Context, release *entity.ReleaseInfo, err error)
+
 // InitReporter initializes the reporter
 func InitReporter(cfg *config.Config) {
 	reporterOnce.Do(func() {
@@ -77,10 +87,16 @@ func InitReporter(cfg *config.Config) {
 				timeout:  cfg.EventReporter.VersionProbe.Timeout,
 				waitTime: cfg.EventReporter.VersionProbe.WaitTime,
 			},
+			disabled: cfg.Operator.Shadow.Enable,
 		}
 	})
 }
 
+// SetLoadFailureHandler 设置版本探测失败后的处理函数, 需要在开始提交发布之前设置
+func SetLoadFailureHandler(handler LoadFailureHandler) {
+	reporter.onLoadFailure = handler
+}
+
 // Start reporter
 func Start(ctx context.Context) {
 	utils.GoroutineWithRecovery(ctx, func() {
@@ -164,21 +180,32 @@ func ReportApplyConfigurationDoingEvent(ctx context.Context, release *entity.Rel
 	addEvent(event)
 }
 
-// ReportApplyConfigurationFailureEvent will report failure event when apply configuration failed
-func ReportApplyConfigurationFailureEvent(ctx context.Context, release *entity.ReleaseInfo, err error) {
+// ReportApplyConfigurationFailureEvent will report failure event when apply configuration failed,
+// targets is the result of each apisix cluster, nil if there is only one cluster
+func ReportApplyConfigurationFailureEvent(
+	ctx context.Context,
+	release *entity.ReleaseInfo,
+	err error,
+	targets map[string]string,
+) {
+	detail := map[string]any{"err_msg": err.Error()}
+	if targets != nil {
+		detail["targets"] = targets
+	}
 	event := reportEvent{
 		ctx:     ctx,
 		release: release,
 		Event:   constant.EventNameApplyConfiguration,
 		status:  constant.EventStatusFailure,
-		detail:  map[string]any{"err_msg": err.Error()},
+		detail:  detail,
 		ts:      time.Now().Unix(),
 	}
 	addEvent(event)
 }
 
-// ReportApplyConfigurationSuccessEvent will report success event when apply configuration successfully
-func ReportApplyConfigurationSuccessEvent(ctx context.Context, release *entity.ReleaseInfo) {
+// ReportApplyConfigurationSuccessEvent will report success event when apply configuration successfully,
+// targets is the result of each apisix cluster, nil if there is only one cluster
+func ReportApplyConfigurationSuccessEvent(ctx context.Context, release *entity.ReleaseInfo, targets map[string]string) {
 	event := reportEvent{
 		ctx:     ctx,
 		release: release,
@@ -186,6 +213,9 @@ func ReportApplyConfigurationSuccessEvent(ctx context.Context, release *entity.R
 		status:  constant.EventStatusSuccess,
 		ts:      time.Now().Unix(),
 	}
+	if targets != nil {
+		event.detail = map[string]any{"targets": targets}
+	}
 	addEvent(event)
 }
 
@@ -213,6 +243,10 @@ func ReportLoadConfigurationResultEvent(ctx context.Context, release *entity.Rel
 		logging.GetLogger().Debugf("event[release: %+v] is not need to report", release.Labels)
 		return
 	}
+	if reporter.disabled {
+		<-stageChan
+		return
+	}
 
 	reporter.versionProbe.chain <- struct{}{} // control concurrency
 	utils.GoroutineWithRecovery(ctx, func() {
@@ -267,6 +301,7 @@ func ReportLoadConfigurationResultEvent(ctx context.Context, release *entity.Rel
 					ts:      time.Now().Unix(),
 				}
 				reporter.eventChain <- event
+				reporter.loadFailed(ctx, release, err)
 			}
 			return
 		case <-reportCtx.Done():
@@ -276,14 +311,21 @@ func ReportLoadConfigurationResultEvent(ctx context.Context, release *entity.Rel
 				release: release,
 				Event:   constant.EventNameLoadConfiguration,
 				status:  constant.EventStatusFailure,
-				detail:  map[string]any{"err_msg": "version publish probe timeout"},
+				detail:  map[string]any{"err_msg": errProbeTimeout.Error()},
 				ts:      time.Now().Unix(),
 			}
 			reporter.eventChain <- event
+			reporter.loadFailed(ctx, release, errProbeTimeout)
 		}
 	})
 }
 
+func (r *Reporter) loadFailed(ctx context.Context, release *entity.ReleaseInfo, err error) {
+	if r.onLoadFailure != nil {
+		r.onLoadFailure(ctx, release, err)
+	}
+}
+
 // addEvent add event to reporter event
 func addEvent(event reportEvent) {
 	// avoid gateway del that cause release to be nil and make panic
@@ -294,6 +336,9 @@ func addEvent(event reportEvent) {
 		logging.GetLogger().Debugf("event[release: %+v] is not need to report", event.release.Labels)
 		return
 	}
+	if reporter.disabled {
+		return
+	}
 	reporter.eventChain <- event
 }
 
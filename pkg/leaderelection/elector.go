@@ -0,0 +1,36 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package leaderelection
+
+import "context"
+
+// LeaderElector 多实例部署时只有 leader 同步 apisix 配置
+type LeaderElector interface {
+	// Run 开始竞选, 不会阻塞
+	Run(ctx context.Context)
+	// WaitForLeading 阻塞到成为 leader, 返回的 channel 在失去 leader 后关闭
+	WaitForLeading() <-chan struct{}
+	// Leader 当前 leader 的实例 ID
+	Leader() string
+}
+
+var (
+	_ LeaderElector = (*EtcdLeaderElector)(nil)
+	_ LeaderElector = (*KubeLeaderElector)(nil)
+)
@@ -0,0 +1,175 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package leaderelection
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
+)
+
+const (
+	kubeLeaseDuration = 15 * time.Second
+	kubeRenewDeadline = 10 * time.Second
+	kubeRetryPeriod   = 2 * time.Second
+)
+
+// KubeLeaderElector 使用 kubernetes Lease 选主, 用于 kubernetes 来源, 不依赖 etcd
+type KubeLeaderElector struct {
+	client     kubernetes.Interface
+	namespace  string
+	name       string
+	instanceID string
+
+	// 每一轮任期使用新的 channel, 失去 leader 后重新竞选
+	mux       sync.Mutex
+	leadingCh chan struct{}
+	closeCh   chan struct{}
+	leader    string
+	running   bool
+
+	logger *zap.SugaredLogger
+}
+
+// NewKubeLeaderElector ...
+func NewKubeLeaderElector(client kubernetes.Interface, namespace, name string) (*KubeLeaderElector, error) {
+	return &KubeLeaderElector{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		instanceID: fmt.Sprintf(
+			"%s_%s",
+			config.InstanceName,
+			config.InstanceIP,
+		),
+		leadingCh: make(chan struct{}),
+		closeCh:   make(chan struct{}),
+		logger:    logging.GetLogger().Named("leader-election"),
+	}, nil
+}
+
+// Run ...
+func (ele *KubeLeaderElector) Run(ctx context.Context) {
+	ele.mux.Lock()
+	defer ele.mux.Unlock()
+	if ele.running {
+		return
+	}
+	ele.running = true
+	go ele.run(ctx)
+}
+
+func (ele *KubeLeaderElector) run(ctx context.Context) {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Namespace: ele.namespace,
+			Name:      ele.name,
+		},
+		Client: ele.client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: ele.instanceID,
+		},
+	}
+	for {
+		ele.logger.Infow("Try to be leader", "id", ele.instanceID, "lease", ele.name)
+		elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			LeaseDuration:   kubeLeaseDuration,
+			RenewDeadline:   kubeRenewDeadline,
+			RetryPeriod:     kubeRetryPeriod,
+			ReleaseOnCancel: true,
+			Name:            ele.name,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(context.Context) {
+					ele.logger.Infow("Become leader now", "id", ele.instanceID)
+					ReportLeaderElectionMetric(ele.instanceID)
+					ele.mux.Lock()
+					// OnNewLeader 是异步回调的, 成为 leader 时直接记录
+					ele.leader = ele.instanceID
+					close(ele.leadingCh)
+					ele.mux.Unlock()
+				},
+				OnStoppedLeading: ele.stopLeading,
+				OnNewLeader: func(identity string) {
+					ele.mux.Lock()
+					ele.leader = identity
+					ele.mux.Unlock()
+				},
+			},
+		})
+		if err != nil {
+			// 配置固定, 只有参数错误时才会失败
+			ele.logger.Error(err, "Create lease leader elector failed", "id", ele.instanceID)
+			return
+		}
+		// 阻塞到失去 leader 或者 ctx 结束
+		elector.Run(ctx)
+		if ctx.Err() != nil {
+			ele.mux.Lock()
+			ele.running = false
+			ele.mux.Unlock()
+			return
+		}
+	}
+}
+
+// stopLeading 关闭本轮任期的 closeCh, 并为下一轮竞选准备新的 channel
+func (ele *KubeLeaderElector) stopLeading() {
+	ele.mux.Lock()
+	defer ele.mux.Unlock()
+	select {
+	case <-ele.leadingCh:
+		ele.logger.Infow("Lose leader now", "id", ele.instanceID)
+		close(ele.closeCh)
+		if ele.leader == ele.instanceID {
+			ele.leader = ""
+		}
+		ele.leadingCh = make(chan struct{})
+		ele.closeCh = make(chan struct{})
+	default:
+		// 没有成为过 leader, 继续等待同一轮的 channel
+	}
+}
+
+// Leader ...
+func (ele *KubeLeaderElector) Leader() string {
+	ele.mux.Lock()
+	defer ele.mux.Unlock()
+	return ele.leader
+}
+
+// WaitForLeading ...
+func (ele *KubeLeaderElector) WaitForLeading() (closeCh <-chan struct{}) {
+	ele.mux.Lock()
+	leadingCh, closeCh := ele.leadingCh, ele.closeCh
+	ele.mux.Unlock()
+	<-leadingCh
+	ele.logger.Info("success get leader")
+	return closeCh
+}
@@ -0,0 +1,68 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package leaderelection_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/leaderelection"
+)
+
+var _ = Describe("KubeLeaderElector", func() {
+	It("should elect one leader by the lease and hand over after the leader stops", func() {
+		client := fake.NewSimpleClientset()
+		config.InstanceName = "test-instance1"
+		config.InstanceIP = "127.0.0.1"
+		elector1, err := leaderelection.NewKubeLeaderElector(client, "blueking", "test-lease")
+		Expect(err).To(BeNil())
+		config.InstanceName = "test-instance2"
+		config.InstanceIP = "127.0.0.2"
+		elector2, err := leaderelection.NewKubeLeaderElector(client, "blueking", "test-lease")
+		Expect(err).To(BeNil())
+
+		ctx1, cancel1 := context.WithCancel(context.Background())
+		defer cancel1()
+		elector1.Run(ctx1)
+		closeCh1 := elector1.WaitForLeading()
+		Expect(elector1.Leader()).To(Equal("test-instance1_127.0.0.1"))
+
+		ctx2, cancel2 := context.WithCancel(context.Background())
+		defer cancel2()
+		elector2.Run(ctx2)
+		Eventually(elector2.Leader, 5*time.Second).Should(Equal("test-instance1_127.0.0.1"))
+
+		leading2 := make(chan (<-chan struct{}))
+		go func() {
+			leading2 <- elector2.WaitForLeading()
+		}()
+		Consistently(leading2, 500*time.Millisecond).ShouldNot(Receive())
+
+		// the lease is released on cancel, the other instance takes over
+		cancel1()
+		Eventually(closeCh1, 5*time.Second).Should(BeClosed())
+		Eventually(leading2, 10*time.Second).Should(Receive())
+		Expect(elector2.Leader()).To(Equal("test-instance2_127.0.0.2"))
+	})
+})
@@ -32,6 +32,12 @@ func ReportResourceCountHelper(
 	if apisixStageResource != nil {
 		handler(gateway, stage, "routes", len(apisixStageResource.Routes))
 		handler(gateway, stage, "services", len(apisixStageResource.Services))
+		handler(gateway, stage, "upstreams", len(apisixStageResource.Upstreams))
+		handler(gateway, stage, "plugin_configs", len(apisixStageResource.PluginConfigs))
+		handler(gateway, stage, "consumers", len(apisixStageResource.Consumers))
+		handler(gateway, stage, "consumer_groups", len(apisixStageResource.ConsumerGroups))
 		handler(gateway, stage, "ssls", len(apisixStageResource.SSLs))
+		handler(gateway, stage, "stream_routes", len(apisixStageResource.StreamRoutes))
+		handler(gateway, stage, "protos", len(apisixStageResource.Protos))
 	}
 }
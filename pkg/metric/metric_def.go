@@ -28,6 +28,7 @@ import (
 const (
 	ResultSuccess = "succ"
 	ResultFail    = "fail"
+	ResultPending = "pending"
 	ActionGet     = "get"
 	ActionPut     = "put"
 	ActionList    = "list"
@@ -52,6 +53,20 @@ var (
 	ApisixOperationHistogram      *prometheus.HistogramVec
 	RegistryActionCounter         *prometheus.CounterVec
 	RegistryActionHistogram       *prometheus.HistogramVec
+	DriftResourceGauge            *prometheus.GaugeVec
+	DriftHealCounter              *prometheus.CounterVec
+	OrphanResourceGauge           *prometheus.GaugeVec
+	OrphanCleanupCounter          *prometheus.CounterVec
+	SynchronizerInFlightGauge     *prometheus.GaugeVec
+	SynchronizerQueuedGauge       *prometheus.GaugeVec
+	CommitRetryCounter            *prometheus.CounterVec
+	CommitDeadLetterGauge         *prometheus.GaugeVec
+	StageRollbackCounter          *prometheus.CounterVec
+	TargetSyncCounter             *prometheus.CounterVec
+	TargetPendingGauge            *prometheus.GaugeVec
+	OriginEventCounter            *prometheus.CounterVec
+	OriginStageGauge              *prometheus.GaugeVec
+	OriginConflictGauge           *prometheus.GaugeVec
 )
 
 // InitMetric ...
@@ -153,6 +168,104 @@ func InitMetric(register prometheus.Registerer) {
 		},
 		[]string{"type", "action", "result"},
 	)
+	DriftResourceGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "drift_resource_count",
+			Help: "drift_resource_count describe count of resources differ between apigw and apisix",
+		},
+		[]string{"gateway", "stage", "type"},
+	)
+	DriftHealCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "drift_heal_count",
+			Help: "drift_heal_count describe counts of drift healing process",
+		},
+		[]string{"gateway", "stage", "result"},
+	)
+	OrphanResourceGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "orphan_resource_count",
+			Help: "orphan_resource_count describe count of apisix resources whose stage is not published",
+		},
+		[]string{"gateway", "stage", "type"},
+	)
+	OrphanCleanupCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "orphan_cleanup_count",
+			Help: "orphan_cleanup_count describe counts of orphan stage cleanup process",
+		},
+		[]string{"gateway", "stage", "result"},
+	)
+	SynchronizerInFlightGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "synchronizer_in_flight_count",
+			Help: "synchronizer_in_flight_count describe count of syncs writing to apisix etcd",
+		},
+		[]string{"type"},
+	)
+	SynchronizerQueuedGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "synchronizer_queued_count",
+			Help: "synchronizer_queued_count describe count of syncs waiting for the stage lock or concurrency slot",
+		},
+		[]string{"type"},
+	)
+	CommitRetryCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "commit_retry_count",
+			Help: "commit_retry_count describe counts of failed stage commits scheduled to retry",
+		},
+		[]string{"gateway", "stage"},
+	)
+	CommitDeadLetterGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "commit_dead_letter",
+			Help: "commit_dead_letter describe the stages failed to commit after max retries",
+		},
+		[]string{"gateway", "stage"},
+	)
+	StageRollbackCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stage_rollback_count",
+			Help: "stage_rollback_count describe counts of stages rolled back to a previous snapshot",
+		},
+		[]string{"gateway", "stage", "result"},
+	)
+	TargetSyncCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "target_sync_count",
+			Help: "target_sync_count describe counts of syncs to each apisix etcd target",
+		},
+		[]string{"target", "result"},
+	)
+	TargetPendingGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "target_pending",
+			Help: "target_pending describe the stages waiting to retry on each apisix etcd target",
+		},
+		[]string{"target"},
+	)
+	OriginEventCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "origin_event_count",
+			Help: "origin_event_count describe counts of events watched from each control plane origin",
+		},
+		[]string{"origin", "result"},
+	)
+	OriginStageGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "origin_stage",
+			Help: "origin_stage describe the stages owned by each control plane origin",
+		},
+		[]string{"origin"},
+	)
+	OriginConflictGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "origin_conflict",
+			Help: "origin_conflict describe the stages of each origin refused for being owned by another origin",
+		},
+		[]string{"origin"},
+	)
 
 	register.MustRegister(LeaderElectionGauge)
 	register.MustRegister(ResourceEventTriggeredCounter)
@@ -167,4 +280,18 @@ func InitMetric(register prometheus.Registerer) {
 	register.MustRegister(RegistryActionHistogram)
 	register.MustRegister(SyncCmpCounter)
 	register.MustRegister(SyncCmpDiffCounter)
+	register.MustRegister(DriftResourceGauge)
+	register.MustRegister(DriftHealCounter)
+	register.MustRegister(OrphanResourceGauge)
+	register.MustRegister(OrphanCleanupCounter)
+	register.MustRegister(SynchronizerInFlightGauge)
+	register.MustRegister(SynchronizerQueuedGauge)
+	register.MustRegister(CommitRetryCounter)
+	register.MustRegister(CommitDeadLetterGauge)
+	register.MustRegister(StageRollbackCounter)
+	register.MustRegister(TargetSyncCounter)
+	register.MustRegister(TargetPendingGauge)
+	register.MustRegister(OriginEventCounter)
+	register.MustRegister(OriginStageGauge)
+	register.MustRegister(OriginConflictGauge)
 }
@@ -0,0 +1,61 @@
+// Package metric ...
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package metric ...
+package metric
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// ReportDriftMetric ...
+func ReportDriftMetric(gateway, stage, resType string, count int) {
+	DriftResourceGauge.WithLabelValues(gateway, stage, resType).Set(float64(count))
+}
+
+// DeleteDriftMetric 清理已下线环境的漂移指标
+func DeleteDriftMetric(gateway, stage string) {
+	DriftResourceGauge.DeletePartialMatch(prometheus.Labels{"gateway": gateway, "stage": stage})
+}
+
+// ReportDriftHealMetric ...
+func ReportDriftHealMetric(gateway, stage string, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultFail
+	}
+	DriftHealCounter.WithLabelValues(gateway, stage, result).Inc()
+}
+
+// ResetOrphanMetric ...
+func ResetOrphanMetric() {
+	OrphanResourceGauge.Reset()
+}
+
+// ReportOrphanMetric ...
+func ReportOrphanMetric(gateway, stage, resType string, count int) {
+	OrphanResourceGauge.WithLabelValues(gateway, stage, resType).Set(float64(count))
+}
+
+// ReportOrphanCleanupMetric ...
+func ReportOrphanCleanupMetric(gateway, stage string, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultFail
+	}
+	OrphanCleanupCounter.WithLabelValues(gateway, stage, result).Inc()
+}
@@ -28,3 +28,14 @@ func ReportRegistryAction(resType, action, result string, started time.Time) {
 	RegistryActionHistogram.WithLabelValues(resType, action, result).
 		Observe(float64(time.Since(started).Milliseconds()))
 }
+
+// ReportOriginEvent result 为 ResultSuccess 或 ResultFail, 被拒绝的冲突事件为 ResultFail
+func ReportOriginEvent(origin, result string) {
+	OriginEventCounter.WithLabelValues(origin, result).Inc()
+}
+
+// ReportOriginStatus 控制面拥有的环境数量和被拒绝的冲突环境数量
+func ReportOriginStatus(origin string, stages, conflicts int) {
+	OriginStageGauge.WithLabelValues(origin).Set(float64(stages))
+	OriginConflictGauge.WithLabelValues(origin).Set(float64(conflicts))
+}
@@ -39,11 +39,46 @@ func ReportApisixEtcdMetric(resType, action string, started time.Time, err error
 		Observe(float64(time.Since(started).Milliseconds()))
 }
 
+// SyncTypeStage ...
+const (
+	SyncTypeStage  = "stage"
+	SyncTypeGlobal = "global"
+)
+
+// ReportSyncQueuedMetric 等待执行的同步数量变化
+func ReportSyncQueuedMetric(syncType string, delta int) {
+	SynchronizerQueuedGauge.WithLabelValues(syncType).Add(float64(delta))
+}
+
+// ReportSyncInFlightMetric 正在写入 apisix etcd 的同步数量变化
+func ReportSyncInFlightMetric(syncType string, delta int) {
+	SynchronizerInFlightGauge.WithLabelValues(syncType).Add(float64(delta))
+}
+
 // ReportStageConfigSyncMetric ...
 func ReportStageConfigSyncMetric(gateway, stage string) {
 	SynchronizerEventCounter.WithLabelValues(gateway, stage).Inc()
 }
 
+// ReportStageRollbackMetric ...
+func ReportStageRollbackMetric(gateway, stage string, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultFail
+	}
+	StageRollbackCounter.WithLabelValues(gateway, stage, result).Inc()
+}
+
+// ReportTargetSyncMetric result 为 ResultSuccess, ResultFail 或 ResultPending
+func ReportTargetSyncMetric(target, result string) {
+	TargetSyncCounter.WithLabelValues(target, result).Inc()
+}
+
+// ReportTargetPendingMetric 等待重试的环境数量
+func ReportTargetPendingMetric(target string, count int) {
+	TargetPendingGauge.WithLabelValues(target).Set(float64(count))
+}
+
 // ReportStageConfigAlterMetric ...
 func ReportStageConfigAlterMetric(
 	stageKey string,
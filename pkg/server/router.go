@@ -25,19 +25,26 @@ import (
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/apis/open"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/archive"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/committer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/reconciler"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/leaderelection"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils"
 )
 
 // NewRouter do the router initialization
 func NewRouter(
-	leaderElector *leaderelection.EtcdLeaderElector,
-	registry *registry.APIGWEtcdRegistry,
+	leaderElector leaderelection.LeaderElector,
+	registry registry.APIGWRegistry,
 	committer *committer.Committer,
-	apiSixConfStore *store.ApisixEtcdStore,
+	apiSixConfStore store.ApisixStore,
+	orphanCollector *reconciler.OrphanCollector,
+	shadowReporter *store.ShadowReporter,
+	synchronizer *synchronizer.ApisixConfigSynchronizer,
+	archiver *archive.Archiver,
 	router *gin.Engine,
 	conf *config.Config,
 ) *gin.Engine {
@@ -52,6 +59,9 @@ func NewRouter(
 		constant.ApiAuthAccount: conf.HttpServer.AuthPassword,
 	}))
 	operatorRouter.Use(gin.Recovery())
-	open.Register(operatorRouter, leaderElector, registry, committer, apiSixConfStore)
+	open.Register(
+		operatorRouter, leaderElector, registry, committer, apiSixConfStore, orphanCollector,
+		shadowReporter, synchronizer, archiver,
+	)
 	return router
 }
@@ -31,19 +31,26 @@ import (
 
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/config"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/archive"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/committer"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/reconciler"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/registry"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/store"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/core/synchronizer"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/leaderelection"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/logging"
 )
 
 // Server ...
 type Server struct {
-	LeaderElector     *leaderelection.EtcdLeaderElector
-	apigwEtcdRegistry *registry.APIGWEtcdRegistry
+	LeaderElector     leaderelection.LeaderElector
+	apigwEtcdRegistry registry.APIGWRegistry
 	committer         *committer.Committer
-	apisixEtcdStore   *store.ApisixEtcdStore
+	apisixEtcdStore   store.ApisixStore
+	orphanCollector   *reconciler.OrphanCollector
+	shadowReporter    *store.ShadowReporter
+	synchronizer      *synchronizer.ApisixConfigSynchronizer
+	archiver          *archive.Archiver
 
 	mux *gin.Engine
 
@@ -52,16 +59,24 @@ type Server struct {
 
 // NewServer ...
 func NewServer(
-	leaderElector *leaderelection.EtcdLeaderElector,
-	apigwEtcdRegistry *registry.APIGWEtcdRegistry,
-	apisixEtcdStore *store.ApisixEtcdStore,
+	leaderElector leaderelection.LeaderElector,
+	apigwEtcdRegistry registry.APIGWRegistry,
+	apisixEtcdStore store.ApisixStore,
 	committer *committer.Committer,
+	orphanCollector *reconciler.OrphanCollector,
+	shadowReporter *store.ShadowReporter,
+	synchronizer *synchronizer.ApisixConfigSynchronizer,
+	archiver *archive.Archiver,
 ) *Server {
 	return &Server{
 		LeaderElector:     leaderElector,
 		apigwEtcdRegistry: apigwEtcdRegistry,
 		apisixEtcdStore:   apisixEtcdStore,
 		committer:         committer,
+		orphanCollector:   orphanCollector,
+		shadowReporter:    shadowReporter,
+		synchronizer:      synchronizer,
+		archiver:          archiver,
 		logger:            logging.GetLogger().Named("server"),
 		mux:               gin.Default(),
 	}
@@ -78,7 +93,18 @@ func (s *Server) RegisterMetric(gatherer prometheus.Gatherer) {
 
 // Run ...
 func (s *Server) Run(ctx context.Context, config *config.Config) error {
-	router := NewRouter(s.LeaderElector, s.apigwEtcdRegistry, s.committer, s.apisixEtcdStore, s.mux, config)
+	router := NewRouter(
+		s.LeaderElector,
+		s.apigwEtcdRegistry,
+		s.committer,
+		s.apisixEtcdStore,
+		s.orphanCollector,
+		s.shadowReporter,
+		s.synchronizer,
+		s.archiver,
+		s.mux,
+		config,
+	)
 	// run http server
 	var addr, addrv6 string
 	if config.HttpServer.BindAddressV6 != "" {
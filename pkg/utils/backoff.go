@@ -0,0 +1,40 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package utils
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff 指数退避, 从 BaseDelay 开始每次翻倍, 不超过 MaxDelay
+type Backoff struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Delay 第 attempt 次重试前的等待时间, 在 [d/2, d] 之间随机抖动, 避免大量任务同时重试
+func (b Backoff) Delay(attempt int64) time.Duration {
+	delay := b.BaseDelay
+	for i := int64(1); i < attempt && delay < b.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, b.MaxDelay)
+	return delay/2 + rand.N(delay/2+1)
+}
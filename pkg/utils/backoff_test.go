@@ -0,0 +1,38 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := utils.Backoff{BaseDelay: 2 * time.Second, MaxDelay: 5 * time.Minute}
+	for attempt := int64(1); attempt <= 20; attempt++ {
+		expected := min(backoff.BaseDelay*time.Duration(1<<min(attempt-1, 20)), backoff.MaxDelay)
+		delay := backoff.Delay(attempt)
+		assert.GreaterOrEqual(t, delay, expected/2, "attempt %d", attempt)
+		assert.LessOrEqual(t, delay, expected, "attempt %d", attempt)
+	}
+}
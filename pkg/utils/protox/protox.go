@@ -0,0 +1,80 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package protox ...
+package protox
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// protoFileName 编译时使用的文件名, 只用于错误信息
+const protoFileName = "proto_content.proto"
+
+// Compile 校验 apisix proto 资源的 content, 与 grpc-transcode 插件一致,
+// content 可以是 .proto 文件的内容, 也可以是 base64 编码的 .pb 文件 (FileDescriptorSet);
+// .proto 文件中只能 import google/protobuf 下的标准文件
+func Compile(content string) error {
+	if content == "" {
+		return errors.New("proto 内容为空")
+	}
+	err := compileSource(content)
+	if err == nil {
+		return nil
+	}
+	if descriptorErr := parseDescriptorSet(content); descriptorErr == nil {
+		return nil
+	}
+	return fmt.Errorf("proto 编译失败: %w", err)
+}
+
+// compileSource 编译 .proto 文件的内容
+func compileSource(content string) error {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{protoFileName: content}),
+		}),
+	}
+	_, err := compiler.Compile(context.Background(), protoFileName)
+	return err
+}
+
+// parseDescriptorSet 解析 base64 编码的 FileDescriptorSet, 并校验其中的文件可以互相链接
+func parseDescriptorSet(content string) error {
+	raw, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return err
+	}
+	var descriptorSet descriptorpb.FileDescriptorSet
+	if err = proto.Unmarshal(raw, &descriptorSet); err != nil {
+		return err
+	}
+	if len(descriptorSet.GetFile()) == 0 {
+		return errors.New("no file in descriptor set")
+	}
+	_, err = protodesc.NewFiles(&descriptorSet)
+	return err
+}
@@ -0,0 +1,80 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+// Package redactx 提供资源配置脱敏相关工具, 用于归档导出和开放接口输出
+package redactx
+
+import "strings"
+
+// Mask 脱敏后的字段值
+const Mask = "******"
+
+// secretFieldKeywords 插件配置中字段名包含这些关键字的字符串字段会被脱敏
+var secretFieldKeywords = []string{"secret", "password", "passwd", "token", "private_key", "access_key"}
+
+// consumerCredentialFields consumer 认证插件中保存凭证但字段名不含敏感关键字的字段, 如 key-auth 的 key
+var consumerCredentialFields = []string{"key"}
+
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, keyword := range secretFieldKeywords {
+		if strings.Contains(name, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// Plugins 脱敏插件配置中的敏感字段
+func Plugins(plugins map[string]any) {
+	for _, conf := range plugins {
+		Value(conf)
+	}
+}
+
+// ConsumerPlugins 脱敏 consumer 插件中的凭证, 包括字段名不含敏感关键字的凭证字段
+func ConsumerPlugins(plugins map[string]any) {
+	for _, conf := range plugins {
+		if fields, ok := conf.(map[string]any); ok {
+			for _, name := range consumerCredentialFields {
+				if _, ok := fields[name].(string); ok {
+					fields[name] = Mask
+				}
+			}
+		}
+		Value(conf)
+	}
+}
+
+// Value 递归脱敏 map 和 slice 中的敏感字段
+func Value(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if _, ok := item.(string); ok && isSecretField(key) {
+				v[key] = Mask
+				continue
+			}
+			Value(item)
+		}
+	case []any:
+		for _, item := range v {
+			Value(item)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making
+ * 蓝鲸智云 - API 网关(BlueKing - APIGateway) available.
+ * Copyright (C) 2025 Tencent. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ *     http://opensource.org/licenses/MIT
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+package redactx_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils/redactx"
+)
+
+func TestPlugins(t *testing.T) {
+	plugins := map[string]any{
+		"jwt-auth": map[string]any{
+			"secret":  "s3cret",
+			"exp":     float64(86400),
+			"servers": []any{map[string]any{"Access_Key": "ak", "host": "127.0.0.1"}},
+		},
+		"key-auth": map[string]any{"key": "k"},
+	}
+	redactx.Plugins(plugins)
+
+	jwt := plugins["jwt-auth"].(map[string]any)
+	assert.Equal(t, redactx.Mask, jwt["secret"])
+	assert.Equal(t, float64(86400), jwt["exp"])
+	server := jwt["servers"].([]any)[0].(map[string]any)
+	assert.Equal(t, redactx.Mask, server["Access_Key"])
+	assert.Equal(t, "127.0.0.1", server["host"])
+	// 非 consumer 插件中的 key 不是凭证
+	assert.Equal(t, "k", plugins["key-auth"].(map[string]any)["key"])
+}
+
+func TestConsumerPlugins(t *testing.T) {
+	plugins := map[string]any{
+		"key-auth":   map[string]any{"key": "k"},
+		"basic-auth": map[string]any{"username": "admin", "password": "p"},
+	}
+	redactx.ConsumerPlugins(plugins)
+
+	assert.Equal(t, redactx.Mask, plugins["key-auth"].(map[string]any)["key"])
+	assert.Equal(t, redactx.Mask, plugins["basic-auth"].(map[string]any)["password"])
+	assert.Equal(t, "admin", plugins["basic-auth"].(map[string]any)["username"])
+}
@@ -33,6 +33,7 @@ import (
 
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/constant"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/entity"
+	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils/protox"
 	"github.com/TencentBlueKing/blueking-apigateway-operator/pkg/utils/sslx"
 )
 
@@ -384,6 +385,10 @@ func (v *APISIXJsonSchemaValidator) checkConf(reqBody any) error {
 		if err != nil {
 			return err
 		}
+	case *entity.Proto:
+		if err := protox.Compile(bodyType.Content); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -436,6 +441,9 @@ func (v *APISIXJsonSchemaValidator) Validate(rawConfig json.RawMessage) error {
 	case constant.StreamRoute:
 		obj = &entity.StreamRoute{}
 		_ = json.Unmarshal(rawConfig, obj)
+	case constant.Proto:
+		obj = &entity.Proto{}
+		_ = json.Unmarshal(rawConfig, obj)
 	}
 	if err := v.checkConf(obj); err != nil {
 		return err
@@ -888,6 +888,26 @@ func TestAPISIXJsonSchemaValidatorValidate(t *testing.T) {
             }`,
 			shouldFail: true,
 		},
+		{
+			name:     "Valid Proto",
+			resource: constant.Proto,
+			jsonPath: "main.proto",
+			config: `{
+              "id": "gw.stage.helloworld",
+              "content": "syntax = \"proto3\"; package helloworld; message HelloRequest { string name = 1; }"
+            }`,
+			shouldFail: false,
+		},
+		{
+			name:     "Invalid Proto content",
+			resource: constant.Proto,
+			jsonPath: "main.proto",
+			config: `{
+              "id": "gw.stage.helloworld",
+              "content": "syntax = \"proto3\"; message HelloRequest { Unknown name = 1; }"
+            }`,
+			shouldFail: true,
+		},
 	}
 
 	for _, version := range APISIXVersionList {
@@ -22,6 +22,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
@@ -39,15 +40,21 @@ import (
 )
 
 const (
-	testGateway           = "bk-default"
-	testStage             = "default"
-	testDataServiceAmount = 1
-	testDataRoutesAmount  = 3
-	operatorURL           = "http://127.0.0.1:6004"
-	publishID             = 1
-	delPublishID          = 2
-	delRouteKey           = "/bk-gateway-apigw/v2/gateway/bk-default/default/route/bk-default.default.2"
-	versionProbeRouteKey  = "/bk-gateway-apigw/v2/gateway/bk-default/default/route/bk-default.default.-1"
+	testGateway             = "bk-default"
+	testStage               = "default"
+	testDataServiceAmount   = 1
+	testDataRoutesAmount    = 3
+	operatorURL             = "http://127.0.0.1:6004"
+	publishID               = 1
+	delPublishID            = 2
+	delRouteKey             = "/bk-gateway-apigw/v2/gateway/bk-default/default/route/bk-default.default.2"
+	versionProbeRouteKey    = "/bk-gateway-apigw/v2/gateway/bk-default/default/route/bk-default.default.-1"
+	sslKey                  = "/bk-gateway-apigw/v2/gateway/bk-default/default/ssl/bk-default.default.1"
+	apisixSSLKey            = "/bk-gateway-apisix/ssls/bk-default.default.1"
+	sslHost                 = "bk-default.example.com"
+	pluginMetadataName      = "bk-concurrency-limit"
+	stagePluginMetadataKey  = "/bk-gateway-apigw/v2/gateway/bk-default/default/plugin_metadata/bk-concurrency-limit"
+	apisixPluginMetadataKey = "/bk-gateway-apisix/plugin_metadata/bk-concurrency-limit"
 )
 
 var stageKey = config.GenStagePrimaryKey(testGateway, testStage)
@@ -280,4 +287,120 @@ var _ = Describe("Operator Integration", func() {
 			})
 		})
 	})
+
+	Describe("test stage scoped resources", func() {
+		stageLabels := map[string]any{
+			"gateway.bk.tencent.com/gateway":        testGateway,
+			"gateway.bk.tencent.com/stage":          testStage,
+			"gateway.bk.tencent.com/apisix-version": "3.13",
+		}
+
+		// publish 发布默认环境的资源和全局资源
+		publish := func(id int) {
+			resources := integration.GetBkDefaultResource()
+			for key, route := range resources.Routes {
+				route.Labels.PublishId = fmt.Sprintf("%d", id)
+				rawConfig, _ := json.Marshal(route)
+				_, err := etcdCli.Put(context.Background(), key, string(rawConfig))
+				Expect(err).NotTo(HaveOccurred())
+			}
+			for key, service := range resources.Services {
+				service.Labels.PublishId = fmt.Sprintf("%d", id)
+				rawConfig, _ := json.Marshal(service)
+				_, err := etcdCli.Put(context.Background(), key, string(rawConfig))
+				Expect(err).NotTo(HaveOccurred())
+			}
+			globalResource := integration.GetBkDefaultGlobalResource()
+			for key, pluginMetadata := range globalResource.PluginMetadata {
+				rawConfig, _ := json.Marshal(pluginMetadata)
+				_, err := etcdCli.Put(context.Background(), key, string(rawConfig))
+				Expect(err).NotTo(HaveOccurred())
+			}
+			for key, release := range integration.GetBkDefaultStageRelease() {
+				release.PublishId = id
+				release.Labels.PublishId = fmt.Sprintf("%d", id)
+				rawConfig, _ := json.Marshal(release)
+				_, err := etcdCli.Put(context.Background(), key, string(rawConfig))
+				Expect(err).NotTo(HaveOccurred())
+			}
+		}
+
+		// getApisixValue 读取写入 apisix 的配置
+		getApisixValue := func(key string) map[string]any {
+			resp, err := etcdCli.Get(context.Background(), key)
+			Expect(err).NotTo(HaveOccurred())
+			if len(resp.Kvs) == 0 {
+				return nil
+			}
+			var value map[string]any
+			Expect(json.Unmarshal(resp.Kvs[0].Value, &value)).To(Succeed())
+			return value
+		}
+
+		putSSL := func() string {
+			cert, key, err := integration.GenerateCertificate(sslHost)
+			Expect(err).NotTo(HaveOccurred())
+			rawConfig, _ := json.Marshal(map[string]any{
+				"id":     "bk-default.default.1",
+				"cert":   cert,
+				"key":    key,
+				"snis":   []string{sslHost},
+				"labels": stageLabels,
+			})
+			_, err = etcdCli.Put(context.Background(), sslKey, string(rawConfig))
+			Expect(err).NotTo(HaveOccurred())
+			return cert
+		}
+
+		Context("test ssl rotation", func() {
+			It("should sync the stage when only the certificate changes", func() {
+				publish(publishID)
+				cert := putSSL()
+				time.Sleep(time.Second * 30)
+
+				Expect(getApisixValue(apisixSSLKey)).To(HaveKeyWithValue("cert", cert))
+
+				// 证书轮换时只写入 ssl, 没有新的发布
+				rotatedCert := putSSL()
+				Expect(rotatedCert).NotTo(Equal(cert))
+				time.Sleep(time.Second * 30)
+
+				metricsAdapter, err := integration.NewMetricsAdapter(operatorURL)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(metricsAdapter.GetResourceEventTriggeredCountMetric(
+					testGateway, testStage, constant.SSL.String()),
+				).To(BeNumerically(">=", 2))
+				Expect(getApisixValue(apisixSSLKey)).To(HaveKeyWithValue("cert", rotatedCert))
+			})
+		})
+
+		Context("test stage plugin metadata", func() {
+			It("should override the global plugin metadata and restore it after deleted", func() {
+				publish(publishID)
+				time.Sleep(time.Second * 30)
+
+				globalValue := getApisixValue(apisixPluginMetadataKey)
+				Expect(globalValue).To(HaveKeyWithValue("conn", float64(2001)))
+
+				// 环境级别的插件元数据优先于全局的配置
+				stageValue := maps.Clone(globalValue)
+				stageValue["conn"] = 100
+				stageValue["labels"] = stageLabels
+				rawConfig, _ := json.Marshal(stageValue)
+				_, err := etcdCli.Put(context.Background(), stagePluginMetadataKey, string(rawConfig))
+				Expect(err).NotTo(HaveOccurred())
+				time.Sleep(time.Second * 30)
+
+				value := getApisixValue(apisixPluginMetadataKey)
+				Expect(value).To(HaveKeyWithValue("conn", float64(100)))
+				Expect(value).NotTo(HaveKey("labels"))
+
+				_, err = etcdCli.Delete(context.Background(), stagePluginMetadataKey)
+				Expect(err).NotTo(HaveOccurred())
+				time.Sleep(time.Second * 30)
+
+				Expect(getApisixValue(apisixPluginMetadataKey)).To(HaveKeyWithValue("conn", float64(2001)))
+			})
+		})
+	})
 })